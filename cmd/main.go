@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"hardhw/config"
 	"hardhw/internal/api"
+	"hardhw/internal/blob"
+	"hardhw/internal/markdown"
 	"hardhw/internal/routes"
 	"hardhw/internal/service"
 	"hardhw/internal/storage"
+	"hardhw/internal/storage/memory"
 	"log"
 	"os"
 )
@@ -18,30 +22,139 @@ func main() {
 	// 	log.Fatalf("Ошибка при загрузкие .env файла")
 	// }
 
-	ctx := context.Background()
-	dbPool, err := config.New(ctx, os.Getenv("PG_DSN"))
+	storageKind := flag.String("storage", "postgres", "хранилище данных: postgres или memory")
+	migrateOnStart := flag.Bool("migrate", true, "применять миграции схемы при запуске")
+	benchmarkMode := flag.Bool("benchmark", config.NewBenchmarkMode(), "режим бенчмарка: изменяющие запросы не требуют авторизации")
+	flag.Parse()
+
+	var (
+		userStorage         storage.UserStorage
+		forumStorage        storage.ForumStorage
+		threadStorage       storage.ThreadStorage
+		postStorage         storage.PostStorage
+		authStorage         storage.AuthStorage
+		moderationStorage   storage.ModerationStorage
+		searchStorage       storage.SearchStorage
+		webhookStorage      storage.WebhookStorage
+		notificationStorage storage.NotificationStorage
+		attachmentStorage   storage.AttachmentStorage
+		conversationStorage storage.ConversationStorage
+		eventListener       storage.EventListener
+		eventPublisher      storage.EventPublisher
+	)
+
+	switch *storageKind {
+	case "postgres":
+		ctx := context.Background()
+		dbPool, err := config.New(ctx, os.Getenv("PG_DSN"))
+		if err != nil {
+			log.Fatalf("Ошибка подключения к базе данных: %v", err)
+		}
+		defer dbPool.Close()
+
+		if flag.Arg(0) == "migrate" {
+			if err := runMigrate(ctx, dbPool, flag.Args()[1:]); err != nil {
+				log.Fatalf("Ошибка миграции: %v", err)
+			}
+			return
+		}
+
+		if *migrateOnStart {
+			if err := applyMigrations(ctx, dbPool); err != nil {
+				log.Fatalf("Ошибка применения миграций: %v", err)
+			}
+		}
+
+		userStorage = storage.NewPostgresUserStorage(dbPool)
+		forumStorage = storage.NewPostgresForumStorage(dbPool)
+		threadStorage = storage.NewPostgresThreadStorage(dbPool)
+		postStorage = storage.NewPostgresPostStorage(dbPool)
+		authStorage = storage.NewPostgresAuthStorage(dbPool)
+		moderationStorage = storage.NewPostgresModerationStorage(dbPool)
+		searchStorage = storage.NewPostgresSearchStorage(dbPool)
+		webhookStorage = storage.NewPostgresWebhookStorage(dbPool)
+		notificationStorage = storage.NewPostgresNotificationStorage(dbPool)
+		attachmentStorage = storage.NewPostgresAttachmentStorage(dbPool)
+		conversationStorage = storage.NewPostgresConversationStorage(dbPool)
+		eventListener = storage.NewPostgresEventListener(dbPool)
+		eventPublisher = storage.NewPostgresEventPublisher(dbPool)
+	case "memory":
+		if flag.Arg(0) == "migrate" {
+			log.Fatalf("миграции доступны только для хранилища postgres")
+		}
+
+		db := memory.New()
+
+		userStorage = memory.NewUserStorage(db)
+		forumStorage = memory.NewForumStorage(db)
+		threadStorage = memory.NewThreadStorage(db)
+		postStorage = memory.NewPostStorage(db)
+		authStorage = memory.NewAuthStorage(db)
+		moderationStorage = memory.NewModerationStorage(db)
+		searchStorage = memory.NewSearchStorage(db)
+		webhookStorage = memory.NewWebhookStorage(db)
+		notificationStorage = memory.NewNotificationStorage(db)
+		attachmentStorage = memory.NewAttachmentStorage(db)
+		conversationStorage = memory.NewConversationStorage(db)
+		eventListener = memory.NewEventListener(db)
+		eventPublisher = memory.NewEventPublisher(db)
+	default:
+		log.Fatalf("неизвестное хранилище: %s", *storageKind)
+	}
+
+	blobStore, err := blob.NewLocalStore(config.NewBlobDir())
 	if err != nil {
-		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+		log.Fatalf("не удалось открыть хранилище файлов: %v", err)
 	}
-	defer dbPool.Close()
 
-	userStorage := storage.NewPostgresUserStorage(dbPool)
-	userService := service.NewUserService(userStorage)
+	userService := service.NewUserService(userStorage, moderationStorage, blobStore, *benchmarkMode)
 	userHandler := api.NewUserHandler(userService)
 
-	forumStorage := storage.NewPostgresForumStorage(dbPool)
-	forumService := service.NewForumService(forumStorage, userStorage)
+	forumService := service.NewForumService(forumStorage, userStorage, moderationStorage, *benchmarkMode)
 	forumHandler := api.NewForumHandler(forumService)
 
-	threadStorage := storage.NewPostgresThreadStorage(dbPool)
-	threadService := service.NewThreadService(forumStorage, userStorage, threadStorage)
-	threadHandler := api.NewThreadHandler(threadService)
+	// Кеш отрисованного Markdown общий для постов и веток.
+	renderer := markdown.NewCache(config.NewMarkdownCacheSize())
+
+	threadService := service.NewThreadService(forumStorage, userStorage, threadStorage, moderationStorage, eventPublisher, *benchmarkMode)
+	threadHandler := api.NewThreadHandler(threadService, renderer)
+
+	postService := service.NewPostService(forumStorage, userStorage, threadStorage, postStorage, moderationStorage, eventPublisher, blobStore, config.NewPostReactions(), *benchmarkMode)
+	postHandler := api.NewPostHandler(postService, renderer)
+
+	authService := service.NewAuthService(authStorage, moderationStorage, *benchmarkMode)
+	authHandler := api.NewAuthHandler(authService, *benchmarkMode)
+
+	moderationService := service.NewModerationService(forumStorage, userStorage, moderationStorage, *benchmarkMode)
+	moderationHandler := api.NewModerationHandler(moderationService)
+
+	searchService := service.NewSearchService(forumStorage, threadStorage, searchStorage)
+	searchHandler := api.NewSearchHandler(searchService)
+
+	streamService := service.NewStreamService(threadStorage, postStorage, eventListener)
+	streamHandler := api.NewStreamHandler(streamService, config.NewWebSocketOrigins())
+	go streamService.Run(context.Background())
+
+	webhookService := service.NewWebhookService(forumStorage, moderationStorage, webhookStorage, *benchmarkMode)
+	webhookHandler := api.NewWebhookHandler(webhookService)
+	go webhookService.Run(context.Background())
+
+	notificationService := service.NewNotificationService(forumStorage, userStorage, threadStorage, moderationStorage, notificationStorage, *benchmarkMode)
+	notificationHandler := api.NewNotificationHandler(notificationService)
+	go notificationService.Run(context.Background())
+
+	maxUploadSize, uploadQuota := config.NewUploadLimits()
+	attachmentService := service.NewAttachmentService(userStorage, threadStorage, postStorage, moderationStorage, attachmentStorage, blobStore, maxUploadSize, uploadQuota, *benchmarkMode)
+	attachmentHandler := api.NewAttachmentHandler(attachmentService, maxUploadSize)
+
+	maxAvatarSize := config.NewAvatarMaxSize()
+	avatarService := service.NewAvatarService(userStorage, moderationStorage, blobStore, maxAvatarSize, *benchmarkMode)
+	avatarHandler := api.NewAvatarHandler(avatarService, maxAvatarSize)
 
-	postStorage := storage.NewPostgresPostStorage(dbPool)
-	postService := service.NewPostService(forumStorage, userStorage, threadStorage, postStorage)
-	postHandler := api.NewPostHandler(postService)
+	conversationService := service.NewConversationService(userStorage, moderationStorage, conversationStorage, *benchmarkMode)
+	conversationHandler := api.NewConversationHandler(conversationService)
 
-	router := routes.InitRoutes(userHandler, forumHandler, threadHandler, postHandler)
+	router := routes.InitRoutes(userHandler, forumHandler, threadHandler, postHandler, authHandler, moderationHandler, searchHandler, streamHandler, webhookHandler, notificationHandler, attachmentHandler, avatarHandler, conversationHandler, config.NewAdminToken())
 
 	address, err := config.NewServerAddress()
 	if err != nil {
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"hardhw/internal/migrate"
+	"hardhw/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrateUsage берёт имя из os.Args[0], чтобы подсказка совпадала с тем, как назван бинарник
+// (в Docker-образе это forum-server).
+func migrateUsage() string {
+	return fmt.Sprintf("использование: %s migrate up|down [шаги]|status", filepath.Base(os.Args[0]))
+}
+
+func runMigrate(ctx context.Context, pool *pgxpool.Pool, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage())
+	}
+
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("применена миграция %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Printf("схема уже актуальна")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("некорректное число шагов: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("откачена миграция %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "не применена"
+			if s.AppliedAt != nil {
+				state = "применена " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		return errors.New(migrateUsage())
+	}
+
+	return nil
+}
+
+func applyMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	migrator, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		log.Printf("Применена миграция %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
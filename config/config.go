@@ -4,8 +4,23 @@ import (
 	"errors"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 )
 
+// defaultMarkdownCacheSize — сколько отрисованных сообщений держит кеш, если MARKDOWN_CACHE_SIZE не задан.
+const defaultMarkdownCacheSize = 10000
+
+const (
+	defaultBlobDir       = "data/blobs"
+	defaultUploadMaxSize = 10 << 20
+	defaultUploadQuota   = 100 << 20
+	defaultAvatarMaxSize = 5 << 20
+)
+
+// defaultPostReactions — набор эмодзи-реакций на посты, если POST_REACTIONS не задан.
+var defaultPostReactions = []string{"👍", "❤️", "😂", "🎉", "😮", "😢"}
+
 func NewServerAddress() (string, error) {
 	host := os.Getenv("HTTP_HOST")
 	if len(host) == 0 {
@@ -21,3 +36,75 @@ func NewServerAddress() (string, error) {
 
 	return addres, nil
 }
+
+func NewAdminToken() string {
+	return os.Getenv("ADMIN_TOKEN")
+}
+
+func NewBenchmarkMode() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("BENCHMARK_MODE"))
+	return err == nil && enabled
+}
+
+// NewPostReactions возвращает разрешённые эмодзи-реакции из POST_REACTIONS (через запятую).
+func NewPostReactions() []string {
+	value := os.Getenv("POST_REACTIONS")
+	if value == "" {
+		return defaultPostReactions
+	}
+	reactions := make([]string, 0)
+	for _, reaction := range strings.Split(value, ",") {
+		if reaction = strings.TrimSpace(reaction); reaction != "" {
+			reactions = append(reactions, reaction)
+		}
+	}
+	return reactions
+}
+
+// NewWebSocketOrigins возвращает дополнительные разрешённые Origin для WebSocket из
+// WS_ALLOWED_ORIGINS (через запятую, например https://forum.example.com).
+func NewWebSocketOrigins() []string {
+	origins := make([]string, 0)
+	for _, origin := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// NewMarkdownCacheSize возвращает размер кеша отрисованного Markdown из MARKDOWN_CACHE_SIZE; 0 отключает кеш.
+func NewMarkdownCacheSize() int {
+	size, err := strconv.Atoi(os.Getenv("MARKDOWN_CACHE_SIZE"))
+	if err != nil || size < 0 {
+		return defaultMarkdownCacheSize
+	}
+	return size
+}
+
+// NewBlobDir возвращает каталог для загруженных файлов из BLOB_DIR.
+func NewBlobDir() string {
+	if dir := os.Getenv("BLOB_DIR"); dir != "" {
+		return dir
+	}
+	return defaultBlobDir
+}
+
+// NewUploadLimits возвращает наибольший размер одного файла (UPLOAD_MAX_SIZE) и квоту
+// на все файлы пользователя (UPLOAD_QUOTA) в байтах.
+func NewUploadLimits() (maxSize int64, quota int64) {
+	return positiveInt64Env("UPLOAD_MAX_SIZE", defaultUploadMaxSize), positiveInt64Env("UPLOAD_QUOTA", defaultUploadQuota)
+}
+
+// NewAvatarMaxSize возвращает наибольший размер загружаемой аватарки в байтах (AVATAR_MAX_SIZE).
+func NewAvatarMaxSize() int64 {
+	return positiveInt64Env("AVATAR_MAX_SIZE", defaultAvatarMaxSize)
+}
+
+func positiveInt64Env(name string, fallback int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead — запас на заголовки и поля формы сверх самого файла.
+const multipartOverhead = 64 << 10
+
+type AttachmentHandler struct {
+	attachmentService service.AttachmentService
+	maxUploadSize     int64
+}
+
+func NewAttachmentHandler(s service.AttachmentService, maxUploadSize int64) *AttachmentHandler {
+	return &AttachmentHandler{attachmentService: s, maxUploadSize: maxUploadSize}
+}
+
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "File is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Multipart form with a 'file' field is required"})
+		return
+	}
+	nickname := c.PostForm("nickname")
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded file of %s: %v", nickname, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(c.Request.Context(), nickname, fileHeader.Filename, fileHeader.Size, file)
+	if err != nil {
+		switch err {
+		case models.ErrOwnerNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't upload files on behalf of another user"})
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+		case models.ErrInvalidAttachment:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "File is empty"})
+		case models.ErrAttachmentTooLarge:
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "File is too large"})
+		case models.ErrQuotaExceeded:
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "Upload quota exceeded"})
+		default:
+			log.Printf("Error uploading file of %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, body, err := h.attachmentService.GetAttachment(c.Request.Context(), id)
+	if err != nil {
+		h.writeAttachmentError(c, id, err)
+		return
+	}
+	defer body.Close()
+
+	// Браузер показывает сам только картинки, остальное скачивается и не исполняется в контексте сайта.
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}),
+	})
+}
+
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid attachment ID"})
+		return
+	}
+
+	if err := h.attachmentService.DeleteAttachment(c.Request.Context(), id); err != nil {
+		h.writeAttachmentError(c, id, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *AttachmentHandler) writeAttachmentError(c *gin.Context, id int64, err error) {
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Can't find attachment with id: " + strconv.FormatInt(id, 10)})
+	case models.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"message": "Only the owner can access an unattached file"})
+	case models.ErrAttachmentBound:
+		c.JSON(http.StatusConflict, gin.H{"message": "File is attached to a post"})
+	default:
+		log.Printf("Error handling attachment %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	}
+}
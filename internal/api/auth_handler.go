@@ -0,0 +1,223 @@
+package api
+
+import (
+	"errors"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	authService   service.AuthService
+	benchmarkMode bool
+}
+
+func NewAuthHandler(s service.AuthService, benchmarkMode bool) *AuthHandler {
+	return &AuthHandler{authService: s, benchmarkMode: benchmarkMode}
+}
+
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Authenticate определяет вызывающего по токену и кладёт его никнейм в контекст запроса.
+func (h *AuthHandler) Authenticate(c *gin.Context) {
+	token := bearerToken(c)
+	if token == "" {
+		c.Next()
+		return
+	}
+
+	nickname, err := h.authService.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, models.ErrUnauthorized) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+			return
+		}
+		log.Printf("Error authenticating request: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.Request = c.Request.WithContext(auth.WithCaller(c.Request.Context(), nickname))
+	c.Next()
+}
+
+// RequireCaller закрывает изменяющие эндпоинты для анонимных запросов.
+func (h *AuthHandler) RequireCaller(c *gin.Context) {
+	if _, ok := auth.CallerFromContext(c.Request.Context()); !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+		return
+	}
+	c.Next()
+}
+
+// RequireBaselineCaller — RequireCaller для базовых эндпоинтов нагрузочного теста:
+// в режиме бенчмарка анонимные запросы к ним пропускаются, как и до появления авторизации.
+func (h *AuthHandler) RequireBaselineCaller(c *gin.Context) {
+	if h.benchmarkMode {
+		c.Next()
+		return
+	}
+	h.RequireCaller(c)
+}
+
+// RequireAdmin закрывает эндпоинт заголовком X-Admin-Token. В режиме бенчмарка открыт
+// только POST /service/clear, которым нагрузочный тест очищает базу перед наполнением.
+func (h *AuthHandler) RequireAdmin(token string) gin.HandlerFunc {
+	if h.benchmarkMode {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return RequireAdminToken(token)
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var credentials models.Credentials
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	token, err := h.authService.Login(c.Request.Context(), credentials)
+	if err != nil {
+		switch err {
+		case models.ErrInvalidCredentials:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid nickname or password"})
+			return
+		default:
+			log.Printf("Error logging in %s: %v", credentials.Nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	err := h.authService.Logout(c.Request.Context(), bearerToken(c))
+	if err != nil {
+		log.Printf("Error logging out: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	var change models.PasswordChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	err := h.authService.ChangePassword(c.Request.Context(), nickname, change)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+			return
+		case models.ErrUnauthorized:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+			return
+		case models.ErrWeakPassword:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Password is too short"})
+			return
+		case models.ErrInvalidCredentials:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Old password doesn't match"})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only the account owner can change the password, and only a site admin can set the first one"})
+			return
+		default:
+			log.Printf("Error changing password for %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *AuthHandler) CreateAPIToken(c *gin.Context) {
+	var request struct {
+		Name    string     `json:"name"`
+		Expires *time.Time `json:"expires,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	token, err := h.authService.CreateAPIToken(c.Request.Context(), request.Name, request.Expires)
+	if err != nil {
+		switch err {
+		case models.ErrUnauthorized:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+			return
+		default:
+			log.Printf("Error creating api token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *AuthHandler) GetAPITokens(c *gin.Context) {
+	tokens, err := h.authService.GetAPITokens(c.Request.Context())
+	if err != nil {
+		switch err {
+		case models.ErrUnauthorized:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+			return
+		default:
+			log.Printf("Error listing api tokens: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) RevokeAPIToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid token ID"})
+		return
+	}
+
+	err = h.authService.RevokeAPIToken(c.Request.Context(), id)
+	if err != nil {
+		switch err {
+		case models.ErrUnauthorized:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+			return
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find api token with id #" + c.Param("id")})
+			return
+		default:
+			log.Printf("Error revoking api token %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
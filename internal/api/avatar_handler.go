@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AvatarHandler struct {
+	avatarService service.AvatarService
+	maxUploadSize int64
+}
+
+func NewAvatarHandler(s service.AvatarService, maxUploadSize int64) *AvatarHandler {
+	return &AvatarHandler{avatarService: s, maxUploadSize: maxUploadSize}
+}
+
+func (h *AvatarHandler) UploadAvatar(c *gin.Context) {
+	nickname := c.Param("nickname")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUploadSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "Image is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Multipart form with a 'file' field is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded avatar of %s: %v", nickname, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	defer file.Close()
+
+	user, err := h.avatarService.UploadAvatar(c.Request.Context(), nickname, file)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't change avatar of another user"})
+		case models.ErrInvalidAvatar:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Avatar must be a PNG or JPEG image"})
+		case models.ErrAvatarTooLarge:
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "Image is too large"})
+		default:
+			log.Printf("Error uploading avatar of %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	setAvatarURL(user)
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AvatarHandler) GetAvatar(c *gin.Context) {
+	key := c.Param("key")
+
+	size := 0
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		parsed, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid size parameter"})
+			return
+		}
+		size = parsed
+	}
+
+	body, err := h.avatarService.GetAvatar(c.Request.Context(), key, size)
+	if err != nil {
+		switch err {
+		case models.ErrInvalidAvatarSize:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid size parameter"})
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find avatar: " + key})
+		default:
+			log.Printf("Error getting avatar %s: %v", key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+	defer body.Close()
+
+	// Новая аватарка получает новый ключ, поэтому по старому адресу содержимое не меняется.
+	c.DataFromReader(http.StatusOK, -1, "image/png", body, map[string]string{
+		"Cache-Control": "public, max-age=31536000, immutable",
+	})
+}
+
+// setAvatarURL заполняет адрес аватарки пользователя, если она загружена.
+func setAvatarURL(user *models.User) {
+	if user != nil && user.AvatarKey != "" {
+		user.Avatar = "/avatar/" + user.AvatarKey
+	}
+}
+
+func setAvatarURLs(users []models.User) {
+	for i := range users {
+		setAvatarURL(&users[i])
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultConversationsLimit = 20
+	maxConversationsLimit     = 100
+	defaultMessagesLimit      = 50
+	maxMessagesLimit          = 500
+)
+
+type ConversationHandler struct {
+	conversationService service.ConversationService
+}
+
+func NewConversationHandler(s service.ConversationService) *ConversationHandler {
+	return &ConversationHandler{conversationService: s}
+}
+
+func (h *ConversationHandler) CreateConversation(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	var request models.NewConversation
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	conversation, err := h.conversationService.CreateConversation(c.Request.Context(), nickname, request.Members)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find one of the conversation members"})
+			return
+		}
+		h.writeConversationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, conversation)
+}
+
+func (h *ConversationHandler) GetConversations(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	var err error
+	limit := defaultConversationsLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxConversationsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'limit' parameter"})
+			return
+		}
+	}
+
+	var after *models.ConversationCursor
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err = service.DecodeConversationCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'cursor' parameter"})
+			return
+		}
+	}
+
+	page, err := h.conversationService.GetConversations(c.Request.Context(), nickname, limit, after)
+	if err != nil {
+		h.writeConversationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *ConversationHandler) GetConversation(c *gin.Context) {
+	nickname := c.Param("nickname")
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.conversationService.GetConversation(c.Request.Context(), nickname, id)
+	if err != nil {
+		h.writeConversationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+func (h *ConversationHandler) SendMessage(c *gin.Context) {
+	nickname := c.Param("nickname")
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	message, err := h.conversationService.SendMessage(c.Request.Context(), nickname, id, request.Message)
+	if err != nil {
+		h.writeConversationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+func (h *ConversationHandler) GetMessages(c *gin.Context) {
+	nickname := c.Param("nickname")
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	limit := defaultMessagesLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxMessagesLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'limit' parameter"})
+			return
+		}
+	}
+
+	var before int64
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		before, err = strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil || before <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'before' parameter"})
+			return
+		}
+	}
+
+	messages, err := h.conversationService.GetMessages(c.Request.Context(), nickname, id, limit, before)
+	if err != nil {
+		h.writeConversationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *ConversationHandler) MarkConversationRead(c *gin.Context) {
+	nickname := c.Param("nickname")
+	id, ok := conversationID(c)
+	if !ok {
+		return
+	}
+
+	// Тело необязательно: без lastRead переписка читается до последнего сообщения.
+	var request struct {
+		LastRead int64 `json:"lastRead"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil || request.LastRead < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+			return
+		}
+	}
+
+	err := h.conversationService.MarkConversationRead(c.Request.Context(), nickname, id, request.LastRead)
+	if err != nil {
+		h.writeConversationError(c, nickname, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func conversationID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid conversation ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *ConversationHandler) writeConversationError(c *gin.Context, nickname string, err error) {
+	switch err {
+	case models.ErrOwnerNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Can't find conversation: " + c.Param("id")})
+	case models.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"message": "Only the participant can access their conversations"})
+	case models.ErrUserSuspended:
+		c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+	case models.ErrUserBlocked:
+		c.JSON(http.StatusForbidden, gin.H{"message": "Messaging is blocked between these users"})
+	case models.ErrInvalidConversation:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Conversation needs from 1 to 49 other members"})
+	case models.ErrInvalidMessage:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Message must be non-empty and at most 10000 characters"})
+	default:
+		log.Printf("Error handling conversations of %s: %v", nickname, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	}
+}
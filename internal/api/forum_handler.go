@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -39,6 +40,18 @@ func (h *ForumHandler) CreateForum(c *gin.Context) {
 		case models.ErrOwnerNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with id #%s" /* + newForum.User*/})
 			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't create forum on behalf of another user"})
+			return
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+			return
+		case models.ErrParentNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find parent forum"})
+			return
+		case models.ErrForumCycle:
+			c.JSON(http.StatusConflict, gin.H{"message": "Forum can't be its own parent"})
+			return
 		default:
 			log.Printf("Error creating forum: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
@@ -52,7 +65,7 @@ func (h *ForumHandler) CreateForum(c *gin.Context) {
 func (h *ForumHandler) GetForumDetails(c *gin.Context) {
 	slug := c.Param("slug")
 
-	forum, err := h.forumService.GetForumBySlug(c.Request.Context(), slug)
+	forum, err := h.forumService.GetForumDetails(c.Request.Context(), slug)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 
@@ -65,6 +78,41 @@ func (h *ForumHandler) GetForumDetails(c *gin.Context) {
 		return
 	}
 
+	setCanonicalSlug(c, slug, forum.Slug)
+	c.JSON(http.StatusOK, forum)
+}
+
+func (h *ForumHandler) UpdateForum(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var update models.ForumUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	forum, err := h.forumService.UpdateForum(c.Request.Context(), slug, update)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find forum with slug: %s", slug)})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only the forum owner can edit it"})
+		case models.ErrInvalidUpdate:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Forum slug must be non-empty and not a number, title must be non-empty"})
+		case models.ErrForumConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Forum slug is already taken"})
+		case models.ErrParentNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find parent forum"})
+		case models.ErrForumCycle:
+			c.JSON(http.StatusConflict, gin.H{"message": "Forum can't be moved into itself or its sub-forum"})
+		default:
+			log.Printf("Error updating forum %s: %v", slug, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, forum)
 }
 
@@ -90,6 +138,24 @@ func (h *ForumHandler) CreateThread(c *gin.Context) {
 		case models.ErrThreadConflict:
 			c.JSON(http.StatusConflict, createdThread)
 			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't create thread on behalf of another user"})
+			return
+		case models.ErrUserBanned:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User is banned in this forum"})
+			return
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+			return
+		case models.ErrForumCategory:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Forum is a category, create threads in its sub-forums: " + forumSlug})
+			return
+		case models.ErrInvalidTags:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid thread tags"})
+			return
+		case models.ErrInvalidPoll:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Poll needs a question, 2 to 10 distinct options and a closing time in the future"})
+			return
 		default:
 			log.Printf("Error creating thread: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
@@ -103,12 +169,99 @@ func (h *ForumHandler) CreateThread(c *gin.Context) {
 func (h *ForumHandler) GetForumThreads(c *gin.Context) {
 	forumSlug := c.Param("slug")
 
+	limit, since, desc, ok := threadListParams(c)
+	if !ok {
+		return
+	}
+
+	var tags []string
+	if tagStr := c.Query("tag"); tagStr != "" {
+		tags = strings.Split(tagStr, ",")
+	}
+
+	threads, err := h.forumService.GetForumThreads(c.Request.Context(), forumSlug, tags, limit, since, desc)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find forum by slug: %s", forumSlug)})
+			return
+		}
+		if errors.Is(err, models.ErrInvalidTags) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'tag' parameter"})
+			return
+		}
+
+		log.Printf("Error getting forum threads: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+func (h *ForumHandler) GetForumTags(c *gin.Context) {
+	slug := c.Param("slug")
+
+	counts, err := h.forumService.GetForumTags(c.Request.Context(), slug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find forum by slug: %s", slug)})
+			return
+		}
+		log.Printf("Error getting tags of forum %s: %v", slug, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
+
+func (h *ForumHandler) GetTagThreads(c *gin.Context) {
+	tag := c.Param("tag")
+
+	limit, since, desc, ok := threadListParams(c)
+	if !ok {
+		return
+	}
+
+	threads, err := h.forumService.GetTagThreads(c.Request.Context(), tag, limit, since, desc)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidTags) {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid tag: " + tag})
+			return
+		}
+		log.Printf("Error getting threads with tag %s: %v", tag, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+func (h *ForumHandler) GetFeaturedThreads(c *gin.Context) {
+	limit, since, desc, ok := threadListParams(c)
+	if !ok {
+		return
+	}
+
+	threads, err := h.forumService.GetFeaturedThreads(c.Request.Context(), limit, since, desc)
+	if err != nil {
+		log.Printf("Error getting featured threads: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+// threadListParams разбирает limit, since и desc списка веток; при ошибке сам отвечает 400.
+func threadListParams(c *gin.Context) (int, *time.Time, bool, bool) {
 	limit := 100
 	if limitStr := c.Query("limit"); limitStr != "" {
 		parsedLimit, err := strconv.Atoi(limitStr)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'limit' parameter"})
-			return
+			return 0, nil, false, false
 		}
 		limit = parsedLimit
 	}
@@ -118,7 +271,7 @@ func (h *ForumHandler) GetForumThreads(c *gin.Context) {
 		parsedTime, err := time.Parse(time.RFC3339, sinceStr)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'since' parameter format"})
-			return
+			return 0, nil, false, false
 		}
 		since = &parsedTime
 	}
@@ -128,25 +281,12 @@ func (h *ForumHandler) GetForumThreads(c *gin.Context) {
 		parsedDesc, err := strconv.ParseBool(descStr)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'desc' parameter"})
-			return
+			return 0, nil, false, false
 		}
 		desc = parsedDesc
 	}
 
-	threads, err := h.forumService.GetForumThreads(c.Request.Context(), forumSlug, limit, since, desc)
-	if err != nil {
-		if errors.Is(err, models.ErrNotFound) {
-
-			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find forum by slug: %s", forumSlug)})
-			return
-		}
-
-		log.Printf("Error getting forum threads: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
-	}
-
-	c.JSON(http.StatusOK, threads)
+	return limit, since, desc, true
 }
 
 func (h *ForumHandler) GetForumUsers(c *gin.Context) {
@@ -155,6 +295,7 @@ func (h *ForumHandler) GetForumUsers(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "100")
 	since := c.Query("since")
 	descStr := c.DefaultQuery("desc", "false")
+	excludeBannedStr := c.DefaultQuery("excludeBanned", "false")
 
 	limit, err := strconv.ParseInt(limitStr, 10, 32)
 	if err != nil || limit < 0 {
@@ -170,7 +311,15 @@ func (h *ForumHandler) GetForumUsers(c *gin.Context) {
 		return
 	}
 
-	users, err := h.forumService.GetForumUsers(c.Request.Context(), slug, int(limit), since, desc)
+	excludeBanned := false
+	if excludeBannedStr == "true" {
+		excludeBanned = true
+	} else if excludeBannedStr != "false" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid excludeBanned parameter, must be 'true' or 'false'"})
+		return
+	}
+
+	users, err := h.forumService.GetForumUsers(c.Request.Context(), slug, int(limit), since, desc, excludeBanned)
 
 	if err != nil {
 		switch err {
@@ -184,5 +333,34 @@ func (h *ForumHandler) GetForumUsers(c *gin.Context) {
 		}
 	}
 
+	setAvatarURLs(users)
 	c.JSON(http.StatusOK, users)
 }
+
+func (h *ForumHandler) GetForumChildren(c *gin.Context) {
+	slug := c.Param("slug")
+
+	children, err := h.forumService.GetForumChildren(c.Request.Context(), slug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find forum with slug: %s", slug)})
+			return
+		}
+		log.Printf("Error getting children of forum %s: %v", slug, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, children)
+}
+
+func (h *ForumHandler) GetForumTree(c *gin.Context) {
+	tree, err := h.forumService.GetForumTree(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting forum tree: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
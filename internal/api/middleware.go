@@ -0,0 +1,22 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const adminTokenHeader = "X-Admin-Token"
+
+func RequireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Пустой токен означает, что администрирование выключено.
+		provided := c.GetHeader(adminTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"crypto/subtle"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationHandler struct {
+	moderationService service.ModerationService
+}
+
+func NewModerationHandler(s service.ModerationService) *ModerationHandler {
+	return &ModerationHandler{moderationService: s}
+}
+
+// RequireSiteAdmin пускает запросы с X-Admin-Token либо от пользователей с флагом администратора.
+func (h *ModerationHandler) RequireSiteAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(adminTokenHeader)
+		if token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			c.Request = c.Request.WithContext(auth.WithAdminToken(c.Request.Context()))
+			c.Next()
+			return
+		}
+
+		if caller, ok := auth.CallerFromContext(c.Request.Context()); ok {
+			isAdmin, err := h.moderationService.IsSiteAdmin(c.Request.Context(), caller)
+			if err != nil {
+				log.Printf("Error checking site admin %s: %v", caller, err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+				return
+			}
+			if isAdmin {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Admin access required"})
+	}
+}
+
+func (h *ModerationHandler) GetForumModerators(c *gin.Context) {
+	slug := c.Param("slug")
+
+	moderators, err := h.moderationService.GetForumModerators(c.Request.Context(), slug)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find forum with slug: " + slug})
+			return
+		default:
+			log.Printf("Error getting moderators of forum %s: %v", slug, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, moderators)
+}
+
+func (h *ModerationHandler) AddForumModerator(c *gin.Context) {
+	slug := c.Param("slug")
+	nickname := c.Param("nickname")
+
+	moderators, err := h.moderationService.AddForumModerator(c.Request.Context(), slug, nickname)
+	if err != nil {
+		h.writeForumMemberError(c, "Not allowed to manage moderators of this forum", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moderators)
+}
+
+func (h *ModerationHandler) RemoveForumModerator(c *gin.Context) {
+	slug := c.Param("slug")
+	nickname := c.Param("nickname")
+
+	moderators, err := h.moderationService.RemoveForumModerator(c.Request.Context(), slug, nickname)
+	if err != nil {
+		h.writeForumMemberError(c, "Not allowed to manage moderators of this forum", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moderators)
+}
+
+func (h *ModerationHandler) BanUser(c *gin.Context) {
+	slug := c.Param("slug")
+	nickname := c.Param("nickname")
+
+	// Тело необязательно: без него бан бессрочный и без причины.
+	var request models.ForumBan
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+			return
+		}
+	}
+
+	ban, err := h.moderationService.BanUser(c.Request.Context(), slug, nickname, request)
+	if err != nil {
+		h.writeForumMemberError(c, "Not allowed to ban this user", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ban)
+}
+
+func (h *ModerationHandler) GetForumBans(c *gin.Context) {
+	slug := c.Param("slug")
+
+	bans, err := h.moderationService.GetForumBans(c.Request.Context(), slug)
+	if err != nil {
+		h.writeForumMemberError(c, "Only forum moderators can see the ban list", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bans)
+}
+
+func (h *ModerationHandler) UnbanUser(c *gin.Context) {
+	slug := c.Param("slug")
+	nickname := c.Param("nickname")
+
+	err := h.moderationService.UnbanUser(c.Request.Context(), slug, nickname)
+	if err != nil {
+		h.writeForumMemberError(c, "Not allowed to unban this user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unbanned"})
+}
+
+func (h *ModerationHandler) SuspendUser(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	var request models.Suspension
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+			return
+		}
+	}
+
+	suspension, err := h.moderationService.SuspendUser(c.Request.Context(), nickname, request)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+			return
+		case models.ErrInvalidExpiry:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Suspension end must be in the future"})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Admin access required"})
+			return
+		default:
+			log.Printf("Error suspending user %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, suspension)
+}
+
+func (h *ModerationHandler) UnsuspendUser(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	err := h.moderationService.UnsuspendUser(c.Request.Context(), nickname)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "User is not suspended: " + nickname})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Admin access required"})
+			return
+		default:
+			log.Printf("Error lifting suspension of %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suspension lifted"})
+}
+
+func (h *ModerationHandler) GrantSiteAdmin(c *gin.Context) {
+	h.setSiteAdmin(c, true)
+}
+
+func (h *ModerationHandler) RevokeSiteAdmin(c *gin.Context) {
+	h.setSiteAdmin(c, false)
+}
+
+func (h *ModerationHandler) setSiteAdmin(c *gin.Context, isAdmin bool) {
+	nickname := c.Param("nickname")
+
+	err := h.moderationService.SetSiteAdmin(c.Request.Context(), nickname, isAdmin)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Admin access required"})
+			return
+		default:
+			log.Printf("Error updating site admin flag for %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"nickname": nickname, "isAdmin": isAdmin})
+}
+
+func (h *ModerationHandler) writeForumMemberError(c *gin.Context, forbiddenMessage string, err error) {
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Can't find forum or record with slug: " + c.Param("slug")})
+	case models.ErrOwnerNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + c.Param("nickname")})
+	case models.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"message": forbiddenMessage})
+	case models.ErrInvalidExpiry:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Ban expiry must be in the future"})
+	default:
+		log.Printf("Error managing forum %s member %s: %v", c.Param("slug"), c.Param("nickname"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	}
+}
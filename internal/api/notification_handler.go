@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 500
+)
+
+type NotificationHandler struct {
+	notificationService service.NotificationService
+}
+
+func NewNotificationHandler(s service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: s}
+}
+
+func (h *NotificationHandler) SubscribeThread(c *gin.Context) {
+	h.changeSubscription(c, c.Param("slug_or_id"), h.notificationService.SubscribeThread)
+}
+
+func (h *NotificationHandler) UnsubscribeThread(c *gin.Context) {
+	h.changeSubscription(c, c.Param("slug_or_id"), h.notificationService.UnsubscribeThread)
+}
+
+func (h *NotificationHandler) SubscribeForum(c *gin.Context) {
+	h.changeSubscription(c, c.Param("slug"), h.notificationService.SubscribeForum)
+}
+
+func (h *NotificationHandler) UnsubscribeForum(c *gin.Context) {
+	h.changeSubscription(c, c.Param("slug"), h.notificationService.UnsubscribeForum)
+}
+
+func (h *NotificationHandler) changeSubscription(c *gin.Context, target string, change func(ctx context.Context, target string, nickname string) error) {
+	var request models.Subscription
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	err := change(c.Request.Context(), target, request.Nickname)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find subscription target: " + target})
+		case models.ErrOwnerNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + request.Nickname})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't change subscriptions of another user"})
+		default:
+			log.Printf("Error changing subscription of %s to %s: %v", request.Nickname, target, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *NotificationHandler) GetSubscriptions(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	subscriptions, err := h.notificationService.GetSubscriptions(c.Request.Context(), nickname)
+	if err != nil {
+		h.writeNotificationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	var err error
+	limit := defaultNotificationsLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxNotificationsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'limit' parameter"})
+			return
+		}
+	}
+
+	var before int64
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		before, err = strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil || before <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'before' parameter"})
+			return
+		}
+	}
+
+	unread := c.Query("unread") == "true"
+
+	notifications, err := h.notificationService.GetNotifications(c.Request.Context(), nickname, unread, limit, before)
+	if err != nil {
+		h.writeNotificationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+func (h *NotificationHandler) MarkNotificationsRead(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	// Тело необязательно: без ids отмечаются все уведомления.
+	var request struct {
+		IDs []int64 `json:"ids"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+			return
+		}
+	}
+
+	marked, err := h.notificationService.MarkNotificationsRead(c.Request.Context(), nickname, request.IDs)
+	if err != nil {
+		h.writeNotificationError(c, nickname, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	nickname := c.Param("nickname")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid notification ID"})
+		return
+	}
+
+	err = h.notificationService.MarkNotificationRead(c.Request.Context(), nickname, id)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find notification: " + c.Param("id")})
+			return
+		}
+		h.writeNotificationError(c, nickname, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *NotificationHandler) writeNotificationError(c *gin.Context, nickname string, err error) {
+	switch err {
+	case models.ErrOwnerNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+	case models.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"message": "Only the user or a site admin can read these notifications"})
+	default:
+		log.Printf("Error reading notifications of %s: %v", nickname, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"hardhw/internal/markdown"
 	"hardhw/internal/models"
 	"hardhw/internal/service"
 	"log"
@@ -12,12 +13,18 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	defaultMentionsLimit = 50
+	maxMentionsLimit     = 500
+)
+
 type PostHandler struct {
 	postService service.PostService
+	renderer    *markdown.Cache
 }
 
-func NewPostHandler(s service.PostService) *PostHandler {
-	return &PostHandler{postService: s}
+func NewPostHandler(s service.PostService, renderer *markdown.Cache) *PostHandler {
+	return &PostHandler{postService: s, renderer: renderer}
 }
 
 func (h *PostHandler) GetPostDetails(c *gin.Context) {
@@ -34,6 +41,11 @@ func (h *PostHandler) GetPostDetails(c *gin.Context) {
 		related = strings.Split(relatedStr, ",")
 	}
 
+	asHTML, ok := parseFormat(c)
+	if !ok {
+		return
+	}
+
 	if len(related) > 0 {
 		response, err := h.postService.GetPostDetailsWithRelated(c.Request.Context(), postID, related)
 		if err != nil {
@@ -50,6 +62,10 @@ func (h *PostHandler) GetPostDetails(c *gin.Context) {
 				return
 			}
 		}
+		if asHTML {
+			renderPost(h.renderer, response.Post)
+			renderThread(h.renderer, response.Thread)
+		}
 		c.JSON(http.StatusOK, response)
 		return
 	}
@@ -66,6 +82,9 @@ func (h *PostHandler) GetPostDetails(c *gin.Context) {
 			return
 		}
 	}
+	if asHTML {
+		renderPost(h.renderer, post)
+	}
 	c.JSON(http.StatusOK, gin.H{"post": post})
 }
 
@@ -89,6 +108,21 @@ func (h *PostHandler) UpdatePostDetails(c *gin.Context) {
 		case models.ErrPostNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find post with id #%d\n", postID)})
 			return
+		case models.ErrPostDeleted:
+			c.JSON(http.StatusConflict, gin.H{"message": fmt.Sprintf("Post with id #%d was deleted", postID)})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only the post author or a forum moderator can edit it"})
+			return
+		case models.ErrThreadLocked:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Thread is closed for edits"})
+			return
+		case models.ErrUserBanned:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User is banned in this forum"})
+			return
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+			return
 		default:
 			log.Printf("Error updating post %d: %v", postID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
@@ -99,6 +133,207 @@ func (h *PostHandler) UpdatePostDetails(c *gin.Context) {
 	c.JSON(http.StatusOK, updatedPost)
 }
 
+func (h *PostHandler) GetPostHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	revisions, err := h.postService.GetPostHistory(c.Request.Context(), postID)
+	if err != nil {
+		switch err {
+		case models.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find post with id #%d\n", postID)})
+			return
+		case models.ErrUnauthorized:
+			c.JSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only forum moderators can see the history of a deleted post"})
+			return
+		default:
+			log.Printf("Error getting history of post %d: %v", postID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (h *PostHandler) RevertPost(c *gin.Context) {
+	idStr := c.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+	revisionID, err := strconv.ParseInt(c.Param("revision"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid revision ID"})
+		return
+	}
+
+	reverted, err := h.postService.RevertPost(c.Request.Context(), postID, revisionID)
+	if err != nil {
+		switch err {
+		case models.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find post with id #%d\n", postID)})
+			return
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find revision #%d of post #%d", revisionID, postID)})
+			return
+		case models.ErrPostDeleted:
+			c.JSON(http.StatusConflict, gin.H{"message": fmt.Sprintf("Post with id #%d was deleted", postID)})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only forum moderators can revert posts"})
+			return
+		default:
+			log.Printf("Error reverting post %d to revision %d: %v", postID, revisionID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, reverted)
+}
+
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	idStr := c.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	deletedPost, err := h.postService.DeletePost(c.Request.Context(), postID)
+	if err != nil {
+		switch err {
+		case models.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find post with id #%d\n", postID)})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only the post author, a forum moderator or a site admin can delete it"})
+			return
+		default:
+			log.Printf("Error deleting post %d: %v", postID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, deletedPost)
+}
+
+func (h *PostHandler) GetUserMentions(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	var err error
+	limit := defaultMentionsLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxMentionsLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'limit' parameter"})
+			return
+		}
+	}
+
+	var before int64
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		before, err = strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil || before <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'before' parameter"})
+			return
+		}
+	}
+
+	posts, err := h.postService.GetUserMentions(c.Request.Context(), nickname, limit, before)
+	if err != nil {
+		if err == models.ErrOwnerNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + nickname})
+			return
+		}
+		log.Printf("Error getting mentions of %s: %v", nickname, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, posts)
+}
+
+func (h *PostHandler) ReactToPost(c *gin.Context) {
+	idStr := c.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	var reaction models.Reaction
+	if err := c.ShouldBindJSON(&reaction); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	updatedPost, err := h.postService.ReactToPost(c.Request.Context(), postID, reaction)
+	if err != nil {
+		switch err {
+		case models.ErrInvalidReaction:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Unknown reaction: " + reaction.Kind})
+		case models.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find post with id #%d\n", postID)})
+		case models.ErrOwnerNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + reaction.Nickname})
+		case models.ErrPostDeleted:
+			c.JSON(http.StatusConflict, gin.H{"message": fmt.Sprintf("Post with id #%d was deleted", postID)})
+		case models.ErrThreadLocked:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Thread is closed for reactions"})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't react on behalf of another user"})
+		case models.ErrUserBanned:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User is banned in this forum"})
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+		default:
+			log.Printf("Error reacting to post %d: %v", postID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedPost)
+}
+
+func (h *PostHandler) PurgePost(c *gin.Context) {
+	idStr := c.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	purged, err := h.postService.PurgePost(c.Request.Context(), postID)
+	if err != nil {
+		switch err {
+		case models.ErrPostNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find post with id #%d\n", postID)})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Admin access required"})
+			return
+		default:
+			log.Printf("Error purging post %d: %v", postID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
 func (h *PostHandler) GetStatus(c *gin.Context) {
 	status, err := h.postService.GetDatabaseStatus(c.Request.Context())
 	if err != nil {
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxSearchLimit = 100
+
+type SearchHandler struct {
+	searchService service.SearchService
+}
+
+func NewSearchHandler(s service.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: s}
+}
+
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := models.SearchQuery{
+		Query:  c.Query("q"),
+		Forum:  c.Query("forum"),
+		Author: c.Query("author"),
+		Limit:  20,
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 || parsedLimit > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'limit' parameter"})
+			return
+		}
+		query.Limit = parsedLimit
+	}
+
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		parsedTime, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'since' parameter format"})
+			return
+		}
+		query.Since = &parsedTime
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := service.DecodeSearchCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'cursor' parameter"})
+			return
+		}
+		query.After = after
+	}
+
+	var related []string
+	if relatedStr := c.Query("related"); relatedStr != "" {
+		related = strings.Split(relatedStr, ",")
+	}
+
+	result, err := h.searchService.Search(c.Request.Context(), query, related)
+	if err != nil {
+		switch err {
+		case models.ErrEmptySearchQuery:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Search query 'q' is required"})
+			return
+		default:
+			log.Printf("Error searching for %q: %v", query.Query, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
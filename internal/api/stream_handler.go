@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat не даёт прокси закрыть простаивающее соединение.
+const streamHeartbeat = 25 * time.Second
+
+type StreamHandler struct {
+	streamService service.StreamService
+	// allowedOrigins — Origin других сайтов, которым можно открывать WebSocket.
+	allowedOrigins []string
+}
+
+func NewStreamHandler(s service.StreamService, allowedOrigins []string) *StreamHandler {
+	return &StreamHandler{streamService: s, allowedOrigins: allowedOrigins}
+}
+
+func (h *StreamHandler) StreamThreadEvents(c *gin.Context) {
+	subscription, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+
+	done := c.Request.Context().Done()
+	pumpThreadEvents(subscription, done, func(event *models.ThreadEvent) error {
+		if event == nil {
+			_, err := fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+			return err
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		// id выставляем только новым постам: по нему клиент возобновляет поток.
+		if event.Type == models.ThreadEventPost {
+			fmt.Fprintf(c.Writer, "id: %d\n", event.Post.ID)
+		}
+		_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, data)
+		c.Writer.Flush()
+		return err
+	})
+}
+
+func (h *StreamHandler) ThreadEventsWebSocket(c *gin.Context) {
+	if !isWebSocketRequest(c.Request) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "WebSocket upgrade required"})
+		return
+	}
+	if !originAllowed(c.Request, h.allowedOrigins) {
+		c.JSON(http.StatusForbidden, gin.H{"message": "WebSocket origin not allowed"})
+		return
+	}
+
+	subscription, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+
+	ws, err := upgradeWebSocket(c)
+	if err != nil {
+		if err == errNotWebSocket {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid WebSocket handshake"})
+			return
+		}
+		log.Printf("Error upgrading thread %d stream to WebSocket: %v", subscription.Thread.ID, err)
+		return
+	}
+	defer ws.Close()
+
+	done := make(chan struct{})
+	go ws.readLoop(done)
+
+	pumpThreadEvents(subscription, done, func(event *models.ThreadEvent) error {
+		if event == nil {
+			return ws.Ping()
+		}
+
+		// В WebSocket нет Last-Event-ID, поэтому id поста передаётся в самом сообщении.
+		message := struct {
+			ID int64 `json:"id,omitempty"`
+			*models.ThreadEvent
+		}{ThreadEvent: event}
+		if event.Type == models.ThreadEventPost {
+			message.ID = event.Post.ID
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		return ws.WriteText(data)
+	})
+}
+
+func (h *StreamHandler) subscribe(c *gin.Context) (*service.ThreadSubscription, bool) {
+	slugOrID := c.Param("slug_or_id")
+
+	lastID := lastEventID(c)
+	if lastID < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid Last-Event-ID"})
+		return nil, false
+	}
+
+	subscription, err := h.streamService.Subscribe(c.Request.Context(), slugOrID, lastID)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find thread with slug or id: " + slugOrID})
+			return nil, false
+		default:
+			log.Printf("Error subscribing to thread %s: %v", slugOrID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return nil, false
+		}
+	}
+	return subscription, true
+}
+
+// lastEventID берёт позицию из заголовка Last-Event-ID или из параметра lastEventId:
+// EventSource при первом подключении не умеет выставлять заголовки. -1 означает ошибку.
+func lastEventID(c *gin.Context) int64 {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("lastEventId")
+	}
+	if value == "" {
+		return 0
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return -1
+	}
+	return id
+}
+
+// pumpThreadEvents отправляет пропущенные посты, затем живые события, пока клиент
+// не отключится. send(nil) означает heartbeat.
+func pumpThreadEvents(subscription *service.ThreadSubscription, done <-chan struct{}, send func(event *models.ThreadEvent) error) {
+	sent := make(map[int64]struct{}, len(subscription.Backlog))
+	for i := range subscription.Backlog {
+		if err := send(&subscription.Backlog[i]); err != nil {
+			return
+		}
+		sent[subscription.Backlog[i].Post.ID] = struct{}{}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-heartbeat.C:
+			if err := send(nil); err != nil {
+				return
+			}
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			// Посты из backlog могли прийти и живым событием — второй раз их не шлём.
+			if event.Type == models.ThreadEventPost {
+				if _, dup := sent[event.Post.ID]; dup {
+					continue
+				}
+			}
+			if err := send(&event); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -1,21 +1,25 @@
 package api
 
 import (
+	"context"
+	"hardhw/internal/markdown"
 	"hardhw/internal/models"
 	"hardhw/internal/service"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ThreadHandler struct {
 	threadService service.ThreadService
+	renderer      *markdown.Cache
 }
 
-func NewThreadHandler(s service.ThreadService) *ThreadHandler {
-	return &ThreadHandler{threadService: s}
+func NewThreadHandler(s service.ThreadService, renderer *markdown.Cache) *ThreadHandler {
+	return &ThreadHandler{threadService: s, renderer: renderer}
 }
 
 func (h *ThreadHandler) CreatePosts(c *gin.Context) {
@@ -41,6 +45,21 @@ func (h *ThreadHandler) CreatePosts(c *gin.Context) {
 		case models.ErrOwnerNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"message": "One or more post authors not found"})
 			return
+		case models.ErrThreadLocked:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Thread is closed for new posts: " + slugOrID})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't create posts on behalf of another user"})
+			return
+		case models.ErrUserBanned:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User is banned in this forum"})
+			return
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+			return
+		case models.ErrInvalidAttachment:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Attachments must be unbound files uploaded by the post author, at most 10 per post"})
+			return
 		default:
 			log.Printf("Error creating posts for thread %s: %v", slugOrID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
@@ -67,6 +86,16 @@ func (h *ThreadHandler) VoteThread(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find thread with slug or id: " + slugOrID})
 		case models.ErrOwnerNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + vote.Nickname})
+		case models.ErrThreadLocked:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Thread is closed for voting: " + slugOrID})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't vote on behalf of another user"})
+		case models.ErrUserBanned:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User is banned in this forum"})
+			return
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+			return
 		default:
 			log.Printf("Error voting for thread %s: %v", slugOrID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
@@ -74,12 +103,59 @@ func (h *ThreadHandler) VoteThread(c *gin.Context) {
 		return
 	}
 
+	setThreadCanonicalSlug(c, slugOrID, updatedThread)
+	c.JSON(http.StatusOK, updatedThread)
+}
+
+func (h *ThreadHandler) VotePoll(c *gin.Context) {
+	slugOrID := c.Param("slug_or_id")
+	var vote models.PollVote
+
+	if err := c.ShouldBindJSON(&vote); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	updatedThread, err := h.threadService.VotePoll(c.Request.Context(), slugOrID, vote)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find thread with slug or id: " + slugOrID})
+		case models.ErrPollNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Thread has no poll: " + slugOrID})
+		case models.ErrOwnerNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find user with nickname: " + vote.Nickname})
+		case models.ErrInvalidPollVote:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Unknown poll option or several options in a single choice poll"})
+		case models.ErrPollClosed:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Poll is closed: " + slugOrID})
+		case models.ErrThreadLocked:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Thread is closed for voting: " + slugOrID})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't vote on behalf of another user"})
+		case models.ErrUserBanned:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User is banned in this forum"})
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+		default:
+			log.Printf("Error voting in poll of thread %s: %v", slugOrID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	setThreadCanonicalSlug(c, slugOrID, updatedThread)
 	c.JSON(http.StatusOK, updatedThread)
 }
 
 func (h *ThreadHandler) GetThreadDetails(c *gin.Context) {
 	slugOrID := c.Param("slug_or_id")
 
+	asHTML, ok := parseFormat(c)
+	if !ok {
+		return
+	}
+
 	thread, err := h.threadService.GetThreadDetails(c.Request.Context(), slugOrID)
 	if err != nil {
 		if err == models.ErrNotFound {
@@ -91,6 +167,10 @@ func (h *ThreadHandler) GetThreadDetails(c *gin.Context) {
 		return
 	}
 
+	if asHTML {
+		renderThread(h.renderer, &thread)
+	}
+	setThreadCanonicalSlug(c, slugOrID, thread)
 	c.JSON(http.StatusOK, thread)
 }
 
@@ -126,6 +206,11 @@ func (h *ThreadHandler) GetThreadPosts(c *gin.Context) {
 		return
 	}
 
+	asHTML, ok := parseFormat(c)
+	if !ok {
+		return
+	}
+
 	posts, err := h.threadService.GetThreadPosts(c.Request.Context(), slugOrID, limit, since, sort, desc)
 	if err != nil {
 		if err == models.ErrNotFound {
@@ -142,6 +227,9 @@ func (h *ThreadHandler) GetThreadPosts(c *gin.Context) {
 		return
 	}
 
+	if asHTML {
+		renderPosts(h.renderer, posts)
+	}
 	c.JSON(http.StatusOK, posts)
 }
 
@@ -160,6 +248,27 @@ func (h *ThreadHandler) UpdateThreadDetails(c *gin.Context) {
 		case models.ErrNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find thread with slug or id: " + slugOrID + "\n"})
 			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only the thread author or a forum moderator can edit it"})
+			return
+		case models.ErrInvalidUpdate:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Thread slug must be non-empty and not a number"})
+			return
+		case models.ErrInvalidTags:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid thread tags"})
+			return
+		case models.ErrThreadConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Thread slug is already taken"})
+			return
+		case models.ErrThreadLocked:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Thread is closed for edits"})
+			return
+		case models.ErrUserBanned:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User is banned in this forum"})
+			return
+		case models.ErrUserSuspended:
+			c.JSON(http.StatusForbidden, gin.H{"message": "User account is suspended"})
+			return
 		default:
 			log.Printf("Error updating thread %s: %v", slugOrID, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
@@ -167,5 +276,126 @@ func (h *ThreadHandler) UpdateThreadDetails(c *gin.Context) {
 		}
 	}
 
+	setThreadCanonicalSlug(c, slugOrID, updatedThread)
 	c.JSON(http.StatusOK, updatedThread)
 }
+
+func (h *ThreadHandler) GetThreadHistory(c *gin.Context) {
+	slugOrID := c.Param("slug_or_id")
+
+	revisions, err := h.threadService.GetThreadHistory(c.Request.Context(), slugOrID)
+	if err != nil {
+		if err == models.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find thread with slug or id: " + slugOrID})
+			return
+		}
+		log.Printf("Error getting history of thread %s: %v", slugOrID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (h *ThreadHandler) LockThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.LockThread)
+}
+
+func (h *ThreadHandler) UnlockThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.UnlockThread)
+}
+
+func (h *ThreadHandler) ArchiveThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.ArchiveThread)
+}
+
+func (h *ThreadHandler) DeleteThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.DeleteThread)
+}
+
+func (h *ThreadHandler) PinThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.PinThread)
+}
+
+func (h *ThreadHandler) UnpinThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.UnpinThread)
+}
+
+func (h *ThreadHandler) FeatureThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.FeatureThread)
+}
+
+func (h *ThreadHandler) UnfeatureThread(c *gin.Context) {
+	h.changeThreadState(c, h.threadService.UnfeatureThread)
+}
+
+func (h *ThreadHandler) changeThreadState(c *gin.Context, change func(ctx context.Context, slugOrID string) (models.Thread, error)) {
+	slugOrID := c.Param("slug_or_id")
+
+	thread, err := change(c.Request.Context(), slugOrID)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": "Can't find thread with slug or id: " + slugOrID})
+			return
+		case models.ErrThreadStateConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Thread state can't be changed this way: " + slugOrID})
+			return
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Not allowed to change state of this thread"})
+			return
+		default:
+			log.Printf("Error changing state of thread %s: %v", slugOrID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// parseFormat разбирает параметр format: без него сообщения отдаются как есть, с format=html —
+// ещё и отрисованными в messageHtml. На неизвестное значение сам отвечает 400.
+func parseFormat(c *gin.Context) (asHTML bool, ok bool) {
+	switch c.Query("format") {
+	case "", "raw":
+		return false, true
+	case "html":
+		return true, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid format parameter"})
+		return false, false
+	}
+}
+
+func renderPosts(renderer *markdown.Cache, posts []models.Post) {
+	for i := range posts {
+		renderPost(renderer, &posts[i])
+	}
+}
+
+func renderPost(renderer *markdown.Cache, post *models.Post) {
+	if post != nil && post.Message != "" {
+		post.MessageHTML = renderer.Render(post.Message)
+	}
+}
+
+func renderThread(renderer *markdown.Cache, thread *models.Thread) {
+	if thread != nil && thread.Message != "" {
+		thread.MessageHTML = renderer.Render(thread.Message)
+	}
+}
+
+// setThreadCanonicalSlug сообщает клиенту актуальный slug, если ветку нашли по старому.
+func setThreadCanonicalSlug(c *gin.Context, slugOrID string, thread models.Thread) {
+	if _, err := strconv.ParseInt(slugOrID, 10, 64); err == nil || thread.Slug == nil {
+		return
+	}
+	setCanonicalSlug(c, slugOrID, *thread.Slug)
+}
+
+func setCanonicalSlug(c *gin.Context, requested, canonical string) {
+	if !strings.EqualFold(requested, canonical) {
+		c.Header("X-Canonical-Slug", canonical)
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"hardhw/internal/models"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetThreadCanonicalSlug(t *testing.T) {
+	slug := "current"
+	tests := []struct {
+		name      string
+		requested string
+		thread    models.Thread
+		want      string
+	}{
+		{name: "old slug", requested: "previous", thread: models.Thread{ID: 7, Slug: &slug}, want: "current"},
+		{name: "current slug", requested: "current", thread: models.Thread{ID: 7, Slug: &slug}},
+		{name: "current slug in other case", requested: "CURRENT", thread: models.Thread{ID: 7, Slug: &slug}},
+		{name: "id", requested: "7", thread: models.Thread{ID: 7, Slug: &slug}},
+		{name: "thread without slug", requested: "7", thread: models.Thread{ID: 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+
+			setThreadCanonicalSlug(c, tt.requested, tt.thread)
+			if got := recorder.Header().Get("X-Canonical-Slug"); got != tt.want {
+				t.Fatalf("got X-Canonical-Slug %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
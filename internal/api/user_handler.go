@@ -7,6 +7,7 @@ import (
 	"hardhw/internal/service"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,6 +33,14 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	newUser.Nickname = nickname
 
 	createdUser, conflictUsers, err := h.userService.CreateUser(c.Request.Context(), newUser)
+	if errors.Is(err, models.ErrWeakPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Password is too short"})
+		return
+	}
+	if errors.Is(err, models.ErrInvalidNickname) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Nickname is reserved"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Ошибка при создании user", "error": err.Error()})
 		return
@@ -59,6 +68,10 @@ func (h *UserHandler) GetUserProfile(c *gin.Context) {
 		return
 	}
 
+	if !strings.EqualFold(nickname, user.Nickname) {
+		c.Header("X-Canonical-Nickname", user.Nickname)
+	}
+	setAvatarURL(user)
 	c.JSON(http.StatusOK, user)
 }
 
@@ -81,10 +94,144 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"message": fmt.Sprintf("Email %s already in use.", updatedUserData.Email)})
 			return
 		}
+		if errors.Is(err, models.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't update profile of another user"})
+			return
+		}
 		log.Printf("Ошибка при обновлении профиля пользователя: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Ошибка при обновлении профиля пользователя", "error": err.Error()})
 		return
 	}
 
+	setAvatarURL(user)
 	c.JSON(http.StatusOK, user)
 }
+
+func (h *UserHandler) RenameUser(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	var request struct {
+		Nickname string `json:"nickname"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	user, conflictUsers, err := h.userService.RenameUser(c.Request.Context(), nickname, request.Nickname)
+	if len(conflictUsers) > 0 {
+		c.JSON(http.StatusConflict, conflictUsers)
+		return
+	}
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find user with nickname: %s", nickname)})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Can't rename another user"})
+		case models.ErrInvalidNickname:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Nickname must be non-empty and contain no slashes or spaces"})
+		case models.ErrUserConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": fmt.Sprintf("Nickname %s already in use.", request.Nickname)})
+		default:
+			log.Printf("Error renaming user %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	err := h.userService.DeleteUser(c.Request.Context(), nickname, c.Query("mode"))
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find user with nickname: %s", nickname)})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only the user or a site admin can delete this account"})
+		case models.ErrInvalidDeleteMode:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Parameter 'mode' must be 'anonymize' or 'erase'"})
+		default:
+			log.Printf("Error deleting user %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *UserHandler) ExportUser(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	export, err := h.userService.ExportUser(c.Request.Context(), nickname)
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find user with nickname: %s", nickname)})
+		case models.ErrForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"message": "Only the user or a site admin can export this account"})
+		default:
+			log.Printf("Error exporting user %s: %v", nickname, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, export.User.Nickname))
+	c.JSON(http.StatusOK, export)
+}
+
+func (h *UserHandler) GetBlockedUsers(c *gin.Context) {
+	nickname := c.Param("nickname")
+
+	blocked, err := h.userService.GetBlockedUsers(c.Request.Context(), nickname)
+	if err != nil {
+		h.writeBlockError(c, nickname, "", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, blocked)
+}
+
+func (h *UserHandler) BlockUser(c *gin.Context) {
+	nickname, target := c.Param("nickname"), c.Param("target")
+
+	if err := h.userService.BlockUser(c.Request.Context(), nickname, target); err != nil {
+		h.writeBlockError(c, nickname, target, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *UserHandler) UnblockUser(c *gin.Context) {
+	nickname, target := c.Param("nickname"), c.Param("target")
+
+	if err := h.userService.UnblockUser(c.Request.Context(), nickname, target); err != nil {
+		h.writeBlockError(c, nickname, target, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *UserHandler) writeBlockError(c *gin.Context, nickname, target string, err error) {
+	switch err {
+	case models.ErrOwnerNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find user with nickname: %s", nickname)})
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": fmt.Sprintf("Can't find user with nickname: %s", target)})
+	case models.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"message": "Can't change the block list of another user"})
+	case models.ErrSelfBlock:
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Can't block yourself"})
+	default:
+		log.Printf("Error changing block list of %s: %v", nickname, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	}
+}
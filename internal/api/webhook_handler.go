@@ -0,0 +1,127 @@
+package api
+
+import (
+	"hardhw/internal/models"
+	"hardhw/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(s service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: s}
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	slug := c.Param("slug")
+
+	var request struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	hook, err := h.webhookService.CreateWebhook(c.Request.Context(), slug, models.Webhook{URL: request.URL, Events: request.Events})
+	if err != nil {
+		switch err {
+		case models.ErrInvalidWebhook:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Webhook needs a public http(s) url and at least one known event"})
+			return
+		default:
+			h.writeWebhookError(c, slug, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	slug := c.Param("slug")
+
+	hooks, err := h.webhookService.GetWebhooks(c.Request.Context(), slug)
+	if err != nil {
+		h.writeWebhookError(c, slug, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	slug := c.Param("slug")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid webhook ID"})
+		return
+	}
+
+	err = h.webhookService.DeleteWebhook(c.Request.Context(), slug, id)
+	if err != nil {
+		h.writeWebhookError(c, slug, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	slug := c.Param("slug")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid webhook ID"})
+		return
+	}
+
+	limit := defaultDeliveriesLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxDeliveriesLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'limit' parameter"})
+			return
+		}
+	}
+
+	var before int64
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		before, err = strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil || before <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid 'before' parameter"})
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(c.Request.Context(), slug, id, limit, before)
+	if err != nil {
+		h.writeWebhookError(c, slug, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) writeWebhookError(c *gin.Context, slug string, err error) {
+	switch err {
+	case models.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "Can't find forum or webhook in forum: " + slug})
+	case models.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"message": "Only the forum owner can manage webhooks"})
+	default:
+		log.Printf("Error managing webhooks of forum %s: %v", slug, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Минимальная серверная часть RFC 6455: сервер шлёт текстовые кадры, а от клиента
+// принимает только служебные (ping, close). Фрагментированные сообщения не поддерживаются.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+
+	wsMaxClientPayload = 4096
+	wsWriteTimeout     = 10 * time.Second
+)
+
+var errNotWebSocket = errors.New("not a websocket handshake")
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+
+	writeMu sync.Mutex
+}
+
+func isWebSocketRequest(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		headerHasToken(r.Header.Get("Connection"), "upgrade")
+}
+
+func headerHasToken(value, token string) bool {
+	for _, part := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// originAllowed защищает от cross-site WebSocket: браузер открывает сокет с чужой страницы
+// вместе с cookie и учётными данными пользователя. Пропускаются запросы без Origin (их шлют
+// не браузеры), Origin с тем же хостом, что у запроса, и Origin из allowed.
+func originAllowed(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+			return true
+		}
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return strings.EqualFold(parsed.Host, r.Host)
+}
+
+// upgradeWebSocket проверяет рукопожатие и забирает соединение у net/http.
+// До ошибки errNotWebSocket ответ клиенту ещё не отправлен.
+func upgradeWebSocket(c *gin.Context) (*wsConn, error) {
+	key := c.GetHeader("Sec-WebSocket-Key")
+	if !isWebSocketRequest(c.Request) || c.GetHeader("Sec-WebSocket-Version") != "13" || key == "" {
+		return nil, errNotWebSocket
+	}
+
+	conn, rw, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	accept := base64.StdEncoding.EncodeToString(hash[:])
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+func (ws *wsConn) WriteText(payload []byte) error {
+	return ws.writeFrame(wsOpText, payload)
+}
+
+func (ws *wsConn) Ping() error {
+	return ws.writeFrame(wsOpPing, nil)
+}
+
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+	case len(payload) <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := ws.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := ws.rw.Write(payload); err != nil {
+		return err
+	}
+	return ws.rw.Flush()
+}
+
+// readFrame читает один кадр клиента. Клиентские кадры по стандарту всегда замаскированы.
+func (ws *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if !masked {
+		return 0, nil, errors.New("unmasked client frame")
+	}
+	if length > wsMaxClientPayload {
+		return 0, nil, errors.New("client frame too large")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// readLoop отвечает на ping и close клиента и закрывает done, когда соединение кончилось.
+func (ws *wsConn) readLoop(done chan<- struct{}) {
+	defer close(done)
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpPing:
+			if err := ws.writeFrame(wsOpPong, payload); err != nil {
+				return
+			}
+		case wsOpClose:
+			ws.writeFrame(wsOpClose, nil)
+			return
+		}
+	}
+}
+
+func (ws *wsConn) Close() error {
+	return ws.conn.Close()
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{name: "no origin", want: true},
+		{name: "same host", origin: "http://forum.test:5000", want: true},
+		{name: "same host in other case", origin: "https://FORUM.test:5000", want: true},
+		{name: "other site", origin: "https://evil.test"},
+		{name: "other port", origin: "http://forum.test:6000"},
+		{name: "allowed site", origin: "https://app.test", allowed: []string{"https://app.test/"}, want: true},
+		{name: "allowed site with other scheme", origin: "http://app.test", allowed: []string{"https://app.test"}},
+		{name: "opaque origin", origin: "null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://forum.test:5000/thread/1/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := originAllowed(r, tt.allowed); got != tt.want {
+				t.Fatalf("originAllowed(%q) = %t, want %t", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadWebSocketRejectsCrossOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// До подписки дело не доходит, поэтому сервис не нужен.
+	router.GET("/thread/:slug_or_id/ws", NewStreamHandler(nil, nil).ThreadEventsWebSocket)
+
+	r := httptest.NewRequest(http.MethodGet, "http://forum.test/thread/1/ws", nil)
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.Header.Set("Origin", "https://evil.test")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+// startWebSocket поднимает сервер, который после рукопожатия шлёт hello и отвечает на кадры
+// клиента, и возвращает соединение клиента с уже прочитанным ответом на рукопожатие.
+func startWebSocket(t *testing.T, key string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", func(c *gin.Context) {
+		ws, err := upgradeWebSocket(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		defer ws.Close()
+		if err := ws.WriteText([]byte("hello")); err != nil {
+			return
+		}
+		done := make(chan struct{})
+		ws.readLoop(done)
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	request := "GET /ws HTTP/1.1\r\n" +
+		"Host: " + server.Listener.Addr().String() + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Version: 13\r\n"
+	if key != "" {
+		request += "Sec-WebSocket-Key: " + key + "\r\n"
+	}
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		t.Fatalf("failed to write handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake response: %v", err)
+	}
+	return conn, reader, response
+}
+
+// clientFrame собирает замаскированный кадр клиента.
+func clientFrame(opcode byte, payload []byte, masked bool) []byte {
+	frame := []byte{0x80 | opcode, byte(len(payload))}
+	if !masked {
+		return append(frame, payload...)
+	}
+	frame[1] |= 0x80
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("failed to read frame header: %v", err)
+	}
+	if head[0]&0x80 == 0 {
+		t.Fatal("server frame is fragmented")
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("server frame is masked")
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("failed to read frame payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+func TestWebSocketHandshakeAndFraming(t *testing.T) {
+	// Ключ и ответ из примера RFC 6455, раздел 1.3.
+	conn, reader, response := startWebSocket(t, "dGhlIHNhbXBsZSBub25jZQ==")
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d, want %d", response.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if got := response.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("got Sec-WebSocket-Accept %q", got)
+	}
+	if !strings.EqualFold(response.Header.Get("Upgrade"), "websocket") {
+		t.Fatalf("got Upgrade %q", response.Header.Get("Upgrade"))
+	}
+
+	if opcode, payload := readServerFrame(t, reader); opcode != wsOpText || string(payload) != "hello" {
+		t.Fatalf("got frame %#x %q, want text \"hello\"", opcode, payload)
+	}
+
+	if _, err := conn.Write(clientFrame(wsOpPing, []byte("ping"), true)); err != nil {
+		t.Fatalf("failed to send ping: %v", err)
+	}
+	if opcode, payload := readServerFrame(t, reader); opcode != wsOpPong || string(payload) != "ping" {
+		t.Fatalf("got frame %#x %q, want pong \"ping\"", opcode, payload)
+	}
+
+	if _, err := conn.Write(clientFrame(wsOpClose, nil, true)); err != nil {
+		t.Fatalf("failed to send close: %v", err)
+	}
+	if opcode, _ := readServerFrame(t, reader); opcode != wsOpClose {
+		t.Fatalf("got frame %#x, want close", opcode)
+	}
+}
+
+func TestWebSocketRejectsUnmaskedFrame(t *testing.T) {
+	conn, reader, _ := startWebSocket(t, "dGhlIHNhbXBsZSBub25jZQ==")
+	readServerFrame(t, reader)
+
+	if _, err := conn.Write(clientFrame(wsOpPing, []byte("ping"), false)); err != nil {
+		t.Fatalf("failed to send ping: %v", err)
+	}
+	// Сервер закрывает соединение без pong.
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read until close: %v", err)
+	}
+	if len(rest) != 0 {
+		t.Fatalf("server answered an unmasked frame with %q", rest)
+	}
+}
+
+func TestWebSocketHandshakeWithoutKey(t *testing.T) {
+	_, _, response := startWebSocket(t, "")
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+	body, _ := io.ReadAll(response.Body)
+	if !bytes.Contains(body, []byte(errNotWebSocket.Error())) {
+		t.Fatalf("got body %q", body)
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type (
+	callerKey     struct{}
+	adminTokenKey struct{}
+)
+
+func WithCaller(ctx context.Context, nickname string) context.Context {
+	return context.WithValue(ctx, callerKey{}, nickname)
+}
+
+// CallerFromContext возвращает никнейм пользователя, от имени которого выполняется запрос.
+// Для анонимного запроса ok == false.
+func CallerFromContext(ctx context.Context) (string, bool) {
+	nickname, ok := ctx.Value(callerKey{}).(string)
+	return nickname, ok && nickname != ""
+}
+
+// WithAdminToken отмечает запрос, прошедший по токену администратора (X-Admin-Token):
+// у такого запроса нет вызывающего, но права у него как у администратора сайта.
+func WithAdminToken(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminTokenKey{}, true)
+}
+
+func AdminTokenFromContext(ctx context.Context) bool {
+	ok, _ := ctx.Value(adminTokenKey{}).(bool)
+	return ok
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to compare password hash: %w", err)
+}
+
+// NewToken генерирует непрозрачный токен. Клиенту отдаётся plain, в хранилище попадает только hash.
+func NewToken() (plain string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain = base64.RawURLEncoding.EncodeToString(buf)
+	return plain, HashToken(plain), nil
+}
+
+func HashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignPayload подписывает тело запроса секретом подписки; получатель сверяет подпись тем же HMAC.
+func SignPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,18 @@
+package blob
+
+import (
+	"context"
+	"io"
+)
+
+// Store хранит содержимое файлов по ключу. Набор операций повторяет базовые вызовы S3
+// (PutObject, GetObject, DeleteObject), чтобы локальный диск можно было заменить
+// S3-совместимым хранилищем, не меняя сервисы.
+type Store interface {
+	// Put записывает ровно size байт из body. contentType сохраняют хранилища, которые его поддерживают.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get открывает содержимое по ключу; models.ErrNotFound, если ключа нет.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete удаляет ключ. Удаление несуществующего ключа ошибкой не считается.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,89 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"hardhw/internal/models"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	if err := store.Put(ctx, "abcdef", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatalf("failed to put blob: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ab", "abcdef")); err != nil {
+		t.Fatalf("blob is not stored under its prefix directory: %v", err)
+	}
+
+	body, err := store.Get(ctx, "abcdef")
+	if err != nil {
+		t.Fatalf("failed to get blob: %v", err)
+	}
+	content, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("got %q (%v), want %q", content, err, "hello")
+	}
+
+	if err := store.Delete(ctx, "abcdef"); err != nil {
+		t.Fatalf("failed to delete blob: %v", err)
+	}
+	if _, err := store.Get(ctx, "abcdef"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("got error %v after delete, want %v", err, models.ErrNotFound)
+	}
+	if err := store.Delete(ctx, "abcdef"); err != nil {
+		t.Fatalf("deleting a missing blob failed: %v", err)
+	}
+}
+
+func TestLocalStoreRejectsSizeMismatch(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	for _, body := range []string{"hey", "hello, world"} {
+		if err := store.Put(ctx, "abcdef", strings.NewReader(body), 5, "text/plain"); err == nil {
+			t.Fatalf("put of %d bytes as 5 succeeded", len(body))
+		}
+	}
+	if _, err := store.Get(ctx, "abcdef"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("failed put left a blob behind: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "ab"))
+	if err != nil {
+		t.Fatalf("failed to read blob directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("failed put left %d temporary files", len(entries))
+	}
+}
+
+func TestLocalStoreRejectsUnsafeKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	for _, key := range []string{"", "ab", "../etc/passwd", `ab\cd`, "ab/cd", ".hidden"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("put with key %q succeeded", key)
+		}
+		if _, err := store.Get(context.Background(), key); err == nil || errors.Is(err, models.ErrNotFound) {
+			t.Errorf("get with key %q returned %v, want an invalid key error", key, err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// contextLines — сколько неизменённых строк показывается вокруг каждого изменения.
+const contextLines = 3
+
+// maxLCSCells ограничивает таблицу LCS: для огромных текстов diff вырождается в «всё удалено, всё добавлено».
+const maxLCSCells = 4_000_000
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+	// aLine и bLine — номера строк (с нуля) в старом и новом тексте перед этой операцией.
+	aLine, bLine int
+}
+
+// Unified возвращает построчный diff в формате unified diff. Для одинаковых текстов — пустая строка.
+func Unified(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := editScript(splitLines(from), splitLines(to))
+	// Тексты могут различаться только последним переводом строки, который splitLines отбрасывает.
+	if !slices.ContainsFunc(ops, func(o op) bool { return o.kind != opEqual }) {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+
+	for start := 0; start < len(ops); {
+		// Ищем следующее изменение и собираем вокруг него ханк с контекстом.
+		first := start
+		for first < len(ops) && ops[first].kind == opEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		hunkStart := max(first-contextLines, start)
+
+		end, equalRun := first, 0
+		for end < len(ops) {
+			if ops[end].kind == opEqual {
+				equalRun++
+				if equalRun > 2*contextLines {
+					equalRun--
+					break
+				}
+			} else {
+				equalRun = 0
+			}
+			end++
+		}
+		// Отрезаем хвост общих строк до contextLines.
+		if equalRun > contextLines {
+			end -= equalRun - contextLines
+		}
+
+		writeHunk(&b, ops[hunkStart:end])
+		start = end
+	}
+	return b.String()
+}
+
+func writeHunk(b *strings.Builder, ops []op) {
+	aCount, bCount := 0, 0
+	for _, o := range ops {
+		if o.kind != opInsert {
+			aCount++
+		}
+		if o.kind != opDelete {
+			bCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(ops[0].aLine, aCount), hunkRange(ops[0].bLine, bCount))
+	for _, o := range ops {
+		b.WriteByte(byte(o.kind))
+		b.WriteString(o.line)
+		b.WriteByte('\n')
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// editScript строит кратчайшую последовательность удалений и вставок через наибольшую общую подпоследовательность.
+func editScript(a, b []string) []op {
+	// Общие начало и конец не участвуют в LCS — так таблица обычно совсем маленькая.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	ops := make([]op, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, op{kind: opEqual, line: a[i], aLine: i, bLine: i})
+	}
+
+	ai, bi := prefix, prefix
+	emit := func(kind opKind, line string) {
+		ops = append(ops, op{kind: kind, line: line, aLine: ai, bLine: bi})
+		if kind != opInsert {
+			ai++
+		}
+		if kind != opDelete {
+			bi++
+		}
+	}
+
+	if len(midA)*len(midB) > maxLCSCells {
+		for _, line := range midA {
+			emit(opDelete, line)
+		}
+		for _, line := range midB {
+			emit(opInsert, line)
+		}
+	} else {
+		// lcs[i][j] — длина LCS для midA[i:] и midB[j:].
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				emit(opEqual, midA[i])
+				i++
+				j++
+			case j < len(midB) && (i == len(midA) || lcs[i][j+1] > lcs[i+1][j]):
+				emit(opInsert, midB[j])
+				j++
+			default:
+				emit(opDelete, midA[i])
+				i++
+			}
+		}
+	}
+
+	for k := len(a) - suffix; k < len(a); k++ {
+		emit(opEqual, a[k])
+	}
+	return ops
+}
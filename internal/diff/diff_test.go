@@ -0,0 +1,37 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{name: "same text", from: "a\nb", to: "a\nb", want: ""},
+		{name: "replaced line", from: "a", to: "b", want: "--- old\n+++ new\n@@ -1 +1 @@\n-a\n+b\n"},
+		{name: "from empty", from: "", to: "a\nb", want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{name: "to empty", from: "a", to: "", want: "--- old\n+++ new\n@@ -1 +0,0 @@\n-a\n"},
+		{name: "trailing newline is ignored", from: "a\n", to: "a", want: ""},
+		{
+			name: "context around change",
+			from: "1\n2\n3\n4\n5\n6\n7\n8",
+			to:   "1\n2\n3\n4\nfive\n6\n7\n8",
+			want: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "distant changes get separate hunks",
+			from: "a\n1\n2\n3\n4\n5\n6\n7\nb",
+			to:   "A\n1\n2\n3\n4\n5\n6\n7\nB",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.from, tt.to); got != tt.want {
+				t.Fatalf("got diff\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
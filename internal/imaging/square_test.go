@@ -0,0 +1,67 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSquareCropsCenter(t *testing.T) {
+	// Полосы по краям красные, центральный квадрат синий: после обрезки красного быть не должно.
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 100 && x < 200 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	for _, size := range []int{256, 64, 32, 7} {
+		dst := Square(src, size)
+		if bounds := dst.Bounds(); bounds.Dx() != size || bounds.Dy() != size {
+			t.Fatalf("got %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), size, size)
+		}
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				if got := dst.RGBAAt(x, y); got != (color.RGBA{B: 255, A: 255}) {
+					t.Fatalf("size %d: pixel (%d, %d) is %v, want blue", size, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestSquareAveragesPixels(t *testing.T) {
+	// Шахматная доска 2×2 из чёрного и белого сжимается в один серый пиксель.
+	src := image.NewGray(image.Rect(0, 0, 2, 2))
+	src.Pix = []uint8{0, 255, 255, 0}
+
+	got := Square(src, 1).RGBAAt(0, 0)
+	if got.R < 127 || got.R > 128 || got.R != got.G || got.G != got.B || got.A != 255 {
+		t.Fatalf("got %v, want mid grey", got)
+	}
+}
+
+func TestSquareEmptySource(t *testing.T) {
+	dst := Square(image.NewRGBA(image.Rect(0, 0, 0, 10)), 4)
+	if bounds := dst.Bounds(); bounds.Dx() != 4 || bounds.Dy() != 4 {
+		t.Fatalf("got %dx%d, want 4x4", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestBoxWeightsSumToOne(t *testing.T) {
+	for _, tt := range []struct{ side, n int }{{100, 7}, {256, 256}, {3, 8}, {4096, 32}} {
+		for i, taps := range boxWeights(tt.side, tt.n) {
+			var sum float32
+			for _, tap := range taps {
+				sum += tap.weight
+			}
+			if sum < 0.999 || sum > 1.001 {
+				t.Fatalf("boxWeights(%d, %d)[%d] sums to %v", tt.side, tt.n, i, sum)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package markdown
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// Cache хранит HTML последних отрисованных текстов и вытесняет давно не запрошенные.
+// Ключ — хеш исходного текста, поэтому каждая ревизия поста рендерится один раз,
+// а после правки старая запись просто уходит в хвост и вытесняется.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	html string
+}
+
+// NewCache создаёт кеш на size текстов. При size <= 0 каждый текст рендерится заново.
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// Render возвращает HTML текста из кеша, а при промахе рендерит его и запоминает.
+func (c *Cache) Render(text string) string {
+	if c.size <= 0 {
+		return Render(text)
+	}
+	key := sha256.Sum256([]byte(text))
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		html := element.Value.(*cacheEntry).html
+		c.mu.Unlock()
+		return html
+	}
+	c.mu.Unlock()
+
+	// Рендер идёт без блокировки: одинаковый текст могут отрисовать дважды, но результат совпадёт.
+	html := Render(text)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return html
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, html: html})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	return html
+}
@@ -0,0 +1,468 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxQuoteDepth и maxInlineDepth ограничивают вложенность цитат и выделений: глубже разметка выводится как текст.
+	maxQuoteDepth  = 8
+	maxInlineDepth = 8
+)
+
+// Render превращает Markdown в HTML. Поддерживается безопасное подмножество: абзацы с переносами строк,
+// заголовки, цитаты, списки, блоки и фрагменты кода, горизонтальная черта, выделение, зачёркивание и ссылки.
+// Сырой HTML из текста экранируется, ссылки допускаются только http(s), mailto и относительные.
+func Render(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var b strings.Builder
+	renderBlocks(&b, lines, 0)
+	return b.String()
+}
+
+func renderBlocks(b *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case line == "":
+			i++
+		case strings.HasPrefix(line, "```"):
+			i = renderCodeBlock(b, lines, i)
+		case isRule(line):
+			b.WriteString("<hr>\n")
+			i++
+		case headingLevel(line) > 0:
+			level := headingLevel(line)
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderInline(strings.TrimSpace(line[level:])), level)
+			i++
+		case isQuote(line, depth):
+			quoted := make([]string, 0)
+			for ; i < len(lines) && isQuote(strings.TrimSpace(lines[i]), depth); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted, depth+1)
+			b.WriteString("</blockquote>\n")
+		case isListItem(line):
+			i = renderList(b, lines, i)
+		default:
+			paragraph := []string{line}
+			for i++; i < len(lines); i++ {
+				next := strings.TrimSpace(lines[i])
+				if next == "" || startsBlock(next, depth) {
+					break
+				}
+				paragraph = append(paragraph, next)
+			}
+			fmt.Fprintf(b, "<p>%s</p>\n", renderInline(strings.Join(paragraph, "\n")))
+		}
+	}
+}
+
+// startsBlock сообщает, прерывает ли строка абзац.
+func startsBlock(line string, depth int) bool {
+	return strings.HasPrefix(line, "```") || isRule(line) || headingLevel(line) > 0 || isQuote(line, depth) || isListItem(line)
+}
+
+func renderCodeBlock(b *strings.Builder, lines []string, start int) int {
+	language := codeLanguage(strings.TrimSpace(strings.TrimSpace(lines[start])[3:]))
+	if language != "" {
+		fmt.Fprintf(b, `<pre><code class="language-%s">`, language)
+	} else {
+		b.WriteString("<pre><code>")
+	}
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+			i++
+			break
+		}
+		b.WriteString(html.EscapeString(lines[i]))
+		b.WriteByte('\n')
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// codeLanguage оставляет от языка блока кода только буквы, цифры и _+-, чтобы его можно было вписать в class.
+func codeLanguage(info string) string {
+	if fields := strings.Fields(info); len(fields) > 0 {
+		info = fields[0]
+	}
+	for _, r := range info {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '+' || r == '-') {
+			return ""
+		}
+	}
+	return info
+}
+
+// isRule узнаёт горизонтальную черту: не меньше трёх '-', '*' или '_', возможно через пробелы.
+func isRule(line string) bool {
+	marker, count := line[0], 0
+	if marker != '-' && marker != '*' && marker != '_' {
+		return false
+	}
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case marker:
+			count++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return 0
+	}
+	return level
+}
+
+func isQuote(line string, depth int) bool {
+	return strings.HasPrefix(line, ">") && depth < maxQuoteDepth
+}
+
+// listItem разбирает пункт списка: "- ", "* ", "+ " или "1. ", "1) ". number равен -1 у маркированного списка.
+func listItem(line string) (number int, content string, ok bool) {
+	if len(line) >= 2 && (line[0] == '-' || line[0] == '*' || line[0] == '+') && line[1] == ' ' {
+		return -1, strings.TrimSpace(line[2:]), true
+	}
+	digits := 0
+	for digits < len(line) && digits < 9 && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits+1 >= len(line) || (line[digits] != '.' && line[digits] != ')') || line[digits+1] != ' ' {
+		return 0, "", false
+	}
+	number, _ = strconv.Atoi(line[:digits])
+	return number, strings.TrimSpace(line[digits+2:]), true
+}
+
+func isListItem(line string) bool {
+	_, _, ok := listItem(line)
+	return ok
+}
+
+// renderList выводит подряд идущие пункты одного вида. Строка с отступом продолжает предыдущий пункт.
+func renderList(b *strings.Builder, lines []string, start int) int {
+	first, _, _ := listItem(strings.TrimSpace(lines[start]))
+	ordered := first >= 0
+	switch {
+	case !ordered:
+		b.WriteString("<ul>\n")
+	case first == 1:
+		b.WriteString("<ol>\n")
+	default:
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", first)
+	}
+
+	items := make([]string, 0)
+	i := start
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			break
+		}
+		if number, content, ok := listItem(line); ok {
+			if (number >= 0) != ordered {
+				break
+			}
+			items = append(items, content)
+			continue
+		}
+		if lines[i][0] != ' ' && lines[i][0] != '\t' {
+			break
+		}
+		items[len(items)-1] += "\n" + line
+	}
+
+	for _, item := range items {
+		fmt.Fprintf(b, "<li>%s</li>\n", renderInline(item))
+	}
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+// inlineRenderer разбирает один абзац. Он запоминает разделители, для которых закрывающей пары уже нет,
+// и ближайшие ']' и ')', чтобы текст вроде "*a *b *c ..." или "[[[[..." не просматривался заново с каждого символа.
+type inlineRenderer struct {
+	text         string
+	unmatched    map[unmatchedKey]bool
+	bracketClose int
+	parenClose   int
+}
+
+// unmatchedKey — разделитель без пары до позиции to.
+type unmatchedKey struct {
+	delimiter string
+	to        int
+}
+
+func renderInline(text string) string {
+	r := &inlineRenderer{text: text, unmatched: make(map[unmatchedKey]bool), bracketClose: -1, parenClose: -1}
+	var b strings.Builder
+	r.render(&b, 0, len(text), 0, false)
+	return b.String()
+}
+
+// render выводит r.text[from:to].
+func (r *inlineRenderer) render(b *strings.Builder, from, to int, depth int, inLink bool) {
+	text := r.text
+	for i := from; i < to; {
+		switch c := text[i]; c {
+		case '\\':
+			if i+1 < to && isPunct(text[i+1]) {
+				escapeByte(b, text[i+1])
+				i += 2
+				continue
+			}
+		case '\n':
+			b.WriteString("<br>\n")
+			i++
+			continue
+		case '`':
+			run := runLength(text[:to], i, c)
+			if end := r.codeSpanEnd(i, to, run); end > 0 {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(trimCodeSpan(text[i+run : end])))
+				b.WriteString("</code>")
+				i = end + run
+			} else {
+				b.WriteString(text[i : i+run])
+				i += run
+			}
+			continue
+		case '*', '_', '~':
+			run := runLength(text[:to], i, c)
+			if end := r.emphasisEnd(i, to, run, depth); end > 0 {
+				tag := emphasisTag(c, run)
+				b.WriteString("<" + tag + ">")
+				r.render(b, i+run, end, depth+1, inLink)
+				b.WriteString("</" + tag + ">")
+				i = end + run
+			} else {
+				b.WriteString(text[i : i+run])
+				i += run
+			}
+			continue
+		case '[':
+			if !inLink && depth < maxInlineDepth {
+				if labelEnd, href, ok := r.link(i, to); ok {
+					fmt.Fprintf(b, `<a href="%s" rel="nofollow noopener noreferrer">`, html.EscapeString(href))
+					r.render(b, i+1, labelEnd, depth+1, true)
+					b.WriteString("</a>")
+					i = r.parenClose + 1
+					continue
+				}
+			}
+		case '<':
+			if !inLink {
+				if end := strings.IndexByte(text[i:to], '>'); end > 0 {
+					target := text[i+1 : i+end]
+					if href, ok := safeURL(target); ok && strings.Contains(target, ":") {
+						escaped := html.EscapeString(href)
+						fmt.Fprintf(b, `<a href="%s" rel="nofollow noopener noreferrer">%s</a>`, escaped, escaped)
+						i += end + 1
+						continue
+					}
+				}
+			}
+		}
+		escapeByte(b, text[i])
+		i++
+	}
+}
+
+// codeSpanEnd ищет до to закрывающую последовательность из run обратных кавычек; -1, если её нет.
+func (r *inlineRenderer) codeSpanEnd(start, to, run int) int {
+	key := unmatchedKey{delimiter: r.text[start : start+run], to: to}
+	if r.unmatched[key] {
+		return -1
+	}
+	for j := start + run; j < to; {
+		if r.text[j] != '`' {
+			j++
+			continue
+		}
+		closing := runLength(r.text[:to], j, '`')
+		if closing == run {
+			return j
+		}
+		j += closing
+	}
+	r.unmatched[key] = true
+	return -1
+}
+
+func trimCodeSpan(code string) string {
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		return code[1 : len(code)-1]
+	}
+	return code
+}
+
+// emphasisEnd ищет до to пару для разделителя выделения из run символов; -1, если выделения нет.
+// '_' работает только на границе слова, чтобы не ломать snake_case.
+func (r *inlineRenderer) emphasisEnd(start, to, run int, depth int) int {
+	text := r.text
+	c := text[start]
+	if depth >= maxInlineDepth || run > 2 || (c == '~' && run != 2) {
+		return -1
+	}
+	open := start + run
+	if open >= to || isSpace(text[open]) || (c == '_' && start > 0 && isWordByte(text[start-1])) {
+		return -1
+	}
+	key := unmatchedKey{delimiter: text[start:open], to: to}
+	if r.unmatched[key] {
+		return -1
+	}
+
+	for j := open; j < to; {
+		switch text[j] {
+		case '\\':
+			j += 2
+		case '`':
+			codeRun := runLength(text[:to], j, '`')
+			if end := r.codeSpanEnd(j, to, codeRun); end > 0 {
+				j = end + codeRun
+			} else {
+				j += codeRun
+			}
+		case c:
+			closing := runLength(text[:to], j, c)
+			if closing == run && j > open && !isSpace(text[j-1]) &&
+				!(c == '_' && j+run < len(text) && isWordByte(text[j+run])) {
+				return j
+			}
+			j += closing
+		default:
+			j++
+		}
+	}
+	r.unmatched[key] = true
+	return -1
+}
+
+func emphasisTag(c byte, run int) string {
+	switch {
+	case c == '~':
+		return "del"
+	case run == 2:
+		return "strong"
+	default:
+		return "em"
+	}
+}
+
+// link разбирает [текст](адрес), начинающийся в start, и возвращает конец текста ссылки и адрес.
+// Конец всей конструкции остаётся в r.parenClose.
+func (r *inlineRenderer) link(start, to int) (labelEnd int, href string, ok bool) {
+	if r.bracketClose <= start {
+		r.bracketClose = r.next(start, ']')
+	}
+	labelEnd = r.bracketClose
+	if labelEnd == start+1 || labelEnd+1 >= to || r.text[labelEnd+1] != '(' {
+		return 0, "", false
+	}
+	if r.parenClose <= labelEnd {
+		r.parenClose = r.next(labelEnd, ')')
+	}
+	if r.parenClose >= to {
+		return 0, "", false
+	}
+
+	href, ok = safeURL(strings.TrimSpace(r.text[labelEnd+2 : r.parenClose]))
+	if !ok {
+		return 0, "", false
+	}
+	return labelEnd, href, true
+}
+
+// next возвращает позицию первого c после from или len(r.text), если его нет.
+func (r *inlineRenderer) next(from int, c byte) int {
+	if i := strings.IndexByte(r.text[from+1:], c); i >= 0 {
+		return from + 1 + i
+	}
+	return len(r.text)
+}
+
+// safeURL пропускает только адреса http(s), mailto и относительные пути: javascript: и data: отбрасываются.
+// Обратная косая в относительном пути тоже отбрасывается: браузер читает /\evil.com как //evil.com.
+func safeURL(raw string) (string, bool) {
+	if raw == "" || strings.ContainsAny(raw, " \t\n") {
+		return "", false
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return raw, parsed.Host != ""
+	case "mailto":
+		return raw, parsed.Opaque != ""
+	case "":
+		if strings.ContainsRune(raw, '\\') {
+			return "", false
+		}
+		return raw, (strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//")) || strings.HasPrefix(raw, "#")
+	default:
+		return "", false
+	}
+}
+
+func runLength(text string, start int, c byte) int {
+	n := 0
+	for start+n < len(text) && text[start+n] == c {
+		n++
+	}
+	return n
+}
+
+func escapeByte(b *strings.Builder, c byte) {
+	switch c {
+	case '<':
+		b.WriteString("&lt;")
+	case '>':
+		b.WriteString("&gt;")
+	case '&':
+		b.WriteString("&amp;")
+	case '"':
+		b.WriteString("&#34;")
+	case '\'':
+		b.WriteString("&#39;")
+	default:
+		b.WriteByte(c)
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// isWordByte считает частью слова буквы, цифры и все не-ASCII байты.
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
@@ -0,0 +1,73 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "raw html is escaped", text: `<script>alert(1)</script>`, want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{name: "inline markup", text: "**b** *i* ~~s~~ `c`", want: "<p><strong>b</strong> <em>i</em> <del>s</del> <code>c</code></p>\n"},
+		{name: "blocks", text: "# H\n\n> q\n\n- a\n- b", want: "<h1>H</h1>\n<blockquote>\n<p>q</p>\n</blockquote>\n<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{name: "escaped markup", text: `\*x\*`, want: "<p>*x*</p>\n"},
+		{name: "https link", text: "[a](https://example.com/x?y=1&z=2)", want: "<p><a href=\"https://example.com/x?y=1&amp;z=2\" rel=\"nofollow noopener noreferrer\">a</a></p>\n"},
+		{name: "relative link", text: "[a](/forum/f1)", want: "<p><a href=\"/forum/f1\" rel=\"nofollow noopener noreferrer\">a</a></p>\n"},
+		{name: "fragment link", text: "[a](#top)", want: "<p><a href=\"#top\" rel=\"nofollow noopener noreferrer\">a</a></p>\n"},
+		{name: "mailto link", text: "[a](mailto:x@example.com)", want: "<p><a href=\"mailto:x@example.com\" rel=\"nofollow noopener noreferrer\">a</a></p>\n"},
+		{name: "javascript link", text: "[a](javascript:alert(1))", want: "<p>[a](javascript:alert(1))</p>\n"},
+		{name: "data link", text: "[a](data:text/html,1)", want: "<p>[a](data:text/html,1)</p>\n"},
+		{name: "protocol-relative link", text: "[a](//evil.com)", want: "<p>[a](//evil.com)</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.text); got != tt.want {
+				t.Fatalf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{raw: "https://example.com/a", want: true},
+		{raw: "http://example.com", want: true},
+		{raw: "/thread/1", want: true},
+		{raw: "#top", want: true},
+		{raw: "mailto:x@example.com", want: true},
+		{raw: "//evil.com", want: false},
+		{raw: `/\evil.com`, want: false},
+		{raw: `/a\b`, want: false},
+		{raw: `\\evil.com`, want: false},
+		{raw: "https://", want: false},
+		{raw: "javascript:alert(1)", want: false},
+		{raw: "JavaScript:alert(1)", want: false},
+		{raw: "/a b", want: false},
+		{raw: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if _, got := safeURL(tt.raw); got != tt.want {
+			t.Errorf("safeURL(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(1)
+	if got, want := cache.Render("*a*"), Render("*a*"); got != want {
+		t.Fatalf("cached render = %q, want %q", got, want)
+	}
+	cache.Render("*b*")
+	if cache.order.Len() != 1 {
+		t.Fatalf("cache keeps %d entries, want 1", cache.order.Len())
+	}
+	if got, want := cache.Render("*a*"), Render("*a*"); got != want {
+		t.Fatalf("render after eviction = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package mention
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxMentions ограничивает число упоминаний в одном тексте, чтобы пост не рассылал уведомления всему форуму.
+const maxMentions = 20
+
+// Extract находит упоминания вида @nickname и возвращает ники без повторов (без учёта регистра)
+// в порядке первого появления. @ внутри слова, как в email, упоминанием не считается,
+// а точка в конце ника — это конец предложения.
+func Extract(text string) []string {
+	var nicknames []string
+	seen := make(map[string]struct{})
+
+	for i := 0; i < len(text) && len(nicknames) < maxMentions; {
+		at := strings.IndexByte(text[i:], '@')
+		if at < 0 {
+			break
+		}
+		at += i
+		i = at + 1
+
+		if at > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(text[:at])
+			if isNicknameRune(prev) {
+				continue
+			}
+		}
+
+		end := i
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isNicknameRune(r) {
+				break
+			}
+			end += size
+		}
+		nickname := strings.TrimRight(text[i:end], ".")
+		i = end
+		if nickname == "" {
+			continue
+		}
+
+		key := strings.ToLower(nickname)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		nicknames = append(nicknames, nickname)
+	}
+	return nicknames
+}
+
+func isNicknameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
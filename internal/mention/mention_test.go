@@ -0,0 +1,45 @@
+package mention
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	// Лишние упоминания сверх maxMentions отбрасываются.
+	var many, kept []string
+	for i := 0; i < maxMentions+5; i++ {
+		many = append(many, fmt.Sprintf("@user%d", i))
+		if i < maxMentions {
+			kept = append(kept, fmt.Sprintf("user%d", i))
+		}
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "no mentions", text: "hello"},
+		{name: "single", text: "hi @ann", want: []string{"ann"}},
+		{name: "punctuation around", text: "(@ann), @bob! @eve?", want: []string{"ann", "bob", "eve"}},
+		{name: "dot ends a sentence", text: "thanks @ann.", want: []string{"ann"}},
+		{name: "dot inside a nickname", text: "@j.smith. ok", want: []string{"j.smith"}},
+		{name: "email is not a mention", text: "write to ann@example.com"},
+		{name: "repeats in other case", text: "@Ann and @ann and @ANN", want: []string{"Ann"}},
+		{name: "bare at signs", text: "@ @. @@"},
+		{name: "double at sign", text: "@@ann", want: []string{"ann"}},
+		{name: "unicode", text: "привет, @Вася_1!", want: []string{"Вася_1"}},
+		{name: "limit", text: strings.Join(many, " "), want: kept},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.text); !slices.Equal(got, tt.want) {
+				t.Fatalf("Extract(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"context"
+	"hardhw/internal/pgtest"
+	"hardhw/migrations"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_second.up.sql":   {Data: []byte("up 2")},
+		"0001_first.up.sql":    {Data: []byte("up 1")},
+		"0001_first.down.sql":  {Data: []byte("down 1")},
+		"0010_tenth.up.sql":    {Data: []byte("up 10")},
+		"README.md":            {Data: []byte("not a migration")},
+		"0003_Bad_Name.up.sql": {Data: []byte("ignored")},
+	}
+
+	got, err := load(fsys)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	want := []Migration{
+		{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+		{Version: 2, Name: "second", Up: "up 2"},
+		{Version: 10, Name: "tenth", Up: "up 10"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d migrations, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("migration %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{
+			name: "no up file",
+			fsys: fstest.MapFS{"0001_first.down.sql": {Data: []byte("down")}},
+		},
+		{
+			name: "conflicting names",
+			fsys: fstest.MapFS{
+				"0001_first.up.sql":   {Data: []byte("up")},
+				"0001_other.down.sql": {Data: []byte("down")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := load(tt.fsys); err == nil {
+				t.Fatal("load succeeded")
+			}
+		})
+	}
+}
+
+// TestUpDown применяет все миграции репозитория, откатывает их и применяет снова:
+// так проверяются и down-файлы.
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.NewPool(t)
+	m, err := New(pool, migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	total := len(m.migrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	if len(applied) != total {
+		t.Fatalf("applied %d migrations, want %d", len(applied), total)
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second run applied %d migrations (%v), want none", len(applied), err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("migration %d_%s is not applied", status.Version, status.Name)
+		}
+	}
+
+	var placeholders int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE nickname = 'deleted'`).Scan(&placeholders); err != nil {
+		t.Fatalf("failed to look up deleted user placeholder: %v", err)
+	}
+	if placeholders != 1 {
+		t.Fatalf("found %d deleted user placeholders, want 1", placeholders)
+	}
+
+	rolledBack, err := m.Down(ctx, total)
+	if err != nil {
+		t.Fatalf("failed to roll back migrations: %v", err)
+	}
+	if len(rolledBack) != total {
+		t.Fatalf("rolled back %d migrations, want %d", len(rolledBack), total)
+	}
+	if rolledBack[0].Version != m.migrations[total-1].Version {
+		t.Fatalf("rolled back %d first, want the newest migration", rolledBack[0].Version)
+	}
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != total {
+		t.Fatalf("reapplied %d migrations (%v), want %d", len(applied), err, total)
+	}
+}
+
+// TestDeletedUserMigration проверяет, что 0023 не делает служебным обычный аккаунт 'deleted',
+// но принимает служебного пользователя, созданного до миграции.
+func TestDeletedUserMigration(t *testing.T) {
+	tests := []struct {
+		name    string
+		insert  string
+		wantErr bool
+	}{
+		{
+			name:    "regular account",
+			insert:  `INSERT INTO users (nickname, fullname, email, about, password_hash) VALUES ('deleted', 'Dee', 'dee@example.com', '', 'hash')`,
+			wantErr: true,
+		},
+		{
+			name:    "regular account without password",
+			insert:  `INSERT INTO users (nickname, fullname, email, about) VALUES ('Deleted', 'Dee', 'dee@example.com', '')`,
+			wantErr: true,
+		},
+		{
+			name:   "old placeholder",
+			insert: `INSERT INTO users (nickname, fullname, email, about) VALUES ('deleted', 'Deleted user', 'deleted@deleted.invalid', '')`,
+		},
+		{
+			name:   "placeholder email taken",
+			insert: `INSERT INTO users (nickname, fullname, email, about) VALUES ('ann', 'Ann', 'deleted@deleted.invalid', '')`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pool := pgtest.NewPool(t)
+			m, err := New(pool, migrations.FS)
+			if err != nil {
+				t.Fatalf("failed to load migrations: %v", err)
+			}
+			all := m.migrations
+			for i, migration := range all {
+				if migration.Name == "deleted_user" {
+					m.migrations = all[:i]
+				}
+			}
+			if _, err := m.Up(ctx); err != nil {
+				t.Fatalf("failed to apply migrations before deleted_user: %v", err)
+			}
+			if _, err := pool.Exec(ctx, tt.insert); err != nil {
+				t.Fatalf("failed to insert user: %v", err)
+			}
+
+			m.migrations = all
+			_, err = m.Up(ctx)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("deleted_user migration adopted a regular account")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to apply deleted_user migration: %v", err)
+			}
+			var fullname string
+			var hash *string
+			err = pool.QueryRow(ctx, `SELECT fullname, password_hash FROM users WHERE nickname = 'deleted'`).Scan(&fullname, &hash)
+			if err != nil || fullname != "Deleted user" || hash != nil {
+				t.Fatalf("placeholder is %q with password %v (%v)", fullname, hash, err)
+			}
+		})
+	}
+}
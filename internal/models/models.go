@@ -1,23 +1,39 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 )
 
 type User struct {
-	Nickname string `json:"nickname"`
-	Fullname string `json:"fullname"`
-	About    string `json:"about"`
-	Email    string `json:"email"`
+	Nickname     string `json:"nickname"`
+	Fullname     string `json:"fullname"`
+	About        string `json:"about"`
+	Email        string `json:"email"`
+	Password     string `json:"password,omitempty"`
+	PasswordHash string `json:"-"`
+	// Avatar — адрес аватарки; AvatarKey — её ключ в хранилище файлов, меняется при каждой загрузке.
+	Avatar    string `json:"avatar,omitempty"`
+	AvatarKey string `json:"-"`
 }
 
 type Forum struct {
-	Title   string `json:"title"`
-	User    string `json:"user"`
-	Slug    string `json:"slug"`
-	Posts   int64  `json:"posts,omitempty"`
-	Threads int32  `json:"threads,omitempty"`
+	Title    string  `json:"title"`
+	User     string  `json:"user"`
+	Slug     string  `json:"slug"`
+	Posts    int64   `json:"posts,omitempty"`
+	Threads  int32   `json:"threads,omitempty"`
+	Parent   *string `json:"parent,omitempty"`
+	Category bool    `json:"category,omitempty"`
+}
+
+// ForumNode — форум в дереве; Total* включают счётчики всех вложенных форумов.
+type ForumNode struct {
+	Forum
+	TotalPosts   int64       `json:"totalPosts"`
+	TotalThreads int64       `json:"totalThreads"`
+	Children     []ForumNode `json:"children,omitempty"`
 }
 
 type Thread struct {
@@ -29,19 +45,62 @@ type Thread struct {
 	Votes   int32     `json:"votes"`
 	Slug    *string   `json:"slug,omitempty"`
 	Created time.Time `json:"created"`
+	State   string    `json:"state,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+	// Pinned держит ветку вверху списка форума, Featured выводит её в общий список избранного.
+	Pinned   bool  `json:"pinned,omitempty"`
+	Featured bool  `json:"featured,omitempty"`
+	Poll     *Poll `json:"poll,omitempty"`
+	// MessageHTML — отрисованный Markdown сообщения, только для запросов с format=html.
+	MessageHTML string `json:"messageHtml,omitempty"`
 }
 
+// TagCount — сколько веток форума отмечено тегом.
+type TagCount struct {
+	Tag     string `json:"tag"`
+	Threads int64  `json:"threads"`
+}
+
+const (
+	ThreadStateOpen     = "open"
+	ThreadStateLocked   = "locked"
+	ThreadStateArchived = "archived"
+	ThreadStateDeleted  = "deleted"
+)
+
 type Post struct {
 	ID           int64     `json:"id"`
 	Parent       int64     `json:"parent"`
 	Author       string    `json:"author"`
 	Message      string    `json:"message"`
 	IsEdited     bool      `json:"isEdited"`
+	IsDeleted    bool      `json:"isDeleted,omitempty"`
 	Forum        string    `json:"forum"`
 	Thread       int64     `json:"thread"`
 	Created      time.Time `json:"created"`
 	Path         []int64   `json:"-"`
 	RootParentID int64     `json:"-"`
+	// Score — разница голосов «за» и «против», Reactions — число реакций каждого вида.
+	Score     int32            `json:"score,omitempty"`
+	Reactions map[string]int32 `json:"reactions,omitempty"`
+	// Mentions — упомянутые существующие пользователи; заполняет сервис при создании и правке.
+	Mentions []string `json:"-"`
+	// MessageHTML — отрисованный Markdown сообщения, только для запросов с format=html.
+	MessageHTML string `json:"messageHtml,omitempty"`
+	// Attachments — прикреплённые файлы. При создании поста достаточно указать их id.
+	Attachments []PostAttachment `json:"attachments,omitempty"`
+}
+
+const (
+	ReactionUp   = "up"
+	ReactionDown = "down"
+)
+
+// Reaction ставит или снимает реакцию Kind пользователя Nickname.
+type Reaction struct {
+	Nickname string `json:"nickname"`
+	Kind     string `json:"kind"`
+	Remove   bool   `json:"remove,omitempty"`
 }
 
 type Vote struct {
@@ -49,9 +108,138 @@ type Vote struct {
 	Voice    int    `json:"voice"`
 }
 
+// Poll — опрос ветки. Варианты нумеруются с 1, Closed вычисляется по Closes при чтении.
+type Poll struct {
+	Question string       `json:"question"`
+	Options  []PollOption `json:"options"`
+	Multiple bool         `json:"multiple,omitempty"`
+	Closes   *time.Time   `json:"closes,omitempty"`
+	Closed   bool         `json:"closed,omitempty"`
+	Voters   int32        `json:"voters"`
+}
+
+type PollOption struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Votes int32  `json:"votes"`
+}
+
+// PollVote заменяет выбор пользователя в опросе; пустой Options снимает голос.
+type PollVote struct {
+	Nickname string `json:"nickname"`
+	Options  []int  `json:"options"`
+}
+
+const (
+	NotificationKindMention      = "mention"
+	NotificationKindReply        = "reply"
+	NotificationKindSubscription = "subscription"
+)
+
+// Notification сообщает пользователю о новом посте. Kind — причина: упоминание,
+// ответ на его пост или подписка на ветку или форум.
+type Notification struct {
+	ID      int64     `json:"id"`
+	Kind    string    `json:"kind"`
+	Post    int64     `json:"post"`
+	Thread  int64     `json:"thread"`
+	Forum   string    `json:"forum"`
+	Author  string    `json:"author"`
+	Read    bool      `json:"read"`
+	Created time.Time `json:"created"`
+}
+
+type Subscriptions struct {
+	Threads []int64  `json:"threads"`
+	Forums  []string `json:"forums"`
+}
+
+// Subscription — тело запросов подписки и отписки.
+type Subscription struct {
+	Nickname string `json:"nickname"`
+}
+
+// Attachment — загруженный файл. Пока Post равен 0, файл ни к одному посту не привязан.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	Owner       string    `json:"owner"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	Post        int64     `json:"post,omitempty"`
+	Created     time.Time `json:"created"`
+	// Key — имя файла в хранилище блобов.
+	Key string `json:"-"`
+}
+
+// PostAttachment — описание вложения внутри поста.
+type PostAttachment struct {
+	ID          int64  `json:"id"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// Conversation — личная переписка. Unread считается для участника, который её запросил:
+// это чужие сообщения новее последнего прочитанного.
+type Conversation struct {
+	ID          int64                `json:"id"`
+	Members     []string             `json:"members"`
+	Created     time.Time            `json:"created"`
+	Updated     time.Time            `json:"updated"`
+	LastMessage *ConversationMessage `json:"lastMessage,omitempty"`
+	Unread      int64                `json:"unread"`
+}
+
+type ConversationMessage struct {
+	ID           int64     `json:"id"`
+	Conversation int64     `json:"conversation"`
+	Author       string    `json:"author"`
+	Message      string    `json:"message"`
+	Created      time.Time `json:"created"`
+}
+
+// NewConversation — тело запроса на создание переписки; создатель в Members не указывается.
+type NewConversation struct {
+	Members []string `json:"members"`
+}
+
+type ConversationPage struct {
+	Conversations []Conversation `json:"conversations"`
+	Next          string         `json:"next,omitempty"`
+}
+
+// ConversationCursor — позиция последней переписки страницы: списки идут по убыванию Updated, затем ID.
+type ConversationCursor struct {
+	Updated time.Time
+	ID      int64
+}
+
 type ThreadUpdate struct {
 	Title   *string `json:"title,omitempty"`
 	Message *string `json:"message,omitempty"`
+	Slug    *string `json:"slug,omitempty"`
+	// Tags заменяет набор тегов целиком; пустой список снимает все теги.
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// ThreadRevision хранит заголовок, текст и slug ветки до правки; Diff относится к тексту.
+type ThreadRevision struct {
+	ID      int64     `json:"id"`
+	Thread  int64     `json:"thread"`
+	Editor  string    `json:"editor,omitempty"`
+	Title   string    `json:"title"`
+	Message string    `json:"message"`
+	Slug    *string   `json:"slug,omitempty"`
+	Created time.Time `json:"created"`
+	Diff    string    `json:"diff,omitempty"`
+}
+
+type ForumUpdate struct {
+	Title *string `json:"title,omitempty"`
+	Slug  *string `json:"slug,omitempty"`
+	// Parent переносит форум; пустая строка делает его корневым.
+	Parent *string `json:"parent,omitempty"`
 }
 
 type PostUpdate struct {
@@ -59,10 +247,249 @@ type PostUpdate struct {
 }
 
 type PostDetailsResponse struct {
-	Author *User   `json:"author,omitempty"`
-	Post   *Post   `json:"post"`
-	Thread *Thread `json:"thread,omitempty"`
-	Forum  *Forum  `json:"forum,omitempty"`
+	Author    *User          `json:"author,omitempty"`
+	Post      *Post          `json:"post"`
+	Thread    *Thread        `json:"thread,omitempty"`
+	Forum     *Forum         `json:"forum,omitempty"`
+	Revisions []PostRevision `json:"revisions,omitempty"`
+}
+
+// PostRevision хранит текст поста до правки; Diff показывает, что эта правка изменила.
+type PostRevision struct {
+	ID      int64     `json:"id"`
+	Post    int64     `json:"post"`
+	Editor  string    `json:"editor,omitempty"`
+	Message string    `json:"message"`
+	Created time.Time `json:"created"`
+	Diff    string    `json:"diff,omitempty"`
+}
+
+type Credentials struct {
+	Nickname string `json:"nickname"`
+	Password string `json:"password"`
+}
+
+type PasswordChange struct {
+	Password    string `json:"password"`
+	OldPassword string `json:"oldPassword,omitempty"`
+}
+
+type AuthToken struct {
+	ID      int64      `json:"id"`
+	User    string     `json:"user"`
+	Kind    string     `json:"kind"`
+	Name    string     `json:"name,omitempty"`
+	Token   string     `json:"token,omitempty"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+const (
+	TokenKindSession = "session"
+	TokenKindAPI     = "api"
+)
+
+const (
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+)
+
+type ForumBan struct {
+	Forum    string     `json:"forum"`
+	User     string     `json:"user"`
+	BannedBy string     `json:"bannedBy,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Created  time.Time  `json:"created"`
+}
+
+type Suspension struct {
+	User        string     `json:"user"`
+	SuspendedBy string     `json:"suspendedBy,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	Until       *time.Time `json:"until,omitempty"`
+	Created     time.Time  `json:"created"`
+}
+
+// DeletedUserNickname — служебный пользователь, которому передаются ветки и посты удалённых аккаунтов.
+const DeletedUserNickname = "deleted"
+
+const (
+	DeleteModeAnonymize = "anonymize"
+	DeleteModeErase     = "erase"
+)
+
+type UserVote struct {
+	Thread int64 `json:"thread"`
+	Voice  int   `json:"voice"`
+}
+
+type UserReaction struct {
+	Post int64  `json:"post"`
+	Kind string `json:"kind"`
+}
+
+type UserPollVote struct {
+	Thread int64 `json:"thread"`
+	Option int   `json:"option"`
+}
+
+// UserFiles — файлы удалённого аккаунта в хранилище блобов. Записи о них удаляются вместе
+// с аккаунтом, а сами файлы сервис убирает уже после фиксации транзакции.
+type UserFiles struct {
+	Attachments []string
+	// Avatar — ключ аватарки, по которому хранятся её копии всех размеров.
+	Avatar string
+}
+
+// UserExport — все данные пользователя, которые хранит форум.
+type UserExport struct {
+	User        User                  `json:"user"`
+	Exported    time.Time             `json:"exported"`
+	Aliases     []string              `json:"aliases"`
+	Forums      []Forum               `json:"forums"`
+	Threads     []Thread              `json:"threads"`
+	Posts       []Post                `json:"posts"`
+	Votes       []UserVote            `json:"votes"`
+	Reactions   []UserReaction        `json:"reactions"`
+	PollVotes   []UserPollVote        `json:"pollVotes"`
+	Attachments []Attachment          `json:"attachments"`
+	Messages    []ConversationMessage `json:"messages"`
+	Blocked     []string              `json:"blocked"`
+	Moderates   []string              `json:"moderates"`
+	Bans        []ForumBan            `json:"bans"`
+	Suspension  *Suspension           `json:"suspension,omitempty"`
+	Tokens      []AuthToken           `json:"tokens"`
+}
+
+const (
+	SearchKindPost   = "post"
+	SearchKindThread = "thread"
+)
+
+type SearchQuery struct {
+	Query  string
+	Forum  string
+	Author string
+	Since  *time.Time
+	Limit  int
+	After  *SearchCursor
+}
+
+// SearchCursor — позиция последнего результата страницы для keyset-пагинации.
+type SearchCursor struct {
+	Rank float32
+	Kind string
+	ID   int64
+}
+
+type SearchHit struct {
+	Kind string  `json:"type"`
+	Rank float32 `json:"rank"`
+	// Snippet — HTML: текст экранирован, совпадения выделены тегами <b>.
+	Snippet string  `json:"snippet"`
+	Post    *Post   `json:"post,omitempty"`
+	Thread  *Thread `json:"thread,omitempty"`
+	Forum   *Forum  `json:"forum,omitempty"`
+}
+
+type SearchResult struct {
+	Hits []SearchHit `json:"results"`
+	Next string      `json:"next,omitempty"`
+}
+
+const (
+	ThreadEventPost = "post"
+	ThreadEventEdit = "edit"
+	ThreadEventVote = "vote"
+)
+
+// ThreadNotification передаётся между репликами через NOTIFY и содержит только
+// идентификаторы: размер payload ограничен, сами посты читаются из базы.
+type ThreadNotification struct {
+	Type   string  `json:"type"`
+	Thread int64   `json:"thread"`
+	Posts  []int64 `json:"posts,omitempty"`
+	Votes  int32   `json:"votes"`
+}
+
+type ThreadEvent struct {
+	Type   string `json:"type"`
+	Thread int64  `json:"thread"`
+	Post   *Post  `json:"post,omitempty"`
+	Votes  *int32 `json:"votes,omitempty"`
+}
+
+const (
+	WebhookEventThreadCreated = "thread.created"
+	WebhookEventPostCreated   = "post.created"
+	WebhookEventPostUpdated   = "post.updated"
+	WebhookEventThreadVoted   = "thread.voted"
+	WebhookEventUserUpdated   = "user.updated"
+)
+
+var WebhookEvents = []string{
+	WebhookEventThreadCreated,
+	WebhookEventPostCreated,
+	WebhookEventPostUpdated,
+	WebhookEventThreadVoted,
+	WebhookEventUserUpdated,
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook — подписка форума на события. Secret отдаётся клиенту только при создании.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	Forum     string    `json:"forum"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	Active    bool      `json:"active"`
+	Created   time.Time `json:"created"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	Webhook        int64           `json:"webhook"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttempt    time.Time       `json:"nextAttempt"`
+	LastError      string          `json:"lastError,omitempty"`
+	ResponseStatus *int            `json:"responseStatus,omitempty"`
+	Created        time.Time       `json:"created"`
+	Delivered      *time.Time      `json:"delivered,omitempty"`
+}
+
+// WebhookDispatch — доставка, взятая воркером в работу, вместе с адресом и секретом подписки.
+type WebhookDispatch struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// WebhookPayload — тело запроса к подписчику. Forum заполняет хранилище для каждой подписки.
+type WebhookPayload struct {
+	Event   string    `json:"event"`
+	Forum   string    `json:"forum"`
+	Created time.Time `json:"created"`
+	Data    any       `json:"data"`
+}
+
+// WebhookUser — пользователь в событии user.updated. Email подписчикам форума не передаётся.
+type WebhookUser struct {
+	Nickname string `json:"nickname"`
+	Fullname string `json:"fullname"`
+	About    string `json:"about"`
 }
 
 type Status struct {
@@ -80,6 +507,55 @@ var (
 	ErrForumConflict  = errors.New("forum conflict")
 	ErrThreadConflict = errors.New("thread conflict")
 
+	ErrForumCycle    = errors.New("forum hierarchy cycle")
+	ErrForumCategory = errors.New("forum is a category")
+
+	ErrThreadLocked        = errors.New("thread locked")
+	ErrThreadStateConflict = errors.New("thread state conflict")
+
 	ErrParentNotFound = errors.New("parent not found")
 	ErrPostNotFound   = errors.New("post not found")
+	ErrPostDeleted    = errors.New("post deleted")
+
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrWeakPassword       = errors.New("password too short")
+
+	ErrUserBanned    = errors.New("user banned")
+	ErrUserSuspended = errors.New("user suspended")
+	ErrInvalidExpiry = errors.New("expiry is in the past")
+
+	ErrEmptySearchQuery = errors.New("empty search query")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	ErrInvalidUpdate   = errors.New("invalid slug or title")
+	ErrInvalidNickname = errors.New("invalid nickname")
+
+	ErrInvalidDeleteMode = errors.New("invalid delete mode")
+
+	ErrInvalidTags = errors.New("invalid tags")
+
+	ErrInvalidReaction = errors.New("invalid reaction")
+
+	ErrInvalidPoll     = errors.New("invalid poll")
+	ErrInvalidPollVote = errors.New("invalid poll vote")
+	ErrPollNotFound    = errors.New("poll not found")
+	ErrPollClosed      = errors.New("poll closed")
+
+	ErrInvalidAttachment  = errors.New("invalid attachment")
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+	ErrQuotaExceeded      = errors.New("upload quota exceeded")
+	ErrAttachmentBound    = errors.New("attachment bound to a post")
+
+	ErrInvalidAvatar     = errors.New("invalid avatar")
+	ErrAvatarTooLarge    = errors.New("avatar too large")
+	ErrInvalidAvatarSize = errors.New("invalid avatar size")
+
+	ErrUserBlocked         = errors.New("user blocked")
+	ErrSelfBlock           = errors.New("can't block yourself")
+	ErrInvalidConversation = errors.New("invalid conversation")
+	ErrInvalidMessage      = errors.New("invalid message")
 )
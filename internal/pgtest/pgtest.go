@@ -0,0 +1,67 @@
+// Package pgtest даёт тестам отдельную схему PostgreSQL в базе из TEST_PG_DSN.
+package pgtest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DSNVariable — переменная окружения со строкой подключения к тестовой базе. Без неё тесты
+// с PostgreSQL пропускаются. Базу лучше завести отдельную: тесты создают и удаляют в ней схемы.
+const DSNVariable = "TEST_PG_DSN"
+
+// NewPool создаёт пустую схему и возвращает пул, у которого она первая в search_path.
+// Схема удаляется вместе со всем содержимым, когда тест заканчивается.
+func NewPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv(DSNVariable)
+	if dsn == "" {
+		t.Skipf("%s is not set", DSNVariable)
+	}
+	ctx := context.Background()
+
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	// Расширения ставим в public: иначе миграция создаст их в схеме теста и они пропадут вместе с ней.
+	_, err = admin.Exec(ctx, `
+		CREATE EXTENSION IF NOT EXISTS citext SCHEMA public;
+		CREATE EXTENSION IF NOT EXISTS ltree SCHEMA public`)
+	if err != nil {
+		admin.Close(ctx)
+		t.Fatalf("failed to create extensions: %v", err)
+	}
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		admin.Close(ctx)
+		t.Fatalf("failed to create schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+		admin.Close(context.Background())
+	})
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", DSNVariable, err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	// Cleanup выполняется в обратном порядке: пул закроется раньше, чем удалится схема.
+	t.Cleanup(pool.Close)
+	return pool
+}
@@ -9,54 +9,146 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func InitRoutes(userHandler *api.UserHandler, forumHandler *api.ForumHandler, threadHandler *api.ThreadHandler, postHandler *api.PostHandler) *gin.Engine {
+func InitRoutes(userHandler *api.UserHandler, forumHandler *api.ForumHandler, threadHandler *api.ThreadHandler, postHandler *api.PostHandler, authHandler *api.AuthHandler, moderationHandler *api.ModerationHandler, searchHandler *api.SearchHandler, streamHandler *api.StreamHandler, webhookHandler *api.WebhookHandler, notificationHandler *api.NotificationHandler, attachmentHandler *api.AttachmentHandler, avatarHandler *api.AvatarHandler, conversationHandler *api.ConversationHandler, adminToken string) *gin.Engine {
 	router := gin.Default()
 
 	corsConfig := cors.Config{
 		AllowOrigins:     []string{"http://localhost:8080"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Admin-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 
 	router.Use(cors.New(corsConfig))
+	router.Use(authHandler.Authenticate)
+
+	requireCaller := authHandler.RequireCaller
+	// Базовые эндпоинты нагрузочного теста: в режиме бенчмарка открыты для анонимных запросов.
+	requireBaselineCaller := authHandler.RequireBaselineCaller
+
+	authGroup := router.Group("/auth")
+	{
+		authGroup.POST("/login", authHandler.Login)
+		authGroup.POST("/logout", requireCaller, authHandler.Logout)
+		authGroup.POST("/tokens", requireCaller, authHandler.CreateAPIToken)
+		authGroup.GET("/tokens", requireCaller, authHandler.GetAPITokens)
+		authGroup.DELETE("/tokens/:id", requireCaller, authHandler.RevokeAPIToken)
+	}
 
 	userGroup := router.Group("/user")
 	{
 		userGroup.POST("/:nickname/create", userHandler.CreateUser)
 		userGroup.GET("/:nickname/profile", userHandler.GetUserProfile)
-		userGroup.POST("/:nickname/profile", userHandler.UpdateUserProfile)
+		userGroup.POST("/:nickname/profile", requireBaselineCaller, userHandler.UpdateUserProfile)
+		userGroup.POST("/:nickname/rename", requireCaller, userHandler.RenameUser)
+		userGroup.GET("/:nickname/export", requireCaller, userHandler.ExportUser)
+		userGroup.DELETE("/:nickname", requireCaller, userHandler.DeleteUser)
+		userGroup.POST("/:nickname/password", requireCaller, authHandler.ChangePassword)
+		userGroup.POST("/:nickname/avatar", requireCaller, avatarHandler.UploadAvatar)
+		userGroup.GET("/:nickname/mentions", postHandler.GetUserMentions)
+		userGroup.GET("/:nickname/subscriptions", requireCaller, notificationHandler.GetSubscriptions)
+		userGroup.GET("/:nickname/notifications", requireCaller, notificationHandler.GetNotifications)
+		userGroup.POST("/:nickname/notifications/read", requireCaller, notificationHandler.MarkNotificationsRead)
+		userGroup.POST("/:nickname/notifications/:id/read", requireCaller, notificationHandler.MarkNotificationRead)
+		userGroup.GET("/:nickname/blocks", requireCaller, userHandler.GetBlockedUsers)
+		userGroup.POST("/:nickname/blocks/:target", requireCaller, userHandler.BlockUser)
+		userGroup.DELETE("/:nickname/blocks/:target", requireCaller, userHandler.UnblockUser)
+		userGroup.POST("/:nickname/conversations", requireCaller, conversationHandler.CreateConversation)
+		userGroup.GET("/:nickname/conversations", requireCaller, conversationHandler.GetConversations)
+		userGroup.GET("/:nickname/conversations/:id", requireCaller, conversationHandler.GetConversation)
+		userGroup.GET("/:nickname/conversations/:id/messages", requireCaller, conversationHandler.GetMessages)
+		userGroup.POST("/:nickname/conversations/:id/messages", requireCaller, conversationHandler.SendMessage)
+		userGroup.POST("/:nickname/conversations/:id/read", requireCaller, conversationHandler.MarkConversationRead)
 	}
 
 	forumGroup := router.Group("/forum")
 	{
-		forumGroup.POST("/create", forumHandler.CreateForum)
+		forumGroup.POST("/create", requireBaselineCaller, forumHandler.CreateForum)
 		forumGroup.GET("/:slug/details", forumHandler.GetForumDetails)
-		forumGroup.POST("/:slug/create", forumHandler.CreateThread)
+		forumGroup.POST("/:slug/details", requireCaller, forumHandler.UpdateForum)
+		forumGroup.POST("/:slug/create", requireBaselineCaller, forumHandler.CreateThread)
 		forumGroup.GET("/:slug/threads", forumHandler.GetForumThreads)
 		forumGroup.GET("/:slug/users", forumHandler.GetForumUsers)
+		forumGroup.GET("/:slug/tags", forumHandler.GetForumTags)
+		forumGroup.GET("/:slug/children", forumHandler.GetForumChildren)
+		forumGroup.GET("/:slug/moderators", moderationHandler.GetForumModerators)
+		forumGroup.POST("/:slug/moderators/:nickname", requireCaller, moderationHandler.AddForumModerator)
+		forumGroup.DELETE("/:slug/moderators/:nickname", requireCaller, moderationHandler.RemoveForumModerator)
+		forumGroup.GET("/:slug/bans", requireCaller, moderationHandler.GetForumBans)
+		forumGroup.POST("/:slug/bans/:nickname", requireCaller, moderationHandler.BanUser)
+		forumGroup.DELETE("/:slug/bans/:nickname", requireCaller, moderationHandler.UnbanUser)
+		forumGroup.POST("/:slug/webhooks", requireCaller, webhookHandler.CreateWebhook)
+		forumGroup.GET("/:slug/webhooks", requireCaller, webhookHandler.GetWebhooks)
+		forumGroup.DELETE("/:slug/webhooks/:id", requireCaller, webhookHandler.DeleteWebhook)
+		forumGroup.GET("/:slug/webhooks/:id/deliveries", requireCaller, webhookHandler.GetDeliveries)
+		forumGroup.POST("/:slug/subscribe", requireCaller, notificationHandler.SubscribeForum)
+		forumGroup.POST("/:slug/unsubscribe", requireCaller, notificationHandler.UnsubscribeForum)
 	}
 
+	router.GET("/forums/tree", forumHandler.GetForumTree)
+	router.GET("/tags/:tag/threads", forumHandler.GetTagThreads)
+	router.GET("/featured", forumHandler.GetFeaturedThreads)
+
 	threadGroup := router.Group("/thread")
 	{
-		threadGroup.POST("/:slug_or_id/create", threadHandler.CreatePosts)
-		threadGroup.POST("/:slug_or_id/vote", threadHandler.VoteThread)
+		threadGroup.POST("/:slug_or_id/create", requireBaselineCaller, threadHandler.CreatePosts)
+		threadGroup.POST("/:slug_or_id/vote", requireBaselineCaller, threadHandler.VoteThread)
 		threadGroup.GET("/:slug_or_id/details", threadHandler.GetThreadDetails)
 		threadGroup.GET("/:slug_or_id/posts", threadHandler.GetThreadPosts)
-		threadGroup.POST("/:slug_or_id/details", threadHandler.UpdateThreadDetails)
+		threadGroup.GET("/:slug_or_id/stream", streamHandler.StreamThreadEvents)
+		threadGroup.GET("/:slug_or_id/ws", streamHandler.ThreadEventsWebSocket)
+		threadGroup.POST("/:slug_or_id/details", requireBaselineCaller, threadHandler.UpdateThreadDetails)
+		threadGroup.GET("/:slug_or_id/history", threadHandler.GetThreadHistory)
+		threadGroup.POST("/:slug_or_id/poll/vote", requireCaller, threadHandler.VotePoll)
+		threadGroup.POST("/:slug_or_id/subscribe", requireCaller, notificationHandler.SubscribeThread)
+		threadGroup.POST("/:slug_or_id/unsubscribe", requireCaller, notificationHandler.UnsubscribeThread)
+		threadGroup.POST("/:slug_or_id/lock", requireCaller, threadHandler.LockThread)
+		threadGroup.POST("/:slug_or_id/unlock", requireCaller, threadHandler.UnlockThread)
+		threadGroup.POST("/:slug_or_id/archive", requireCaller, threadHandler.ArchiveThread)
+		threadGroup.POST("/:slug_or_id/pin", requireCaller, threadHandler.PinThread)
+		threadGroup.POST("/:slug_or_id/unpin", requireCaller, threadHandler.UnpinThread)
+		threadGroup.POST("/:slug_or_id/feature", requireCaller, threadHandler.FeatureThread)
+		threadGroup.POST("/:slug_or_id/unfeature", requireCaller, threadHandler.UnfeatureThread)
+		threadGroup.DELETE("/:slug_or_id", requireCaller, threadHandler.DeleteThread)
 	}
 
 	postGroup := router.Group("/post")
 	{
 		postGroup.GET("/:id/details", postHandler.GetPostDetails)
-		postGroup.POST("/:id/details", postHandler.UpdatePostDetails)
+		postGroup.POST("/:id/details", requireBaselineCaller, postHandler.UpdatePostDetails)
+		postGroup.GET("/:id/history", postHandler.GetPostHistory)
+		postGroup.POST("/:id/history/:revision/revert", requireCaller, postHandler.RevertPost)
+		postGroup.POST("/:id/react", requireCaller, postHandler.ReactToPost)
+		postGroup.DELETE("/:id", requireCaller, postHandler.DeletePost)
+	}
+
+	router.POST("/upload", requireCaller, attachmentHandler.Upload)
+
+	attachmentGroup := router.Group("/attachment")
+	{
+		attachmentGroup.GET("/:id", attachmentHandler.GetAttachment)
+		attachmentGroup.DELETE("/:id", requireCaller, attachmentHandler.DeleteAttachment)
+	}
+
+	router.GET("/avatar/:key", avatarHandler.GetAvatar)
+
+	router.GET("/search", searchHandler.Search)
+
+	adminGroup := router.Group("/admin", moderationHandler.RequireSiteAdmin(adminToken))
+	{
+		adminGroup.DELETE("/post/:id", postHandler.PurgePost)
+		adminGroup.POST("/user/:nickname/password", authHandler.ChangePassword)
+		adminGroup.POST("/user/:nickname/admin", moderationHandler.GrantSiteAdmin)
+		adminGroup.DELETE("/user/:nickname/admin", moderationHandler.RevokeSiteAdmin)
+		adminGroup.POST("/user/:nickname/suspend", moderationHandler.SuspendUser)
+		adminGroup.DELETE("/user/:nickname/suspend", moderationHandler.UnsuspendUser)
 	}
 
 	serviceGroup := router.Group("/service")
 	{
-		serviceGroup.POST("/clear", postHandler.ClearDatabase)
+		serviceGroup.POST("/clear", authHandler.RequireAdmin(adminToken), postHandler.ClearDatabase)
 		serviceGroup.GET("/status", postHandler.GetStatus)
 	}
 
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"strings"
+)
+
+// access проверяет права вызывающего. Анонимный запрос получает отказ. Режим бенчмарка
+// пропускает анонимные запросы только в базовых эндпоинтах нагрузочного теста (создание
+// пользователей, форумов, веток и постов, голоса, правка профиля, веток и постов), которые
+// проверяются методами ensureBaseline*. Остальные проверки закрыты и в режиме бенчмарка.
+type access struct {
+	moderationStorage storage.ModerationStorage
+	benchmarkMode     bool
+}
+
+func newAccess(ms storage.ModerationStorage, benchmarkMode bool) access {
+	return access{moderationStorage: ms, benchmarkMode: benchmarkMode}
+}
+
+// caller возвращает вызывающего или ErrForbidden для анонимного запроса.
+func (a access) caller(ctx context.Context) (string, error) {
+	if nickname, ok := auth.CallerFromContext(ctx); ok {
+		return nickname, nil
+	}
+	return "", models.ErrForbidden
+}
+
+// benchmarkSkip сообщает, что анонимный запрос пропускается режимом бенчмарка.
+func (a access) benchmarkSkip(ctx context.Context) bool {
+	_, ok := auth.CallerFromContext(ctx)
+	return !ok && a.benchmarkMode
+}
+
+// ensureCaller проверяет, что запрос выполняется от имени nickname.
+func (a access) ensureCaller(ctx context.Context, nickname string) error {
+	caller, err := a.caller(ctx)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(caller, nickname) {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// ensureBaselineCaller — ensureCaller для базовых эндпоинтов нагрузочного теста.
+func (a access) ensureBaselineCaller(ctx context.Context, nickname string) error {
+	if a.benchmarkSkip(ctx) {
+		return nil
+	}
+	return a.ensureCaller(ctx, nickname)
+}
+
+// ensureForumRole проверяет, что вызывающий имеет в форуме роль не ниже minRole.
+func (a access) ensureForumRole(ctx context.Context, forumSlug string, minRole string) error {
+	if auth.AdminTokenFromContext(ctx) {
+		return nil
+	}
+	caller, err := a.caller(ctx)
+	if err != nil {
+		return err
+	}
+
+	role, err := a.moderationStorage.GetForumRole(ctx, forumSlug, caller)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrForbidden
+		}
+		return fmt.Errorf("failed to get role of %s in forum %s: %w", caller, forumSlug, err)
+	}
+	if roleRanks[role] < roleRanks[minRole] {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// ensureAuthorOrModerator пропускает автора контента и модераторов его форума.
+func (a access) ensureAuthorOrModerator(ctx context.Context, forumSlug string, author string) error {
+	if caller, ok := auth.CallerFromContext(ctx); ok && strings.EqualFold(caller, author) {
+		return nil
+	}
+	return a.ensureForumRole(ctx, forumSlug, models.RoleModerator)
+}
+
+// ensureBaselineAuthorOrModerator — ensureAuthorOrModerator для базовых эндпоинтов нагрузочного теста.
+func (a access) ensureBaselineAuthorOrModerator(ctx context.Context, forumSlug string, author string) error {
+	if a.benchmarkSkip(ctx) {
+		return nil
+	}
+	return a.ensureAuthorOrModerator(ctx, forumSlug, author)
+}
+
+func (a access) ensureSiteAdmin(ctx context.Context) error {
+	if auth.AdminTokenFromContext(ctx) {
+		return nil
+	}
+	caller, err := a.caller(ctx)
+	if err != nil {
+		return err
+	}
+
+	isAdmin, err := a.moderationStorage.IsSiteAdmin(ctx, caller)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrForbidden
+		}
+		return fmt.Errorf("failed to check site admin %s: %w", caller, err)
+	}
+	if !isAdmin {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// ensureSelfOrSiteAdmin пропускает самого пользователя и администраторов сайта.
+func (a access) ensureSelfOrSiteAdmin(ctx context.Context, nickname string) error {
+	if caller, ok := auth.CallerFromContext(ctx); ok && strings.EqualFold(caller, nickname) {
+		return nil
+	}
+	return a.ensureSiteAdmin(ctx)
+}
+
+// ensureCanEdit — общее правило правки постов и веток. Закрытая (запертая или архивная)
+// ветка заморожена для авторов, но открыта модераторам форума. Бан в форуме и блокировка
+// аккаунта запрещают правку всем, кроме запроса с X-Admin-Token; в режиме бенчмарка анонимная
+// правка проверяется от имени автора.
+func (a access) ensureCanEdit(ctx context.Context, thread *models.Thread, author string) error {
+	if !acceptsActivity(thread) {
+		if err := a.ensureForumRole(ctx, thread.Forum, models.RoleModerator); err != nil {
+			if errors.Is(err, models.ErrForbidden) {
+				return models.ErrThreadLocked
+			}
+			return err
+		}
+	}
+
+	participant, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		if auth.AdminTokenFromContext(ctx) {
+			return nil
+		}
+		participant = author
+	}
+	return ensureCanParticipate(ctx, a.moderationStorage, thread.Forum, []string{participant})
+}
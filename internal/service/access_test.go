@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"testing"
+)
+
+func TestAccessChecks(t *testing.T) {
+	anonymous := context.Background()
+
+	tests := []struct {
+		name          string
+		benchmarkMode bool
+		ctx           context.Context
+		call          func(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error
+		want          error
+	}{
+		{
+			name: "author edits own post",
+			ctx:  as("ann"),
+			call: updatePost,
+		},
+		{
+			name: "forum moderator edits post",
+			ctx:  as("mod"),
+			call: updatePost,
+		},
+		{
+			name: "site admin edits post",
+			ctx:  as("root"),
+			call: updatePost,
+		},
+		{
+			name: "member edits foreign post",
+			ctx:  as("eve"),
+			call: updatePost,
+			want: models.ErrForbidden,
+		},
+		{
+			name: "anonymous edits post",
+			ctx:  anonymous,
+			call: updatePost,
+			want: models.ErrForbidden,
+		},
+		{
+			name:          "anonymous edits post in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call:          updatePost,
+		},
+		{
+			name:          "member edits foreign post in benchmark mode",
+			benchmarkMode: true,
+			ctx:           as("eve"),
+			call:          updatePost,
+			want:          models.ErrForbidden,
+		},
+		{
+			name: "member deletes foreign post",
+			ctx:  as("eve"),
+			call: deletePost,
+			want: models.ErrForbidden,
+		},
+		{
+			name: "anonymous deletes post",
+			ctx:  anonymous,
+			call: deletePost,
+			want: models.ErrForbidden,
+		},
+		{
+			name: "author deletes own post",
+			ctx:  as("ann"),
+			call: deletePost,
+		},
+		{
+			name:          "anonymous posts in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call:          createPost,
+		},
+		{
+			name:          "anonymous deletes post in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call:          deletePost,
+			want:          models.ErrForbidden,
+		},
+		{
+			name:          "anonymous locks thread in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call: func(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+				_, err := env.threads.LockThread(ctx, threadRef(thread))
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name:          "anonymous suspends user in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call: func(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+				_, err := env.moderation.SuspendUser(ctx, "ann", models.Suspension{})
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name:          "anonymous exports account in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call: func(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+				_, err := env.users.ExportUser(ctx, "ann")
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name:          "anonymous erases account in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call: func(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+				return env.users.DeleteUser(ctx, "ann", models.DeleteModeErase)
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name:          "anonymous reads conversations in benchmark mode",
+			benchmarkMode: true,
+			ctx:           anonymous,
+			call: func(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+				if _, err := env.conversations.CreateConversation(as("ann"), "ann", []string{"eve"}); err != nil {
+					return err
+				}
+				_, err := env.conversations.GetConversations(ctx, "ann", 10, nil)
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name: "member posts on behalf of another user",
+			ctx:  as("eve"),
+			call: func(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+				_, err := env.threads.CreatePosts(ctx, threadRef(thread), []models.Post{{Author: "ann", Message: "fake"}})
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.benchmarkMode)
+			thread, posts := env.createThread(t, "ann")
+
+			checkErr(t, tt.call(tt.ctx, env, thread, posts), tt.want)
+		})
+	}
+}
+
+func updatePost(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+	_, err := env.posts.UpdatePostDetails(ctx, posts[0].ID, "edited")
+	return err
+}
+
+func deletePost(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+	_, err := env.posts.DeletePost(ctx, posts[0].ID)
+	return err
+}
+
+func createPost(ctx context.Context, env *testEnv, thread models.Thread, posts []models.Post) error {
+	_, err := env.threads.CreatePosts(ctx, threadRef(thread), []models.Post{{Author: "ann", Message: "more", Parent: posts[0].ID}})
+	return err
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/blob"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxPostAttachments          = 10
+	maxAttachmentFilenameLength = 255
+	// sniffLength — сколько первых байт файла смотрит http.DetectContentType.
+	sniffLength = 512
+)
+
+type AttachmentService interface {
+	Upload(ctx context.Context, nickname string, filename string, size int64, body io.Reader) (*models.Attachment, error)
+	GetAttachment(ctx context.Context, id int64) (*models.Attachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, id int64) error
+}
+
+type attachmentServiceImpl struct {
+	userStorage       storage.UserStorage
+	threadStorage     storage.ThreadStorage
+	postStorage       storage.PostStorage
+	moderationStorage storage.ModerationStorage
+	attachmentStorage storage.AttachmentStorage
+	blobs             blob.Store
+	maxSize           int64
+	quota             int64
+	access            access
+}
+
+func NewAttachmentService(us storage.UserStorage, ts storage.ThreadStorage, ps storage.PostStorage, ms storage.ModerationStorage, as storage.AttachmentStorage, blobs blob.Store, maxSize, quota int64, benchmarkMode bool) AttachmentService {
+	return &attachmentServiceImpl{
+		userStorage:       us,
+		threadStorage:     ts,
+		postStorage:       ps,
+		moderationStorage: ms,
+		attachmentStorage: as,
+		blobs:             blobs,
+		maxSize:           maxSize,
+		quota:             quota,
+		access:            newAccess(ms, benchmarkMode),
+	}
+}
+
+func (s *attachmentServiceImpl) Upload(ctx context.Context, nickname string, filename string, size int64, body io.Reader) (*models.Attachment, error) {
+	if err := s.access.ensureCaller(ctx, nickname); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to check uploader: %w", err)
+	}
+	if err := ensureNotSuspended(ctx, s.moderationStorage, []string{user.Nickname}); err != nil {
+		return nil, err
+	}
+
+	if size <= 0 {
+		return nil, models.ErrInvalidAttachment
+	}
+	if size > s.maxSize {
+		return nil, models.ErrAttachmentTooLarge
+	}
+
+	// Тип определяем по содержимому: заголовку клиента верить нельзя.
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+
+	attachment, err := s.attachmentStorage.CreateAttachment(ctx, models.Attachment{
+		Owner:       user.Nickname,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        size,
+		Key:         uuid.NewString(),
+	}, s.quota)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			return nil, models.ErrOwnerNotFound
+		case errors.Is(err, models.ErrQuotaExceeded):
+			return nil, models.ErrQuotaExceeded
+		}
+		return nil, fmt.Errorf("failed to create attachment in storage: %w", err)
+	}
+
+	err = s.blobs.Put(ctx, attachment.Key, io.MultiReader(bytes.NewReader(head), body), size, contentType)
+	if err != nil {
+		// Файл не сохранился — освобождаем место в квоте.
+		if err := s.attachmentStorage.DeleteUnboundAttachment(context.WithoutCancel(ctx), attachment.ID); err != nil {
+			log.Printf("Error removing attachment %d after failed upload: %v", attachment.ID, err)
+		}
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// GetAttachment открывает файл. Файл, ещё не прикреплённый к посту, видят только владелец и администраторы сайта,
+// а файл удалённого поста или поста из удалённой ветки не видит никто. Для остальных такой файл не существует.
+func (s *attachmentServiceImpl) GetAttachment(ctx context.Context, id int64) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.attachment(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.Post == 0 {
+		if _, ok := auth.CallerFromContext(ctx); !ok {
+			return nil, nil, models.ErrNotFound
+		}
+		if err := s.access.ensureSelfOrSiteAdmin(ctx, attachment.Owner); err != nil {
+			if errors.Is(err, models.ErrForbidden) {
+				return nil, nil, models.ErrNotFound
+			}
+			return nil, nil, err
+		}
+	} else if err := s.ensurePostVisible(ctx, attachment.Post); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := s.blobs.Get(ctx, attachment.Key)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open attachment %d: %w", id, err)
+	}
+	return attachment, body, nil
+}
+
+// DeleteAttachment удаляет файл, который ещё не прикреплён к посту, и возвращает место в квоте.
+func (s *attachmentServiceImpl) DeleteAttachment(ctx context.Context, id int64) error {
+	attachment, err := s.attachment(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.access.ensureSelfOrSiteAdmin(ctx, attachment.Owner); err != nil {
+		return err
+	}
+	if attachment.Post != 0 {
+		return models.ErrAttachmentBound
+	}
+
+	err = s.attachmentStorage.DeleteUnboundAttachment(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			// Файл успели прикрепить или удалить параллельно.
+			return models.ErrAttachmentBound
+		}
+		return fmt.Errorf("failed to delete attachment in storage: %w", err)
+	}
+
+	if err := s.blobs.Delete(ctx, attachment.Key); err != nil {
+		log.Printf("Error deleting blob of attachment %d: %v", id, err)
+	}
+	return nil
+}
+
+// ensurePostVisible возвращает ErrNotFound, если пост удалён или его ветка удалена.
+func (s *attachmentServiceImpl) ensurePostVisible(ctx context.Context, id int64) error {
+	post, err := s.postStorage.GetPostByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to get post %d of attachment: %w", id, err)
+	}
+	if post.IsDeleted {
+		return models.ErrNotFound
+	}
+
+	_, err = s.threadStorage.GetThreadByID(ctx, post.Thread)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to get thread %d of attachment: %w", post.Thread, err)
+	}
+	return nil
+}
+
+func (s *attachmentServiceImpl) attachment(ctx context.Context, id int64) (*models.Attachment, error) {
+	attachment, err := s.attachmentStorage.GetAttachment(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment %d: %w", id, err)
+	}
+	return attachment, nil
+}
+
+// normalizePostAttachments проверяет ссылки поста на файлы: не больше maxPostAttachments, без повторов.
+// Принадлежность автору и то, что файл ещё свободен, проверяет хранилище при создании поста.
+func normalizePostAttachments(attachments []models.PostAttachment) ([]models.PostAttachment, error) {
+	if len(attachments) > maxPostAttachments {
+		return nil, models.ErrInvalidAttachment
+	}
+	refs := make([]models.PostAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		if attachment.ID <= 0 {
+			return nil, models.ErrInvalidAttachment
+		}
+		for _, ref := range refs {
+			if ref.ID == attachment.ID {
+				return nil, models.ErrInvalidAttachment
+			}
+		}
+		refs = append(refs, models.PostAttachment{ID: attachment.ID})
+	}
+	return refs, nil
+}
+
+// cleanFilename оставляет от имени файла последний элемент пути без управляющих символов.
+func cleanFilename(filename string) string {
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	filename = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, filename))
+	if filename == "" || filename == "." || filename == "/" {
+		return "file"
+	}
+	if utf8.RuneCountInString(filename) > maxAttachmentFilenameLength {
+		filename = string([]rune(filename)[:maxAttachmentFilenameLength])
+	}
+	return filename
+}
+
+// deleteBlobs убирает файлы, записи о которых уже удалены. Ошибки только пишутся в лог:
+// без записи файл никому не виден, а откатывать из-за него удаление данных нельзя.
+func deleteBlobs(blobs blob.Store, keys []string) {
+	for _, key := range keys {
+		if err := blobs.Delete(context.Background(), key); err != nil {
+			log.Printf("Error deleting blob %s: %v", key, err)
+		}
+	}
+}
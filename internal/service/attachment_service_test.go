@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAttachmentVisibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		attach bool
+		change func(t *testing.T, env *testEnv, post models.Post)
+		ctx    context.Context
+		want   error
+	}{
+		{name: "owner reads unbound file", ctx: as("ann")},
+		{name: "site admin reads unbound file", ctx: as("root")},
+		{name: "member reads unbound file", ctx: as("eve"), want: models.ErrNotFound},
+		{name: "anonymous reads unbound file", ctx: context.Background(), want: models.ErrNotFound},
+		{name: "anonymous reads file of a post", attach: true, ctx: context.Background()},
+		{
+			name:   "file of a deleted post",
+			attach: true,
+			change: func(t *testing.T, env *testEnv, post models.Post) {
+				if _, err := env.posts.DeletePost(as("ann"), post.ID); err != nil {
+					t.Fatalf("failed to delete post: %v", err)
+				}
+			},
+			ctx:  as("ann"),
+			want: models.ErrNotFound,
+		},
+		{
+			name:   "file of a deleted thread",
+			attach: true,
+			change: func(t *testing.T, env *testEnv, post models.Post) {
+				if _, err := env.threads.DeleteThread(as("mod"), strconv.FormatInt(post.Thread, 10)); err != nil {
+					t.Fatalf("failed to delete thread: %v", err)
+				}
+			},
+			ctx:  as("ann"),
+			want: models.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			attachment := env.upload(t, "ann", "hello")
+			if tt.attach {
+				post := env.attach(t, attachment)
+				if tt.change != nil {
+					tt.change(t, env, post)
+				}
+			}
+
+			_, body, err := env.attachments.GetAttachment(tt.ctx, attachment.ID)
+			checkErr(t, err, tt.want)
+			if err != nil {
+				return
+			}
+			defer body.Close()
+			if content, _ := io.ReadAll(body); string(content) != "hello" {
+				t.Fatalf("got content %q, want %q", content, "hello")
+			}
+		})
+	}
+}
+
+func TestAttachmentUploadRules(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	_, err := env.attachments.Upload(as("eve"), "ann", "a.txt", 5, strings.NewReader("hello"))
+	checkErr(t, err, models.ErrForbidden)
+
+	_, err = env.attachments.Upload(as("ann"), "ann", "big.bin", testMaxUploadSize+1, strings.NewReader(""))
+	checkErr(t, err, models.ErrAttachmentTooLarge)
+
+	for i := 0; i < testUploadQuota/testMaxUploadSize; i++ {
+		body := strings.Repeat("x", testMaxUploadSize)
+		if _, err := env.attachments.Upload(as("ann"), "ann", "part.bin", int64(len(body)), strings.NewReader(body)); err != nil {
+			t.Fatalf("failed to upload part %d: %v", i, err)
+		}
+	}
+	_, err = env.attachments.Upload(as("ann"), "ann", "extra.txt", 5, strings.NewReader("hello"))
+	checkErr(t, err, models.ErrQuotaExceeded)
+
+	// Чужой файл к посту не прикрепить.
+	attachment := env.upload(t, "eve", "hello")
+	thread, _ := env.createThread(t, "bob")
+	_, err = env.threads.CreatePosts(as("bob"), threadRef(thread), []models.Post{{Author: "bob", Message: "mine", Attachments: []models.PostAttachment{{ID: attachment.ID}}}})
+	checkErr(t, err, models.ErrInvalidAttachment)
+}
+
+func TestAttachmentBlobsAreRemoved(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(t *testing.T, env *testEnv, post models.Post)
+	}{
+		{
+			name: "account erased",
+			remove: func(t *testing.T, env *testEnv, post models.Post) {
+				if err := env.users.DeleteUser(as("ann"), "ann", models.DeleteModeErase); err != nil {
+					t.Fatalf("failed to delete user: %v", err)
+				}
+			},
+		},
+		{
+			name: "post purged",
+			remove: func(t *testing.T, env *testEnv, post models.Post) {
+				if _, err := env.posts.PurgePost(as("root"), post.ID); err != nil {
+					t.Fatalf("failed to purge post: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			attachment := env.upload(t, "ann", "hello")
+			post := env.attach(t, attachment)
+
+			tt.remove(t, env, post)
+
+			env.checkBlobGone(t, attachment.Key)
+		})
+	}
+}
+
+func TestDeleteUnboundAttachment(t *testing.T) {
+	env := newTestEnv(t, false)
+	attachment := env.upload(t, "ann", "hello")
+
+	checkErr(t, env.attachments.DeleteAttachment(as("eve"), attachment.ID), models.ErrForbidden)
+	if err := env.attachments.DeleteAttachment(as("ann"), attachment.ID); err != nil {
+		t.Fatalf("failed to delete attachment: %v", err)
+	}
+	env.checkBlobGone(t, attachment.Key)
+}
+
+// upload загружает файл от имени nickname.
+func (env *testEnv) upload(t *testing.T, nickname string, content string) *models.Attachment {
+	t.Helper()
+
+	attachment, err := env.attachments.Upload(as(nickname), nickname, "file.txt", int64(len(content)), strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to upload file: %v", err)
+	}
+	return attachment
+}
+
+// attach прикрепляет файл ann к новому посту ann в ветке bob.
+func (env *testEnv) attach(t *testing.T, attachment *models.Attachment) models.Post {
+	t.Helper()
+
+	thread, _ := env.createThread(t, "bob")
+	posts, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{
+		Author:      "ann",
+		Message:     "with file",
+		Attachments: []models.PostAttachment{{ID: attachment.ID}},
+	}})
+	if err != nil {
+		t.Fatalf("failed to attach file: %v", err)
+	}
+	return posts[0]
+}
+
+func (env *testEnv) checkBlobGone(t *testing.T, key string) {
+	t.Helper()
+
+	body, err := env.blobs.Get(context.Background(), key)
+	if err == nil {
+		body.Close()
+		t.Fatalf("blob %s is still stored", key)
+	}
+	checkErr(t, err, models.ErrNotFound)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"strings"
+	"time"
+)
+
+const (
+	sessionTTL        = 30 * 24 * time.Hour
+	minPasswordLength = 8
+)
+
+type AuthService interface {
+	Login(ctx context.Context, credentials models.Credentials) (models.AuthToken, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (string, error)
+	ChangePassword(ctx context.Context, nickname string, change models.PasswordChange) error
+	CreateAPIToken(ctx context.Context, name string, expires *time.Time) (models.AuthToken, error)
+	GetAPITokens(ctx context.Context) ([]models.AuthToken, error)
+	RevokeAPIToken(ctx context.Context, id int64) error
+}
+
+type authServiceImpl struct {
+	authStorage storage.AuthStorage
+	access      access
+}
+
+func NewAuthService(as storage.AuthStorage, ms storage.ModerationStorage, benchmarkMode bool) AuthService {
+	return &authServiceImpl{authStorage: as, access: newAccess(ms, benchmarkMode)}
+}
+
+func (s *authServiceImpl) Login(ctx context.Context, credentials models.Credentials) (models.AuthToken, error) {
+	nickname, hash, err := s.authStorage.GetPasswordHash(ctx, credentials.Nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.AuthToken{}, models.ErrInvalidCredentials
+		}
+		return models.AuthToken{}, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	if hash == "" {
+		return models.AuthToken{}, models.ErrInvalidCredentials
+	}
+
+	ok, err := auth.CheckPassword(hash, credentials.Password)
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+	if !ok {
+		return models.AuthToken{}, models.ErrInvalidCredentials
+	}
+
+	expires := time.Now().Add(sessionTTL)
+	return s.issueToken(ctx, models.AuthToken{User: nickname, Kind: models.TokenKindSession, Expires: &expires})
+}
+
+func (s *authServiceImpl) issueToken(ctx context.Context, token models.AuthToken) (models.AuthToken, error) {
+	plain, hash, err := auth.NewToken()
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+
+	created, err := s.authStorage.CreateToken(ctx, token, hash)
+	if err != nil {
+		return models.AuthToken{}, fmt.Errorf("failed to save %s token: %w", token.Kind, err)
+	}
+
+	created.Token = plain
+	return *created, nil
+}
+
+func (s *authServiceImpl) Logout(ctx context.Context, token string) error {
+	err := s.authStorage.DeleteToken(ctx, auth.HashToken(token))
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *authServiceImpl) Authenticate(ctx context.Context, token string) (string, error) {
+	nickname, err := s.authStorage.GetTokenOwner(ctx, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return "", models.ErrUnauthorized
+		}
+		return "", fmt.Errorf("failed to resolve token: %w", err)
+	}
+	return nickname, nil
+}
+
+// ChangePassword меняет пароль владельца аккаунта по старому паролю. Пользователь, созданный
+// без пароля, войти не может, поэтому первый пароль ему задаёт администратор сайта: дальше
+// пользователь входит с ним и меняет его сам. Администратор может прийти и без аккаунта,
+// только с X-Admin-Token (POST /admin/user/{nickname}/password).
+func (s *authServiceImpl) ChangePassword(ctx context.Context, nickname string, change models.PasswordChange) error {
+	// Смена пароля всегда требует вызывающего, в том числе в режиме бенчмарка.
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok && !auth.AdminTokenFromContext(ctx) {
+		return models.ErrUnauthorized
+	}
+	if len(change.Password) < minPasswordLength {
+		return models.ErrWeakPassword
+	}
+
+	canonicalNickname, hash, err := s.authStorage.GetPasswordHash(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to get current password: %w", err)
+	}
+	if isReservedNickname(canonicalNickname) {
+		return models.ErrForbidden
+	}
+
+	if hash == "" {
+		if err := s.access.ensureSiteAdmin(ctx); err != nil {
+			return err
+		}
+	} else {
+		if !strings.EqualFold(caller, canonicalNickname) {
+			return models.ErrForbidden
+		}
+		ok, err := auth.CheckPassword(hash, change.OldPassword)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return models.ErrInvalidCredentials
+		}
+	}
+
+	newHash, err := auth.HashPassword(change.Password)
+	if err != nil {
+		return err
+	}
+
+	err = s.authStorage.SetPasswordHash(ctx, canonicalNickname, newHash)
+	if err != nil {
+		return fmt.Errorf("failed to save password: %w", err)
+	}
+	return nil
+}
+
+func (s *authServiceImpl) CreateAPIToken(ctx context.Context, name string, expires *time.Time) (models.AuthToken, error) {
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return models.AuthToken{}, models.ErrUnauthorized
+	}
+	return s.issueToken(ctx, models.AuthToken{User: caller, Kind: models.TokenKindAPI, Name: name, Expires: expires})
+}
+
+func (s *authServiceImpl) GetAPITokens(ctx context.Context) ([]models.AuthToken, error) {
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return nil, models.ErrUnauthorized
+	}
+
+	tokens, err := s.authStorage.GetTokensByUser(ctx, caller, models.TokenKindAPI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *authServiceImpl) RevokeAPIToken(ctx context.Context, id int64) error {
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return models.ErrUnauthorized
+	}
+
+	err := s.authStorage.DeleteTokenByID(ctx, caller, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to revoke api token %d: %w", id, err)
+	}
+	return nil
+}
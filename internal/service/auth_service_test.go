@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"testing"
+)
+
+const testPassword = "first-password"
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		hasPassword bool
+		ctx         context.Context
+		change      models.PasswordChange
+		want        error
+	}{
+		{
+			name:   "site admin sets first password",
+			ctx:    as("root"),
+			change: models.PasswordChange{Password: "new-password"},
+		},
+		{
+			name:   "user without password sets own",
+			ctx:    as("ann"),
+			change: models.PasswordChange{Password: "new-password"},
+			want:   models.ErrForbidden,
+		},
+		{
+			name:   "member sets first password of another user",
+			ctx:    as("eve"),
+			change: models.PasswordChange{Password: "new-password"},
+			want:   models.ErrForbidden,
+		},
+		{
+			name:        "owner changes password",
+			hasPassword: true,
+			ctx:         as("ann"),
+			change:      models.PasswordChange{Password: "new-password", OldPassword: testPassword},
+		},
+		{
+			name:        "owner gives wrong old password",
+			hasPassword: true,
+			ctx:         as("ann"),
+			change:      models.PasswordChange{Password: "new-password", OldPassword: "wrong-password"},
+			want:        models.ErrInvalidCredentials,
+		},
+		{
+			name:        "site admin changes existing password",
+			hasPassword: true,
+			ctx:         as("root"),
+			change:      models.PasswordChange{Password: "new-password"},
+			want:        models.ErrForbidden,
+		},
+		{
+			name:   "admin token sets first password",
+			ctx:    auth.WithAdminToken(context.Background()),
+			change: models.PasswordChange{Password: "new-password"},
+		},
+		{
+			name:        "admin token changes existing password",
+			hasPassword: true,
+			ctx:         auth.WithAdminToken(context.Background()),
+			change:      models.PasswordChange{Password: "new-password"},
+			want:        models.ErrForbidden,
+		},
+		{
+			name:   "anonymous sets first password",
+			ctx:    context.Background(),
+			change: models.PasswordChange{Password: "new-password"},
+			want:   models.ErrUnauthorized,
+		},
+		{
+			name:   "short password",
+			ctx:    as("root"),
+			change: models.PasswordChange{Password: "short"},
+			want:   models.ErrWeakPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			if tt.hasPassword {
+				if err := env.auth.ChangePassword(as("root"), "ann", models.PasswordChange{Password: testPassword}); err != nil {
+					t.Fatalf("failed to set first password: %v", err)
+				}
+			}
+
+			err := env.auth.ChangePassword(tt.ctx, "ann", tt.change)
+			checkErr(t, err, tt.want)
+			if err != nil {
+				return
+			}
+			if _, err := env.auth.Login(context.Background(), models.Credentials{Nickname: "ann", Password: tt.change.Password}); err != nil {
+				t.Fatalf("failed to log in with the new password: %v", err)
+			}
+		})
+	}
+}
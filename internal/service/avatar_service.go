@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/blob"
+	"hardhw/internal/imaging"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxAvatarPixels ограничивает разрешение исходника: маленький файл может распаковаться в огромную картинку.
+	maxAvatarPixels   = 4096 * 4096
+	defaultAvatarSize = 256
+)
+
+// avatarSizes — стороны уменьшенных копий, от большей к меньшей: меньшие считаются из большей.
+var avatarSizes = []int{256, 64, 32}
+
+type AvatarService interface {
+	UploadAvatar(ctx context.Context, nickname string, body io.Reader) (*models.User, error)
+	GetAvatar(ctx context.Context, key string, size int) (io.ReadCloser, error)
+}
+
+type avatarServiceImpl struct {
+	userStorage storage.UserStorage
+	blobs       blob.Store
+	maxSize     int64
+	access      access
+}
+
+func NewAvatarService(us storage.UserStorage, ms storage.ModerationStorage, blobs blob.Store, maxSize int64, benchmarkMode bool) AvatarService {
+	return &avatarServiceImpl{userStorage: us, blobs: blobs, maxSize: maxSize, access: newAccess(ms, benchmarkMode)}
+}
+
+// UploadAvatar принимает PNG или JPEG, сохраняет квадратные копии всех размеров из avatarSizes
+// и удаляет копии прежней аватарки.
+func (s *avatarServiceImpl) UploadAvatar(ctx context.Context, nickname string, body io.Reader) (*models.User, error) {
+	if err := s.access.ensureCaller(ctx, nickname); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get user %s: %w", nickname, err)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, s.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, models.ErrAvatarTooLarge
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "png" && format != "jpeg") || config.Width == 0 || config.Height == 0 {
+		return nil, models.ErrInvalidAvatar
+	}
+	if config.Width*config.Height > maxAvatarPixels {
+		return nil, models.ErrAvatarTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrInvalidAvatar
+	}
+
+	key := uuid.NewString()
+	stored := make([]string, 0, len(avatarSizes))
+	source := image.Image(img)
+	for _, size := range avatarSizes {
+		variant := imaging.Square(source, size)
+		source = variant
+
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, variant); err != nil {
+			deleteBlobs(s.blobs, stored)
+			return nil, fmt.Errorf("failed to encode avatar of %s: %w", user.Nickname, err)
+		}
+		blobKey := avatarBlobKey(key, size)
+		if err := s.blobs.Put(ctx, blobKey, &encoded, int64(encoded.Len()), "image/png"); err != nil {
+			deleteBlobs(s.blobs, stored)
+			return nil, fmt.Errorf("failed to store avatar of %s: %w", user.Nickname, err)
+		}
+		stored = append(stored, blobKey)
+	}
+
+	previous, err := s.userStorage.SetUserAvatar(ctx, user.Nickname, key)
+	if err != nil {
+		deleteBlobs(s.blobs, stored)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to save avatar of %s: %w", user.Nickname, err)
+	}
+
+	if previous != "" {
+		deleteBlobs(s.blobs, avatarBlobKeys(previous))
+	}
+
+	user.AvatarKey = key
+	return user, nil
+}
+
+// GetAvatar открывает копию аватарки нужного размера; size = 0 означает наибольшую.
+func (s *avatarServiceImpl) GetAvatar(ctx context.Context, key string, size int) (io.ReadCloser, error) {
+	if size == 0 {
+		size = defaultAvatarSize
+	}
+	if !slices.Contains(avatarSizes, size) {
+		return nil, models.ErrInvalidAvatarSize
+	}
+	if _, err := uuid.Parse(key); err != nil {
+		return nil, models.ErrNotFound
+	}
+
+	body, err := s.blobs.Get(ctx, avatarBlobKey(key, size))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open avatar %s: %w", key, err)
+	}
+	return body, nil
+}
+
+func avatarBlobKey(key string, size int) string {
+	return key + "-" + strconv.Itoa(size)
+}
+
+// avatarBlobKeys перечисляет копии аватарки key всех размеров.
+func avatarBlobKeys(key string) []string {
+	keys := make([]string, 0, len(avatarSizes))
+	for _, size := range avatarSizes {
+		keys = append(keys, avatarBlobKey(key, size))
+	}
+	return keys
+}
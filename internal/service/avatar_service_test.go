@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"hardhw/internal/models"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestUploadAvatar(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	user, err := env.avatars.UploadAvatar(as("ann"), "ann", encodePNG(t, 300, 200))
+	if err != nil {
+		t.Fatalf("failed to upload avatar: %v", err)
+	}
+	for _, size := range avatarSizes {
+		body, err := env.avatars.GetAvatar(context.Background(), user.AvatarKey, size)
+		if err != nil {
+			t.Fatalf("failed to get %d px avatar: %v", size, err)
+		}
+		img, err := png.Decode(body)
+		body.Close()
+		if err != nil {
+			t.Fatalf("failed to decode %d px avatar: %v", size, err)
+		}
+		if bounds := img.Bounds(); bounds.Dx() != size || bounds.Dy() != size {
+			t.Fatalf("avatar is %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), size, size)
+		}
+	}
+
+	_, err = env.avatars.GetAvatar(context.Background(), user.AvatarKey, 100)
+	checkErr(t, err, models.ErrInvalidAvatarSize)
+
+	replaced, err := env.avatars.UploadAvatar(as("ann"), "ann", encodePNG(t, 64, 64))
+	if err != nil {
+		t.Fatalf("failed to replace avatar: %v", err)
+	}
+	if replaced.AvatarKey == user.AvatarKey {
+		t.Fatal("replaced avatar kept the old key")
+	}
+	for _, key := range avatarBlobKeys(user.AvatarKey) {
+		env.checkBlobGone(t, key)
+	}
+
+	if err := env.users.DeleteUser(as("ann"), "ann", models.DeleteModeAnonymize); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	for _, key := range avatarBlobKeys(replaced.AvatarKey) {
+		env.checkBlobGone(t, key)
+	}
+}
+
+func TestUploadAvatarRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		body func(t *testing.T) *bytes.Reader
+		want error
+	}{
+		{name: "another user", ctx: as("eve"), body: func(t *testing.T) *bytes.Reader { return encodePNG(t, 8, 8) }, want: models.ErrForbidden},
+		{name: "anonymous", ctx: context.Background(), body: func(t *testing.T) *bytes.Reader { return encodePNG(t, 8, 8) }, want: models.ErrForbidden},
+		{name: "not an image", ctx: as("ann"), body: func(t *testing.T) *bytes.Reader { return bytes.NewReader([]byte("GIF89a")) }, want: models.ErrInvalidAvatar},
+		{
+			name: "file too large",
+			ctx:  as("ann"),
+			body: func(t *testing.T) *bytes.Reader {
+				return bytes.NewReader([]byte(strings.Repeat("x", testMaxUploadSize+1)))
+			},
+			want: models.ErrAvatarTooLarge,
+		},
+		{
+			name: "too many pixels",
+			ctx:  as("ann"),
+			body: func(t *testing.T) *bytes.Reader { return encodePNG(t, 5000, 5000) },
+			want: models.ErrAvatarTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			_, err := env.avatars.UploadAvatar(tt.ctx, "ann", tt.body(t))
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+// encodePNG рисует однотонную картинку width×height.
+func encodePNG(t *testing.T, width, height int) *bytes.Reader {
+	t.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	var encoded bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&encoded, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return bytes.NewReader(encoded.Bytes())
+}
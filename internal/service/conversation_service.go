@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxConversationMembers учитывает и создателя переписки.
+	maxConversationMembers       = 50
+	maxConversationMessageLength = 10000
+)
+
+type ConversationService interface {
+	CreateConversation(ctx context.Context, nickname string, members []string) (*models.Conversation, error)
+	GetConversation(ctx context.Context, nickname string, id int64) (*models.Conversation, error)
+	GetConversations(ctx context.Context, nickname string, limit int, after *models.ConversationCursor) (*models.ConversationPage, error)
+	SendMessage(ctx context.Context, nickname string, id int64, message string) (*models.ConversationMessage, error)
+	GetMessages(ctx context.Context, nickname string, id int64, limit int, before int64) ([]models.ConversationMessage, error)
+	MarkConversationRead(ctx context.Context, nickname string, id int64, upTo int64) error
+}
+
+type conversationServiceImpl struct {
+	userStorage         storage.UserStorage
+	moderationStorage   storage.ModerationStorage
+	conversationStorage storage.ConversationStorage
+	access              access
+}
+
+func NewConversationService(us storage.UserStorage, ms storage.ModerationStorage, cs storage.ConversationStorage, benchmarkMode bool) ConversationService {
+	return &conversationServiceImpl{userStorage: us, moderationStorage: ms, conversationStorage: cs, access: newAccess(ms, benchmarkMode)}
+}
+
+// CreateConversation создаёт переписку nickname с members. Никто из участников не должен
+// быть в чёрном списке создателя, и создатель не должен быть в чёрном списке участников.
+func (s *conversationServiceImpl) CreateConversation(ctx context.Context, nickname string, members []string) (*models.Conversation, error) {
+	creator, err := s.participant(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureNotSuspended(ctx, s.moderationStorage, []string{creator.Nickname}); err != nil {
+		return nil, err
+	}
+
+	others := make([]string, 0, len(members))
+	for _, member := range members {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			return nil, models.ErrInvalidConversation
+		}
+		user, err := s.userStorage.GetUserByNickname(ctx, member)
+		if err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				return nil, models.ErrNotFound
+			}
+			return nil, fmt.Errorf("failed to check conversation member %s: %w", member, err)
+		}
+		if strings.EqualFold(user.Nickname, creator.Nickname) || slices.Contains(others, user.Nickname) {
+			continue
+		}
+		others = append(others, user.Nickname)
+		if len(others) >= maxConversationMembers {
+			return nil, models.ErrInvalidConversation
+		}
+	}
+	if len(others) == 0 {
+		return nil, models.ErrInvalidConversation
+	}
+
+	if err := s.ensureNotBlocked(ctx, creator.Nickname, others); err != nil {
+		return nil, err
+	}
+
+	participants := append([]string{creator.Nickname}, others...)
+	slices.SortFunc(participants, func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	conversation, err := s.conversationStorage.CreateConversation(ctx, participants)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to create conversation in storage: %w", err)
+	}
+	return conversation, nil
+}
+
+func (s *conversationServiceImpl) GetConversation(ctx context.Context, nickname string, id int64) (*models.Conversation, error) {
+	user, err := s.participant(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	return s.conversation(ctx, id, user.Nickname)
+}
+
+func (s *conversationServiceImpl) GetConversations(ctx context.Context, nickname string, limit int, after *models.ConversationCursor) (*models.ConversationPage, error) {
+	user, err := s.participant(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	// Запрашиваем на одну переписку больше, чтобы понять, есть ли следующая страница.
+	conversations, err := s.conversationStorage.GetConversations(ctx, user.Nickname, limit+1, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations from storage: %w", err)
+	}
+
+	page := &models.ConversationPage{Conversations: conversations}
+	if len(conversations) > limit {
+		page.Conversations = conversations[:limit]
+		last := page.Conversations[limit-1]
+		page.Next = EncodeConversationCursor(models.ConversationCursor{Updated: last.Updated, ID: last.ID})
+	}
+	return page, nil
+}
+
+// SendMessage добавляет сообщение в переписку. Если между автором и кем-то из участников
+// есть блокировка, сообщение не отправляется.
+func (s *conversationServiceImpl) SendMessage(ctx context.Context, nickname string, id int64, message string) (*models.ConversationMessage, error) {
+	author, err := s.participant(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(message) == "" || utf8.RuneCountInString(message) > maxConversationMessageLength {
+		return nil, models.ErrInvalidMessage
+	}
+	if err := ensureNotSuspended(ctx, s.moderationStorage, []string{author.Nickname}); err != nil {
+		return nil, err
+	}
+
+	conversation, err := s.conversation(ctx, id, author.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	others := slices.DeleteFunc(conversation.Members, func(member string) bool {
+		return strings.EqualFold(member, author.Nickname)
+	})
+	if err := s.ensureNotBlocked(ctx, author.Nickname, others); err != nil {
+		return nil, err
+	}
+
+	created, err := s.conversationStorage.CreateMessage(ctx, conversation.ID, author.Nickname, message)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to create message in storage: %w", err)
+	}
+	return created, nil
+}
+
+// GetMessages возвращает сообщения от новых к старым; before — id последнего полученного сообщения.
+func (s *conversationServiceImpl) GetMessages(ctx context.Context, nickname string, id int64, limit int, before int64) ([]models.ConversationMessage, error) {
+	user, err := s.participant(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err := s.conversation(ctx, id, user.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.conversationStorage.GetMessages(ctx, conversation.ID, limit, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages from storage: %w", err)
+	}
+	return messages, nil
+}
+
+func (s *conversationServiceImpl) MarkConversationRead(ctx context.Context, nickname string, id int64, upTo int64) error {
+	user, err := s.participant(ctx, nickname)
+	if err != nil {
+		return err
+	}
+	err = s.conversationStorage.MarkConversationRead(ctx, id, user.Nickname, upTo)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to mark conversation as read in storage: %w", err)
+	}
+	return nil
+}
+
+// participant находит пользователя, от имени которого идёт запрос. Переписку читает только сам участник:
+// администраторам сайта доступа к личным сообщениям нет.
+func (s *conversationServiceImpl) participant(ctx context.Context, nickname string) (*models.User, error) {
+	if err := s.access.ensureCaller(ctx, nickname); err != nil {
+		return nil, err
+	}
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to get conversation participant: %w", err)
+	}
+	return user, nil
+}
+
+// conversation возвращает ErrNotFound и для чужой переписки, чтобы не выдавать, что она существует.
+func (s *conversationServiceImpl) conversation(ctx context.Context, id int64, nickname string) (*models.Conversation, error) {
+	conversation, err := s.conversationStorage.GetConversation(ctx, id, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get conversation %d: %w", id, err)
+	}
+	return conversation, nil
+}
+
+func (s *conversationServiceImpl) ensureNotBlocked(ctx context.Context, nickname string, others []string) error {
+	blocked, err := s.userStorage.GetBlockedBetween(ctx, nickname, others)
+	if err != nil {
+		return fmt.Errorf("failed to check user blocks: %w", err)
+	}
+	if len(blocked) > 0 {
+		return models.ErrUserBlocked
+	}
+	return nil
+}
+
+// EncodeConversationCursor упаковывает позицию переписки в непрозрачную строку для параметра cursor.
+func EncodeConversationCursor(cursor models.ConversationCursor) string {
+	raw := strconv.FormatInt(cursor.Updated.UnixNano(), 10) + ":" + strconv.FormatInt(cursor.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeConversationCursor(cursor string) (*models.ConversationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	updated, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, models.ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(updated, 10, 64)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	conversationID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	return &models.ConversationCursor{Updated: time.Unix(0, nanos), ID: conversationID}, nil
+}
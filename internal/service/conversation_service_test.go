@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"slices"
+	"testing"
+)
+
+func TestConversationMessages(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	conversation, err := env.conversations.CreateConversation(as("ann"), "ann", []string{"Bob", "bob", "eve"})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+	if want := []string{"ann", "bob", "eve"}; !slices.Equal(conversation.Members, want) {
+		t.Fatalf("got members %v, want %v", conversation.Members, want)
+	}
+
+	var sent []int64
+	for _, text := range []string{"one", "two", "three"} {
+		message, err := env.conversations.SendMessage(as("ann"), "ann", conversation.ID, text)
+		if err != nil {
+			t.Fatalf("failed to send message: %v", err)
+		}
+		sent = append(sent, message.ID)
+	}
+
+	checkUnread := func(nickname string, want int64) {
+		t.Helper()
+		got, err := env.conversations.GetConversation(as(nickname), nickname, conversation.ID)
+		if err != nil {
+			t.Fatalf("failed to get conversation: %v", err)
+		}
+		if got.Unread != want {
+			t.Fatalf("%s has %d unread messages, want %d", nickname, got.Unread, want)
+		}
+	}
+	checkUnread("ann", 0)
+	checkUnread("bob", 3)
+
+	messages, err := env.conversations.GetMessages(as("bob"), "bob", conversation.ID, 2, 0)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != sent[2] || messages[1].ID != sent[1] {
+		t.Fatalf("got first page %v, want the two newest messages", messages)
+	}
+	messages, err = env.conversations.GetMessages(as("bob"), "bob", conversation.ID, 2, messages[1].ID)
+	if err != nil {
+		t.Fatalf("failed to get messages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != sent[0] {
+		t.Fatalf("got second page %v, want the oldest message", messages)
+	}
+
+	if err := env.conversations.MarkConversationRead(as("bob"), "bob", conversation.ID, sent[1]); err != nil {
+		t.Fatalf("failed to mark conversation read: %v", err)
+	}
+	checkUnread("bob", 1)
+	if err := env.conversations.MarkConversationRead(as("bob"), "bob", conversation.ID, 0); err != nil {
+		t.Fatalf("failed to mark conversation read: %v", err)
+	}
+	checkUnread("bob", 0)
+	checkUnread("eve", 3)
+}
+
+func TestConversationAccess(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(ctx context.Context, env *testEnv, id int64) error
+		want error
+	}{
+		{
+			name: "member reads",
+			ctx:  as("bob"),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				_, err := env.conversations.GetMessages(ctx, "bob", id, 10, 0)
+				return err
+			},
+		},
+		{
+			name: "outsider reads",
+			ctx:  as("eve"),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				_, err := env.conversations.GetMessages(ctx, "eve", id, 10, 0)
+				return err
+			},
+			want: models.ErrNotFound,
+		},
+		{
+			name: "site admin reads",
+			ctx:  as("root"),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				_, err := env.conversations.GetConversation(ctx, "root", id)
+				return err
+			},
+			want: models.ErrNotFound,
+		},
+		{
+			name: "outsider writes",
+			ctx:  as("eve"),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				_, err := env.conversations.SendMessage(ctx, "eve", id, "hi")
+				return err
+			},
+			want: models.ErrNotFound,
+		},
+		{
+			name: "outsider marks read",
+			ctx:  as("eve"),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				return env.conversations.MarkConversationRead(ctx, "eve", id, 0)
+			},
+			want: models.ErrNotFound,
+		},
+		{
+			name: "reads as another member",
+			ctx:  as("eve"),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				_, err := env.conversations.GetMessages(ctx, "bob", id, 10, 0)
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name: "anonymous lists",
+			ctx:  context.Background(),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				_, err := env.conversations.GetConversations(ctx, "bob", 10, nil)
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name: "empty message",
+			ctx:  as("bob"),
+			call: func(ctx context.Context, env *testEnv, id int64) error {
+				_, err := env.conversations.SendMessage(ctx, "bob", id, "  ")
+				return err
+			},
+			want: models.ErrInvalidMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			conversation, err := env.conversations.CreateConversation(as("ann"), "ann", []string{"bob"})
+			if err != nil {
+				t.Fatalf("failed to create conversation: %v", err)
+			}
+			checkErr(t, tt.call(tt.ctx, env, conversation.ID), tt.want)
+		})
+	}
+}
+
+func TestConversationCreateRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []string
+		want    error
+	}{
+		{name: "no members", want: models.ErrInvalidConversation},
+		{name: "only self", members: []string{"ANN"}, want: models.ErrInvalidConversation},
+		{name: "blank member", members: []string{"bob", " "}, want: models.ErrInvalidConversation},
+		{name: "unknown member", members: []string{"nobody"}, want: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			_, err := env.conversations.CreateConversation(as("ann"), "ann", tt.members)
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+func TestBlockedUsersCannotMessage(t *testing.T) {
+	env := newTestEnv(t, false)
+	conversation, err := env.conversations.CreateConversation(as("ann"), "ann", []string{"bob", "eve"})
+	if err != nil {
+		t.Fatalf("failed to create conversation: %v", err)
+	}
+
+	if err := env.users.BlockUser(as("bob"), "bob", "ann"); err != nil {
+		t.Fatalf("failed to block user: %v", err)
+	}
+	// Блокировка работает в обе стороны: ни ann, ни bob не могут писать в общую переписку.
+	_, err = env.conversations.SendMessage(as("ann"), "ann", conversation.ID, "hi")
+	checkErr(t, err, models.ErrUserBlocked)
+	_, err = env.conversations.SendMessage(as("bob"), "bob", conversation.ID, "hi")
+	checkErr(t, err, models.ErrUserBlocked)
+	_, err = env.conversations.CreateConversation(as("ann"), "ann", []string{"bob"})
+	checkErr(t, err, models.ErrUserBlocked)
+	_, err = env.conversations.CreateConversation(as("bob"), "bob", []string{"ann"})
+	checkErr(t, err, models.ErrUserBlocked)
+	if _, err := env.conversations.CreateConversation(as("ann"), "ann", []string{"eve"}); err != nil {
+		t.Fatalf("failed to create conversation without the blocker: %v", err)
+	}
+
+	if err := env.users.UnblockUser(as("bob"), "bob", "ann"); err != nil {
+		t.Fatalf("failed to unblock user: %v", err)
+	}
+	if _, err := env.conversations.SendMessage(as("ann"), "ann", conversation.ID, "hi"); err != nil {
+		t.Fatalf("failed to send message after unblock: %v", err)
+	}
+}
+
+func TestConversationsPages(t *testing.T) {
+	env := newTestEnv(t, false)
+	for _, member := range []string{"bob", "eve", "mod"} {
+		if _, err := env.conversations.CreateConversation(as("ann"), "ann", []string{member}); err != nil {
+			t.Fatalf("failed to create conversation: %v", err)
+		}
+	}
+
+	var seen []int64
+	var after *models.ConversationCursor
+	for {
+		page, err := env.conversations.GetConversations(as("ann"), "ann", 2, after)
+		if err != nil {
+			t.Fatalf("failed to get conversations: %v", err)
+		}
+		for _, conversation := range page.Conversations {
+			seen = append(seen, conversation.ID)
+		}
+		if page.Next == "" {
+			break
+		}
+		cursor, err := DecodeConversationCursor(page.Next)
+		if err != nil {
+			t.Fatalf("failed to decode cursor %q: %v", page.Next, err)
+		}
+		after = cursor
+	}
+	if len(seen) != 3 || seen[0] == seen[1] || seen[1] == seen[2] || seen[0] == seen[2] {
+		t.Fatalf("got conversations %v, want three distinct ones", seen)
+	}
+}
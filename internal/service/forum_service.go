@@ -6,27 +6,41 @@ import (
 	"fmt"
 	"hardhw/internal/models"
 	"hardhw/internal/storage"
+	"strings"
 	"time"
 )
 
 type ForumService interface {
 	CreateForum(ctx context.Context, newForum models.Forum) (models.Forum, error)
 	GetForumBySlug(ctx context.Context, slug string) (*models.Forum, error)
+	GetForumDetails(ctx context.Context, slug string) (models.ForumNode, error)
+	UpdateForum(ctx context.Context, slug string, update models.ForumUpdate) (*models.Forum, error)
+	GetForumChildren(ctx context.Context, slug string) ([]models.ForumNode, error)
+	GetForumTree(ctx context.Context) ([]models.ForumNode, error)
 	CreateThread(ctx context.Context, forumSlug string, newThread models.Thread) (models.Thread, error)
-	GetForumThreads(ctx context.Context, forumSlug string, limit int, since *time.Time, desc bool) ([]models.Thread, error)
-	GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool) ([]models.User, error)
+	GetForumThreads(ctx context.Context, forumSlug string, tags []string, limit int, since *time.Time, desc bool) ([]models.Thread, error)
+	GetForumTags(ctx context.Context, forumSlug string) ([]models.TagCount, error)
+	GetTagThreads(ctx context.Context, tag string, limit int, since *time.Time, desc bool) ([]models.Thread, error)
+	GetFeaturedThreads(ctx context.Context, limit int, since *time.Time, desc bool) ([]models.Thread, error)
+	GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool, excludeBanned bool) ([]models.User, error)
 }
 
 type forumServiceImpl struct {
-	forumStorage storage.ForumStorage
-	userStorage  storage.UserStorage
+	forumStorage      storage.ForumStorage
+	userStorage       storage.UserStorage
+	moderationStorage storage.ModerationStorage
+	access            access
 }
 
-func NewForumService(fs storage.ForumStorage, us storage.UserStorage) ForumService {
-	return &forumServiceImpl{forumStorage: fs, userStorage: us}
+func NewForumService(fs storage.ForumStorage, us storage.UserStorage, ms storage.ModerationStorage, benchmarkMode bool) ForumService {
+	return &forumServiceImpl{forumStorage: fs, userStorage: us, moderationStorage: ms, access: newAccess(ms, benchmarkMode)}
 }
 
 func (s *forumServiceImpl) CreateForum(ctx context.Context, newForum models.Forum) (models.Forum, error) {
+	if err := s.access.ensureBaselineCaller(ctx, newForum.User); err != nil {
+		return models.Forum{}, err
+	}
+
 	userFromDB, err := s.userStorage.GetUserByNickname(ctx, newForum.User)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
@@ -35,7 +49,15 @@ func (s *forumServiceImpl) CreateForum(ctx context.Context, newForum models.Foru
 		return models.Forum{}, fmt.Errorf("failed to check user existence: %w", err)
 	}
 
+	if err := ensureNotSuspended(ctx, s.moderationStorage, []string{userFromDB.Nickname}); err != nil {
+		return models.Forum{}, err
+	}
+
+	// Прежний slug переименованного форума свободен, как и у веток: новый форум его перехватывает.
 	existingForum, err := s.forumStorage.GetForumBySlug(ctx, newForum.Slug)
+	if err == nil && !strings.EqualFold(existingForum.Slug, newForum.Slug) {
+		err = models.ErrNotFound
+	}
 	if err == nil {
 		existingForum.User = userFromDB.Nickname
 		return *existingForum, models.ErrForumConflict
@@ -46,13 +68,28 @@ func (s *forumServiceImpl) CreateForum(ctx context.Context, newForum models.Foru
 	}
 
 	forumToCreate := &models.Forum{
-		Slug:  newForum.Slug,
-		Title: newForum.Title,
-		User:  userFromDB.Nickname,
+		Slug:     newForum.Slug,
+		Title:    newForum.Title,
+		User:     userFromDB.Nickname,
+		Category: newForum.Category,
+	}
+
+	if newForum.Parent != nil && *newForum.Parent != "" {
+		parent, err := s.parentForum(ctx, *newForum.Parent)
+		if err != nil {
+			return models.Forum{}, err
+		}
+		if strings.EqualFold(parent.Slug, newForum.Slug) {
+			return models.Forum{}, models.ErrForumCycle
+		}
+		forumToCreate.Parent = &parent.Slug
 	}
 
 	createdForum, err := s.forumStorage.CreateForum(ctx, forumToCreate)
 	if err != nil {
+		if errors.Is(err, models.ErrParentNotFound) || errors.Is(err, models.ErrForumCycle) {
+			return models.Forum{}, err
+		}
 		if errors.Is(err, models.ErrForumConflict) {
 			recheckForum, recheckErr := s.forumStorage.GetForumBySlug(ctx, newForum.Slug)
 			if recheckErr == nil {
@@ -81,7 +118,150 @@ func (s *forumServiceImpl) GetForumBySlug(ctx context.Context, slug string) (*mo
 	return forum, nil
 }
 
+// GetForumDetails возвращает форум со счётчиками, собранными по всем его подфорумам, как в дереве форумов.
+func (s *forumServiceImpl) GetForumDetails(ctx context.Context, slug string) (models.ForumNode, error) {
+	forum, err := s.GetForumBySlug(ctx, slug)
+	if err != nil {
+		return models.ForumNode{}, err
+	}
+
+	posts, threads, err := s.forumStorage.GetSubforumTotals(ctx, forum.Slug)
+	if err != nil {
+		return models.ForumNode{}, fmt.Errorf("failed to get subforum totals from storage: %w", err)
+	}
+
+	return models.ForumNode{
+		Forum:        *forum,
+		TotalPosts:   forum.Posts + posts,
+		TotalThreads: int64(forum.Threads) + threads,
+	}, nil
+}
+
+// UpdateForum меняет название и slug форума; старый slug остаётся ссылкой на форум.
+func (s *forumServiceImpl) UpdateForum(ctx context.Context, slug string, update models.ForumUpdate) (*models.Forum, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get forum for update: %w", err)
+	}
+
+	if err := s.access.ensureForumRole(ctx, forum.Slug, models.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	if update.Slug != nil && !validSlug(*update.Slug) {
+		return nil, models.ErrInvalidUpdate
+	}
+	if update.Title != nil && strings.TrimSpace(*update.Title) == "" {
+		return nil, models.ErrInvalidUpdate
+	}
+	if update.Parent != nil && *update.Parent != "" {
+		parent, err := s.parentForum(ctx, *update.Parent)
+		if err != nil {
+			return nil, err
+		}
+		update.Parent = &parent.Slug
+	}
+
+	updated, err := s.forumStorage.UpdateForum(ctx, forum.Slug, update)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			return nil, models.ErrNotFound
+		case errors.Is(err, models.ErrForumConflict):
+			return nil, models.ErrForumConflict
+		case errors.Is(err, models.ErrParentNotFound):
+			return nil, models.ErrParentNotFound
+		case errors.Is(err, models.ErrForumCycle):
+			return nil, models.ErrForumCycle
+		}
+		return nil, fmt.Errorf("failed to update forum: %w", err)
+	}
+	return updated, nil
+}
+
+// parentForum находит будущего родителя форума. Вкладывать форумы может только владелец родителя.
+func (s *forumServiceImpl) parentForum(ctx context.Context, slug string) (*models.Forum, error) {
+	parent, err := s.forumStorage.GetForumBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrParentNotFound
+		}
+		return nil, fmt.Errorf("failed to check parent forum existence: %w", err)
+	}
+
+	if err := s.access.ensureForumRole(ctx, parent.Slug, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	return parent, nil
+}
+
+// GetForumChildren возвращает прямых потомков форума со счётчиками, собранными по всем их подфорумам.
+func (s *forumServiceImpl) GetForumChildren(ctx context.Context, slug string) ([]models.ForumNode, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to check forum existence: %w", err)
+	}
+
+	forums, err := s.forumStorage.GetForums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forums from storage: %w", err)
+	}
+
+	children := buildForumTree(forums, forum.Slug)
+	for i := range children {
+		children[i].Children = nil
+	}
+	return children, nil
+}
+
+func (s *forumServiceImpl) GetForumTree(ctx context.Context) ([]models.ForumNode, error) {
+	forums, err := s.forumStorage.GetForums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forums from storage: %w", err)
+	}
+	return buildForumTree(forums, ""), nil
+}
+
+// buildForumTree собирает поддерево форумов с родителем parent ("" — корни сайта)
+// и суммирует счётчики снизу вверх.
+func buildForumTree(forums []models.Forum, parent string) []models.ForumNode {
+	byParent := make(map[string][]models.Forum)
+	for _, forum := range forums {
+		key := ""
+		if forum.Parent != nil {
+			key = strings.ToLower(*forum.Parent)
+		}
+		byParent[key] = append(byParent[key], forum)
+	}
+
+	var build func(parent string) []models.ForumNode
+	build = func(parent string) []models.ForumNode {
+		nodes := make([]models.ForumNode, 0, len(byParent[parent]))
+		for _, forum := range byParent[parent] {
+			node := models.ForumNode{Forum: forum, TotalPosts: forum.Posts, TotalThreads: int64(forum.Threads)}
+			node.Children = build(strings.ToLower(forum.Slug))
+			for _, child := range node.Children {
+				node.TotalPosts += child.TotalPosts
+				node.TotalThreads += child.TotalThreads
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(strings.ToLower(parent))
+}
+
 func (s *forumServiceImpl) CreateThread(ctx context.Context, forumSlug string, newThread models.Thread) (models.Thread, error) {
+	if err := s.access.ensureBaselineCaller(ctx, newThread.Author); err != nil {
+		return models.Thread{}, err
+	}
+
 	forumFromDB, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
@@ -98,6 +278,24 @@ func (s *forumServiceImpl) CreateThread(ctx context.Context, forumSlug string, n
 		return models.Thread{}, fmt.Errorf("failed to check author existence: %w", err)
 	}
 
+	if err := ensureCanParticipate(ctx, s.moderationStorage, forumFromDB.Slug, []string{userFromDB.Nickname}); err != nil {
+		return models.Thread{}, err
+	}
+
+	if forumFromDB.Category {
+		return models.Thread{}, models.ErrForumCategory
+	}
+
+	tags, err := normalizeTags(newThread.Tags)
+	if err != nil {
+		return models.Thread{}, err
+	}
+
+	poll, err := normalizePoll(newThread.Poll)
+	if err != nil {
+		return models.Thread{}, err
+	}
+
 	if newThread.Slug != nil && *newThread.Slug != "" {
 		existingThread, err := s.forumStorage.GetThreadBySlug(ctx, *newThread.Slug)
 		if err == nil {
@@ -121,6 +319,8 @@ func (s *forumServiceImpl) CreateThread(ctx context.Context, forumSlug string, n
 		Slug:    newThread.Slug,
 		Created: creationTime,
 		Votes:   0,
+		Tags:    tags,
+		Poll:    poll,
 	}
 
 	createdThread, err := s.forumStorage.CreateThread(ctx, threadToCreate)
@@ -145,9 +345,14 @@ func (s *forumServiceImpl) CreateThread(ctx context.Context, forumSlug string, n
 	return *createdThread, nil
 }
 
-func (s *forumServiceImpl) GetForumThreads(ctx context.Context, forumSlug string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+// GetForumThreads возвращает ветки форума; если заданы tags, только отмеченные всеми этими тегами.
+func (s *forumServiceImpl) GetForumThreads(ctx context.Context, forumSlug string, tags []string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
+	forum, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return nil, models.ErrNotFound
@@ -155,7 +360,7 @@ func (s *forumServiceImpl) GetForumThreads(ctx context.Context, forumSlug string
 		return nil, fmt.Errorf("ошибка при проверке существования форума '%s': %w", forumSlug, err)
 	}
 
-	threads, err := s.forumStorage.GetThreadsByForumSlug(ctx, forumSlug, limit, since, desc) // <-- Предполагается этот метод в ForumStorage
+	threads, err := s.forumStorage.GetThreadsByForumSlug(ctx, forum.Slug, tags, limit, since, desc) // <-- Предполагается этот метод в ForumStorage
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 
@@ -167,8 +372,46 @@ func (s *forumServiceImpl) GetForumThreads(ctx context.Context, forumSlug string
 	return threads, nil
 }
 
-func (s *forumServiceImpl) GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool) ([]models.User, error) {
-	_, err := s.forumStorage.GetForumBySlug(ctx, slug)
+func (s *forumServiceImpl) GetForumTags(ctx context.Context, forumSlug string) ([]models.TagCount, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to check forum existence: %w", err)
+	}
+
+	counts, err := s.forumStorage.GetForumTags(ctx, forum.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags of forum %s: %w", forum.Slug, err)
+	}
+	return counts, nil
+}
+
+// GetTagThreads возвращает ветки всех форумов с тегом tag.
+func (s *forumServiceImpl) GetTagThreads(ctx context.Context, tag string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	tags, err := normalizeTags([]string{tag})
+	if err != nil {
+		return nil, err
+	}
+
+	threads, err := s.forumStorage.GetThreadsByTag(ctx, tags[0], limit, since, desc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get threads with tag %s: %w", tags[0], err)
+	}
+	return threads, nil
+}
+
+func (s *forumServiceImpl) GetFeaturedThreads(ctx context.Context, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	threads, err := s.forumStorage.GetFeaturedThreads(ctx, limit, since, desc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get featured threads: %w", err)
+	}
+	return threads, nil
+}
+
+func (s *forumServiceImpl) GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool, excludeBanned bool) ([]models.User, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, slug)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return nil, models.ErrNotFound
@@ -176,7 +419,7 @@ func (s *forumServiceImpl) GetForumUsers(ctx context.Context, slug string, limit
 		return nil, fmt.Errorf("failed to check forum existence: %w", err)
 	}
 
-	users, err := s.forumStorage.GetForumUsers(ctx, slug, limit, since, desc)
+	users, err := s.forumStorage.GetForumUsers(ctx, forum.Slug, limit, since, desc, excludeBanned)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve forum users from storage: %w", err)
 	}
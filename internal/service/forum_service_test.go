@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestForumThreadsPinnedFirst(t *testing.T) {
+	env := newTestEnv(t, false)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	titles := []string{"first", "second", "third", "fourth"}
+	threads := make([]models.Thread, len(titles))
+	for i, title := range titles {
+		thread, err := env.forums.CreateThread(as("ann"), "f1", models.Thread{Title: title, Author: "ann", Message: "text", Created: start.Add(time.Duration(i) * time.Hour)})
+		if err != nil {
+			t.Fatalf("failed to create thread %s: %v", title, err)
+		}
+		threads[i] = thread
+	}
+	if _, err := env.threads.PinThread(as("mod"), threadRef(threads[2])); err != nil {
+		t.Fatalf("failed to pin thread: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		since *time.Time
+		desc  bool
+		want  []string
+	}{
+		{name: "first page", limit: 2, want: []string{"third", "first", "second"}},
+		{name: "limit skips pinned threads", limit: 1, want: []string{"third", "first"}},
+		{name: "next page", limit: 2, since: &threads[1].Created, want: []string{"third", "second", "fourth"}},
+		{name: "since after pinned", limit: 2, since: &threads[3].Created, want: []string{"third", "fourth"}},
+		{name: "first page desc", limit: 2, desc: true, want: []string{"third", "fourth", "second"}},
+		{name: "next page desc", limit: 3, since: &threads[1].Created, desc: true, want: []string{"third", "second", "first"}},
+		{name: "since before pinned desc", limit: 1, since: &threads[0].Created, desc: true, want: []string{"third", "first"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := env.forums.GetForumThreads(context.Background(), "f1", nil, tt.limit, tt.since, tt.desc)
+			if err != nil {
+				t.Fatalf("failed to get threads: %v", err)
+			}
+			got := make([]string, len(page))
+			for i, thread := range page {
+				got[i] = thread.Title
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got threads %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got threads %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// Закреплённых веток не меньше limit, а обычные ветки всё равно листаются.
+	if _, err := env.threads.PinThread(as("mod"), threadRef(threads[0])); err != nil {
+		t.Fatalf("failed to pin thread: %v", err)
+	}
+	for _, since := range []*time.Time{nil, &threads[3].Created} {
+		page, err := env.forums.GetForumThreads(context.Background(), "f1", nil, 1, since, false)
+		if err != nil {
+			t.Fatalf("failed to get threads: %v", err)
+		}
+		want := threads[1].ID
+		if since != nil {
+			want = threads[3].ID
+		}
+		if len(page) != 3 || page[0].ID != threads[0].ID || page[1].ID != threads[2].ID || page[2].ID != want {
+			t.Fatalf("got %d threads, want both pinned threads followed by thread %d", len(page), want)
+		}
+	}
+}
+
+func TestForumSlugAliases(t *testing.T) {
+	env := newTestEnv(t, false)
+	env.createThread(t, "ann")
+	if _, err := env.forums.CreateForum(as("eve"), models.Forum{Slug: "f3", Title: "Other", User: "eve"}); err != nil {
+		t.Fatalf("failed to create forum: %v", err)
+	}
+	update := func(caller, slug string) error {
+		_, err := env.forums.UpdateForum(as(caller), "f1", models.ForumUpdate{Slug: &slug})
+		return err
+	}
+	if err := update("bob", "f2"); err != nil {
+		t.Fatalf("failed to rename forum: %v", err)
+	}
+
+	errs := []struct {
+		name   string
+		caller string
+		slug   string
+		want   error
+	}{
+		{name: "moderator", caller: "mod", slug: "f4", want: models.ErrForbidden},
+		{name: "member", caller: "ann", slug: "f4", want: models.ErrForbidden},
+		{name: "taken slug", caller: "bob", slug: "F3", want: models.ErrForumConflict},
+		{name: "numeric slug", caller: "bob", slug: "7", want: models.ErrInvalidUpdate},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, update(tt.caller, tt.slug), tt.want)
+		})
+	}
+
+	// По прежнему slug открываются сам форум, его ветки и участники.
+	for _, slug := range []string{"f2", "f1", "F1"} {
+		t.Run("lookup "+slug, func(t *testing.T) {
+			forum, err := env.forums.GetForumBySlug(context.Background(), slug)
+			if err != nil || forum.Slug != "f2" || forum.Threads != 1 {
+				t.Fatalf("got forum %+v (%v), want f2 with one thread", forum, err)
+			}
+			threads, err := env.forums.GetForumThreads(context.Background(), slug, nil, 10, nil, false)
+			if err != nil || len(threads) != 1 || threads[0].Forum != "f2" {
+				t.Fatalf("got threads %+v (%v), want one thread in f2", threads, err)
+			}
+		})
+	}
+
+	// Новый форум со старым slug перехватывает его у алиаса.
+	if _, err := env.forums.CreateForum(as("ann"), models.Forum{Slug: "f1", Title: "New", User: "ann"}); err != nil {
+		t.Fatalf("failed to create forum over alias: %v", err)
+	}
+	if forum, err := env.forums.GetForumBySlug(context.Background(), "f1"); err != nil || forum.User != "ann" {
+		t.Fatalf("got forum %+v (%v), want the new f1", forum, err)
+	}
+}
+
+func TestThreadTags(t *testing.T) {
+	env := newTestEnv(t, false)
+	if _, err := env.forums.CreateForum(as("eve"), models.Forum{Slug: "f2", Title: "Other", User: "eve"}); err != nil {
+		t.Fatalf("failed to create forum: %v", err)
+	}
+	create := func(forum string, tags ...string) models.Thread {
+		t.Helper()
+		thread, err := env.forums.CreateThread(as("ann"), forum, models.Thread{Title: "Thread", Author: "ann", Message: "text", Tags: tags})
+		if err != nil {
+			t.Fatalf("failed to create thread: %v", err)
+		}
+		return thread
+	}
+	goSQL := create("f1", "SQL", "Go")
+	goOnly := create("f1", "go")
+	untagged := create("f1")
+	other := create("f2", "go")
+
+	if !slices.Equal(goSQL.Tags, []string{"go", "sql"}) {
+		t.Fatalf("created thread has tags %v, want normalized", goSQL.Tags)
+	}
+	_, err := env.forums.CreateThread(as("ann"), "f1", models.Thread{Title: "Thread", Author: "ann", Message: "text", Tags: []string{"bad tag"}})
+	checkErr(t, err, models.ErrInvalidTags)
+
+	ids := func(threads []models.Thread) []int64 {
+		result := make([]int64, len(threads))
+		for i, thread := range threads {
+			result[i] = thread.ID
+		}
+		return result
+	}
+	listings := []struct {
+		name string
+		tags []string
+		want []int64
+		err  error
+	}{
+		{name: "no filter", want: []int64{goSQL.ID, goOnly.ID, untagged.ID}},
+		{name: "one tag", tags: []string{"GO"}, want: []int64{goSQL.ID, goOnly.ID}},
+		{name: "all tags must match", tags: []string{"go", "sql"}, want: []int64{goSQL.ID}},
+		{name: "unknown tag", tags: []string{"rust"}, want: []int64{}},
+		{name: "invalid tag", tags: []string{"a b"}, err: models.ErrInvalidTags},
+	}
+	for _, tt := range listings {
+		t.Run(tt.name, func(t *testing.T) {
+			threads, err := env.forums.GetForumThreads(context.Background(), "f1", tt.tags, 10, nil, false)
+			checkErr(t, err, tt.err)
+			if err == nil && !slices.Equal(ids(threads), tt.want) {
+				t.Fatalf("got threads %v, want %v", ids(threads), tt.want)
+			}
+		})
+	}
+
+	threads, err := env.forums.GetTagThreads(context.Background(), "Go", 10, nil, true)
+	if err != nil || !slices.Equal(ids(threads), []int64{other.ID, goOnly.ID, goSQL.ID}) {
+		t.Fatalf("got tag threads %v (%v), want all go threads newest first", ids(threads), err)
+	}
+
+	// Новый набор тегов заменяет прежний, и счётчики форума это учитывают.
+	tags := []string{"sql", "rust"}
+	if _, err := env.threads.UpdateThread(as("ann"), threadRef(goOnly), models.ThreadUpdate{Tags: &tags}); err != nil {
+		t.Fatalf("failed to update tags: %v", err)
+	}
+	counts, err := env.forums.GetForumTags(context.Background(), "f1")
+	want := []models.TagCount{{Tag: "sql", Threads: 2}, {Tag: "go", Threads: 1}, {Tag: "rust", Threads: 1}}
+	if err != nil || !slices.Equal(counts, want) {
+		t.Fatalf("got tag counts %v (%v), want %v", counts, err, want)
+	}
+
+	// Удалённая ветка пропадает и из счётчиков, и из выборки по тегу.
+	if _, err := setThreadState(env, goSQL, models.ThreadStateDeleted); err != nil {
+		t.Fatalf("failed to delete thread: %v", err)
+	}
+	counts, err = env.forums.GetForumTags(context.Background(), "f1")
+	want = []models.TagCount{{Tag: "rust", Threads: 1}, {Tag: "sql", Threads: 1}}
+	if err != nil || !slices.Equal(counts, want) {
+		t.Fatalf("got tag counts %v (%v) after deletion, want %v", counts, err, want)
+	}
+	threads, err = env.forums.GetTagThreads(context.Background(), "go", 10, nil, false)
+	if err != nil || !slices.Equal(ids(threads), []int64{other.ID}) {
+		t.Fatalf("got tag threads %v (%v) after deletion, want only the other forum's thread", ids(threads), err)
+	}
+}
+
+func TestForumDetailsTotals(t *testing.T) {
+	env := newTestEnv(t, false)
+	ctx := context.Background()
+
+	// cat (категория) -> f1 -> f2: итоги форума включают все его подфорумы.
+	if _, err := env.forums.CreateForum(as("bob"), models.Forum{Slug: "cat", Title: "Category", User: "bob", Category: true}); err != nil {
+		t.Fatalf("failed to create category: %v", err)
+	}
+	parent := "cat"
+	if _, err := env.forums.UpdateForum(as("bob"), "f1", models.ForumUpdate{Parent: &parent}); err != nil {
+		t.Fatalf("failed to move forum into category: %v", err)
+	}
+	parent = "f1"
+	if _, err := env.forums.CreateForum(as("bob"), models.Forum{Slug: "f2", Title: "Nested", User: "bob", Parent: &parent}); err != nil {
+		t.Fatalf("failed to create nested forum: %v", err)
+	}
+
+	env.createThread(t, "ann")
+	env.createThread(t, "ann")
+	nested, err := env.forums.CreateThread(as("ann"), "f2", models.Thread{Title: "Thread", Author: "ann", Message: "text"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	if _, err := env.threads.CreatePosts(as("ann"), threadRef(nested), []models.Post{{Author: "ann", Message: "root"}}); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	tests := []struct {
+		slug         string
+		posts        int64
+		threads      int32
+		totalPosts   int64
+		totalThreads int64
+	}{
+		{slug: "CAT", totalPosts: 5, totalThreads: 3},
+		{slug: "f1", posts: 4, threads: 2, totalPosts: 5, totalThreads: 3},
+		{slug: "f2", posts: 1, threads: 1, totalPosts: 1, totalThreads: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			details, err := env.forums.GetForumDetails(ctx, tt.slug)
+			if err != nil {
+				t.Fatalf("failed to get forum details: %v", err)
+			}
+			if details.Posts != tt.posts || details.Threads != tt.threads {
+				t.Fatalf("forum has own counters %d/%d, want %d/%d", details.Posts, details.Threads, tt.posts, tt.threads)
+			}
+			if details.TotalPosts != tt.totalPosts || details.TotalThreads != tt.totalThreads {
+				t.Fatalf("forum has totals %d/%d, want %d/%d", details.TotalPosts, details.TotalThreads, tt.totalPosts, tt.totalThreads)
+			}
+			if details.Children != nil {
+				t.Fatalf("forum details list children %v, want none", details.Children)
+			}
+		})
+	}
+
+	_, err = env.forums.GetForumDetails(ctx, "missing")
+	checkErr(t, err, models.ErrNotFound)
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"strings"
+	"time"
+)
+
+type ModerationService interface {
+	GetForumModerators(ctx context.Context, forumSlug string) ([]models.User, error)
+	AddForumModerator(ctx context.Context, forumSlug string, nickname string) ([]models.User, error)
+	RemoveForumModerator(ctx context.Context, forumSlug string, nickname string) ([]models.User, error)
+	BanUser(ctx context.Context, forumSlug string, nickname string, ban models.ForumBan) (*models.ForumBan, error)
+	UnbanUser(ctx context.Context, forumSlug string, nickname string) error
+	GetForumBans(ctx context.Context, forumSlug string) ([]models.ForumBan, error)
+	SuspendUser(ctx context.Context, nickname string, suspension models.Suspension) (*models.Suspension, error)
+	UnsuspendUser(ctx context.Context, nickname string) error
+	SetSiteAdmin(ctx context.Context, nickname string, isAdmin bool) error
+	IsSiteAdmin(ctx context.Context, nickname string) (bool, error)
+}
+
+type moderationServiceImpl struct {
+	forumStorage      storage.ForumStorage
+	userStorage       storage.UserStorage
+	moderationStorage storage.ModerationStorage
+	access            access
+}
+
+func NewModerationService(fs storage.ForumStorage, us storage.UserStorage, ms storage.ModerationStorage, benchmarkMode bool) ModerationService {
+	return &moderationServiceImpl{forumStorage: fs, userStorage: us, moderationStorage: ms, access: newAccess(ms, benchmarkMode)}
+}
+
+func (s *moderationServiceImpl) GetForumModerators(ctx context.Context, forumSlug string) ([]models.User, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to check forum existence: %w", err)
+	}
+
+	moderators, err := s.moderationStorage.GetForumModerators(ctx, forum.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forum moderators from storage: %w", err)
+	}
+	return moderators, nil
+}
+
+func (s *moderationServiceImpl) AddForumModerator(ctx context.Context, forumSlug string, nickname string) ([]models.User, error) {
+	forum, user, err := s.forumAndUser(ctx, forumSlug, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.access.ensureForumRole(ctx, forum.Slug, models.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	grantedBy, _ := auth.CallerFromContext(ctx)
+	err = s.moderationStorage.AddForumModerator(ctx, forum.Slug, user.Nickname, grantedBy)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to add forum moderator in storage: %w", err)
+	}
+
+	return s.GetForumModerators(ctx, forum.Slug)
+}
+
+func (s *moderationServiceImpl) RemoveForumModerator(ctx context.Context, forumSlug string, nickname string) ([]models.User, error) {
+	forum, user, err := s.forumAndUser(ctx, forumSlug, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	// Модератор может сложить с себя полномочия сам, снять другого — только владелец.
+	caller, _ := auth.CallerFromContext(ctx)
+	if !strings.EqualFold(caller, user.Nickname) {
+		if err := s.access.ensureForumRole(ctx, forum.Slug, models.RoleOwner); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.moderationStorage.RemoveForumModerator(ctx, forum.Slug, user.Nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to remove forum moderator in storage: %w", err)
+	}
+
+	return s.GetForumModerators(ctx, forum.Slug)
+}
+
+func (s *moderationServiceImpl) BanUser(ctx context.Context, forumSlug string, nickname string, ban models.ForumBan) (*models.ForumBan, error) {
+	if ban.Expires != nil && !ban.Expires.After(time.Now()) {
+		return nil, models.ErrInvalidExpiry
+	}
+
+	forum, user, err := s.forumAndUser(ctx, forumSlug, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.access.ensureForumRole(ctx, forum.Slug, models.RoleModerator); err != nil {
+		return nil, err
+	}
+
+	// Забанить можно только рядового участника: модератора сначала нужно снять.
+	targetRole, err := s.moderationStorage.GetForumRole(ctx, forum.Slug, user.Nickname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role of ban target: %w", err)
+	}
+	if targetRole != models.RoleMember {
+		return nil, models.ErrForbidden
+	}
+
+	ban.Forum = forum.Slug
+	ban.User = user.Nickname
+	ban.BannedBy, _ = auth.CallerFromContext(ctx)
+
+	stored, err := s.moderationStorage.BanUser(ctx, ban)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to ban user in storage: %w", err)
+	}
+	return stored, nil
+}
+
+func (s *moderationServiceImpl) UnbanUser(ctx context.Context, forumSlug string, nickname string) error {
+	forum, user, err := s.forumAndUser(ctx, forumSlug, nickname)
+	if err != nil {
+		return err
+	}
+
+	if err := s.access.ensureForumRole(ctx, forum.Slug, models.RoleModerator); err != nil {
+		return err
+	}
+
+	err = s.moderationStorage.UnbanUser(ctx, forum.Slug, user.Nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to unban user in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *moderationServiceImpl) GetForumBans(ctx context.Context, forumSlug string) ([]models.ForumBan, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to check forum existence: %w", err)
+	}
+
+	if err := s.access.ensureForumRole(ctx, forum.Slug, models.RoleModerator); err != nil {
+		return nil, err
+	}
+
+	bans, err := s.moderationStorage.GetForumBans(ctx, forum.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forum bans from storage: %w", err)
+	}
+	return bans, nil
+}
+
+func (s *moderationServiceImpl) SuspendUser(ctx context.Context, nickname string, suspension models.Suspension) (*models.Suspension, error) {
+	if suspension.Until != nil && !suspension.Until.After(time.Now()) {
+		return nil, models.ErrInvalidExpiry
+	}
+
+	if err := s.access.ensureSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+
+	suspension.User = user.Nickname
+	suspension.SuspendedBy, _ = auth.CallerFromContext(ctx)
+
+	stored, err := s.moderationStorage.SuspendUser(ctx, suspension)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to suspend user in storage: %w", err)
+	}
+	return stored, nil
+}
+
+func (s *moderationServiceImpl) UnsuspendUser(ctx context.Context, nickname string) error {
+	if err := s.access.ensureSiteAdmin(ctx); err != nil {
+		return err
+	}
+
+	err := s.moderationStorage.UnsuspendUser(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to lift suspension in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *moderationServiceImpl) SetSiteAdmin(ctx context.Context, nickname string, isAdmin bool) error {
+	if err := s.access.ensureSiteAdmin(ctx); err != nil {
+		return err
+	}
+
+	err := s.moderationStorage.SetSiteAdmin(ctx, nickname, isAdmin)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to update site admin flag in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *moderationServiceImpl) IsSiteAdmin(ctx context.Context, nickname string) (bool, error) {
+	isAdmin, err := s.moderationStorage.IsSiteAdmin(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check site admin in storage: %w", err)
+	}
+	return isAdmin, nil
+}
+
+func (s *moderationServiceImpl) forumAndUser(ctx context.Context, forumSlug string, nickname string) (*models.Forum, *models.User, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to check forum existence: %w", err)
+	}
+
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrOwnerNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+
+	return forum, user, nil
+}
+
+// roleRanks упорядочивает роли: каждая следующая включает права предыдущих.
+var roleRanks = map[string]int{
+	models.RoleMember:    0,
+	models.RoleModerator: 1,
+	models.RoleOwner:     2,
+	models.RoleAdmin:     3,
+}
+
+func ensureNotSuspended(ctx context.Context, ms storage.ModerationStorage, nicknames []string) error {
+	suspended, err := ms.GetSuspendedUsers(ctx, nicknames)
+	if err != nil {
+		return fmt.Errorf("failed to check suspensions: %w", err)
+	}
+	if len(suspended) > 0 {
+		return models.ErrUserSuspended
+	}
+	return nil
+}
+
+// ensureCanParticipate отклоняет действия заблокированных на сайте и забаненных в форуме пользователей.
+func ensureCanParticipate(ctx context.Context, ms storage.ModerationStorage, forumSlug string, nicknames []string) error {
+	if err := ensureNotSuspended(ctx, ms, nicknames); err != nil {
+		return err
+	}
+
+	banned, err := ms.GetBannedUsers(ctx, forumSlug, nicknames)
+	if err != nil {
+		return fmt.Errorf("failed to check bans in forum %s: %w", forumSlug, err)
+	}
+	if len(banned) > 0 {
+		return models.ErrUserBanned
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"testing"
+	"time"
+)
+
+func TestForumRoles(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(ctx context.Context, env *testEnv) error
+		want error
+	}{
+		{name: "moderator appoints moderator", ctx: as("mod"), call: addModerator, want: models.ErrForbidden},
+		{name: "owner appoints moderator", ctx: as("bob"), call: addModerator},
+		{name: "site admin appoints moderator", ctx: as("root"), call: addModerator},
+		{name: "anonymous appoints moderator", ctx: context.Background(), call: addModerator, want: models.ErrForbidden},
+		{
+			name: "moderator resigns",
+			ctx:  as("mod"),
+			call: func(ctx context.Context, env *testEnv) error {
+				_, err := env.moderation.RemoveForumModerator(ctx, "f1", "mod")
+				return err
+			},
+		},
+		{
+			name: "moderator removes another moderator",
+			ctx:  as("mod"),
+			call: func(ctx context.Context, env *testEnv) error {
+				if err := addModerator(as("bob"), env); err != nil {
+					return err
+				}
+				_, err := env.moderation.RemoveForumModerator(ctx, "f1", "eve")
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{name: "member bans member", ctx: as("eve"), call: banAnn, want: models.ErrForbidden},
+		{name: "moderator bans member", ctx: as("mod"), call: banAnn},
+		{
+			name: "owner bans moderator",
+			ctx:  as("bob"),
+			call: func(ctx context.Context, env *testEnv) error {
+				_, err := env.moderation.BanUser(ctx, "f1", "mod", models.ForumBan{})
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name: "moderator grants site admin",
+			ctx:  as("mod"),
+			call: func(ctx context.Context, env *testEnv) error {
+				return env.moderation.SetSiteAdmin(ctx, "eve", true)
+			},
+			want: models.ErrForbidden,
+		},
+		{
+			name: "member reads bans",
+			ctx:  as("eve"),
+			call: func(ctx context.Context, env *testEnv) error {
+				_, err := env.moderation.GetForumBans(ctx, "f1")
+				return err
+			},
+			want: models.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			checkErr(t, tt.call(tt.ctx, env), tt.want)
+		})
+	}
+}
+
+func TestSuspensionRights(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		suspension models.Suspension
+		want       error
+	}{
+		{name: "moderator suspends user", ctx: as("mod"), want: models.ErrForbidden},
+		{name: "owner suspends user", ctx: as("bob"), want: models.ErrForbidden},
+		{name: "site admin suspends user", ctx: as("root")},
+		{name: "suspension in the past", ctx: as("root"), suspension: models.Suspension{Until: &past}, want: models.ErrInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			_, err := env.moderation.SuspendUser(tt.ctx, "ann", tt.suspension)
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+func TestRestrictedUsersCannotParticipate(t *testing.T) {
+	tests := []struct {
+		name     string
+		restrict func(env *testEnv) error
+		want     error
+	}{
+		{
+			name: "banned in forum",
+			restrict: func(env *testEnv) error {
+				_, err := env.moderation.BanUser(as("mod"), "f1", "ann", models.ForumBan{})
+				return err
+			},
+			want: models.ErrUserBanned,
+		},
+		{
+			name: "suspended on site",
+			restrict: func(env *testEnv) error {
+				_, err := env.moderation.SuspendUser(as("root"), "ann", models.Suspension{})
+				return err
+			},
+			want: models.ErrUserSuspended,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, posts := env.createThread(t, "ann")
+			if err := tt.restrict(env); err != nil {
+				t.Fatalf("failed to restrict ann: %v", err)
+			}
+
+			_, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "more"}})
+			checkErr(t, err, tt.want)
+
+			_, err = env.posts.UpdatePostDetails(as("ann"), posts[0].ID, "edited")
+			checkErr(t, err, tt.want)
+
+			_, err = env.posts.ReactToPost(as("ann"), posts[0].ID, models.Reaction{Nickname: "ann", Kind: models.ReactionUp})
+			checkErr(t, err, tt.want)
+
+			title := "Retitled"
+			_, err = env.threads.UpdateThread(as("ann"), threadRef(thread), models.ThreadUpdate{Title: &title})
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+func TestExpiredBanLiftsRestriction(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, _ := env.createThread(t, "bob")
+
+	expires := time.Now().Add(50 * time.Millisecond)
+	if _, err := env.moderation.BanUser(as("mod"), "f1", "ann", models.ForumBan{Expires: &expires}); err != nil {
+		t.Fatalf("failed to ban ann: %v", err)
+	}
+	_, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "banned"}})
+	checkErr(t, err, models.ErrUserBanned)
+
+	time.Sleep(time.Until(expires) + 10*time.Millisecond)
+	if _, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "back"}}); err != nil {
+		t.Fatalf("failed to post after the ban expired: %v", err)
+	}
+}
+
+func addModerator(ctx context.Context, env *testEnv) error {
+	_, err := env.moderation.AddForumModerator(ctx, "f1", "eve")
+	return err
+}
+
+func banAnn(ctx context.Context, env *testEnv) error {
+	_, err := env.moderation.BanUser(ctx, "f1", "ann", models.ForumBan{})
+	return err
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"log"
+	"time"
+)
+
+const (
+	notificationPollInterval = time.Second
+	notificationBatchSize    = 200
+)
+
+type NotificationService interface {
+	SubscribeThread(ctx context.Context, slugOrID string, nickname string) error
+	UnsubscribeThread(ctx context.Context, slugOrID string, nickname string) error
+	SubscribeForum(ctx context.Context, forumSlug string, nickname string) error
+	UnsubscribeForum(ctx context.Context, forumSlug string, nickname string) error
+	GetSubscriptions(ctx context.Context, nickname string) (*models.Subscriptions, error)
+	GetNotifications(ctx context.Context, nickname string, unread bool, limit int, before int64) ([]models.Notification, error)
+	MarkNotificationsRead(ctx context.Context, nickname string, ids []int64) (int64, error)
+	MarkNotificationRead(ctx context.Context, nickname string, id int64) error
+	Run(ctx context.Context)
+}
+
+type notificationServiceImpl struct {
+	forumStorage        storage.ForumStorage
+	userStorage         storage.UserStorage
+	threadStorage       storage.ThreadStorage
+	moderationStorage   storage.ModerationStorage
+	notificationStorage storage.NotificationStorage
+	access              access
+}
+
+func NewNotificationService(fs storage.ForumStorage, us storage.UserStorage, ts storage.ThreadStorage, ms storage.ModerationStorage, ns storage.NotificationStorage, benchmarkMode bool) NotificationService {
+	return &notificationServiceImpl{forumStorage: fs, userStorage: us, threadStorage: ts, moderationStorage: ms, notificationStorage: ns, access: newAccess(ms, benchmarkMode)}
+}
+
+func (s *notificationServiceImpl) SubscribeThread(ctx context.Context, slugOrID string, nickname string) error {
+	user, thread, err := s.subscriptionThread(ctx, slugOrID, nickname)
+	if err != nil {
+		return err
+	}
+	if err := s.notificationStorage.SubscribeThread(ctx, thread.ID, user.Nickname); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to subscribe to thread in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *notificationServiceImpl) UnsubscribeThread(ctx context.Context, slugOrID string, nickname string) error {
+	user, thread, err := s.subscriptionThread(ctx, slugOrID, nickname)
+	if err != nil {
+		return err
+	}
+	if err := s.notificationStorage.UnsubscribeThread(ctx, thread.ID, user.Nickname); err != nil {
+		return fmt.Errorf("failed to unsubscribe from thread in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *notificationServiceImpl) subscriptionThread(ctx context.Context, slugOrID string, nickname string) (*models.User, *models.Thread, error) {
+	user, err := s.subscriber(ctx, nickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get thread for subscription: %w", err)
+	}
+	return user, thread, nil
+}
+
+func (s *notificationServiceImpl) SubscribeForum(ctx context.Context, forumSlug string, nickname string) error {
+	user, forum, err := s.subscriptionForum(ctx, forumSlug, nickname)
+	if err != nil {
+		return err
+	}
+	if err := s.notificationStorage.SubscribeForum(ctx, forum.Slug, user.Nickname); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to subscribe to forum in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *notificationServiceImpl) UnsubscribeForum(ctx context.Context, forumSlug string, nickname string) error {
+	user, forum, err := s.subscriptionForum(ctx, forumSlug, nickname)
+	if err != nil {
+		return err
+	}
+	if err := s.notificationStorage.UnsubscribeForum(ctx, forum.Slug, user.Nickname); err != nil {
+		return fmt.Errorf("failed to unsubscribe from forum in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *notificationServiceImpl) subscriptionForum(ctx context.Context, forumSlug string, nickname string) (*models.User, *models.Forum, error) {
+	user, err := s.subscriber(ctx, nickname)
+	if err != nil {
+		return nil, nil, err
+	}
+	forum, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get forum for subscription: %w", err)
+	}
+	return user, forum, nil
+}
+
+// subscriber проверяет, что подписку меняет сам пользователь и что он существует.
+func (s *notificationServiceImpl) subscriber(ctx context.Context, nickname string) (*models.User, error) {
+	if err := s.access.ensureCaller(ctx, nickname); err != nil {
+		return nil, err
+	}
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to check subscriber existence: %w", err)
+	}
+	return user, nil
+}
+
+func (s *notificationServiceImpl) GetSubscriptions(ctx context.Context, nickname string) (*models.Subscriptions, error) {
+	user, err := s.recipient(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := s.notificationStorage.GetSubscriptions(ctx, user.Nickname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions from storage: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (s *notificationServiceImpl) GetNotifications(ctx context.Context, nickname string, unread bool, limit int, before int64) ([]models.Notification, error) {
+	user, err := s.recipient(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := s.notificationStorage.GetNotifications(ctx, user.Nickname, unread, limit, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications from storage: %w", err)
+	}
+	return notifications, nil
+}
+
+// MarkNotificationsRead отмечает прочитанными уведомления ids, а если список пуст — все.
+func (s *notificationServiceImpl) MarkNotificationsRead(ctx context.Context, nickname string, ids []int64) (int64, error) {
+	user, err := s.recipient(ctx, nickname)
+	if err != nil {
+		return 0, err
+	}
+	marked, err := s.notificationStorage.MarkNotificationsRead(ctx, user.Nickname, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read in storage: %w", err)
+	}
+	return marked, nil
+}
+
+func (s *notificationServiceImpl) MarkNotificationRead(ctx context.Context, nickname string, id int64) error {
+	marked, err := s.MarkNotificationsRead(ctx, nickname, []int64{id})
+	if err != nil {
+		return err
+	}
+	if marked == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// recipient проверяет, что уведомления читает их владелец или администратор сайта.
+func (s *notificationServiceImpl) recipient(ctx context.Context, nickname string) (*models.User, error) {
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification recipient: %w", err)
+	}
+	if err := s.access.ensureSelfOrSiteAdmin(ctx, user.Nickname); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Run разбирает новые посты в фоне, поэтому рассылка не замедляет их создание.
+func (s *notificationServiceImpl) Run(ctx context.Context) {
+	ticker := time.NewTicker(notificationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			processed, err := s.notificationStorage.NotifyPendingPosts(ctx, notificationBatchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Error sending post notifications: %v", err)
+				}
+				break
+			}
+			// Полная пачка — в очереди, скорее всего, есть ещё; не ждём следующего тика.
+			if processed < notificationBatchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"testing"
+	"time"
+)
+
+// notifyPending разбирает очередь уведомлений так же, как фоновый воркер.
+func (env *testEnv) notifyPending(t *testing.T) {
+	t.Helper()
+
+	if _, err := env.notificationStorage.NotifyPendingPosts(context.Background(), notificationBatchSize); err != nil {
+		t.Fatalf("failed to notify pending posts: %v", err)
+	}
+}
+
+// inbox возвращает виды уведомлений nickname о посте post.
+func (env *testEnv) inbox(t *testing.T, nickname string, post int64) []string {
+	t.Helper()
+
+	notifications, err := env.notifications.GetNotifications(as(nickname), nickname, false, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to get notifications of %s: %v", nickname, err)
+	}
+	var kinds []string
+	for _, n := range notifications {
+		if n.Post == post {
+			kinds = append(kinds, n.Kind)
+		}
+	}
+	return kinds
+}
+
+func TestPostNotifications(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, posts := env.createThread(t, "bob")
+	if err := env.notifications.SubscribeThread(as("ann"), threadRef(thread), "ann"); err != nil {
+		t.Fatalf("failed to subscribe to thread: %v", err)
+	}
+	if err := env.notifications.SubscribeForum(as("eve"), "f1", "eve"); err != nil {
+		t.Fatalf("failed to subscribe to forum: %v", err)
+	}
+	env.notifyPending(t)
+
+	// Ответ eve на пост bob с упоминаниями: на пост и пользователя одно уведомление,
+	// упоминание важнее ответа и подписки, а автор и несуществующие ники пропускаются.
+	reply, err := env.threads.CreatePosts(as("eve"), threadRef(thread), []models.Post{{Author: "eve", Message: "@ann @bob @eve @ghost", Parent: posts[0].ID}})
+	if err != nil {
+		t.Fatalf("failed to create reply: %v", err)
+	}
+	// Корневой пост mod получают только подписчики.
+	root, err := env.threads.CreatePosts(as("mod"), threadRef(thread), []models.Post{{Author: "mod", Message: "news"}})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	if kinds := env.inbox(t, "bob", reply[0].ID); len(kinds) != 0 {
+		t.Fatalf("bob got %v before the worker ran, want nothing yet", kinds)
+	}
+	env.notifyPending(t)
+
+	tests := []struct {
+		nickname string
+		post     int64
+		want     string
+	}{
+		{nickname: "ann", post: reply[0].ID, want: models.NotificationKindMention},
+		{nickname: "bob", post: reply[0].ID, want: models.NotificationKindMention},
+		{nickname: "eve", post: reply[0].ID},
+		{nickname: "mod", post: reply[0].ID},
+		{nickname: "ann", post: root[0].ID, want: models.NotificationKindSubscription},
+		{nickname: "eve", post: root[0].ID, want: models.NotificationKindSubscription},
+		{nickname: "bob", post: root[0].ID},
+		{nickname: "mod", post: root[0].ID},
+	}
+	for _, tt := range tests {
+		kinds := env.inbox(t, tt.nickname, tt.post)
+		if (tt.want == "" && len(kinds) != 0) || (tt.want != "" && (len(kinds) != 1 || kinds[0] != tt.want)) {
+			t.Fatalf("%s got %v for post %d, want %q", tt.nickname, kinds, tt.post, tt.want)
+		}
+	}
+
+	// Прямой ответ без упоминания приходит как reply; отписка останавливает подписку.
+	if err := env.notifications.UnsubscribeThread(as("ann"), threadRef(thread), "ann"); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	answer, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "thanks", Parent: reply[0].ID}})
+	if err != nil {
+		t.Fatalf("failed to create answer: %v", err)
+	}
+	late, err := env.threads.CreatePosts(as("bob"), threadRef(thread), []models.Post{{Author: "bob", Message: "late"}})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	env.notifyPending(t)
+	if kinds := env.inbox(t, "eve", answer[0].ID); len(kinds) != 1 || kinds[0] != models.NotificationKindReply {
+		t.Fatalf("eve got %v for the answer, want a reply", kinds)
+	}
+	if kinds := env.inbox(t, "ann", late[0].ID); len(kinds) != 0 {
+		t.Fatalf("ann got %v after unsubscribing", kinds)
+	}
+}
+
+func TestNotificationQueueSkipsDeletedPosts(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, posts := env.createThread(t, "bob")
+	env.notifyPending(t)
+
+	replies, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{
+		{Author: "ann", Message: "first", Parent: posts[0].ID},
+		{Author: "ann", Message: "second", Parent: posts[0].ID},
+	})
+	if err != nil {
+		t.Fatalf("failed to create replies: %v", err)
+	}
+	if _, err := env.posts.DeletePost(as("ann"), replies[1].ID); err != nil {
+		t.Fatalf("failed to delete reply: %v", err)
+	}
+
+	// Пачка ограничена, и каждый пост разбирается ровно один раз.
+	for i, want := range []int{1, 1, 0} {
+		processed, err := env.notificationStorage.NotifyPendingPosts(context.Background(), 1)
+		if err != nil || processed != want {
+			t.Fatalf("batch %d processed %d posts (%v), want %d", i, processed, err, want)
+		}
+	}
+	if kinds := env.inbox(t, "bob", replies[0].ID); len(kinds) != 1 {
+		t.Fatalf("bob got %v for the reply, want one notification", kinds)
+	}
+	if kinds := env.inbox(t, "bob", replies[1].ID); len(kinds) != 0 {
+		t.Fatalf("bob got %v for a deleted reply", kinds)
+	}
+}
+
+func TestNotificationWorker(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, posts := env.createThread(t, "bob")
+	reply, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "hi", Parent: posts[0].ID}})
+	if err != nil {
+		t.Fatalf("failed to create reply: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		env.notifications.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * notificationPollInterval)
+	for len(env.inbox(t, "bob", reply[0].ID)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("worker did not deliver the reply notification")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNotificationInboxAccess(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, posts := env.createThread(t, "bob")
+	if _, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{
+		{Author: "ann", Message: "one", Parent: posts[0].ID},
+		{Author: "ann", Message: "two", Parent: posts[0].ID},
+	}); err != nil {
+		t.Fatalf("failed to create replies: %v", err)
+	}
+	env.notifyPending(t)
+
+	inbox, err := env.notifications.GetNotifications(as("bob"), "bob", false, 100, 0)
+	if err != nil || len(inbox) != 2 || inbox[0].ID < inbox[1].ID {
+		t.Fatalf("got inbox %+v (%v), want two notifications, newest first", inbox, err)
+	}
+	page, err := env.notifications.GetNotifications(as("bob"), "bob", false, 100, inbox[0].ID)
+	if err != nil || len(page) != 1 || page[0].ID != inbox[1].ID {
+		t.Fatalf("got page %+v (%v), want the older notification", page, err)
+	}
+
+	tests := []struct {
+		name   string
+		caller string
+		id     int64
+		want   error
+	}{
+		{name: "other user", caller: "eve", id: inbox[0].ID, want: models.ErrForbidden},
+		{name: "moderator", caller: "mod", id: inbox[0].ID, want: models.ErrForbidden},
+		{name: "missing notification", caller: "bob", id: inbox[0].ID + 100, want: models.ErrNotFound},
+		{name: "owner", caller: "bob", id: inbox[0].ID},
+		{name: "site admin", caller: "root", id: inbox[1].ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, env.notifications.MarkNotificationRead(as(tt.caller), "bob", tt.id), tt.want)
+		})
+	}
+
+	unread, err := env.notifications.GetNotifications(as("bob"), "bob", true, 100, 0)
+	if err != nil || len(unread) != 0 {
+		t.Fatalf("got unread %+v (%v), want none", unread, err)
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/blob"
+	"hardhw/internal/diff"
+	"hardhw/internal/mention"
 	"hardhw/internal/models"
 	"hardhw/internal/storage"
 	"strings"
@@ -13,19 +17,35 @@ type PostService interface {
 	UpdatePostDetails(ctx context.Context, id int64, newMessage string) (*models.Post, error)
 	GetPostDetailsWithRelated(ctx context.Context, id int64, related []string) (*models.PostDetailsResponse, error)
 	GetPostDetails(ctx context.Context, id int64) (*models.Post, error)
+	GetPostHistory(ctx context.Context, id int64) ([]models.PostRevision, error)
+	RevertPost(ctx context.Context, id int64, revisionID int64) (*models.Post, error)
+	GetUserMentions(ctx context.Context, nickname string, limit int, before int64) ([]models.Post, error)
+	DeletePost(ctx context.Context, id int64) (*models.Post, error)
+	PurgePost(ctx context.Context, id int64) (int64, error)
+	ReactToPost(ctx context.Context, id int64, reaction models.Reaction) (*models.Post, error)
 	GetDatabaseStatus(ctx context.Context) (*models.Status, error)
 	ClearAllData(ctx context.Context) error
 }
 
 type postServiceImpl struct {
-	forumStorage  storage.ForumStorage
-	userStorage   storage.UserStorage
-	threadStorage storage.ThreadStorage
-	postStorage   storage.PostStorage
+	forumStorage      storage.ForumStorage
+	userStorage       storage.UserStorage
+	threadStorage     storage.ThreadStorage
+	postStorage       storage.PostStorage
+	moderationStorage storage.ModerationStorage
+	events            storage.EventPublisher
+	blobs             blob.Store
+	// reactions — разрешённые эмодзи; голоса up и down доступны всегда.
+	reactions map[string]struct{}
+	access    access
 }
 
-func NewPostService(fs storage.ForumStorage, us storage.UserStorage, ts storage.ThreadStorage, ps storage.PostStorage) PostService {
-	return &postServiceImpl{forumStorage: fs, userStorage: us, threadStorage: ts, postStorage: ps}
+func NewPostService(fs storage.ForumStorage, us storage.UserStorage, ts storage.ThreadStorage, ps storage.PostStorage, ms storage.ModerationStorage, events storage.EventPublisher, blobs blob.Store, reactions []string, benchmarkMode bool) PostService {
+	allowed := make(map[string]struct{}, len(reactions)+2)
+	for _, reaction := range append([]string{models.ReactionUp, models.ReactionDown}, reactions...) {
+		allowed[reaction] = struct{}{}
+	}
+	return &postServiceImpl{forumStorage: fs, userStorage: us, threadStorage: ts, postStorage: ps, moderationStorage: ms, events: events, blobs: blobs, reactions: allowed, access: newAccess(ms, benchmarkMode)}
 }
 
 func (s *postServiceImpl) GetPostDetails(ctx context.Context, id int64) (*models.Post, error) {
@@ -36,6 +56,7 @@ func (s *postServiceImpl) GetPostDetails(ctx context.Context, id int64) (*models
 		}
 		return nil, fmt.Errorf("failed to get post by ID from storage: %w", err)
 	}
+	hideDeletedContent(post)
 	return post, nil
 }
 
@@ -48,6 +69,7 @@ func (s *postServiceImpl) GetPostDetailsWithRelated(ctx context.Context, id int6
 		return nil, fmt.Errorf("failed to get post by ID: %w", err)
 	}
 
+	hideDeletedContent(post)
 	response := &models.PostDetailsResponse{
 		Post: post,
 	}
@@ -82,6 +104,16 @@ func (s *postServiceImpl) GetPostDetailsWithRelated(ctx context.Context, id int6
 				return nil, fmt.Errorf("failed to get related forum %s: %w", post.Forum, err)
 			}
 			response.Forum = forum
+		case "revisions":
+			// История удалённого поста раскрыла бы скрытый текст.
+			if post.IsDeleted {
+				continue
+			}
+			revisions, err := s.postRevisions(ctx, post)
+			if err != nil {
+				return nil, err
+			}
+			response.Revisions = revisions
 		default:
 
 			continue
@@ -100,15 +132,35 @@ func (s *postServiceImpl) UpdatePostDetails(ctx context.Context, id int64, newMe
 		return nil, fmt.Errorf("failed to get existing post for update: %w", err)
 	}
 
-	if newMessage == "" {
-		return existingPost, nil
+	if err := s.access.ensureBaselineAuthorOrModerator(ctx, existingPost.Forum, existingPost.Author); err != nil {
+		return nil, err
 	}
 
-	if existingPost.Message == newMessage {
+	// Пустой или неизменный текст ничего не меняет, поэтому такой запрос отвечает как чтение.
+	if !existingPost.IsDeleted && (newMessage == "" || existingPost.Message == newMessage) {
 		return existingPost, nil
 	}
 
-	updatedPost, err := s.postStorage.UpdatePostMessage(ctx, id, newMessage)
+	thread, err := s.postThread(ctx, existingPost)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.access.ensureCanEdit(ctx, thread, existingPost.Author); err != nil {
+		return nil, err
+	}
+
+	// Об удалении поста узнаёт только тот, кто мог бы его править.
+	if existingPost.IsDeleted {
+		return nil, models.ErrPostDeleted
+	}
+
+	mentions, err := resolveMentions(ctx, s.userStorage, []string{newMessage})
+	if err != nil {
+		return nil, err
+	}
+
+	editor, _ := auth.CallerFromContext(ctx)
+	updatedPost, err := s.postStorage.UpdatePostMessage(ctx, id, newMessage, editor, mentions[0])
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return nil, models.ErrPostNotFound
@@ -116,9 +168,288 @@ func (s *postServiceImpl) UpdatePostDetails(ctx context.Context, id int64, newMe
 		return nil, fmt.Errorf("failed to update post message in storage: %w", err)
 	}
 
+	publishThreadEvent(ctx, s.events, models.ThreadNotification{Type: models.ThreadEventEdit, Thread: updatedPost.Thread, Posts: []int64{updatedPost.ID}})
+
 	return updatedPost, nil
 }
 
+func (s *postServiceImpl) GetPostHistory(ctx context.Context, id int64) ([]models.PostRevision, error) {
+	post, err := s.postStorage.GetPostByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to get post for history: %w", err)
+	}
+
+	// Текст удалённого поста скрыт, поэтому его историю видят только модераторы. Эндпоинт открыт
+	// для чтения, так что анонимный запрос отклоняется и в режиме бенчмарка.
+	if post.IsDeleted {
+		if _, ok := auth.CallerFromContext(ctx); !ok {
+			return nil, models.ErrUnauthorized
+		}
+		if err := s.access.ensureForumRole(ctx, post.Forum, models.RoleModerator); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.postRevisions(ctx, post)
+}
+
+// RevertPost возвращает посту текст, который был до правки revisionID. Сам откат
+// тоже сохраняется в истории, поэтому его можно отменить.
+func (s *postServiceImpl) RevertPost(ctx context.Context, id int64, revisionID int64) (*models.Post, error) {
+	post, err := s.postStorage.GetPostByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to get post for revert: %w", err)
+	}
+
+	if err := s.access.ensureForumRole(ctx, post.Forum, models.RoleModerator); err != nil {
+		return nil, err
+	}
+
+	if post.IsDeleted {
+		return nil, models.ErrPostDeleted
+	}
+
+	revision, err := s.postStorage.GetPostRevision(ctx, id, revisionID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get post revision: %w", err)
+	}
+
+	if revision.Message == post.Message {
+		return post, nil
+	}
+
+	mentions, err := resolveMentions(ctx, s.userStorage, []string{revision.Message})
+	if err != nil {
+		return nil, err
+	}
+
+	editor, _ := auth.CallerFromContext(ctx)
+	reverted, err := s.postStorage.UpdatePostMessage(ctx, id, revision.Message, editor, mentions[0])
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to revert post message in storage: %w", err)
+	}
+
+	publishThreadEvent(ctx, s.events, models.ThreadNotification{Type: models.ThreadEventEdit, Thread: reverted.Thread, Posts: []int64{reverted.ID}})
+	return reverted, nil
+}
+
+// GetUserMentions отдаёт посты, где упомянут пользователь, от новых к старым.
+func (s *postServiceImpl) GetUserMentions(ctx context.Context, nickname string, limit int, before int64) ([]models.Post, error) {
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to get mentioned user: %w", err)
+	}
+
+	posts, err := s.postStorage.GetUserMentions(ctx, user.Nickname, limit, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user mentions from storage: %w", err)
+	}
+	return posts, nil
+}
+
+// resolveMentions находит упоминания в каждом тексте и одним запросом отбрасывает
+// несуществующих пользователей: такие @nickname остаются обычным текстом.
+func resolveMentions(ctx context.Context, us storage.UserStorage, messages []string) ([][]string, error) {
+	extracted := make([][]string, len(messages))
+	candidates := make([]string, 0)
+	seen := make(map[string]struct{})
+	for i, message := range messages {
+		extracted[i] = mention.Extract(message)
+		for _, nickname := range extracted[i] {
+			if _, ok := seen[strings.ToLower(nickname)]; !ok {
+				seen[strings.ToLower(nickname)] = struct{}{}
+				candidates = append(candidates, nickname)
+			}
+		}
+	}
+
+	result := make([][]string, len(messages))
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	existing, err := us.GetExistingNicknames(ctx, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check mentioned users: %w", err)
+	}
+	canonical := make(map[string]string, len(existing))
+	for _, nickname := range existing {
+		canonical[strings.ToLower(nickname)] = nickname
+	}
+
+	for i, nicknames := range extracted {
+		for _, nickname := range nicknames {
+			if found, ok := canonical[strings.ToLower(nickname)]; ok {
+				result[i] = append(result[i], found)
+			}
+		}
+	}
+	return result, nil
+}
+
+// postRevisions возвращает историю правок с diff каждой правки: от сохранённого
+// текста до следующей версии или до текущего текста поста.
+func (s *postServiceImpl) postRevisions(ctx context.Context, post *models.Post) ([]models.PostRevision, error) {
+	revisions, err := s.postStorage.GetPostRevisions(ctx, post.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post revisions from storage: %w", err)
+	}
+
+	for i := range revisions {
+		nextName, nextMessage := "current", post.Message
+		if i+1 < len(revisions) {
+			nextName = fmt.Sprintf("revision %d", revisions[i+1].ID)
+			nextMessage = revisions[i+1].Message
+		}
+		revisions[i].Diff = diff.Unified(fmt.Sprintf("revision %d", revisions[i].ID), nextName, revisions[i].Message, nextMessage)
+	}
+	return revisions, nil
+}
+
+// DeletePost превращает пост в надгробие. Удалять может только автор, модератор форума
+// или администратор сайта; остальные получают ErrForbidden.
+func (s *postServiceImpl) DeletePost(ctx context.Context, id int64) (*models.Post, error) {
+	existingPost, err := s.postStorage.GetPostByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to get post for deletion: %w", err)
+	}
+
+	if err := s.access.ensureAuthorOrModerator(ctx, existingPost.Forum, existingPost.Author); err != nil {
+		return nil, err
+	}
+
+	deletedPost, err := s.postStorage.DeletePost(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to delete post in storage: %w", err)
+	}
+
+	hideDeletedContent(deletedPost)
+	return deletedPost, nil
+}
+
+func (s *postServiceImpl) PurgePost(ctx context.Context, id int64) (int64, error) {
+	if err := s.access.ensureSiteAdmin(ctx); err != nil {
+		return 0, err
+	}
+
+	purged, attachmentKeys, err := s.postStorage.PurgePostSubtree(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return 0, models.ErrPostNotFound
+		}
+		return 0, fmt.Errorf("failed to purge post subtree in storage: %w", err)
+	}
+	deleteBlobs(s.blobs, attachmentKeys)
+	return purged, nil
+}
+
+// ReactToPost ставит или снимает реакцию. Голоса up и down взаимоисключающие:
+// новый голос заменяет противоположный, эмодзи копятся независимо.
+func (s *postServiceImpl) ReactToPost(ctx context.Context, id int64, reaction models.Reaction) (*models.Post, error) {
+	if err := s.access.ensureCaller(ctx, reaction.Nickname); err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.reactions[reaction.Kind]; !ok {
+		return nil, models.ErrInvalidReaction
+	}
+
+	_, err := s.userStorage.GetUserByNickname(ctx, reaction.Nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to check reacting user existence: %w", err)
+	}
+
+	post, err := s.postStorage.GetPostByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to get post for reaction: %w", err)
+	}
+
+	if post.IsDeleted {
+		return nil, models.ErrPostDeleted
+	}
+
+	if err := s.ensureThreadOpen(ctx, post); err != nil {
+		return nil, err
+	}
+
+	if err := ensureCanParticipate(ctx, s.moderationStorage, post.Forum, []string{reaction.Nickname}); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.postStorage.SetPostReaction(ctx, id, reaction.Nickname, reaction.Kind, !reaction.Remove)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			return nil, models.ErrPostNotFound
+		case errors.Is(err, models.ErrOwnerNotFound):
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to set post reaction in storage: %w", err)
+	}
+	return updated, nil
+}
+
+// postThread возвращает ветку поста. Пропавшая ветка (её удалили между чтениями)
+// означает, что пропал и пост.
+func (s *postServiceImpl) postThread(ctx context.Context, post *models.Post) (*models.Thread, error) {
+	thread, err := s.threadStorage.GetThreadByID(ctx, post.Thread)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrPostNotFound
+		}
+		return nil, fmt.Errorf("failed to get thread of post %d: %w", post.ID, err)
+	}
+	return thread, nil
+}
+
+// ensureThreadOpen проверяет, что ветка поста принимает реакции.
+func (s *postServiceImpl) ensureThreadOpen(ctx context.Context, post *models.Post) error {
+	thread, err := s.postThread(ctx, post)
+	if err != nil {
+		return err
+	}
+	if !acceptsActivity(thread) {
+		return models.ErrThreadLocked
+	}
+	return nil
+}
+
+// hideDeletedContent превращает удалённый пост в «надгробие»: место в дереве
+// сохраняется, а текст больше не отдаётся клиентам.
+func hideDeletedContent(post *models.Post) {
+	if post.IsDeleted {
+		post.Message = ""
+		post.Attachments = nil
+	}
+}
+
 func (s *postServiceImpl) GetDatabaseStatus(ctx context.Context) (*models.Status, error) {
 	status, err := s.postStorage.CountTableRows(ctx)
 	if err != nil {
@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"hardhw/internal/models"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// editPost правит пост от имени автора и падает при ошибке.
+func (env *testEnv) editPost(t *testing.T, author string, id int64, message string) {
+	t.Helper()
+
+	if _, err := env.posts.UpdatePostDetails(as(author), id, message); err != nil {
+		t.Fatalf("failed to edit post: %v", err)
+	}
+}
+
+func TestPostHistory(t *testing.T) {
+	env := newTestEnv(t, false)
+	_, posts := env.createThread(t, "ann")
+	id := posts[0].ID
+	env.editPost(t, "ann", id, "second")
+	env.editPost(t, "ann", id, "third")
+	// Неизменный текст правкой не считается.
+	env.editPost(t, "ann", id, "third")
+
+	history, err := env.posts.GetPostHistory(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(history) != 2 || history[0].Message != "root" || history[1].Message != "second" {
+		t.Fatalf("got history %+v, want revisions root and second", history)
+	}
+	for _, revision := range history {
+		if revision.Editor != "ann" || revision.Post != id {
+			t.Fatalf("got revision %+v, want post %d edited by ann", revision, id)
+		}
+	}
+	wantDiffs := []string{
+		fmt.Sprintf("--- revision %d\n+++ revision %d\n@@ -1 +1 @@\n-root\n+second\n", history[0].ID, history[1].ID),
+		fmt.Sprintf("--- revision %d\n+++ current\n@@ -1 +1 @@\n-second\n+third\n", history[1].ID),
+	}
+	for i, want := range wantDiffs {
+		if history[i].Diff != want {
+			t.Fatalf("revision %d diff:\n%s\nwant\n%s", i, history[i].Diff, want)
+		}
+	}
+
+	details, err := env.posts.GetPostDetailsWithRelated(context.Background(), id, []string{"revisions"})
+	if err != nil {
+		t.Fatalf("failed to get post details: %v", err)
+	}
+	if len(details.Revisions) != 2 || details.Revisions[1].Diff != wantDiffs[1] {
+		t.Fatalf("got related revisions %+v, want the history", details.Revisions)
+	}
+}
+
+func TestDeletedPostHistory(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{name: "anonymous", ctx: context.Background(), want: models.ErrUnauthorized},
+		{name: "author", ctx: as("ann"), want: models.ErrForbidden},
+		{name: "member", ctx: as("eve"), want: models.ErrForbidden},
+		{name: "moderator", ctx: as("mod")},
+		{name: "forum owner", ctx: as("bob")},
+		{name: "site admin", ctx: as("root")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			_, posts := env.createThread(t, "ann")
+			env.editPost(t, "ann", posts[1].ID, "secret")
+			if _, err := env.posts.DeletePost(as("ann"), posts[1].ID); err != nil {
+				t.Fatalf("failed to delete post: %v", err)
+			}
+
+			history, err := env.posts.GetPostHistory(tt.ctx, posts[1].ID)
+			checkErr(t, err, tt.want)
+			if err == nil && len(history) != 1 {
+				t.Fatalf("got history %+v, want one revision", history)
+			}
+
+			// В связанных данных история удалённого поста не раскрывается никому.
+			details, err := env.posts.GetPostDetailsWithRelated(tt.ctx, posts[1].ID, []string{"revisions"})
+			if err != nil {
+				t.Fatalf("failed to get post details: %v", err)
+			}
+			if len(details.Revisions) != 0 {
+				t.Fatalf("got related revisions %+v for a deleted post", details.Revisions)
+			}
+		})
+	}
+}
+
+func TestRevertPost(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller string
+		// revision — индекс правки в истории; -1 берёт правку другого поста.
+		revision int
+		delete   bool
+		want     error
+	}{
+		{name: "moderator to first", caller: "mod", revision: 0},
+		{name: "moderator to second", caller: "mod", revision: 1},
+		{name: "forum owner", caller: "bob", revision: 0},
+		{name: "site admin", caller: "root", revision: 0},
+		{name: "author", caller: "ann", revision: 0, want: models.ErrForbidden},
+		{name: "member", caller: "eve", revision: 0, want: models.ErrForbidden},
+		{name: "anonymous", revision: 0, want: models.ErrForbidden},
+		{name: "revision of another post", caller: "mod", revision: -1, want: models.ErrNotFound},
+		{name: "deleted post", caller: "mod", revision: 0, delete: true, want: models.ErrPostDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			_, posts := env.createThread(t, "ann")
+			id := posts[0].ID
+			env.editPost(t, "ann", id, "second")
+			env.editPost(t, "ann", id, "third")
+			env.editPost(t, "ann", posts[1].ID, "other")
+
+			history, err := env.posts.GetPostHistory(context.Background(), id)
+			if err != nil {
+				t.Fatalf("failed to get history: %v", err)
+			}
+			revision := history[0]
+			if tt.revision >= 0 {
+				revision = history[tt.revision]
+			} else {
+				other, err := env.posts.GetPostHistory(context.Background(), posts[1].ID)
+				if err != nil {
+					t.Fatalf("failed to get history: %v", err)
+				}
+				revision = other[0]
+			}
+			if tt.delete {
+				if _, err := env.posts.DeletePost(as("ann"), id); err != nil {
+					t.Fatalf("failed to delete post: %v", err)
+				}
+			}
+
+			ctx := context.Background()
+			if tt.caller != "" {
+				ctx = as(tt.caller)
+			}
+			reverted, err := env.posts.RevertPost(ctx, id, revision.ID)
+			checkErr(t, err, tt.want)
+			if err != nil {
+				return
+			}
+			if reverted.Message != revision.Message || !reverted.IsEdited {
+				t.Fatalf("got post %+v, want edited post with %q", reverted, revision.Message)
+			}
+
+			// Откат сам становится правкой, и его можно отменить.
+			history, err = env.posts.GetPostHistory(context.Background(), id)
+			if err != nil {
+				t.Fatalf("failed to get history: %v", err)
+			}
+			last := history[len(history)-1]
+			if len(history) != 3 || last.Message != "third" || last.Editor != tt.caller {
+				t.Fatalf("got history %+v, want the reverted text saved by %s", history, tt.caller)
+			}
+			if !strings.HasSuffix(last.Diff, "-third\n+"+revision.Message+"\n") {
+				t.Fatalf("revert diff:\n%s", last.Diff)
+			}
+		})
+	}
+}
+
+func TestReactToPost(t *testing.T) {
+	up := models.Reaction{Nickname: "eve", Kind: models.ReactionUp}
+	down := models.Reaction{Nickname: "eve", Kind: models.ReactionDown}
+	heart := models.Reaction{Nickname: "eve", Kind: "heart"}
+	tests := []struct {
+		name      string
+		reactions []models.Reaction
+		score     int32
+		counts    map[string]int32
+	}{
+		{name: "upvote", reactions: []models.Reaction{up}, score: 1, counts: map[string]int32{"up": 1}},
+		{name: "same kind twice", reactions: []models.Reaction{up, up}, score: 1, counts: map[string]int32{"up": 1}},
+		{name: "downvote replaces upvote", reactions: []models.Reaction{up, down}, score: -1, counts: map[string]int32{"down": 1}},
+		{name: "emoji next to a vote", reactions: []models.Reaction{up, heart}, score: 1, counts: map[string]int32{"up": 1, "heart": 1}},
+		{name: "remove", reactions: []models.Reaction{up, heart, {Nickname: "eve", Kind: models.ReactionUp, Remove: true}}, counts: map[string]int32{"heart": 1}},
+		{name: "remove missing", reactions: []models.Reaction{{Nickname: "eve", Kind: "heart", Remove: true}}, counts: map[string]int32{}},
+		{name: "several users", reactions: []models.Reaction{up, {Nickname: "ann", Kind: models.ReactionUp}, {Nickname: "mod", Kind: models.ReactionDown}}, score: 1, counts: map[string]int32{"up": 2, "down": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			_, posts := env.createThread(t, "bob")
+
+			var post *models.Post
+			for _, reaction := range tt.reactions {
+				var err error
+				post, err = env.posts.ReactToPost(as(reaction.Nickname), posts[0].ID, reaction)
+				if err != nil {
+					t.Fatalf("failed to react with %+v: %v", reaction, err)
+				}
+			}
+			if post.Score != tt.score || len(post.Reactions) != len(tt.counts) {
+				t.Fatalf("got score %d and reactions %v, want %d and %v", post.Score, post.Reactions, tt.score, tt.counts)
+			}
+			for kind, count := range tt.counts {
+				if post.Reactions[kind] != count {
+					t.Fatalf("got reactions %v, want %v", post.Reactions, tt.counts)
+				}
+			}
+
+			// Счётчики сохраняются в посте, а не только в ответе.
+			stored, err := env.posts.GetPostDetails(context.Background(), posts[0].ID)
+			if err != nil || stored.Score != tt.score {
+				t.Fatalf("stored post %+v (%v), want score %d", stored, err, tt.score)
+			}
+		})
+	}
+}
+
+func TestReactToPostRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		caller   string
+		reaction models.Reaction
+		prepare  func(t *testing.T, env *testEnv, thread models.Thread, post models.Post)
+		want     error
+	}{
+		{name: "unknown kind", caller: "eve", reaction: models.Reaction{Nickname: "eve", Kind: "fire"}, want: models.ErrInvalidReaction},
+		{name: "on behalf of another user", caller: "ann", reaction: models.Reaction{Nickname: "eve", Kind: models.ReactionUp}, want: models.ErrForbidden},
+		{
+			name: "deleted post", caller: "eve", reaction: models.Reaction{Nickname: "eve", Kind: models.ReactionUp},
+			prepare: func(t *testing.T, env *testEnv, _ models.Thread, post models.Post) {
+				if _, err := env.posts.DeletePost(as("bob"), post.ID); err != nil {
+					t.Fatalf("failed to delete post: %v", err)
+				}
+			},
+			want: models.ErrPostDeleted,
+		},
+		{
+			name: "locked thread", caller: "eve", reaction: models.Reaction{Nickname: "eve", Kind: models.ReactionUp},
+			prepare: func(t *testing.T, env *testEnv, thread models.Thread, _ models.Post) {
+				if _, err := setThreadState(env, thread, models.ThreadStateLocked); err != nil {
+					t.Fatalf("failed to lock thread: %v", err)
+				}
+			},
+			want: models.ErrThreadLocked,
+		},
+		{
+			name: "banned user", caller: "eve", reaction: models.Reaction{Nickname: "eve", Kind: models.ReactionUp},
+			prepare: func(t *testing.T, env *testEnv, _ models.Thread, _ models.Post) {
+				if _, err := env.moderation.BanUser(as("mod"), "f1", "eve", models.ForumBan{}); err != nil {
+					t.Fatalf("failed to ban: %v", err)
+				}
+			},
+			want: models.ErrUserBanned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, posts := env.createThread(t, "bob")
+			if tt.prepare != nil {
+				tt.prepare(t, env, thread, posts[0])
+			}
+
+			_, err := env.posts.ReactToPost(as(tt.caller), posts[0].ID, tt.reaction)
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+func TestTopThreadPosts(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, posts := env.createThread(t, "bob")
+	roots, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "second"}, {Author: "ann", Message: "third"}})
+	if err != nil {
+		t.Fatalf("failed to create posts: %v", err)
+	}
+	first, reply, second, third := posts[0], posts[1], roots[0], roots[1]
+
+	// third набирает два голоса, second — один, first остаётся с нулём; ответ своего
+	// score не учитывается и идёт за корнем.
+	for _, vote := range []struct {
+		nickname string
+		post     models.Post
+	}{{"eve", third}, {"mod", third}, {"eve", second}, {"eve", reply}, {"mod", reply}, {"root", reply}} {
+		if _, err := env.posts.ReactToPost(as(vote.nickname), vote.post.ID, models.Reaction{Nickname: vote.nickname, Kind: models.ReactionUp}); err != nil {
+			t.Fatalf("failed to vote: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		since int64
+		desc  bool
+		want  []int64
+	}{
+		{name: "by score", want: []int64{third.ID, second.ID, first.ID, reply.ID}},
+		{name: "desc", desc: true, want: []int64{first.ID, reply.ID, second.ID, third.ID}},
+		{name: "limit counts roots", limit: 2, want: []int64{third.ID, second.ID}},
+		{name: "since root", since: second.ID, want: []int64{first.ID, reply.ID}},
+		{name: "since reply", since: reply.ID, desc: true, want: []int64{second.ID, third.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := env.threads.GetThreadPosts(context.Background(), threadRef(thread), tt.limit, tt.since, "top", tt.desc)
+			if err != nil {
+				t.Fatalf("failed to get posts: %v", err)
+			}
+			ids := make([]int64, len(got))
+			for i, post := range got {
+				ids[i] = post.ID
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Fatalf("got posts %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+// mentionIDs возвращает id постов, где упомянут nickname, в порядке выдачи.
+func (env *testEnv) mentionIDs(t *testing.T, nickname string, limit int, before int64) []int64 {
+	t.Helper()
+
+	posts, err := env.posts.GetUserMentions(context.Background(), nickname, limit, before)
+	if err != nil {
+		t.Fatalf("failed to get mentions of %s: %v", nickname, err)
+	}
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+func TestPostMentions(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, _ := env.createThread(t, "bob")
+
+	// Несуществующий ник остаётся текстом и не мешает остальным постам пачки.
+	created, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{
+		{Author: "ann", Message: "@EVE and @ghost"},
+		{Author: "ann", Message: "@eve again, mail eve@example.com"},
+		{Author: "ann", Message: "no one"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create posts: %v", err)
+	}
+	if created[0].Message != "@EVE and @ghost" {
+		t.Fatalf("message changed to %q", created[0].Message)
+	}
+	if got := env.mentionIDs(t, "eve", 10, 0); !slices.Equal(got, []int64{created[1].ID, created[0].ID}) {
+		t.Fatalf("eve is mentioned in %v, want %v", got, []int64{created[1].ID, created[0].ID})
+	}
+
+	// Правка пересчитывает упоминания.
+	env.editPost(t, "ann", created[0].ID, "@mod instead")
+	env.editPost(t, "ann", created[2].ID, "now @Eve")
+	if got := env.mentionIDs(t, "eve", 10, 0); !slices.Equal(got, []int64{created[2].ID, created[1].ID}) {
+		t.Fatalf("eve is mentioned in %v after edits", got)
+	}
+	if got := env.mentionIDs(t, "mod", 10, 0); !slices.Equal(got, []int64{created[0].ID}) {
+		t.Fatalf("mod is mentioned in %v after edits", got)
+	}
+
+	// Страницы идут от новых к старым: before — id последнего поста предыдущей страницы.
+	page := env.mentionIDs(t, "eve", 1, 0)
+	if !slices.Equal(page, []int64{created[2].ID}) {
+		t.Fatalf("first page is %v", page)
+	}
+	if got := env.mentionIDs(t, "eve", 1, page[0]); !slices.Equal(got, []int64{created[1].ID}) {
+		t.Fatalf("second page is %v", got)
+	}
+
+	// Удалённый пост из упоминаний пропадает.
+	if _, err := env.posts.DeletePost(as("ann"), created[1].ID); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+	if got := env.mentionIDs(t, "eve", 10, 0); !slices.Equal(got, []int64{created[2].ID}) {
+		t.Fatalf("eve is mentioned in %v after delete", got)
+	}
+
+	_, err = env.posts.GetUserMentions(context.Background(), "ghost", 10, 0)
+	checkErr(t, err, models.ErrOwnerNotFound)
+}
+
+func TestEditDeletedPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		caller  string
+		message string
+		ban     bool
+		want    error
+	}{
+		{name: "author", caller: "ann", message: "edited", want: models.ErrPostDeleted},
+		{name: "author without changes", caller: "ann", want: models.ErrPostDeleted},
+		{name: "moderator", caller: "mod", message: "edited", want: models.ErrPostDeleted},
+		{name: "member", caller: "eve", message: "edited", want: models.ErrForbidden},
+		{name: "member without changes", caller: "eve", want: models.ErrForbidden},
+		{name: "anonymous", message: "edited", want: models.ErrForbidden},
+		{name: "banned author", caller: "ann", message: "edited", ban: true, want: models.ErrUserBanned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			_, posts := env.createThread(t, "ann")
+			if _, err := env.posts.DeletePost(as("ann"), posts[1].ID); err != nil {
+				t.Fatalf("failed to delete post: %v", err)
+			}
+			if tt.ban {
+				if _, err := env.moderation.BanUser(as("mod"), "f1", "ann", models.ForumBan{}); err != nil {
+					t.Fatalf("failed to ban: %v", err)
+				}
+			}
+
+			_, err := env.posts.UpdatePostDetails(as(tt.caller), posts[1].ID, tt.message)
+			checkErr(t, err, tt.want)
+		})
+	}
+}
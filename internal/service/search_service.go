@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"strconv"
+	"strings"
+)
+
+type SearchService interface {
+	Search(ctx context.Context, query models.SearchQuery, related []string) (*models.SearchResult, error)
+}
+
+type searchServiceImpl struct {
+	forumStorage  storage.ForumStorage
+	threadStorage storage.ThreadStorage
+	searchStorage storage.SearchStorage
+}
+
+func NewSearchService(fs storage.ForumStorage, ts storage.ThreadStorage, ss storage.SearchStorage) SearchService {
+	return &searchServiceImpl{forumStorage: fs, threadStorage: ts, searchStorage: ss}
+}
+
+func (s *searchServiceImpl) Search(ctx context.Context, query models.SearchQuery, related []string) (*models.SearchResult, error) {
+	query.Query = strings.TrimSpace(query.Query)
+	if query.Query == "" {
+		return nil, models.ErrEmptySearchQuery
+	}
+
+	// Запрашиваем на один результат больше, чтобы понять, есть ли следующая страница.
+	pageSize := query.Limit
+	query.Limit++
+
+	hits, err := s.searchStorage.Search(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search in storage: %w", err)
+	}
+
+	result := &models.SearchResult{Hits: hits}
+	if len(hits) > pageSize {
+		result.Hits = hits[:pageSize]
+		result.Next = EncodeSearchCursor(result.Hits[pageSize-1])
+	}
+
+	if err := s.attachRelated(ctx, result.Hits, related); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *searchServiceImpl) attachRelated(ctx context.Context, hits []models.SearchHit, related []string) error {
+	threads := make(map[int64]*models.Thread)
+	forums := make(map[string]*models.Forum)
+
+	for _, r := range related {
+		switch strings.ToLower(r) {
+		case "thread":
+			for i := range hits {
+				if hits[i].Post == nil {
+					continue
+				}
+				threadID := hits[i].Post.Thread
+				thread, ok := threads[threadID]
+				if !ok {
+					var err error
+					thread, err = s.threadStorage.GetThreadByID(ctx, threadID)
+					if err != nil && !errors.Is(err, models.ErrNotFound) {
+						return fmt.Errorf("failed to get related thread %d: %w", threadID, err)
+					}
+					threads[threadID] = thread
+				}
+				hits[i].Thread = thread
+			}
+		case "forum":
+			for i := range hits {
+				slug := hitForum(hits[i])
+				forum, ok := forums[strings.ToLower(slug)]
+				if !ok {
+					var err error
+					forum, err = s.forumStorage.GetForumBySlug(ctx, slug)
+					if err != nil && !errors.Is(err, models.ErrNotFound) {
+						return fmt.Errorf("failed to get related forum %s: %w", slug, err)
+					}
+					forums[strings.ToLower(slug)] = forum
+				}
+				hits[i].Forum = forum
+			}
+		default:
+			continue
+		}
+	}
+	return nil
+}
+
+func hitForum(hit models.SearchHit) string {
+	if hit.Post != nil {
+		return hit.Post.Forum
+	}
+	return hit.Thread.Forum
+}
+
+// EncodeSearchCursor упаковывает позицию результата в непрозрачную строку для параметра cursor.
+func EncodeSearchCursor(hit models.SearchHit) string {
+	id := int64(0)
+	if hit.Post != nil {
+		id = hit.Post.ID
+	} else if hit.Thread != nil {
+		id = hit.Thread.ID
+	}
+	raw := strconv.FormatFloat(float64(hit.Rank), 'g', -1, 32) + ":" + hit.Kind + ":" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeSearchCursor(cursor string) (*models.SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || (parts[1] != models.SearchKindPost && parts[1] != models.SearchKindThread) {
+		return nil, models.ErrInvalidCursor
+	}
+	rank, err := strconv.ParseFloat(parts[0], 32)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+	return &models.SearchCursor{Rank: float32(rank), Kind: parts[1], ID: id}, nil
+}
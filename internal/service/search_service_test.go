@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestSearchCursor(t *testing.T) {
+	hits := []models.SearchHit{
+		{Kind: models.SearchKindPost, Rank: 0.0607927, Post: &models.Post{ID: 42}},
+		{Kind: models.SearchKindThread, Rank: 1, Thread: &models.Thread{ID: 7}},
+	}
+	for _, hit := range hits {
+		cursor, err := DecodeSearchCursor(EncodeSearchCursor(hit))
+		if err != nil {
+			t.Fatalf("failed to decode cursor of %+v: %v", hit, err)
+		}
+		if cursor.Rank != hit.Rank || cursor.Kind != hit.Kind || cursor.ID != hitID(hit) {
+			t.Fatalf("got cursor %+v for hit %+v", cursor, hit)
+		}
+	}
+
+	for _, cursor := range []string{"", "not base64!", "MTpwb3N0", "MTpmb3J1bToy", "eDpwb3N0OjE", "MTpwb3N0Ong"} {
+		_, err := DecodeSearchCursor(cursor)
+		checkErr(t, err, models.ErrInvalidCursor)
+	}
+}
+
+func hitID(hit models.SearchHit) int64 {
+	if hit.Post != nil {
+		return hit.Post.ID
+	}
+	return hit.Thread.ID
+}
+
+func TestSearchPages(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, _ := env.createThread(t, "bob")
+	// Одинаковые тексты дают одинаковый ранг, и порядок держится только на (kind, id).
+	posts := make([]models.Post, 7)
+	for i := range posts {
+		posts[i] = models.Post{Author: "ann", Message: "apple pie"}
+	}
+	if _, err := env.threads.CreatePosts(as("ann"), threadRef(thread), posts); err != nil {
+		t.Fatalf("failed to create posts: %v", err)
+	}
+	if _, err := env.forums.CreateThread(as("eve"), "f1", models.Thread{Title: "Apple", Author: "eve", Message: "pie"}); err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var order []models.SearchHit
+	query := models.SearchQuery{Query: "apple", Limit: 3}
+	for page := 0; ; page++ {
+		if page > 5 {
+			t.Fatalf("pagination does not end")
+		}
+		result, err := env.search.Search(context.Background(), query, nil)
+		if err != nil {
+			t.Fatalf("failed to search: %v", err)
+		}
+		for _, hit := range result.Hits {
+			key := EncodeSearchCursor(hit)
+			if seen[key] {
+				t.Fatalf("hit %+v is repeated on page %d", hit, page)
+			}
+			seen[key] = true
+			order = append(order, hit)
+		}
+		if result.Next == "" {
+			break
+		}
+		if len(result.Hits) != query.Limit {
+			t.Fatalf("page %d has %d hits and a next cursor", page, len(result.Hits))
+		}
+		query.After, err = DecodeSearchCursor(result.Next)
+		if err != nil {
+			t.Fatalf("failed to decode next cursor: %v", err)
+		}
+	}
+
+	if len(order) != 8 {
+		t.Fatalf("got %d hits, want 7 posts and 1 thread", len(order))
+	}
+	// Заголовок весит больше, поэтому ветка первая; посты с равным рангом идут по убыванию id.
+	if order[0].Kind != models.SearchKindThread {
+		t.Fatalf("first hit is %+v, want the thread", order[0])
+	}
+	for i := 2; i < len(order); i++ {
+		if order[i].Post.ID >= order[i-1].Post.ID {
+			t.Fatalf("posts are out of order: %d after %d", order[i].Post.ID, order[i-1].Post.ID)
+		}
+	}
+
+	filtered, err := env.search.Search(context.Background(), models.SearchQuery{Query: "apple", Author: "EVE", Limit: 10}, []string{"forum"})
+	if err != nil || len(filtered.Hits) != 1 || filtered.Hits[0].Forum == nil || filtered.Hits[0].Forum.Slug != "f1" {
+		t.Fatalf("got %+v (%v), want the thread of eve with its forum", filtered, err)
+	}
+
+	_, err = env.search.Search(context.Background(), models.SearchQuery{Query: "  ", Limit: 10}, nil)
+	checkErr(t, err, models.ErrEmptySearchQuery)
+}
+
+func TestSearchSnippetEscaping(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, _ := env.createThread(t, "bob")
+	message := `<script>alert("apple")</script> & <b>apple</b>`
+	if _, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: message}}); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	result, err := env.search.Search(context.Background(), models.SearchQuery{Query: "apple", Limit: 10}, nil)
+	if err != nil || len(result.Hits) != 1 {
+		t.Fatalf("got %+v (%v), want one hit", result, err)
+	}
+	snippet := result.Hits[0].Snippet
+	if strings.Contains(snippet, "<script>") || strings.Contains(snippet, "<b>apple</b>") {
+		t.Fatalf("snippet %q keeps markup from the post", snippet)
+	}
+	for _, want := range []string{"&lt;script&gt;", "&amp;", "<b>&lt;b&gt;apple&lt;/b&gt;</b>"} {
+		if !strings.Contains(snippet, want) {
+			t.Fatalf("snippet %q does not contain %q", snippet, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// subscriberBuffer — сколько событий может отстать подписчик, прежде чем его отключат.
+	subscriberBuffer = 64
+	// maxStreamBacklog ограничивает число постов, догружаемых при переподключении.
+	maxStreamBacklog = 1000
+
+	listenRetryMin  = time.Second
+	listenRetryMax  = 30 * time.Second
+	dispatchTimeout = 5 * time.Second
+)
+
+type StreamService interface {
+	Run(ctx context.Context)
+	Subscribe(ctx context.Context, slugOrID string, lastEventID int64) (*ThreadSubscription, error)
+}
+
+// ThreadSubscription — живая подписка на события ветки. Backlog содержит посты,
+// пропущенные клиентом с момента lastEventID; Events закрывается, если подписчик
+// не успевает читать или вызван Close.
+type ThreadSubscription struct {
+	Thread  models.Thread
+	Backlog []models.ThreadEvent
+	Events  <-chan models.ThreadEvent
+
+	close func()
+}
+
+func (s *ThreadSubscription) Close() {
+	s.close()
+}
+
+type streamServiceImpl struct {
+	threadStorage storage.ThreadStorage
+	postStorage   storage.PostStorage
+	listener      storage.EventListener
+
+	mu          sync.Mutex
+	subscribers map[int64]map[chan models.ThreadEvent]struct{}
+}
+
+func NewStreamService(ts storage.ThreadStorage, ps storage.PostStorage, listener storage.EventListener) StreamService {
+	return &streamServiceImpl{
+		threadStorage: ts,
+		postStorage:   ps,
+		listener:      listener,
+		subscribers:   make(map[int64]map[chan models.ThreadEvent]struct{}),
+	}
+}
+
+// Run слушает уведомления хранилища и раздаёт их подписчикам, переподключаясь при обрыве.
+func (s *streamServiceImpl) Run(ctx context.Context) {
+	delay := listenRetryMin
+	for {
+		err := s.listener.Listen(ctx, s.dispatch)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Thread events listener stopped, reconnecting in %s: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > listenRetryMax {
+			delay = listenRetryMax
+		}
+	}
+}
+
+func (s *streamServiceImpl) Subscribe(ctx context.Context, slugOrID string, lastEventID int64) (*ThreadSubscription, error) {
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get thread for subscription: %w", err)
+	}
+
+	// Подписываемся до чтения пропущенных постов, чтобы между ними не потерять события.
+	events := make(chan models.ThreadEvent, subscriberBuffer)
+	s.mu.Lock()
+	if s.subscribers[thread.ID] == nil {
+		s.subscribers[thread.ID] = make(map[chan models.ThreadEvent]struct{})
+	}
+	s.subscribers[thread.ID][events] = struct{}{}
+	s.mu.Unlock()
+
+	subscription := &ThreadSubscription{
+		Thread: *thread,
+		Events: events,
+		close:  func() { s.unsubscribe(thread.ID, events) },
+	}
+
+	if lastEventID > 0 {
+		posts, err := s.postStorage.GetThreadPostsAfter(ctx, thread.ID, lastEventID, maxStreamBacklog)
+		if err != nil {
+			subscription.Close()
+			return nil, fmt.Errorf("failed to get missed posts: %w", err)
+		}
+		subscription.Backlog = postEvents(models.ThreadEventPost, thread.ID, posts)
+	}
+
+	return subscription, nil
+}
+
+func (s *streamServiceImpl) unsubscribe(threadID int64, events chan models.ThreadEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[threadID][events]; !ok {
+		return
+	}
+	delete(s.subscribers[threadID], events)
+	if len(s.subscribers[threadID]) == 0 {
+		delete(s.subscribers, threadID)
+	}
+	close(events)
+}
+
+func (s *streamServiceImpl) dispatch(notification models.ThreadNotification) {
+	s.mu.Lock()
+	watched := len(s.subscribers[notification.Thread]) > 0
+	s.mu.Unlock()
+	if !watched {
+		return
+	}
+
+	var events []models.ThreadEvent
+	switch notification.Type {
+	case models.ThreadEventVote:
+		votes := notification.Votes
+		events = []models.ThreadEvent{{Type: models.ThreadEventVote, Thread: notification.Thread, Votes: &votes}}
+	case models.ThreadEventPost, models.ThreadEventEdit:
+		ctx, cancel := context.WithTimeout(context.Background(), dispatchTimeout)
+		posts, err := s.postStorage.GetPostsByIDs(ctx, notification.Posts)
+		cancel()
+		if err != nil {
+			log.Printf("Error loading posts for thread %d event: %v", notification.Thread, err)
+			return
+		}
+		events = postEvents(notification.Type, notification.Thread, posts)
+	default:
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for subscriber := range s.subscribers[notification.Thread] {
+		for _, event := range events {
+			select {
+			case subscriber <- event:
+				continue
+			default:
+			}
+			// Медленного подписчика отключаем: клиент переподключится с Last-Event-ID.
+			delete(s.subscribers[notification.Thread], subscriber)
+			close(subscriber)
+			break
+		}
+	}
+	if len(s.subscribers[notification.Thread]) == 0 {
+		delete(s.subscribers, notification.Thread)
+	}
+}
+
+// publishThreadEvent рассылает событие после фиксации записи. Запись уже сохранена, поэтому
+// ошибка рассылки только логируется: клиент догрузит пропущенное по Last-Event-ID.
+func publishThreadEvent(ctx context.Context, events storage.EventPublisher, event models.ThreadNotification) {
+	if err := events.Publish(ctx, event); err != nil {
+		log.Printf("Error publishing %s event of thread %d: %v", event.Type, event.Thread, err)
+	}
+}
+
+func postEvents(eventType string, threadID int64, posts []models.Post) []models.ThreadEvent {
+	events := make([]models.ThreadEvent, len(posts))
+	for i := range posts {
+		hideDeletedContent(&posts[i])
+		events[i] = models.ThreadEvent{Type: eventType, Thread: threadID, Post: &posts[i]}
+	}
+	return events
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hardhw/internal/auth"
+	"hardhw/internal/blob"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"hardhw/internal/storage/memory"
+	"strconv"
+	"testing"
+)
+
+const (
+	testMaxUploadSize = 1 << 20
+	testUploadQuota   = 4 << 20
+)
+
+// testReactions — эмодзи-реакции, которые разрешены вдобавок к голосам up и down.
+var testReactions = []string{"heart"}
+
+// testEnv — сервисы поверх хранилища в памяти с заранее созданными пользователями:
+// bob владеет форумом f1, mod — его модератор, ann и eve — рядовые участники, root — администратор сайта.
+type testEnv struct {
+	users         UserService
+	forums        ForumService
+	threads       ThreadService
+	posts         PostService
+	moderation    ModerationService
+	webhooks      WebhookService
+	conversations ConversationService
+	auth          AuthService
+	attachments   AttachmentService
+	avatars       AvatarService
+	notifications NotificationService
+	search        SearchService
+
+	blobs               blob.Store
+	threadStorage       storage.ThreadStorage
+	postStorage         storage.PostStorage
+	webhookStorage      storage.WebhookStorage
+	notificationStorage storage.NotificationStorage
+	listener            storage.EventListener
+}
+
+func newTestEnv(t *testing.T, benchmarkMode bool) *testEnv {
+	t.Helper()
+
+	db := memory.New()
+	us := memory.NewUserStorage(db)
+	fs := memory.NewForumStorage(db)
+	ts := memory.NewThreadStorage(db)
+	ps := memory.NewPostStorage(db)
+	ms := memory.NewModerationStorage(db)
+	ws := memory.NewWebhookStorage(db)
+	cs := memory.NewConversationStorage(db)
+	auths := memory.NewAuthStorage(db)
+	atts := memory.NewAttachmentStorage(db)
+	ns := memory.NewNotificationStorage(db)
+	events := memory.NewEventPublisher(db)
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	env := &testEnv{
+		users:               NewUserService(us, ms, blobs, benchmarkMode),
+		forums:              NewForumService(fs, us, ms, benchmarkMode),
+		threads:             NewThreadService(fs, us, ts, ms, events, benchmarkMode),
+		posts:               NewPostService(fs, us, ts, ps, ms, events, blobs, testReactions, benchmarkMode),
+		moderation:          NewModerationService(fs, us, ms, benchmarkMode),
+		webhooks:            NewWebhookService(fs, ms, ws, benchmarkMode),
+		conversations:       NewConversationService(us, ms, cs, benchmarkMode),
+		auth:                NewAuthService(auths, ms, benchmarkMode),
+		attachments:         NewAttachmentService(us, ts, ps, ms, atts, blobs, testMaxUploadSize, testUploadQuota, benchmarkMode),
+		avatars:             NewAvatarService(us, ms, blobs, testMaxUploadSize, benchmarkMode),
+		notifications:       NewNotificationService(fs, us, ts, ms, ns, benchmarkMode),
+		search:              NewSearchService(fs, ts, memory.NewSearchStorage(db)),
+		blobs:               blobs,
+		threadStorage:       ts,
+		postStorage:         ps,
+		webhookStorage:      ws,
+		notificationStorage: ns,
+		listener:            memory.NewEventListener(db),
+	}
+
+	for _, nickname := range []string{"bob", "ann", "eve", "mod", "root"} {
+		_, _, err := env.users.CreateUser(context.Background(), models.User{Nickname: nickname, Fullname: nickname, Email: nickname + "@example.com"})
+		if err != nil {
+			t.Fatalf("failed to create user %s: %v", nickname, err)
+		}
+	}
+	if err := env.moderation.SetSiteAdmin(auth.WithAdminToken(context.Background()), "root", true); err != nil {
+		t.Fatalf("failed to make root a site admin: %v", err)
+	}
+	if _, err := env.forums.CreateForum(as("bob"), models.Forum{Slug: "f1", Title: "Forum", User: "bob"}); err != nil {
+		t.Fatalf("failed to create forum: %v", err)
+	}
+	if _, err := env.moderation.AddForumModerator(as("bob"), "f1", "mod"); err != nil {
+		t.Fatalf("failed to add moderator: %v", err)
+	}
+	return env
+}
+
+// as возвращает контекст запроса от имени nickname.
+func as(nickname string) context.Context {
+	return auth.WithCaller(context.Background(), nickname)
+}
+
+// createThread создаёт в f1 ветку author с двумя постами: корнем и ответом на него.
+func (env *testEnv) createThread(t *testing.T, author string) (models.Thread, []models.Post) {
+	t.Helper()
+
+	thread, err := env.forums.CreateThread(as(author), "f1", models.Thread{Title: "Thread", Author: author, Message: "text"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	root, err := env.threads.CreatePosts(as(author), threadRef(thread), []models.Post{{Author: author, Message: "root"}})
+	if err != nil {
+		t.Fatalf("failed to create root post: %v", err)
+	}
+	reply, err := env.threads.CreatePosts(as(author), threadRef(thread), []models.Post{{Author: author, Message: "reply", Parent: root[0].ID}})
+	if err != nil {
+		t.Fatalf("failed to create reply: %v", err)
+	}
+	return thread, append(root, reply...)
+}
+
+// threadRef — параметр slug_or_id для ветки без slug.
+func threadRef(thread models.Thread) string {
+	return strconv.FormatInt(thread.ID, 10)
+}
+
+func (env *testEnv) forum(t *testing.T) *models.Forum {
+	t.Helper()
+
+	forum, err := env.forums.GetForumBySlug(context.Background(), "f1")
+	if err != nil {
+		t.Fatalf("failed to get forum: %v", err)
+	}
+	return forum
+}
+
+func checkErr(t *testing.T, got, want error) {
+	t.Helper()
+
+	if !errors.Is(got, want) {
+		t.Fatalf("got error %v, want %v", got, want)
+	}
+}
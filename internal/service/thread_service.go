@@ -4,37 +4,63 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/diff"
 	"hardhw/internal/models"
 	"hardhw/internal/storage"
 	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 type ThreadService interface {
 	CreatePosts(ctx context.Context, slugOrID string, newPosts []models.Post) ([]models.Post, error)
 	VoteThread(ctx context.Context, slugOrID string, vote models.Vote) (models.Thread, error)
+	VotePoll(ctx context.Context, slugOrID string, vote models.PollVote) (models.Thread, error)
 	GetThreadDetails(ctx context.Context, slugOrID string) (models.Thread, error)
 	GetThreadPosts(ctx context.Context, slugOrID string, limit int, since int64, sort string, desc bool) ([]models.Post, error)
 	UpdateThread(ctx context.Context, slugOrID string, updateData models.ThreadUpdate) (models.Thread, error)
+	GetThreadHistory(ctx context.Context, slugOrID string) ([]models.ThreadRevision, error)
+	LockThread(ctx context.Context, slugOrID string) (models.Thread, error)
+	UnlockThread(ctx context.Context, slugOrID string) (models.Thread, error)
+	ArchiveThread(ctx context.Context, slugOrID string) (models.Thread, error)
+	DeleteThread(ctx context.Context, slugOrID string) (models.Thread, error)
+	PinThread(ctx context.Context, slugOrID string) (models.Thread, error)
+	UnpinThread(ctx context.Context, slugOrID string) (models.Thread, error)
+	FeatureThread(ctx context.Context, slugOrID string) (models.Thread, error)
+	UnfeatureThread(ctx context.Context, slugOrID string) (models.Thread, error)
 }
 
 type threadServiceImpl struct {
-	forumStorage  storage.ForumStorage
-	userStorage   storage.UserStorage
-	threadStorage storage.ThreadStorage
+	forumStorage      storage.ForumStorage
+	userStorage       storage.UserStorage
+	threadStorage     storage.ThreadStorage
+	moderationStorage storage.ModerationStorage
+	events            storage.EventPublisher
+	access            access
 }
 
-func NewThreadService(fs storage.ForumStorage, us storage.UserStorage, ts storage.ThreadStorage) ThreadService {
-	return &threadServiceImpl{forumStorage: fs, userStorage: us, threadStorage: ts}
+func NewThreadService(fs storage.ForumStorage, us storage.UserStorage, ts storage.ThreadStorage, ms storage.ModerationStorage, events storage.EventPublisher, benchmarkMode bool) ThreadService {
+	return &threadServiceImpl{forumStorage: fs, userStorage: us, threadStorage: ts, moderationStorage: ms, events: events, access: newAccess(ms, benchmarkMode)}
 }
 
 func (s *threadServiceImpl) CreatePosts(ctx context.Context, slugOrID string, newPosts []models.Post) ([]models.Post, error) {
-	threadID, err := s.threadStorage.GetThreadIDBySlugOrID(ctx, slugOrID)
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return nil, models.ErrNotFound
 		}
-		return nil, fmt.Errorf("failed to get thread ID: %w", err)
+		return nil, fmt.Errorf("failed to get thread: %w", err)
+	}
+	threadID := thread.ID
+
+	if !acceptsActivity(thread) {
+		return nil, models.ErrThreadLocked
 	}
 
 	if len(newPosts) == 0 {
@@ -42,10 +68,21 @@ func (s *threadServiceImpl) CreatePosts(ctx context.Context, slugOrID string, ne
 	}
 
 	uniqueAuthors := make(map[string]struct{})
+	authors := make([]string, 0, len(newPosts))
 	for _, post := range newPosts {
+		if err := s.access.ensureBaselineCaller(ctx, post.Author); err != nil {
+			return nil, err
+		}
+		if _, seen := uniqueAuthors[post.Author]; !seen {
+			authors = append(authors, post.Author)
+		}
 		uniqueAuthors[post.Author] = struct{}{}
 	}
 
+	if err := ensureCanParticipate(ctx, s.moderationStorage, thread.Forum, authors); err != nil {
+		return nil, err
+	}
+
 	for author := range uniqueAuthors {
 		_, err := s.userStorage.GetUserByNickname(ctx, author)
 		if err != nil {
@@ -68,14 +105,33 @@ func (s *threadServiceImpl) CreatePosts(ctx context.Context, slugOrID string, ne
 		}
 	}
 
+	attachments := make([][]models.PostAttachment, len(newPosts))
+	for i, post := range newPosts {
+		attachments[i], err = normalizePostAttachments(post.Attachments)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	messages := make([]string, len(newPosts))
+	for i, post := range newPosts {
+		messages[i] = post.Message
+	}
+	mentions, err := resolveMentions(ctx, s.userStorage, messages)
+	if err != nil {
+		return nil, err
+	}
+
 	creationTime := time.Now()
 
 	postsToCreate := make([]*models.Post, len(newPosts))
 	for i, post := range newPosts {
 		postsToCreate[i] = &models.Post{
-			Parent:  post.Parent,
-			Author:  post.Author,
-			Message: post.Message,
+			Parent:      post.Parent,
+			Author:      post.Author,
+			Message:     post.Message,
+			Mentions:    mentions[i],
+			Attachments: attachments[i],
 
 			Forum:   "",
 			Thread:  threadID,
@@ -83,15 +139,34 @@ func (s *threadServiceImpl) CreatePosts(ctx context.Context, slugOrID string, ne
 		}
 	}
 
+	// Хранилище ещё раз проверяет состояние ветки под блокировкой: её могли закрыть
+	// или удалить после проверки выше.
 	createdPosts, err := s.threadStorage.CreatePosts(ctx, postsToCreate)
 	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidAttachment):
+			return nil, models.ErrInvalidAttachment
+		case errors.Is(err, models.ErrThreadLocked):
+			return nil, models.ErrThreadLocked
+		case errors.Is(err, models.ErrNotFound):
+			return nil, models.ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to create posts in storage: %w", err)
 	}
 
+	createdIDs := make([]int64, len(createdPosts))
+	for i := range createdPosts {
+		createdIDs[i] = createdPosts[i].ID
+	}
+	publishThreadEvent(ctx, s.events, models.ThreadNotification{Type: models.ThreadEventPost, Thread: threadID, Posts: createdIDs})
+
 	return createdPosts, nil
 }
 
 func (s *threadServiceImpl) VoteThread(ctx context.Context, slugOrID string, vote models.Vote) (models.Thread, error) {
+	if err := s.access.ensureBaselineCaller(ctx, vote.Nickname); err != nil {
+		return models.Thread{}, err
+	}
 
 	_, err := s.userStorage.GetUserByNickname(ctx, vote.Nickname)
 	if err != nil {
@@ -101,25 +176,97 @@ func (s *threadServiceImpl) VoteThread(ctx context.Context, slugOrID string, vot
 		return models.Thread{}, fmt.Errorf("failed to check voter existence: %w", err)
 	}
 
-	threadID, err := s.threadStorage.GetThreadIDBySlugOrID(ctx, slugOrID)
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return models.Thread{}, models.ErrNotFound
 		}
-		return models.Thread{}, fmt.Errorf("failed to get thread ID for voting: %w", err)
+		return models.Thread{}, fmt.Errorf("failed to get thread for voting: %w", err)
+	}
+
+	if !acceptsActivity(thread) {
+		return models.Thread{}, models.ErrThreadLocked
+	}
+
+	if err := ensureCanParticipate(ctx, s.moderationStorage, thread.Forum, []string{vote.Nickname}); err != nil {
+		return models.Thread{}, err
 	}
 
-	updatedThread, err := s.threadStorage.UpdateThreadVote(ctx, threadID, vote.Nickname, vote.Voice)
+	// Хранилище ещё раз проверяет состояние ветки под блокировкой.
+	updatedThread, err := s.threadStorage.UpdateThreadVote(ctx, thread.ID, vote.Nickname, vote.Voice)
 	if err != nil {
-		if errors.Is(err, models.ErrNotFound) {
+		switch {
+		case errors.Is(err, models.ErrThreadLocked):
+			return models.Thread{}, models.ErrThreadLocked
+		case errors.Is(err, models.ErrNotFound):
 			return models.Thread{}, models.ErrNotFound
 		}
 		return models.Thread{}, fmt.Errorf("failed to update thread vote: %w", err)
 	}
 
+	publishThreadEvent(ctx, s.events, models.ThreadNotification{Type: models.ThreadEventVote, Thread: updatedThread.ID, Votes: updatedThread.Votes})
+
 	return *updatedThread, nil
 }
 
+// VotePoll заменяет выбор пользователя в опросе ветки и возвращает ветку с результатами.
+func (s *threadServiceImpl) VotePoll(ctx context.Context, slugOrID string, vote models.PollVote) (models.Thread, error) {
+	if err := s.access.ensureCaller(ctx, vote.Nickname); err != nil {
+		return models.Thread{}, err
+	}
+
+	_, err := s.userStorage.GetUserByNickname(ctx, vote.Nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrOwnerNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to check voter existence: %w", err)
+	}
+
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to get thread for poll voting: %w", err)
+	}
+
+	if !acceptsActivity(thread) {
+		return models.Thread{}, models.ErrThreadLocked
+	}
+
+	if err := ensureCanParticipate(ctx, s.moderationStorage, thread.Forum, []string{vote.Nickname}); err != nil {
+		return models.Thread{}, err
+	}
+
+	poll, err := s.threadStorage.GetThreadPoll(ctx, thread.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrPollNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to get thread poll: %w", err)
+	}
+
+	options, err := normalizePollChoice(poll, vote.Options)
+	if err != nil {
+		return models.Thread{}, err
+	}
+
+	// Хранилище ещё раз проверяет состояние ветки под блокировкой.
+	thread.Poll, err = s.threadStorage.VoteThreadPoll(ctx, thread.ID, vote.Nickname, options)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			return models.Thread{}, models.ErrPollNotFound
+		case errors.Is(err, models.ErrThreadLocked), errors.Is(err, models.ErrPollClosed), errors.Is(err, models.ErrInvalidPollVote), errors.Is(err, models.ErrOwnerNotFound):
+			return models.Thread{}, err
+		}
+		return models.Thread{}, fmt.Errorf("failed to save poll vote: %w", err)
+	}
+
+	return *thread, nil
+}
+
 func (s *threadServiceImpl) GetThreadDetails(ctx context.Context, slugOrID string) (models.Thread, error) {
 	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
 	if err != nil {
@@ -128,6 +275,11 @@ func (s *threadServiceImpl) GetThreadDetails(ctx context.Context, slugOrID strin
 		}
 		return models.Thread{}, fmt.Errorf("failed to get thread details from storage: %w", err)
 	}
+
+	thread.Poll, err = s.threadStorage.GetThreadPoll(ctx, thread.ID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return models.Thread{}, fmt.Errorf("failed to get thread poll: %w", err)
+	}
 	return *thread, nil
 }
 
@@ -148,6 +300,8 @@ func (s *threadServiceImpl) GetThreadPosts(ctx context.Context, slugOrID string,
 		posts, err = s.threadStorage.GetTreeThreadPosts(ctx, threadID, limit, since, desc)
 	case "parent_tree":
 		posts, err = s.threadStorage.GetParentTreeThreadPosts(ctx, threadID, limit, since, desc)
+	case "top":
+		posts, err = s.threadStorage.GetTopThreadPosts(ctx, threadID, limit, since, desc)
 	default:
 		return nil, errors.New("invalid sort type")
 	}
@@ -160,19 +314,355 @@ func (s *threadServiceImpl) GetThreadPosts(ctx context.Context, slugOrID string,
 		return nil, fmt.Errorf("failed to get thread posts from storage for sort %s: %w", sort, err)
 	}
 
+	for i := range posts {
+		hideDeletedContent(&posts[i])
+	}
+
 	log.Printf("DEBUG: GetThreadPosts for thread %d, sort %s, desc %t, limit %d, since %d - returning %d posts. Is nil: %t",
 		threadID, sort, desc, limit, since, len(posts), posts == nil)
 	return posts, nil
 }
 
 func (s *threadServiceImpl) UpdateThread(ctx context.Context, slugOrID string, updateData models.ThreadUpdate) (models.Thread, error) {
-	updatedThread, err := s.threadStorage.UpdateThread(ctx, slugOrID, updateData)
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return models.Thread{}, models.ErrNotFound
 		}
+		return models.Thread{}, fmt.Errorf("failed to get thread for update: %w", err)
+	}
+
+	if err := s.access.ensureBaselineAuthorOrModerator(ctx, thread.Forum, thread.Author); err != nil {
+		return models.Thread{}, err
+	}
+
+	if updateData.Slug != nil && !validSlug(*updateData.Slug) {
+		return models.Thread{}, models.ErrInvalidUpdate
+	}
+	if updateData.Tags != nil {
+		tags, err := normalizeTags(*updateData.Tags)
+		if err != nil {
+			return models.Thread{}, err
+		}
+		updateData.Tags = &tags
+	}
+
+	if !changesThread(thread, updateData) {
+		return *thread, nil
+	}
+	if err := s.access.ensureCanEdit(ctx, thread, thread.Author); err != nil {
+		return models.Thread{}, err
+	}
+
+	editor, _ := auth.CallerFromContext(ctx)
+	updatedThread, err := s.threadStorage.UpdateThread(ctx, strconv.FormatInt(thread.ID, 10), updateData, editor)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			return models.Thread{}, models.ErrNotFound
+		case errors.Is(err, models.ErrThreadConflict):
+			return models.Thread{}, models.ErrThreadConflict
+		}
 		return models.Thread{}, fmt.Errorf("failed to update thread: %w", err)
 	}
 
 	return updatedThread, nil
 }
+
+// GetThreadHistory возвращает правки ветки от старых к новым; Diff показывает изменение текста.
+func (s *threadServiceImpl) GetThreadHistory(ctx context.Context, slugOrID string) ([]models.ThreadRevision, error) {
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get thread for history: %w", err)
+	}
+
+	revisions, err := s.threadStorage.GetThreadRevisions(ctx, thread.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread revisions from storage: %w", err)
+	}
+
+	for i := range revisions {
+		nextName, nextMessage := "current", thread.Message
+		if i+1 < len(revisions) {
+			nextName = fmt.Sprintf("revision %d", revisions[i+1].ID)
+			nextMessage = revisions[i+1].Message
+		}
+		revisions[i].Diff = diff.Unified(fmt.Sprintf("revision %d", revisions[i].ID), nextName, revisions[i].Message, nextMessage)
+	}
+	return revisions, nil
+}
+
+// validSlug отклоняет пустые и чисто числовые slug: последние неотличимы от id ветки.
+func validSlug(slug string) bool {
+	if strings.TrimSpace(slug) == "" {
+		return false
+	}
+	_, err := strconv.ParseInt(slug, 10, 64)
+	return err != nil
+}
+
+const (
+	maxThreadTags = 5
+	maxTagLength  = 32
+)
+
+// normalizeTags приводит теги к нижнему регистру, убирает повторы и сортирует.
+// Тег — от 1 до maxTagLength букв, цифр, '-' или '_'; у ветки не больше maxThreadTags тегов.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !validTag(tag) {
+			return nil, models.ErrInvalidTags
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) > maxThreadTags {
+		return nil, models.ErrInvalidTags
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func validTag(tag string) bool {
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+		return false
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	minPollOptions        = 2
+	maxPollOptions        = 10
+	maxPollQuestionLength = 300
+	maxPollOptionLength   = 100
+)
+
+// normalizePoll проверяет опрос новой ветки и обрезает пробелы по краям текстов.
+// Номера вариантов и результаты проставляет хранилище.
+func normalizePoll(poll *models.Poll) (*models.Poll, error) {
+	if poll == nil {
+		return nil, nil
+	}
+
+	question := strings.TrimSpace(poll.Question)
+	if question == "" || utf8.RuneCountInString(question) > maxPollQuestionLength {
+		return nil, models.ErrInvalidPoll
+	}
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return nil, models.ErrInvalidPoll
+	}
+	if poll.Closes != nil && !poll.Closes.After(time.Now()) {
+		return nil, models.ErrInvalidPoll
+	}
+
+	normalized := &models.Poll{
+		Question: question,
+		Multiple: poll.Multiple,
+		Closes:   poll.Closes,
+		Options:  make([]models.PollOption, 0, len(poll.Options)),
+	}
+	seen := make(map[string]struct{}, len(poll.Options))
+	for _, option := range poll.Options {
+		text := strings.TrimSpace(option.Text)
+		if text == "" || utf8.RuneCountInString(text) > maxPollOptionLength {
+			return nil, models.ErrInvalidPoll
+		}
+		if _, dup := seen[strings.ToLower(text)]; dup {
+			return nil, models.ErrInvalidPoll
+		}
+		seen[strings.ToLower(text)] = struct{}{}
+		normalized.Options = append(normalized.Options, models.PollOption{Text: text})
+	}
+	return normalized, nil
+}
+
+// normalizePollChoice убирает повторы и сортирует выбранные варианты. В опросе
+// с одним ответом можно выбрать только один вариант; пустой выбор снимает голос.
+func normalizePollChoice(poll *models.Poll, options []int) ([]int, error) {
+	choice := make([]int, 0, len(options))
+	for _, id := range options {
+		if id < 1 || id > len(poll.Options) {
+			return nil, models.ErrInvalidPollVote
+		}
+		if !slices.Contains(choice, id) {
+			choice = append(choice, id)
+		}
+	}
+	if !poll.Multiple && len(choice) > 1 {
+		return nil, models.ErrInvalidPollVote
+	}
+	sort.Ints(choice)
+	return choice, nil
+}
+
+// threadTransitions перечисляет, из каких состояний ветку можно перевести в целевое.
+var threadTransitions = map[string][]string{
+	models.ThreadStateOpen:     {models.ThreadStateLocked},
+	models.ThreadStateLocked:   {models.ThreadStateOpen},
+	models.ThreadStateArchived: {models.ThreadStateOpen, models.ThreadStateLocked},
+	models.ThreadStateDeleted:  {models.ThreadStateOpen, models.ThreadStateLocked, models.ThreadStateArchived},
+}
+
+func acceptsActivity(thread *models.Thread) bool {
+	return thread.State == "" || thread.State == models.ThreadStateOpen
+}
+
+// changesThread сообщает, меняет ли правка что-нибудь в ветке; сравнение то же, что в хранилище.
+func changesThread(thread *models.Thread, update models.ThreadUpdate) bool {
+	switch {
+	case update.Title != nil && *update.Title != thread.Title:
+		return true
+	case update.Message != nil && *update.Message != thread.Message:
+		return true
+	case update.Slug != nil && (thread.Slug == nil || *update.Slug != *thread.Slug):
+		return true
+	case update.Tags != nil && !slices.Equal(*update.Tags, thread.Tags):
+		return true
+	}
+	return false
+}
+
+func (s *threadServiceImpl) LockThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.changeThreadState(ctx, slugOrID, models.ThreadStateLocked)
+}
+
+func (s *threadServiceImpl) UnlockThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.changeThreadState(ctx, slugOrID, models.ThreadStateOpen)
+}
+
+func (s *threadServiceImpl) ArchiveThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.changeThreadState(ctx, slugOrID, models.ThreadStateArchived)
+}
+
+func (s *threadServiceImpl) DeleteThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.changeThreadState(ctx, slugOrID, models.ThreadStateDeleted)
+}
+
+func (s *threadServiceImpl) changeThreadState(ctx context.Context, slugOrID string, state string) (models.Thread, error) {
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to get thread for state change: %w", err)
+	}
+
+	// Закрыть, архивировать и удалить свою ветку может автор, а открыть её снова — только
+	// модератор: иначе автор снимал бы закрытие, наложенное модератором.
+	if state == models.ThreadStateOpen {
+		err = s.access.ensureForumRole(ctx, thread.Forum, models.RoleModerator)
+	} else {
+		err = s.access.ensureAuthorOrModerator(ctx, thread.Forum, thread.Author)
+	}
+	if err != nil {
+		return models.Thread{}, err
+	}
+
+	if thread.State == state {
+		return *thread, nil
+	}
+
+	allowed := false
+	for _, from := range threadTransitions[state] {
+		if thread.State == from {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return models.Thread{}, models.ErrThreadStateConflict
+	}
+
+	updatedThread, err := s.threadStorage.SetThreadState(ctx, thread.ID, state)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to set thread state to %s: %w", state, err)
+	}
+
+	return *updatedThread, nil
+}
+
+// PinThread закрепляет ветку вверху списка её форума. Это делают модераторы форума.
+func (s *threadServiceImpl) PinThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.setThreadPinned(ctx, slugOrID, true)
+}
+
+func (s *threadServiceImpl) UnpinThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.setThreadPinned(ctx, slugOrID, false)
+}
+
+// FeatureThread добавляет ветку в избранное сайта. Это делают администраторы сайта.
+func (s *threadServiceImpl) FeatureThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.setThreadFeatured(ctx, slugOrID, true)
+}
+
+func (s *threadServiceImpl) UnfeatureThread(ctx context.Context, slugOrID string) (models.Thread, error) {
+	return s.setThreadFeatured(ctx, slugOrID, false)
+}
+
+func (s *threadServiceImpl) setThreadPinned(ctx context.Context, slugOrID string, pinned bool) (models.Thread, error) {
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to get thread for pinning: %w", err)
+	}
+
+	if err := s.access.ensureForumRole(ctx, thread.Forum, models.RoleModerator); err != nil {
+		return models.Thread{}, err
+	}
+
+	if thread.Pinned == pinned {
+		return *thread, nil
+	}
+
+	updatedThread, err := s.threadStorage.SetThreadPinned(ctx, thread.ID, pinned)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to pin thread %d: %w", thread.ID, err)
+	}
+	return *updatedThread, nil
+}
+
+func (s *threadServiceImpl) setThreadFeatured(ctx context.Context, slugOrID string, featured bool) (models.Thread, error) {
+	thread, err := s.threadStorage.GetThreadBySlugOrID(ctx, slugOrID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to get thread for featuring: %w", err)
+	}
+
+	if err := s.access.ensureSiteAdmin(ctx); err != nil {
+		return models.Thread{}, err
+	}
+
+	if thread.Featured == featured {
+		return *thread, nil
+	}
+
+	updatedThread, err := s.threadStorage.SetThreadFeatured(ctx, thread.ID, featured)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to feature thread %d: %w", thread.ID, err)
+	}
+	return *updatedThread, nil
+}
@@ -0,0 +1,695 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThreadStateTransitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   []string
+		target string
+		want   error
+	}{
+		{name: "open to locked", target: models.ThreadStateLocked},
+		{name: "locked to open", path: []string{models.ThreadStateLocked}, target: models.ThreadStateOpen},
+		{name: "open to archived", target: models.ThreadStateArchived},
+		{name: "locked to archived", path: []string{models.ThreadStateLocked}, target: models.ThreadStateArchived},
+		{name: "open to open is a no-op", target: models.ThreadStateOpen},
+		{name: "archived to open", path: []string{models.ThreadStateArchived}, target: models.ThreadStateOpen, want: models.ErrThreadStateConflict},
+		{name: "archived to locked", path: []string{models.ThreadStateArchived}, target: models.ThreadStateLocked, want: models.ErrThreadStateConflict},
+		{name: "archived to deleted", path: []string{models.ThreadStateArchived}, target: models.ThreadStateDeleted},
+		{name: "deleted thread is gone", path: []string{models.ThreadStateDeleted}, target: models.ThreadStateOpen, want: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, _ := env.createThread(t, "ann")
+
+			for _, state := range tt.path {
+				if _, err := setThreadState(env, thread, state); err != nil {
+					t.Fatalf("failed to move thread to %s: %v", state, err)
+				}
+			}
+
+			updated, err := setThreadState(env, thread, tt.target)
+			checkErr(t, err, tt.want)
+			if err == nil && updated.State != tt.target {
+				t.Fatalf("thread state is %q, want %q", updated.State, tt.target)
+			}
+		})
+	}
+}
+
+func TestClosedThreadRejectsActivity(t *testing.T) {
+	for _, state := range []string{models.ThreadStateLocked, models.ThreadStateArchived} {
+		t.Run(state, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, posts := env.createThread(t, "ann")
+			if _, err := setThreadState(env, thread, state); err != nil {
+				t.Fatalf("failed to move thread to %s: %v", state, err)
+			}
+
+			_, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "late"}})
+			checkErr(t, err, models.ErrThreadLocked)
+
+			_, err = env.posts.UpdatePostDetails(as("ann"), posts[0].ID, "edited")
+			checkErr(t, err, models.ErrThreadLocked)
+
+			_, err = env.posts.ReactToPost(as("eve"), posts[0].ID, models.Reaction{Nickname: "eve", Kind: models.ReactionUp})
+			checkErr(t, err, models.ErrThreadLocked)
+
+			title := "Retitled"
+			_, err = env.threads.UpdateThread(as("ann"), threadRef(thread), models.ThreadUpdate{Title: &title})
+			checkErr(t, err, models.ErrThreadLocked)
+		})
+	}
+}
+
+// TestClosedThreadEdits проверяет общее правило правки: закрытая ветка заморожена для автора,
+// открыта модераторам форума, а правка без изменений отвечает как чтение.
+func TestClosedThreadEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		caller  string
+		message string
+		title   string
+		want    error
+	}{
+		{name: "author edits", caller: "ann", message: "edited", title: "Retitled", want: models.ErrThreadLocked},
+		{name: "moderator edits", caller: "mod", message: "edited", title: "Retitled"},
+		{name: "forum owner edits", caller: "bob", message: "edited", title: "Retitled"},
+		{name: "author sends empty message and same title", caller: "ann", message: "", title: "Thread"},
+		{name: "author sends the same text", caller: "ann", message: "root", title: "Thread"},
+	}
+
+	for _, state := range []string{models.ThreadStateLocked, models.ThreadStateArchived} {
+		for _, tt := range tests {
+			t.Run(state+"/"+tt.name, func(t *testing.T) {
+				env := newTestEnv(t, false)
+				thread, posts := env.createThread(t, "ann")
+				if _, err := setThreadState(env, thread, state); err != nil {
+					t.Fatalf("failed to move thread to %s: %v", state, err)
+				}
+
+				post, err := env.posts.UpdatePostDetails(as(tt.caller), posts[0].ID, tt.message)
+				checkErr(t, err, tt.want)
+				if err == nil && tt.message != "" && post.Message != tt.message {
+					t.Fatalf("post message is %q, want %q", post.Message, tt.message)
+				}
+
+				updated, err := env.threads.UpdateThread(as(tt.caller), threadRef(thread), models.ThreadUpdate{Title: &tt.title})
+				checkErr(t, err, tt.want)
+				if err == nil && updated.Title != tt.title {
+					t.Fatalf("thread title is %q, want %q", updated.Title, tt.title)
+				}
+			})
+		}
+	}
+}
+
+func TestCreatePostsRechecksThreadState(t *testing.T) {
+	tests := []struct {
+		state string
+		want  error
+	}{
+		{state: models.ThreadStateLocked, want: models.ErrThreadLocked},
+		{state: models.ThreadStateArchived, want: models.ErrThreadLocked},
+		{state: models.ThreadStateDeleted, want: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, _ := env.createThread(t, "ann")
+			// Ветку закрывают между проверкой в сервисе и вставкой в хранилище.
+			if _, err := env.threadStorage.SetThreadState(context.Background(), thread.ID, tt.state); err != nil {
+				t.Fatalf("failed to move thread to %s: %v", tt.state, err)
+			}
+
+			_, err := env.threadStorage.CreatePosts(context.Background(), []*models.Post{{Author: "ann", Message: "late", Thread: thread.ID}})
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+func TestForumCounters(t *testing.T) {
+	tests := []struct {
+		name        string
+		change      func(t *testing.T, env *testEnv, thread models.Thread, posts []models.Post)
+		wantThreads int32
+		wantPosts   int64
+	}{
+		{
+			name:        "new thread",
+			change:      func(t *testing.T, env *testEnv, thread models.Thread, posts []models.Post) {},
+			wantThreads: 1,
+			wantPosts:   2,
+		},
+		{
+			name: "post deleted twice",
+			change: func(t *testing.T, env *testEnv, thread models.Thread, posts []models.Post) {
+				for i := 0; i < 2; i++ {
+					if _, err := env.posts.DeletePost(as("ann"), posts[1].ID); err != nil {
+						t.Fatalf("failed to delete post: %v", err)
+					}
+				}
+			},
+			wantThreads: 1,
+			wantPosts:   1,
+		},
+		{
+			name: "subtree purged",
+			change: func(t *testing.T, env *testEnv, thread models.Thread, posts []models.Post) {
+				if _, err := env.posts.PurgePost(as("root"), posts[0].ID); err != nil {
+					t.Fatalf("failed to purge post: %v", err)
+				}
+			},
+			wantThreads: 1,
+			wantPosts:   0,
+		},
+		{
+			name: "thread deleted",
+			change: func(t *testing.T, env *testEnv, thread models.Thread, posts []models.Post) {
+				if _, err := env.threads.DeleteThread(as("ann"), threadRef(thread)); err != nil {
+					t.Fatalf("failed to delete thread: %v", err)
+				}
+			},
+			wantThreads: 0,
+			wantPosts:   0,
+		},
+		{
+			name: "post of deleted thread",
+			change: func(t *testing.T, env *testEnv, thread models.Thread, posts []models.Post) {
+				if _, err := env.posts.DeletePost(as("ann"), posts[1].ID); err != nil {
+					t.Fatalf("failed to delete post: %v", err)
+				}
+				if _, err := env.threads.DeleteThread(as("ann"), threadRef(thread)); err != nil {
+					t.Fatalf("failed to delete thread: %v", err)
+				}
+
+				_, err := env.posts.DeletePost(as("ann"), posts[0].ID)
+				checkErr(t, err, models.ErrPostNotFound)
+				// Счётчики уже уменьшены удалением ветки, очистка хранилища их не трогает.
+				if _, _, err := env.postStorage.PurgePostSubtree(as("root"), posts[0].ID); err != nil {
+					t.Fatalf("failed to purge post: %v", err)
+				}
+			},
+			wantThreads: 0,
+			wantPosts:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, posts := env.createThread(t, "ann")
+
+			tt.change(t, env, thread, posts)
+
+			forum := env.forum(t)
+			if forum.Threads != tt.wantThreads || forum.Posts != tt.wantPosts {
+				t.Fatalf("forum has %d threads and %d posts, want %d and %d",
+					forum.Threads, forum.Posts, tt.wantThreads, tt.wantPosts)
+			}
+		})
+	}
+}
+
+func TestThreadStateRights(t *testing.T) {
+	tests := []struct {
+		name     string
+		lockedBy string
+		actor    string
+		target   string
+		want     error
+	}{
+		{name: "author locks own thread", actor: "ann", target: models.ThreadStateLocked},
+		{name: "member locks foreign thread", actor: "eve", target: models.ThreadStateLocked, want: models.ErrForbidden},
+		{name: "author archives own thread", actor: "ann", target: models.ThreadStateArchived},
+		{name: "author unlocks moderator lock", lockedBy: "mod", actor: "ann", target: models.ThreadStateOpen, want: models.ErrForbidden},
+		{name: "author unlocks own lock", lockedBy: "ann", actor: "ann", target: models.ThreadStateOpen, want: models.ErrForbidden},
+		{name: "moderator unlocks author lock", lockedBy: "ann", actor: "mod", target: models.ThreadStateOpen},
+		{name: "site admin unlocks moderator lock", lockedBy: "mod", actor: "root", target: models.ThreadStateOpen},
+		{name: "author deletes locked thread", lockedBy: "mod", actor: "ann", target: models.ThreadStateDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, _ := env.createThread(t, "ann")
+			if tt.lockedBy != "" {
+				if _, err := env.threads.LockThread(as(tt.lockedBy), threadRef(thread)); err != nil {
+					t.Fatalf("failed to lock thread: %v", err)
+				}
+			}
+
+			_, err := setThreadStateAs(env, tt.actor, thread, tt.target)
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+func setThreadState(env *testEnv, thread models.Thread, state string) (models.Thread, error) {
+	return setThreadStateAs(env, "mod", thread, state)
+}
+
+func setThreadStateAs(env *testEnv, nickname string, thread models.Thread, state string) (models.Thread, error) {
+	ctx, ref := as(nickname), threadRef(thread)
+	switch state {
+	case models.ThreadStateLocked:
+		return env.threads.LockThread(ctx, ref)
+	case models.ThreadStateArchived:
+		return env.threads.ArchiveThread(ctx, ref)
+	case models.ThreadStateDeleted:
+		return env.threads.DeleteThread(ctx, ref)
+	}
+	return env.threads.UnlockThread(ctx, ref)
+}
+
+// TestThreadEventsPublishedAfterWrite проверяет, что сервисы рассылают события ветки
+// после записи: хранилище само больше ничего не публикует.
+func TestThreadEventsPublishedAfterWrite(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, posts := env.createThread(t, "ann")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan models.ThreadNotification, 16)
+	go env.listener.Listen(ctx, func(event models.ThreadNotification) { events <- event })
+
+	next := func() models.ThreadNotification {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no thread event published")
+			return models.ThreadNotification{}
+		}
+	}
+	// Посты из createThread.
+	next()
+	next()
+
+	created, err := env.threads.CreatePosts(as("eve"), threadRef(thread), []models.Post{{Author: "eve", Message: "a"}, {Author: "eve", Message: "b"}})
+	if err != nil {
+		t.Fatalf("failed to create posts: %v", err)
+	}
+	if event := next(); event.Type != models.ThreadEventPost || len(event.Posts) != 2 || event.Posts[0] != created[0].ID {
+		t.Fatalf("got event %+v, want post event for %d and %d", event, created[0].ID, created[1].ID)
+	}
+
+	if _, err := env.threads.VoteThread(as("eve"), threadRef(thread), models.Vote{Nickname: "eve", Voice: 1}); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+	if event := next(); event.Type != models.ThreadEventVote || event.Votes != 1 {
+		t.Fatalf("got event %+v, want vote event with 1 vote", event)
+	}
+
+	if _, err := env.posts.UpdatePostDetails(as("ann"), posts[0].ID, "edited"); err != nil {
+		t.Fatalf("failed to edit post: %v", err)
+	}
+	if event := next(); event.Type != models.ThreadEventEdit || len(event.Posts) != 1 || event.Posts[0] != posts[0].ID {
+		t.Fatalf("got event %+v, want edit event for post %d", event, posts[0].ID)
+	}
+}
+
+func pollOptions(n int) []models.PollOption {
+	options := make([]models.PollOption, n)
+	for i := range options {
+		options[i] = models.PollOption{Text: "option " + strconv.Itoa(i+1)}
+	}
+	return options
+}
+
+func TestCreateThreadPollLimits(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name string
+		poll models.Poll
+		want error
+	}{
+		{name: "two options", poll: models.Poll{Question: "Q", Options: pollOptions(2)}},
+		{name: "ten options", poll: models.Poll{Question: "Q", Options: pollOptions(10)}},
+		{name: "one option", poll: models.Poll{Question: "Q", Options: pollOptions(1)}, want: models.ErrInvalidPoll},
+		{name: "eleven options", poll: models.Poll{Question: "Q", Options: pollOptions(11)}, want: models.ErrInvalidPoll},
+		{name: "blank question", poll: models.Poll{Question: "  ", Options: pollOptions(2)}, want: models.ErrInvalidPoll},
+		{name: "blank option", poll: models.Poll{Question: "Q", Options: []models.PollOption{{Text: "yes"}, {Text: " "}}}, want: models.ErrInvalidPoll},
+		{name: "duplicate options", poll: models.Poll{Question: "Q", Options: []models.PollOption{{Text: "Yes"}, {Text: "yes "}}}, want: models.ErrInvalidPoll},
+		{name: "closes in the past", poll: models.Poll{Question: "Q", Options: pollOptions(2), Closes: &past}, want: models.ErrInvalidPoll},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, err := env.forums.CreateThread(as("ann"), "f1", models.Thread{Title: "Poll", Author: "ann", Message: "text", Poll: &tt.poll})
+			checkErr(t, err, tt.want)
+			if err != nil {
+				return
+			}
+			details, err := env.threads.GetThreadDetails(context.Background(), threadRef(thread))
+			if err != nil {
+				t.Fatalf("failed to get thread details: %v", err)
+			}
+			if details.Poll == nil || len(details.Poll.Options) != len(tt.poll.Options) {
+				t.Fatalf("thread poll is %+v, want %d options", details.Poll, len(tt.poll.Options))
+			}
+		})
+	}
+}
+
+// createPollThread создаёт в f1 ветку ann с опросом из трёх вариантов.
+func (env *testEnv) createPollThread(t *testing.T, multiple bool, closes *time.Time) models.Thread {
+	t.Helper()
+
+	poll := models.Poll{Question: "Q", Options: pollOptions(3), Multiple: multiple, Closes: closes}
+	thread, err := env.forums.CreateThread(as("ann"), "f1", models.Thread{Title: "Poll", Author: "ann", Message: "text", Poll: &poll})
+	if err != nil {
+		t.Fatalf("failed to create poll thread: %v", err)
+	}
+	return thread
+}
+
+func TestVotePoll(t *testing.T) {
+	tests := []struct {
+		name      string
+		multiple  bool
+		previous  []int
+		options   []int
+		want      error
+		wantVotes []int32
+		voters    int32
+	}{
+		{name: "single choice", options: []int{2}, wantVotes: []int32{0, 1, 0}, voters: 1},
+		{name: "single choice with two options", options: []int{1, 2}, want: models.ErrInvalidPollVote},
+		{name: "single choice repeats an option", options: []int{3, 3}, wantVotes: []int32{0, 0, 1}, voters: 1},
+		{name: "multiple choice", multiple: true, options: []int{3, 1}, wantVotes: []int32{1, 0, 1}, voters: 1},
+		{name: "unknown option", options: []int{4}, want: models.ErrInvalidPollVote},
+		{name: "zero option", multiple: true, options: []int{0, 1}, want: models.ErrInvalidPollVote},
+		{name: "revote replaces the choice", previous: []int{1}, options: []int{2}, wantVotes: []int32{0, 1, 0}, voters: 1},
+		{name: "empty choice withdraws the vote", previous: []int{1}, options: []int{}, wantVotes: []int32{0, 0, 0}, voters: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread := env.createPollThread(t, tt.multiple, nil)
+			if tt.previous != nil {
+				if _, err := env.threads.VotePoll(as("eve"), threadRef(thread), models.PollVote{Nickname: "eve", Options: tt.previous}); err != nil {
+					t.Fatalf("failed to cast previous vote: %v", err)
+				}
+			}
+
+			voted, err := env.threads.VotePoll(as("eve"), threadRef(thread), models.PollVote{Nickname: "eve", Options: tt.options})
+			checkErr(t, err, tt.want)
+			if err != nil {
+				return
+			}
+			for i, option := range voted.Poll.Options {
+				if option.Votes != tt.wantVotes[i] {
+					t.Fatalf("option %d has %d votes, want %d", option.ID, option.Votes, tt.wantVotes[i])
+				}
+			}
+			if voted.Poll.Voters != tt.voters {
+				t.Fatalf("poll has %d voters, want %d", voted.Poll.Voters, tt.voters)
+			}
+		})
+	}
+}
+
+func TestVotePollRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, env *testEnv) models.Thread
+		ctx     context.Context
+		want    error
+	}{
+		{
+			name: "closed poll",
+			prepare: func(t *testing.T, env *testEnv) models.Thread {
+				closes := time.Now().Add(50 * time.Millisecond)
+				thread := env.createPollThread(t, false, &closes)
+				time.Sleep(time.Until(closes) + 10*time.Millisecond)
+				return thread
+			},
+			want: models.ErrPollClosed,
+		},
+		{
+			name: "locked thread",
+			prepare: func(t *testing.T, env *testEnv) models.Thread {
+				thread := env.createPollThread(t, false, nil)
+				if _, err := setThreadState(env, thread, models.ThreadStateLocked); err != nil {
+					t.Fatalf("failed to lock thread: %v", err)
+				}
+				return thread
+			},
+			want: models.ErrThreadLocked,
+		},
+		{
+			name: "thread without poll",
+			prepare: func(t *testing.T, env *testEnv) models.Thread {
+				thread, _ := env.createThread(t, "ann")
+				return thread
+			},
+			want: models.ErrPollNotFound,
+		},
+		{
+			name: "banned voter",
+			prepare: func(t *testing.T, env *testEnv) models.Thread {
+				if _, err := env.moderation.BanUser(as("mod"), "f1", "eve", models.ForumBan{}); err != nil {
+					t.Fatalf("failed to ban eve: %v", err)
+				}
+				return env.createPollThread(t, false, nil)
+			},
+			want: models.ErrUserBanned,
+		},
+		{
+			name: "vote for another user",
+			prepare: func(t *testing.T, env *testEnv) models.Thread {
+				return env.createPollThread(t, false, nil)
+			},
+			ctx:  as("ann"),
+			want: models.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread := tt.prepare(t, env)
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = as("eve")
+			}
+
+			_, err := env.threads.VotePoll(ctx, threadRef(thread), models.PollVote{Nickname: "eve", Options: []int{1}})
+			checkErr(t, err, tt.want)
+		})
+	}
+}
+
+func TestVotePollConcurrentVoters(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread := env.createPollThread(t, true, nil)
+
+	const voters = 20
+	for i := 0; i < voters; i++ {
+		nickname := "voter" + strconv.Itoa(i)
+		if _, _, err := env.users.CreateUser(context.Background(), models.User{Nickname: nickname, Fullname: nickname, Email: nickname + "@example.com"}); err != nil {
+			t.Fatalf("failed to create %s: %v", nickname, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, voters*2)
+	for i := 0; i < voters; i++ {
+		nickname := "voter" + strconv.Itoa(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Каждый голосует дважды: второй выбор заменяет первый.
+			_, err := env.threads.VotePoll(as(nickname), threadRef(thread), models.PollVote{Nickname: nickname, Options: []int{1}})
+			errs <- err
+			_, err = env.threads.VotePoll(as(nickname), threadRef(thread), models.PollVote{Nickname: nickname, Options: []int{2, 3}})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to vote: %v", err)
+		}
+	}
+
+	details, err := env.threads.GetThreadDetails(context.Background(), threadRef(thread))
+	if err != nil {
+		t.Fatalf("failed to get thread details: %v", err)
+	}
+	want := []int32{0, voters, voters}
+	for i, option := range details.Poll.Options {
+		if option.Votes != want[i] {
+			t.Fatalf("option %d has %d votes, want %d", option.ID, option.Votes, want[i])
+		}
+	}
+	if details.Poll.Voters != voters {
+		t.Fatalf("poll has %d voters, want %d", details.Poll.Voters, voters)
+	}
+}
+
+func TestThreadHistory(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, _ := env.createThread(t, "ann")
+	title, message, slug := "Renamed", "text\nmore", "first"
+	if _, err := env.threads.UpdateThread(as("ann"), threadRef(thread), models.ThreadUpdate{Title: &title, Message: &message, Slug: &slug}); err != nil {
+		t.Fatalf("failed to update thread: %v", err)
+	}
+	// Теги в историю не попадают, а неизменные поля правкой не считаются.
+	tags := []string{"go"}
+	if _, err := env.threads.UpdateThread(as("ann"), threadRef(thread), models.ThreadUpdate{Title: &title, Tags: &tags}); err != nil {
+		t.Fatalf("failed to update tags: %v", err)
+	}
+	slug = "second"
+	if _, err := env.threads.UpdateThread(as("mod"), "first", models.ThreadUpdate{Slug: &slug}); err != nil {
+		t.Fatalf("failed to update slug: %v", err)
+	}
+
+	history, err := env.threads.GetThreadHistory(context.Background(), "first")
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	tests := []struct {
+		// slug пуст, если у ветки его ещё не было.
+		editor, title, message, slug string
+		diff                         string
+	}{
+		{editor: "ann", title: "Thread", message: "text", diff: "+more\n"},
+		{editor: "mod", title: "Renamed", message: "text\nmore", slug: "first"},
+	}
+	if len(history) != len(tests) {
+		t.Fatalf("got history %+v, want %d revisions", history, len(tests))
+	}
+	for i, tt := range tests {
+		revision := history[i]
+		if revision.Thread != thread.ID || revision.Editor != tt.editor || revision.Title != tt.title || revision.Message != tt.message {
+			t.Fatalf("revision %d is %+v, want %+v", i, revision, tt)
+		}
+		if slug := revision.Slug; (slug == nil) != (tt.slug == "") || (slug != nil && *slug != tt.slug) {
+			t.Fatalf("revision %d slug is %v, want %q", i, slug, tt.slug)
+		}
+		if !strings.HasSuffix(revision.Diff, tt.diff) || (tt.diff == "") != (revision.Diff == "") {
+			t.Fatalf("revision %d diff:\n%s\nwant suffix %q", i, revision.Diff, tt.diff)
+		}
+	}
+}
+
+func TestThreadSlugAliases(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, _ := env.createThread(t, "ann")
+	other, _ := env.createThread(t, "eve")
+	rename := func(t *testing.T, caller string, thread models.Thread, slug string) error {
+		t.Helper()
+		_, err := env.threads.UpdateThread(as(caller), threadRef(thread), models.ThreadUpdate{Slug: &slug})
+		return err
+	}
+	for _, slug := range []string{"first", "second"} {
+		if err := rename(t, "ann", thread, slug); err != nil {
+			t.Fatalf("failed to rename to %s: %v", slug, err)
+		}
+	}
+	if err := rename(t, "eve", other, "taken"); err != nil {
+		t.Fatalf("failed to rename other thread: %v", err)
+	}
+
+	errs := []struct {
+		name   string
+		caller string
+		slug   string
+		want   error
+	}{
+		{name: "current slug of another thread", caller: "ann", slug: "TAKEN", want: models.ErrThreadConflict},
+		{name: "numeric slug", caller: "ann", slug: "42", want: models.ErrInvalidUpdate},
+		{name: "empty slug", caller: "ann", slug: " ", want: models.ErrInvalidUpdate},
+		{name: "member", caller: "eve", slug: "third", want: models.ErrForbidden},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErr(t, rename(t, tt.caller, thread, tt.slug), tt.want)
+		})
+	}
+
+	lookups := []struct {
+		ref  string
+		want int64
+	}{
+		{ref: "second", want: thread.ID},
+		{ref: "first", want: thread.ID},
+		{ref: "FIRST", want: thread.ID},
+		{ref: threadRef(thread), want: thread.ID},
+		{ref: "taken", want: other.ID},
+	}
+	for _, tt := range lookups {
+		t.Run("lookup "+tt.ref, func(t *testing.T) {
+			got, err := env.threads.GetThreadDetails(context.Background(), tt.ref)
+			if err != nil || got.ID != tt.want || got.Slug == nil {
+				t.Fatalf("got thread %+v (%v), want thread %d", got, err, tt.want)
+			}
+			if got.ID == thread.ID && *got.Slug != "second" {
+				t.Fatalf("got slug %q, want the current one", *got.Slug)
+			}
+		})
+	}
+
+	// Прежний slug свободен: заняв его, другая ветка перехватывает и ссылки на него.
+	if err := rename(t, "eve", other, "first"); err != nil {
+		t.Fatalf("failed to take an alias: %v", err)
+	}
+	if got, err := env.threads.GetThreadDetails(context.Background(), "first"); err != nil || got.ID != other.ID {
+		t.Fatalf("got thread %+v (%v), want thread %d", got, err, other.ID)
+	}
+	if _, err := env.threads.GetThreadDetails(context.Background(), "taken"); err != nil {
+		t.Fatalf("old slug of the other thread is lost: %v", err)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+		err  error
+	}{
+		{name: "none", tags: nil, want: []string{}},
+		{name: "case, spaces and order", tags: []string{" SQL", "go ", "Postgres"}, want: []string{"go", "postgres", "sql"}},
+		{name: "repeats", tags: []string{"go", "Go", "GO"}, want: []string{"go"}},
+		{name: "dash, underscore and digits", tags: []string{"web-2_0"}, want: []string{"web-2_0"}},
+		{name: "unicode", tags: []string{"Базы"}, want: []string{"базы"}},
+		{name: "longest", tags: []string{strings.Repeat("я", maxTagLength)}, want: []string{strings.Repeat("я", maxTagLength)}},
+		{name: "too long", tags: []string{strings.Repeat("a", maxTagLength+1)}, err: models.ErrInvalidTags},
+		{name: "empty", tags: []string{" "}, err: models.ErrInvalidTags},
+		{name: "space inside", tags: []string{"go lang"}, err: models.ErrInvalidTags},
+		{name: "comma", tags: []string{"go,sql"}, err: models.ErrInvalidTags},
+		{name: "most tags", tags: []string{"a", "b", "c", "d", "e"}, want: []string{"a", "b", "c", "d", "e"}},
+		{name: "repeats do not count", tags: []string{"a", "b", "c", "d", "e", "A"}, want: []string{"a", "b", "c", "d", "e"}},
+		{name: "too many", tags: []string{"a", "b", "c", "d", "e", "f"}, err: models.ErrInvalidTags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags(tt.tags)
+			checkErr(t, err, tt.err)
+			if err == nil && !slices.Equal(got, tt.want) {
+				t.Fatalf("got tags %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
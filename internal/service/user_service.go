@@ -4,28 +4,44 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/blob"
 	"hardhw/internal/models"
 	"hardhw/internal/storage"
+	"strings"
 )
 
 type UserService interface {
 	CreateUser(ctx context.Context, newUser models.User) (models.User, []models.User, error)
 	GetUserByNickname(ctx context.Context, nickname string) (*models.User, error)
 	UpdateUser(ctx context.Context, nickname string, updates models.User) (*models.User, error)
+	RenameUser(ctx context.Context, nickname, newNickname string) (*models.User, []models.User, error)
+	DeleteUser(ctx context.Context, nickname string, mode string) error
+	ExportUser(ctx context.Context, nickname string) (*models.UserExport, error)
+	BlockUser(ctx context.Context, nickname, target string) error
+	UnblockUser(ctx context.Context, nickname, target string) error
+	GetBlockedUsers(ctx context.Context, nickname string) ([]string, error)
 }
 
 type userServiceImpl struct {
-	userStorage storage.UserStorage
+	userStorage       storage.UserStorage
+	moderationStorage storage.ModerationStorage
+	blobs             blob.Store
+	access            access
 }
 
-func NewUserService(s storage.UserStorage) UserService {
-	return &userServiceImpl{userStorage: s}
+func NewUserService(s storage.UserStorage, ms storage.ModerationStorage, blobs blob.Store, benchmarkMode bool) UserService {
+	return &userServiceImpl{userStorage: s, moderationStorage: ms, blobs: blobs, access: newAccess(ms, benchmarkMode)}
 }
 
 func (s *userServiceImpl) CreateUser(ctx context.Context, newUser models.User) (models.User, []models.User, error) {
 	var conflictUsers []models.User
 	var createdUser models.User
 
+	if isReservedNickname(newUser.Nickname) {
+		return createdUser, nil, models.ErrInvalidNickname
+	}
+
 	foundUserByNickname, err := s.userStorage.GetUserByNickname(ctx, newUser.Nickname)
 	if err != nil && !errors.Is(err, models.ErrNotFound) {
 		return createdUser, nil, fmt.Errorf("ошибка при поиске пользователя по nickname: %w", err)
@@ -49,6 +65,17 @@ func (s *userServiceImpl) CreateUser(ctx context.Context, newUser models.User) (
 		return createdUser, conflictUsers, nil
 	}
 
+	if newUser.Password != "" {
+		if len(newUser.Password) < minPasswordLength {
+			return createdUser, nil, models.ErrWeakPassword
+		}
+		newUser.PasswordHash, err = auth.HashPassword(newUser.Password)
+		if err != nil {
+			return createdUser, nil, err
+		}
+		newUser.Password = ""
+	}
+
 	err = s.userStorage.CreateUser(ctx, &newUser)
 	if err != nil {
 		if errors.Is(err, models.ErrUserConflict) {
@@ -74,6 +101,10 @@ func (s *userServiceImpl) CreateUser(ctx context.Context, newUser models.User) (
 
 func (s *userServiceImpl) GetUserByNickname(ctx context.Context, nickname string) (*models.User, error) {
 	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if errors.Is(err, models.ErrNotFound) {
+		// Профиль по прежнему нику тоже открывается.
+		user, err = s.userStorage.GetUserByAlias(ctx, nickname)
+	}
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return nil, models.ErrNotFound
@@ -84,6 +115,9 @@ func (s *userServiceImpl) GetUserByNickname(ctx context.Context, nickname string
 }
 
 func (s *userServiceImpl) UpdateUser(ctx context.Context, nickname string, updates models.User) (*models.User, error) {
+	if err := s.access.ensureBaselineCaller(ctx, nickname); err != nil {
+		return nil, err
+	}
 
 	existingUser, err := s.userStorage.GetUserByNickname(ctx, nickname)
 	if err != nil {
@@ -126,3 +160,180 @@ func (s *userServiceImpl) UpdateUser(ctx context.Context, nickname string, updat
 
 	return updatedUser, nil
 }
+
+// RenameUser меняет nickname пользователя. Конфликт возвращается так же, как в CreateUser: списком занявших ник.
+func (s *userServiceImpl) RenameUser(ctx context.Context, nickname, newNickname string) (*models.User, []models.User, error) {
+	if err := s.access.ensureCaller(ctx, nickname); err != nil {
+		return nil, nil, err
+	}
+
+	if !validNickname(newNickname) || isReservedNickname(newNickname) || isReservedNickname(nickname) {
+		return nil, nil, models.ErrInvalidNickname
+	}
+
+	existingUser, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("ошибка при поиске пользователя для переименования: %w", err)
+	}
+
+	foundUserByNickname, err := s.userStorage.GetUserByNickname(ctx, newNickname)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, nil, fmt.Errorf("ошибка при поиске пользователя по nickname: %w", err)
+	}
+	if foundUserByNickname != nil && !strings.EqualFold(foundUserByNickname.Nickname, existingUser.Nickname) {
+		return nil, []models.User{*foundUserByNickname}, models.ErrUserConflict
+	}
+
+	renamedUser, err := s.userStorage.RenameUser(ctx, existingUser.Nickname, newNickname)
+	if err != nil {
+		if errors.Is(err, models.ErrUserConflict) {
+			foundUserByNickname, _ = s.userStorage.GetUserByNickname(ctx, newNickname)
+			if foundUserByNickname != nil {
+				return nil, []models.User{*foundUserByNickname}, models.ErrUserConflict
+			}
+			return nil, nil, models.ErrUserConflict
+		}
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("ошибка при переименовании пользователя в хранилище: %w", err)
+	}
+
+	return renamedUser, nil, nil
+}
+
+// validNickname не пускает пустые ники и символы, которые ломают пути вида /user/{nickname}/...
+func validNickname(nickname string) bool {
+	return nickname != "" && !strings.ContainsAny(nickname, "/ \t\n")
+}
+
+// DeleteUser удаляет аккаунт: anonymize оставляет ветки и посты от имени служебного пользователя,
+// erase вдобавок стирает их тексты.
+func (s *userServiceImpl) DeleteUser(ctx context.Context, nickname string, mode string) error {
+	if mode == "" {
+		mode = models.DeleteModeAnonymize
+	}
+	if mode != models.DeleteModeAnonymize && mode != models.DeleteModeErase {
+		return models.ErrInvalidDeleteMode
+	}
+
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("ошибка при поиске пользователя для удаления: %w", err)
+	}
+
+	if err := s.access.ensureSelfOrSiteAdmin(ctx, user.Nickname); err != nil {
+		return err
+	}
+	if isReservedNickname(user.Nickname) {
+		return models.ErrForbidden
+	}
+
+	files, err := s.userStorage.DeleteUser(ctx, user.Nickname, mode)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("ошибка при удалении пользователя в хранилище: %w", err)
+	}
+	deleteBlobs(s.blobs, files.Attachments)
+	if files.Avatar != "" {
+		deleteBlobs(s.blobs, avatarBlobKeys(files.Avatar))
+	}
+	return nil
+}
+
+func (s *userServiceImpl) ExportUser(ctx context.Context, nickname string) (*models.UserExport, error) {
+	if err := s.access.ensureSelfOrSiteAdmin(ctx, nickname); err != nil {
+		return nil, err
+	}
+
+	export, err := s.userStorage.ExportUser(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("ошибка при выгрузке данных пользователя: %w", err)
+	}
+	return export, nil
+}
+
+// BlockUser добавляет target в чёрный список nickname: после этого они не могут писать друг другу личные сообщения.
+func (s *userServiceImpl) BlockUser(ctx context.Context, nickname, target string) error {
+	user, blocked, err := s.blockPair(ctx, nickname, target)
+	if err != nil {
+		return err
+	}
+	err = s.userStorage.BlockUser(ctx, user.Nickname, blocked.Nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("ошибка при добавлении в чёрный список: %w", err)
+	}
+	return nil
+}
+
+func (s *userServiceImpl) UnblockUser(ctx context.Context, nickname, target string) error {
+	user, blocked, err := s.blockPair(ctx, nickname, target)
+	if err != nil {
+		return err
+	}
+	if err := s.userStorage.UnblockUser(ctx, user.Nickname, blocked.Nickname); err != nil {
+		return fmt.Errorf("ошибка при удалении из чёрного списка: %w", err)
+	}
+	return nil
+}
+
+func (s *userServiceImpl) GetBlockedUsers(ctx context.Context, nickname string) ([]string, error) {
+	if err := s.access.ensureCaller(ctx, nickname); err != nil {
+		return nil, err
+	}
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("ошибка при поиске пользователя: %w", err)
+	}
+	blocked, err := s.userStorage.GetBlockedUsers(ctx, user.Nickname)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе чёрного списка: %w", err)
+	}
+	return blocked, nil
+}
+
+// blockPair проверяет, что nickname управляет своим чёрным списком, и находит обоих пользователей.
+func (s *userServiceImpl) blockPair(ctx context.Context, nickname, target string) (*models.User, *models.User, error) {
+	if err := s.access.ensureCaller(ctx, nickname); err != nil {
+		return nil, nil, err
+	}
+	user, err := s.userStorage.GetUserByNickname(ctx, nickname)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrOwnerNotFound
+		}
+		return nil, nil, fmt.Errorf("ошибка при поиске пользователя: %w", err)
+	}
+	blocked, err := s.userStorage.GetUserByNickname(ctx, target)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil, models.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("ошибка при поиске блокируемого пользователя: %w", err)
+	}
+	if strings.EqualFold(user.Nickname, blocked.Nickname) {
+		return nil, nil, models.ErrSelfBlock
+	}
+	return user, blocked, nil
+}
+
+func isReservedNickname(nickname string) bool {
+	return strings.EqualFold(nickname, models.DeletedUserNickname)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"hardhw/internal/models"
+	"testing"
+)
+
+func TestDeleteUserHandsContentToPlaceholder(t *testing.T) {
+	for _, mode := range []string{models.DeleteModeAnonymize, models.DeleteModeErase} {
+		t.Run(mode, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			thread, _ := env.createThread(t, "bob")
+			reply, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "reply"}})
+			if err != nil {
+				t.Fatalf("failed to create post: %v", err)
+			}
+
+			if err := env.users.DeleteUser(as("ann"), "ann", mode); err != nil {
+				t.Fatalf("failed to delete user: %v", err)
+			}
+
+			post, err := env.posts.GetPostDetails(context.Background(), reply[0].ID)
+			if err != nil {
+				t.Fatalf("failed to get post: %v", err)
+			}
+			if post.Author != models.DeletedUserNickname {
+				t.Fatalf("post author is %q, want %q", post.Author, models.DeletedUserNickname)
+			}
+
+			members, err := env.forums.GetForumUsers(context.Background(), "f1", 100, "", false, false)
+			if err != nil {
+				t.Fatalf("failed to get forum users: %v", err)
+			}
+			for _, member := range members {
+				if member.Nickname == "ann" || member.Nickname == models.DeletedUserNickname {
+					t.Fatalf("forum users still list %q", member.Nickname)
+				}
+			}
+
+			// bob, eve, mod и root; служебный пользователь не считается.
+			status, err := env.posts.GetDatabaseStatus(context.Background())
+			if err != nil {
+				t.Fatalf("failed to get status: %v", err)
+			}
+			if status.User != 4 {
+				t.Fatalf("status counts %d users, want 4", status.User)
+			}
+		})
+	}
+}
+
+func TestDeletedUserPlaceholderIsReserved(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	_, conflicts, err := env.users.CreateUser(context.Background(), models.User{Nickname: "squatter", Fullname: "S", Email: "deleted@deleted.invalid"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Nickname != models.DeletedUserNickname {
+		t.Fatalf("got conflicts %v, want the placeholder", conflicts)
+	}
+
+	err = env.auth.ChangePassword(as("root"), models.DeletedUserNickname, models.PasswordChange{Password: "new-password"})
+	checkErr(t, err, models.ErrForbidden)
+
+	if err := env.posts.ClearAllData(context.Background()); err != nil {
+		t.Fatalf("failed to clear data: %v", err)
+	}
+	if _, err := env.users.GetUserByNickname(context.Background(), models.DeletedUserNickname); err != nil {
+		t.Fatalf("placeholder is gone after clear: %v", err)
+	}
+}
+
+func TestRenameUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		caller      string
+		nickname    string
+		newNickname string
+		want        string
+		conflict    string
+		err         error
+	}{
+		{name: "new nickname", caller: "ann", nickname: "ann", newNickname: "anna", want: "anna"},
+		{name: "case only", caller: "ann", nickname: "ann", newNickname: "Ann", want: "Ann"},
+		{name: "taken", caller: "ann", nickname: "ann", newNickname: "eve", conflict: "eve", err: models.ErrUserConflict},
+		{name: "taken in other case", caller: "ann", nickname: "ann", newNickname: "EVE", conflict: "eve", err: models.ErrUserConflict},
+		{name: "placeholder", caller: "ann", nickname: "ann", newNickname: models.DeletedUserNickname, err: models.ErrInvalidNickname},
+		{name: "slash", caller: "ann", nickname: "ann", newNickname: "a/b", err: models.ErrInvalidNickname},
+		{name: "other user", caller: "eve", nickname: "ann", newNickname: "anna", err: models.ErrForbidden},
+		{name: "moderator", caller: "mod", nickname: "ann", newNickname: "anna", err: models.ErrForbidden},
+		{name: "site admin", caller: "root", nickname: "ann", newNickname: "anna", err: models.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+
+			renamed, conflicts, err := env.users.RenameUser(as(tt.caller), tt.nickname, tt.newNickname)
+			checkErr(t, err, tt.err)
+			if tt.conflict != "" && (len(conflicts) != 1 || conflicts[0].Nickname != tt.conflict) {
+				t.Fatalf("got conflicts %v, want %s", conflicts, tt.conflict)
+			}
+			if tt.err != nil {
+				return
+			}
+			if renamed.Nickname != tt.want {
+				t.Fatalf("renamed to %q, want %q", renamed.Nickname, tt.want)
+			}
+			user, err := env.users.GetUserByNickname(context.Background(), tt.want)
+			if err != nil || user.Nickname != tt.want {
+				t.Fatalf("got user %+v (%v), want %q", user, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameUserMovesContent(t *testing.T) {
+	env := newTestEnv(t, false)
+	thread, posts := env.createThread(t, "mod")
+	mention, err := env.threads.CreatePosts(as("ann"), threadRef(thread), []models.Post{{Author: "ann", Message: "hi @mod"}})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	if _, err := env.threads.VoteThread(as("mod"), threadRef(thread), models.Vote{Nickname: "mod", Voice: 1}); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+
+	if _, _, err := env.users.RenameUser(as("mod"), "mod", "keeper"); err != nil {
+		t.Fatalf("failed to rename: %v", err)
+	}
+
+	got, err := env.threads.GetThreadDetails(context.Background(), threadRef(thread))
+	if err != nil || got.Author != "keeper" || got.Votes != 1 {
+		t.Fatalf("thread after rename: %+v (%v), want author keeper with 1 vote", got, err)
+	}
+	post, err := env.posts.GetPostDetails(context.Background(), posts[0].ID)
+	if err != nil || post.Author != "keeper" {
+		t.Fatalf("post after rename: %+v (%v), want author keeper", post, err)
+	}
+
+	// Голос, права модератора и упоминания переходят на новый ник.
+	if _, err := env.threads.VoteThread(as("keeper"), threadRef(thread), models.Vote{Nickname: "keeper", Voice: -1}); err != nil {
+		t.Fatalf("failed to revote: %v", err)
+	}
+	if got, _ := env.threads.GetThreadDetails(context.Background(), threadRef(thread)); got.Votes != -1 {
+		t.Fatalf("thread votes %d after revote, want -1", got.Votes)
+	}
+	moderators, err := env.moderation.GetForumModerators(context.Background(), "f1")
+	if err != nil || len(moderators) != 1 || moderators[0].Nickname != "keeper" {
+		t.Fatalf("got moderators %v (%v), want keeper", moderators, err)
+	}
+	mentions, err := env.posts.GetUserMentions(context.Background(), "keeper", 10, 0)
+	if err != nil || len(mentions) != 1 || mentions[0].ID != mention[0].ID {
+		t.Fatalf("got mentions %v (%v), want post %d", mentions, err, mention[0].ID)
+	}
+	members, err := env.forums.GetForumUsers(context.Background(), "f1", 100, "", false, false)
+	if err != nil {
+		t.Fatalf("failed to get forum users: %v", err)
+	}
+	for _, member := range members {
+		if member.Nickname == "mod" {
+			t.Fatalf("forum users still list mod")
+		}
+	}
+}
+
+func TestRenameUserAliases(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	if _, _, err := env.users.RenameUser(as("ann"), "ann", "anna"); err != nil {
+		t.Fatalf("failed to rename: %v", err)
+	}
+
+	// Прежний ник открывает профиль в любом регистре, но не считается занятым.
+	for _, nickname := range []string{"ann", "ANN"} {
+		user, err := env.users.GetUserByNickname(context.Background(), nickname)
+		if err != nil || user.Nickname != "anna" {
+			t.Fatalf("lookup %q: %+v (%v), want anna", nickname, user, err)
+		}
+	}
+	_, conflicts, err := env.users.RenameUser(as("eve"), "eve", "ann")
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("rename to alias: conflicts %v (%v), want none", conflicts, err)
+	}
+	user, err := env.users.GetUserByNickname(context.Background(), "ann")
+	if err != nil || user.Nickname != "ann" || user.Email != "eve@example.com" {
+		t.Fatalf("lookup after alias is taken: %+v (%v), want eve's account", user, err)
+	}
+
+	// Алиас не мешает регистрации, и зарегистрированный пользователь важнее алиаса.
+	if _, _, err := env.users.RenameUser(as("bob"), "bob", "robert"); err != nil {
+		t.Fatalf("failed to rename: %v", err)
+	}
+	_, conflicts, err = env.users.CreateUser(context.Background(), models.User{Nickname: "Bob", Fullname: "B", Email: "new-bob@example.com"})
+	if err != nil || len(conflicts) != 0 {
+		t.Fatalf("create over alias: conflicts %v (%v), want none", conflicts, err)
+	}
+	user, err = env.users.GetUserByNickname(context.Background(), "bob")
+	if err != nil || user.Nickname != "Bob" {
+		t.Fatalf("lookup after create: %+v (%v), want the new Bob", user, err)
+	}
+
+	// Переименование обратно в свой прежний ник не оставляет алиаса на самого себя.
+	if _, _, err := env.users.RenameUser(as("anna"), "anna", "ann2"); err != nil {
+		t.Fatalf("failed to rename: %v", err)
+	}
+	if _, _, err := env.users.RenameUser(as("ann2"), "ann2", "anna"); err != nil {
+		t.Fatalf("failed to rename back: %v", err)
+	}
+	user, err = env.users.GetUserByNickname(context.Background(), "ann2")
+	if err != nil || user.Nickname != "anna" {
+		t.Fatalf("lookup %q: %+v (%v), want anna", "ann2", user, err)
+	}
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	webhookPollInterval = time.Second
+	webhookBatchSize    = 20
+	webhookTimeout      = 10 * time.Second
+	// webhookLease должен быть больше таймаута запроса: пока он не истёк, доставку не возьмёт другой воркер.
+	webhookLease = time.Minute
+
+	webhookRetryMin    = 10 * time.Second
+	webhookRetryMax    = time.Hour
+	webhookMaxAttempts = 8
+
+	maxWebhookErrorLength = 500
+)
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, forumSlug string, hook models.Webhook) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context, forumSlug string) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, forumSlug string, id int64) error
+	GetDeliveries(ctx context.Context, forumSlug string, id int64, limit int, before int64) ([]models.WebhookDelivery, error)
+	Run(ctx context.Context)
+}
+
+type webhookServiceImpl struct {
+	forumStorage      storage.ForumStorage
+	moderationStorage storage.ModerationStorage
+	webhookStorage    storage.WebhookStorage
+	client            *http.Client
+	access            access
+}
+
+func NewWebhookService(fs storage.ForumStorage, ms storage.ModerationStorage, ws storage.WebhookStorage, benchmarkMode bool) WebhookService {
+	return &webhookServiceImpl{
+		forumStorage:      fs,
+		moderationStorage: ms,
+		webhookStorage:    ws,
+		client:            newWebhookClient(),
+		access:            newAccess(ms, benchmarkMode),
+	}
+}
+
+func (s *webhookServiceImpl) CreateWebhook(ctx context.Context, forumSlug string, hook models.Webhook) (*models.Webhook, error) {
+	forum, err := s.ownedForum(ctx, forumSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := validateWebhook(hook)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWebhookHost(ctx, hook.URL); err != nil {
+		return nil, err
+	}
+
+	// Секрет генерирует сервер и показывает один раз — в ответе на создание.
+	secret, _, err := auth.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hook.Forum = forum.Slug
+	hook.Events = events
+	hook.Secret = secret
+	hook.CreatedBy, _ = auth.CallerFromContext(ctx)
+
+	created, err := s.webhookStorage.CreateWebhook(ctx, hook)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to create webhook in storage: %w", err)
+	}
+	return created, nil
+}
+
+func (s *webhookServiceImpl) GetWebhooks(ctx context.Context, forumSlug string) ([]models.Webhook, error) {
+	forum, err := s.ownedForum(ctx, forumSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := s.webhookStorage.GetWebhooks(ctx, forum.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks from storage: %w", err)
+	}
+	return hooks, nil
+}
+
+func (s *webhookServiceImpl) DeleteWebhook(ctx context.Context, forumSlug string, id int64) error {
+	forum, err := s.ownedForum(ctx, forumSlug)
+	if err != nil {
+		return err
+	}
+
+	err = s.webhookStorage.DeleteWebhook(ctx, forum.Slug, id)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to delete webhook in storage: %w", err)
+	}
+	return nil
+}
+
+func (s *webhookServiceImpl) GetDeliveries(ctx context.Context, forumSlug string, id int64, limit int, before int64) ([]models.WebhookDelivery, error) {
+	forum, err := s.ownedForum(ctx, forumSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.webhookStorage.GetWebhook(ctx, forum.Slug, id); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook from storage: %w", err)
+	}
+
+	deliveries, err := s.webhookStorage.GetDeliveries(ctx, id, limit, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries from storage: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ownedForum возвращает форум, если вызывающий — его владелец или администратор сайта.
+func (s *webhookServiceImpl) ownedForum(ctx context.Context, forumSlug string) (*models.Forum, error) {
+	forum, err := s.forumStorage.GetForumBySlug(ctx, forumSlug)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to check forum existence: %w", err)
+	}
+
+	if err := s.access.ensureForumRole(ctx, forum.Slug, models.RoleOwner); err != nil {
+		return nil, err
+	}
+	return forum, nil
+}
+
+// validateWebhook проверяет адрес и возвращает список событий без повторов.
+func validateWebhook(hook models.Webhook) ([]string, error) {
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, models.ErrInvalidWebhook
+	}
+	if len(hook.Events) == 0 {
+		return nil, models.ErrInvalidWebhook
+	}
+
+	events := make([]string, 0, len(hook.Events))
+	seen := make(map[string]struct{}, len(hook.Events))
+	for _, event := range hook.Events {
+		if !isWebhookEvent(event) {
+			return nil, models.ErrInvalidWebhook
+		}
+		if _, dup := seen[event]; dup {
+			continue
+		}
+		seen[event] = struct{}{}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// checkWebhookHost заранее отклоняет адреса, которые ведут во внутреннюю сеть. Окончательная
+// проверка — при каждом соединении в newWebhookClient: DNS-ответ к тому времени может измениться.
+func checkWebhookHost(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return models.ErrInvalidWebhook
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", target.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return models.ErrInvalidWebhook
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return models.ErrInvalidWebhook
+		}
+	}
+	return nil
+}
+
+// nonPublicPrefixes — служебные диапазоны, которых нет среди проверок netip.Addr.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isPublicAddr сообщает, можно ли отправлять вебхук на addr: петля, частные сети,
+// link-local (в том числе адрес метаданных облака 169.254.169.254) и multicast запрещены.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+var errWebhookTarget = errors.New("webhook target address is not public")
+
+// newWebhookClient проверяет адрес каждого соединения уже после разрешения имени, поэтому подписчик
+// не доберётся до внутренней сети ни подменой DNS-ответа, ни редиректом: редиректы не выполняются,
+// а ответ 3xx считается ошибкой доставки. Прокси из окружения не используется — иначе проверялся бы его адрес.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublicAddr(addrPort.Addr()) {
+				return errWebhookTarget
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConns:        webhookBatchSize,
+			IdleConnTimeout:     webhookLease,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range models.WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Run периодически забирает созревшие доставки из outbox и отправляет их подписчикам.
+func (s *webhookServiceImpl) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			claimed, err := s.webhookStorage.ClaimDeliveries(ctx, webhookBatchSize, webhookLease)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Error claiming webhook deliveries: %v", err)
+				}
+				break
+			}
+
+			var wg sync.WaitGroup
+			for _, job := range claimed {
+				wg.Add(1)
+				go func(job models.WebhookDispatch) {
+					defer wg.Done()
+					s.deliver(ctx, job)
+				}(job)
+			}
+			wg.Wait()
+
+			// Полная пачка — в очереди, скорее всего, есть ещё; не ждём следующего тика.
+			if len(claimed) < webhookBatchSize {
+				break
+			}
+		}
+	}
+}
+
+func (s *webhookServiceImpl) deliver(ctx context.Context, job models.WebhookDispatch) {
+	delivery := job.Delivery
+	delivery.Attempts++
+	delivery.ResponseStatus = nil
+
+	status, err := s.send(ctx, job)
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.Delivered = &now
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = truncateError(err)
+	default:
+		delivery.LastError = truncateError(err)
+		delivery.NextAttempt = now.Add(webhookBackoff(delivery.Attempts))
+	}
+
+	// Результат сохраняем даже при остановке сервера, иначе попытка не будет учтена.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookTimeout)
+	defer cancel()
+	if err := s.webhookStorage.UpdateDelivery(saveCtx, delivery); err != nil {
+		log.Printf("Error saving webhook delivery %d result: %v", delivery.ID, err)
+	}
+}
+
+func (s *webhookServiceImpl) send(ctx context.Context, job models.WebhookDispatch) (int, error) {
+	body := []byte(job.Delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "hardhw-webhooks")
+	req.Header.Set("X-Forum-Event", job.Delivery.Event)
+	req.Header.Set("X-Forum-Delivery", strconv.FormatInt(job.Delivery.ID, 10))
+	req.Header.Set("X-Forum-Signature", auth.SignPayload(job.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// webhookBackoff удваивает паузу после каждой неудачи: 10с, 20с, 40с... но не больше часа.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryMin
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMax {
+			return webhookRetryMax
+		}
+	}
+	return delay
+}
+
+func truncateError(err error) string {
+	message := err.Error()
+	if len(message) > maxWebhookErrorLength {
+		message = message[:maxWebhookErrorLength]
+	}
+	return message
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"hardhw/internal/auth"
+	"hardhw/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "test-secret"
+
+// webhookRequest — то, что тестовый подписчик получил в последнем запросе.
+type webhookRequest struct {
+	event     string
+	delivery  string
+	signature string
+	body      []byte
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		attempts     int
+		wantStatus   string
+		wantAttempts int
+		wantDelay    time.Duration
+	}{
+		{name: "delivered", status: http.StatusNoContent, wantStatus: models.DeliveryDelivered, wantAttempts: 1},
+		{name: "first failure", status: http.StatusInternalServerError, wantStatus: models.DeliveryPending, wantAttempts: 1, wantDelay: 10 * time.Second},
+		{name: "third failure", status: http.StatusServiceUnavailable, attempts: 2, wantStatus: models.DeliveryPending, wantAttempts: 3, wantDelay: 40 * time.Second},
+		{name: "redirect is not followed", status: http.StatusFound, wantStatus: models.DeliveryPending, wantAttempts: 1, wantDelay: 10 * time.Second},
+		{name: "last attempt", status: http.StatusBadGateway, attempts: webhookMaxAttempts - 1, wantStatus: models.DeliveryFailed, wantAttempts: webhookMaxAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+
+			var got webhookRequest
+			requests := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, _ := io.ReadAll(r.Body)
+				got = webhookRequest{
+					event:     r.Header.Get("X-Forum-Event"),
+					delivery:  r.Header.Get("X-Forum-Delivery"),
+					signature: r.Header.Get("X-Forum-Signature"),
+					body:      body,
+				}
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+			})
+			mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+				requests++
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			s := env.webhookService(server)
+			hook := env.subscribe(t, server.URL+"/hook", models.WebhookEventThreadCreated)
+			env.createThread(t, "ann")
+
+			job := claimDelivery(t, env)
+			job.Delivery.Attempts = tt.attempts
+			before := time.Now()
+			s.deliver(context.Background(), job)
+			after := time.Now()
+
+			if requests != 1 {
+				t.Fatalf("subscriber got %d requests, want 1", requests)
+			}
+			if got.event != models.WebhookEventThreadCreated || got.delivery != strconv.FormatInt(job.Delivery.ID, 10) {
+				t.Fatalf("got event %q and delivery %q", got.event, got.delivery)
+			}
+			if want := auth.SignPayload(testWebhookSecret, got.body); got.signature != want {
+				t.Fatalf("got signature %q, want %q", got.signature, want)
+			}
+
+			delivery := env.delivery(t, hook.ID)
+			if delivery.Status != tt.wantStatus || delivery.Attempts != tt.wantAttempts {
+				t.Fatalf("delivery is %s after %d attempts, want %s after %d",
+					delivery.Status, delivery.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if delivery.ResponseStatus == nil || *delivery.ResponseStatus != tt.status {
+				t.Fatalf("got response status %v, want %d", delivery.ResponseStatus, tt.status)
+			}
+			if tt.wantDelay > 0 {
+				if delivery.NextAttempt.Before(before.Add(tt.wantDelay)) || delivery.NextAttempt.After(after.Add(tt.wantDelay)) {
+					t.Fatalf("next attempt in %v, want %v", delivery.NextAttempt.Sub(before), tt.wantDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 9, want: 2560 * time.Second},
+		{attempts: 10, want: time.Hour},
+		{attempts: 30, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookRejectsInternalTargets(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://[::1]/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		t.Run(target, func(t *testing.T) {
+			_, err := env.webhooks.CreateWebhook(as("bob"), "f1", models.Webhook{URL: target, Events: []string{models.WebhookEventPostCreated}})
+			checkErr(t, err, models.ErrInvalidWebhook)
+		})
+	}
+
+	t.Run("public address", func(t *testing.T) {
+		if !isPublicAddr(netip.MustParseAddr("93.184.216.34")) {
+			t.Fatal("public address is rejected")
+		}
+	})
+
+	t.Run("dial to loopback", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("request reached loopback server")
+		}))
+		defer server.Close()
+
+		if _, err := newWebhookClient().Post(server.URL, "application/json", nil); err == nil {
+			t.Fatal("client connected to loopback address")
+		}
+	})
+}
+
+func TestUserWebhookOmitsEmail(t *testing.T) {
+	env := newTestEnv(t, false)
+	hook := env.subscribe(t, "http://hooks.example.com/", models.WebhookEventUserUpdated)
+	env.createThread(t, "ann")
+
+	_, err := env.users.UpdateUser(as("ann"), "ann", models.User{Fullname: "Ann", Email: "new@example.com"})
+	if err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+
+	var payload struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(env.delivery(t, hook.ID).Payload, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if _, ok := payload.Data["email"]; ok {
+		t.Fatalf("payload leaks email: %v", payload.Data)
+	}
+	if payload.Data["nickname"] != "ann" || payload.Data["fullname"] != "Ann" {
+		t.Fatalf("unexpected payload data: %v", payload.Data)
+	}
+}
+
+// webhookService отправляет запросы через транспорт тестового сервера: обычный транспорт
+// не соединяется с петлёй, на которой слушает httptest. Остальные настройки клиента не меняются.
+func (env *testEnv) webhookService(server *httptest.Server) *webhookServiceImpl {
+	s := env.webhooks.(*webhookServiceImpl)
+	s.client.Transport = server.Client().Transport
+	return s
+}
+
+// subscribe создаёт подписку форума f1 в обход проверки адреса в CreateWebhook.
+func (env *testEnv) subscribe(t *testing.T, url string, event string) *models.Webhook {
+	t.Helper()
+
+	hook, err := env.webhookStorage.CreateWebhook(context.Background(), models.Webhook{
+		Forum:  "f1",
+		URL:    url,
+		Secret: testWebhookSecret,
+		Events: []string{event},
+	})
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	return hook
+}
+
+func (env *testEnv) delivery(t *testing.T, hookID int64) models.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := env.webhookStorage.GetDeliveries(context.Background(), hookID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get deliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+func claimDelivery(t *testing.T, env *testEnv) models.WebhookDispatch {
+	t.Helper()
+
+	claimed, err := env.webhookStorage.ClaimDeliveries(context.Background(), webhookBatchSize, webhookLease)
+	if err != nil {
+		t.Fatalf("failed to claim deliveries: %v", err)
+	}
+	if len(claimed) != 1 {
+		t.Fatalf("claimed %d deliveries, want 1", len(claimed))
+	}
+	return claimed[0]
+}
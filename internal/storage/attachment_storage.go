@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AttachmentStorage interface {
+	// CreateAttachment сохраняет описание файла, если вместе с ним файлы владельца не превысят quota байт.
+	CreateAttachment(ctx context.Context, attachment models.Attachment, quota int64) (*models.Attachment, error)
+	GetAttachment(ctx context.Context, id int64) (*models.Attachment, error)
+	// DeleteUnboundAttachment удаляет файл, ещё не привязанный к посту.
+	DeleteUnboundAttachment(ctx context.Context, id int64) error
+}
+
+type postgresAttachmentStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresAttachmentStorage(pool *pgxpool.Pool) AttachmentStorage {
+	return &postgresAttachmentStorage{pool: pool}
+}
+
+func (s *postgresAttachmentStorage) CreateAttachment(ctx context.Context, attachment models.Attachment, quota int64) (*models.Attachment, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем пользователя, чтобы параллельные загрузки не обошли квоту.
+	var owner string
+	err = tx.QueryRow(ctx, `SELECT nickname FROM users WHERE nickname = $1 FOR UPDATE`, attachment.Owner).Scan(&owner)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock attachment owner %s: %w", attachment.Owner, err)
+	}
+
+	var used int64
+	err = tx.QueryRow(ctx, `SELECT COALESCE(SUM(size), 0) FROM attachments WHERE owner_nickname = $1`, owner).Scan(&used)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uploads size of %s: %w", owner, err)
+	}
+	if used+attachment.Size > quota {
+		return nil, models.ErrQuotaExceeded
+	}
+
+	created := &models.Attachment{}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO attachments (owner_nickname, filename, content_type, size, storage_key)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, owner_nickname, filename, content_type, size, storage_key, created`,
+		owner, attachment.Filename, attachment.ContentType, attachment.Size, attachment.Key).Scan(
+		&created.ID, &created.Owner, &created.Filename, &created.ContentType, &created.Size, &created.Key, &created.Created,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+func (s *postgresAttachmentStorage) GetAttachment(ctx context.Context, id int64) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, owner_nickname, filename, content_type, size, COALESCE(post_id, 0), storage_key, created
+		FROM attachments
+		WHERE id = $1`, id).Scan(
+		&attachment.ID, &attachment.Owner, &attachment.Filename, &attachment.ContentType,
+		&attachment.Size, &attachment.Post, &attachment.Key, &attachment.Created,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment %d: %w", id, err)
+	}
+	return attachment, nil
+}
+
+func (s *postgresAttachmentStorage) DeleteUnboundAttachment(ctx context.Context, id int64) error {
+	commandTag, err := s.pool.Exec(ctx, `DELETE FROM attachments WHERE id = $1 AND post_id IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment %d: %w", id, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+// bindPostAttachments привязывает файлы автора к посту и записывает их описания в posts.attachments.
+// Чужой, уже привязанный или несуществующий файл отменяет всю пачку постов.
+func bindPostAttachments(ctx context.Context, tx pgx.Tx, postID int64, author string, attachments []models.PostAttachment) ([]models.PostAttachment, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+
+	bound := make([]models.PostAttachment, len(attachments))
+	for i, attachment := range attachments {
+		err := tx.QueryRow(ctx, `
+			UPDATE attachments
+			SET post_id = $1
+			WHERE id = $2 AND owner_nickname = $3 AND post_id IS NULL
+			RETURNING id, filename, content_type, size`, postID, attachment.ID, author).Scan(
+			&bound[i].ID, &bound[i].Filename, &bound[i].ContentType, &bound[i].Size,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, models.ErrInvalidAttachment
+			}
+			return nil, fmt.Errorf("failed to bind attachment %d to post %d: %w", attachment.ID, postID, err)
+		}
+	}
+
+	encoded, err := json.Marshal(bound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attachments of post %d: %w", postID, err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE posts SET attachments = $1::jsonb WHERE id = $2`, string(encoded), postID); err != nil {
+		return nil, fmt.Errorf("failed to save attachments of post %d: %w", postID, err)
+	}
+	return bound, nil
+}
+
+// deleteAttachments выполняет query — DELETE ... RETURNING storage_key — и возвращает ключи
+// удалённых файлов, чтобы после фиксации транзакции убрать и сами файлы.
+func deleteAttachments(ctx context.Context, tx pgx.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
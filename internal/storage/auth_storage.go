@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuthStorage interface {
+	GetPasswordHash(ctx context.Context, nickname string) (string, string, error)
+	SetPasswordHash(ctx context.Context, nickname string, hash string) error
+	CreateToken(ctx context.Context, token models.AuthToken, tokenHash string) (*models.AuthToken, error)
+	GetTokenOwner(ctx context.Context, tokenHash string) (string, error)
+	DeleteToken(ctx context.Context, tokenHash string) error
+	GetTokensByUser(ctx context.Context, nickname string, kind string) ([]models.AuthToken, error)
+	DeleteTokenByID(ctx context.Context, nickname string, id int64) error
+}
+
+type postgresAuthStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresAuthStorage(pool *pgxpool.Pool) AuthStorage {
+	return &postgresAuthStorage{pool: pool}
+}
+
+func (s *postgresAuthStorage) GetPasswordHash(ctx context.Context, nickname string) (string, string, error) {
+	var canonicalNickname string
+	var hash sql.NullString
+	err := s.pool.QueryRow(ctx, `SELECT nickname, password_hash FROM users WHERE nickname = $1`, nickname).
+		Scan(&canonicalNickname, &hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", models.ErrNotFound
+		}
+		return "", "", fmt.Errorf("failed to get password hash for %s: %w", nickname, err)
+	}
+	return canonicalNickname, hash.String, nil
+}
+
+func (s *postgresAuthStorage) SetPasswordHash(ctx context.Context, nickname string, hash string) error {
+	commandTag, err := s.pool.Exec(ctx, `UPDATE users SET password_hash = $1 WHERE nickname = $2`, hash, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to set password hash for %s: %w", nickname, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresAuthStorage) CreateToken(ctx context.Context, token models.AuthToken, tokenHash string) (*models.AuthToken, error) {
+	query := `
+        INSERT INTO auth_tokens (token_hash, nickname, kind, name, expires)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, nickname, kind, name, created, expires`
+
+	var created models.AuthToken
+	err := s.pool.QueryRow(ctx, query, tokenHash, token.User, token.Kind, token.Name, token.Expires).Scan(
+		&created.ID,
+		&created.User,
+		&created.Kind,
+		&created.Name,
+		&created.Created,
+		&created.Expires,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert auth token: %w", err)
+	}
+	return &created, nil
+}
+
+func (s *postgresAuthStorage) GetTokenOwner(ctx context.Context, tokenHash string) (string, error) {
+	var nickname string
+	err := s.pool.QueryRow(ctx, `
+        SELECT nickname
+        FROM auth_tokens
+        WHERE token_hash = $1 AND (expires IS NULL OR expires > $2)`, tokenHash, time.Now()).Scan(&nickname)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", models.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to resolve auth token: %w", err)
+	}
+	return nickname, nil
+}
+
+func (s *postgresAuthStorage) DeleteToken(ctx context.Context, tokenHash string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM auth_tokens WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete auth token: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresAuthStorage) GetTokensByUser(ctx context.Context, nickname string, kind string) ([]models.AuthToken, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT id, nickname, kind, name, created, expires
+        FROM auth_tokens
+        WHERE nickname = $1 AND kind = $2
+        ORDER BY id`, nickname, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auth tokens for %s: %w", nickname, err)
+	}
+	defer rows.Close()
+
+	tokens := make([]models.AuthToken, 0)
+	for rows.Next() {
+		var token models.AuthToken
+		if err := rows.Scan(&token.ID, &token.User, &token.Kind, &token.Name, &token.Created, &token.Expires); err != nil {
+			return nil, fmt.Errorf("failed to scan auth token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading auth tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *postgresAuthStorage) DeleteTokenByID(ctx context.Context, nickname string, id int64) error {
+	commandTag, err := s.pool.Exec(ctx, `DELETE FROM auth_tokens WHERE id = $1 AND nickname = $2`, id, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to delete auth token %d: %w", id, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
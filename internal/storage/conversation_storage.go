@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ConversationStorage interface {
+	// CreateConversation создаёт переписку; members — существующие пользователи в каноничном написании.
+	CreateConversation(ctx context.Context, members []string) (*models.Conversation, error)
+	// GetConversation возвращает переписку глазами участника nickname; если он в ней не состоит — ErrNotFound.
+	GetConversation(ctx context.Context, id int64, nickname string) (*models.Conversation, error)
+	GetConversations(ctx context.Context, nickname string, limit int, after *models.ConversationCursor) ([]models.Conversation, error)
+	// CreateMessage добавляет сообщение и отмечает переписку прочитанной для автора.
+	CreateMessage(ctx context.Context, conversationID int64, author string, message string) (*models.ConversationMessage, error)
+	GetMessages(ctx context.Context, conversationID int64, limit int, before int64) ([]models.ConversationMessage, error)
+	// MarkConversationRead сдвигает отметку прочитанного вперёд до сообщения upTo, а при upTo = 0 — до последнего.
+	MarkConversationRead(ctx context.Context, conversationID int64, nickname string, upTo int64) error
+}
+
+type postgresConversationStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresConversationStorage(pool *pgxpool.Pool) ConversationStorage {
+	return &postgresConversationStorage{pool: pool}
+}
+
+// conversationQuery выбирает переписки участника cm.user_nickname вместе с последним сообщением и числом непрочитанных.
+const conversationQuery = `
+	SELECT c.id, c.created, c.updated,
+	       ARRAY(SELECT m.user_nickname::text FROM conversation_members m
+	             WHERE m.conversation_id = c.id
+	             ORDER BY lower(m.user_nickname::text) COLLATE "C"),
+	       (SELECT COUNT(*) FROM conversation_messages msg
+	        WHERE msg.conversation_id = c.id AND msg.id > cm.last_read AND msg.author <> cm.user_nickname),
+	       last.id, last.author, last.message, last.created
+	FROM conversation_members cm
+	JOIN conversations c ON c.id = cm.conversation_id
+	LEFT JOIN LATERAL (
+	    SELECT id, author, message, created FROM conversation_messages
+	    WHERE conversation_id = c.id
+	    ORDER BY id DESC
+	    LIMIT 1
+	) last ON TRUE`
+
+func (s *postgresConversationStorage) CreateConversation(ctx context.Context, members []string) (*models.Conversation, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	conversation := &models.Conversation{Members: members}
+	err = tx.QueryRow(ctx, `INSERT INTO conversations DEFAULT VALUES RETURNING id, created, updated`).
+		Scan(&conversation.ID, &conversation.Created, &conversation.Updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO conversation_members (conversation_id, user_nickname)
+		SELECT $1, unnest($2::citext[])`, conversation.ID, members)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to add members to conversation %d: %w", conversation.ID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return conversation, nil
+}
+
+func (s *postgresConversationStorage) GetConversation(ctx context.Context, id int64, nickname string) (*models.Conversation, error) {
+	row := s.pool.QueryRow(ctx, conversationQuery+`
+		WHERE cm.conversation_id = $1 AND cm.user_nickname = $2`, id, nickname)
+	conversation, err := scanConversation(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get conversation %d: %w", id, err)
+	}
+	return conversation, nil
+}
+
+func (s *postgresConversationStorage) GetConversations(ctx context.Context, nickname string, limit int, after *models.ConversationCursor) ([]models.Conversation, error) {
+	var afterUpdated *time.Time
+	var afterID int64
+	if after != nil {
+		afterUpdated, afterID = &after.Updated, after.ID
+	}
+
+	rows, err := s.pool.Query(ctx, conversationQuery+`
+		WHERE cm.user_nickname = $1
+		  AND ($2::timestamptz IS NULL OR (c.updated, c.id) < ($2::timestamptz, $3))
+		ORDER BY c.updated DESC, c.id DESC
+		LIMIT $4`, nickname, afterUpdated, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations of %s: %w", nickname, err)
+	}
+	defer rows.Close()
+
+	conversations := make([]models.Conversation, 0)
+	for rows.Next() {
+		conversation, err := scanConversation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		conversations = append(conversations, *conversation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return conversations, nil
+}
+
+func scanConversation(row pgx.Row) (*models.Conversation, error) {
+	var conversation models.Conversation
+	var lastID *int64
+	var lastAuthor, lastMessage *string
+	var lastCreated *time.Time
+	err := row.Scan(
+		&conversation.ID, &conversation.Created, &conversation.Updated, &conversation.Members, &conversation.Unread,
+		&lastID, &lastAuthor, &lastMessage, &lastCreated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if lastID != nil {
+		conversation.LastMessage = &models.ConversationMessage{
+			ID:           *lastID,
+			Conversation: conversation.ID,
+			Author:       *lastAuthor,
+			Message:      *lastMessage,
+			Created:      *lastCreated,
+		}
+	}
+	return &conversation, nil
+}
+
+func (s *postgresConversationStorage) CreateMessage(ctx context.Context, conversationID int64, author string, message string) (*models.ConversationMessage, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	created := &models.ConversationMessage{}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO conversation_messages (conversation_id, author, message)
+		VALUES ($1, $2, $3)
+		RETURNING id, conversation_id, author, message, created`, conversationID, author, message).Scan(
+		&created.ID, &created.Conversation, &created.Author, &created.Message, &created.Created,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to create message in conversation %d: %w", conversationID, err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE conversations SET updated = $2 WHERE id = $1`, conversationID, created.Created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update conversation %d: %w", conversationID, err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE conversation_members SET last_read = $3
+		WHERE conversation_id = $1 AND user_nickname = $2`, conversationID, author, created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark conversation %d as read: %w", conversationID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+func (s *postgresConversationStorage) GetMessages(ctx context.Context, conversationID int64, limit int, before int64) ([]models.ConversationMessage, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, conversation_id, author, message, created
+		FROM conversation_messages
+		WHERE conversation_id = $1 AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`, conversationID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages of conversation %d: %w", conversationID, err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.ConversationMessage, 0)
+	for rows.Next() {
+		var m models.ConversationMessage
+		if err := rows.Scan(&m.ID, &m.Conversation, &m.Author, &m.Message, &m.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return messages, nil
+}
+
+func (s *postgresConversationStorage) MarkConversationRead(ctx context.Context, conversationID int64, nickname string, upTo int64) error {
+	// Отметка не уходит дальше последнего существующего сообщения, иначе будущие сообщения сразу стали бы прочитанными.
+	commandTag, err := s.pool.Exec(ctx, `
+		UPDATE conversation_members
+		SET last_read = GREATEST(last_read, (
+		    SELECT COALESCE(MAX(id), 0) FROM conversation_messages
+		    WHERE conversation_id = $1 AND ($3 = 0 OR id <= $3)))
+		WHERE conversation_id = $1 AND user_nickname = $2`, conversationID, nickname, upTo)
+	if err != nil {
+		return fmt.Errorf("failed to mark conversation %d as read: %w", conversationID, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hardhw/internal/models"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const threadEventsChannel = "thread_events"
+
+// maxNotifyPosts ограничивает число id в одном уведомлении: payload NOTIFY не больше 8000 байт.
+const maxNotifyPosts = 400
+
+type EventListener interface {
+	Listen(ctx context.Context, handle func(models.ThreadNotification)) error
+}
+
+// EventPublisher рассылает событие ветки слушателям всех реплик. Сервисы вызывают его после
+// фиксации записи: NOTIFY внутри транзакции берёт при COMMIT общую блокировку очереди
+// уведомлений и выстроил бы в очередь одновременные вставки постов и голоса.
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.ThreadNotification) error
+}
+
+type postgresEventListener struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresEventListener(pool *pgxpool.Pool) EventListener {
+	return &postgresEventListener{pool: pool}
+}
+
+// Listen держит отдельное соединение с LISTEN и вызывает handle на каждое уведомление,
+// пока не отменён ctx или не оборвалось соединение.
+func (l *postgresEventListener) Listen(ctx context.Context, handle func(models.ThreadNotification)) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for listener: %w", err)
+	}
+	// Соединение забираем из пула насовсем, чтобы LISTEN не достался чужим запросам.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+threadEventsChannel)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", threadEventsChannel, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		var event models.ThreadNotification
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Printf("Skipping malformed thread notification %q: %v", notification.Payload, err)
+			continue
+		}
+		handle(event)
+	}
+}
+
+type postgresEventPublisher struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresEventPublisher(pool *pgxpool.Pool) EventPublisher {
+	return &postgresEventPublisher{pool: pool}
+}
+
+// Publish отправляет уведомление отдельной короткой транзакцией.
+func (p *postgresEventPublisher) Publish(ctx context.Context, event models.ThreadNotification) error {
+	return notifyThreadEvent(ctx, p.pool, event)
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// notifyThreadEvent отправляет уведомление, при необходимости разбивая список постов на части.
+func notifyThreadEvent(ctx context.Context, db execer, event models.ThreadNotification) error {
+	for {
+		chunk := event
+		if len(chunk.Posts) > maxNotifyPosts {
+			chunk.Posts = event.Posts[:maxNotifyPosts]
+		}
+
+		payload, err := json.Marshal(chunk)
+		if err != nil {
+			return fmt.Errorf("failed to encode thread notification: %w", err)
+		}
+		_, err = db.Exec(ctx, `SELECT pg_notify($1, $2)`, threadEventsChannel, string(payload))
+		if err != nil {
+			return fmt.Errorf("failed to notify %s: %w", threadEventsChannel, err)
+		}
+
+		if len(event.Posts) <= maxNotifyPosts {
+			return nil
+		}
+		event.Posts = event.Posts[maxNotifyPosts:]
+	}
+}
@@ -18,12 +18,18 @@ import (
 type ForumStorage interface {
 	GetForumBySlug(ctx context.Context, slug string) (*models.Forum, error)
 	CreateForum(ctx context.Context, forum *models.Forum) (*models.Forum, error)
+	UpdateForum(ctx context.Context, slug string, update models.ForumUpdate) (*models.Forum, error)
+	GetForums(ctx context.Context) ([]models.Forum, error)
+	GetSubforumTotals(ctx context.Context, slug string) (posts int64, threads int64, err error)
 	IncrementForumThreadsCount(ctx context.Context, forumSlug string) error
 	GetThreadBySlug(ctx context.Context, slug string) (*models.Thread, error)
 	CreateThread(ctx context.Context, thread *models.Thread) (*models.Thread, error)
 	GetThreadByID(ctx context.Context, id uuid.UUID) (*models.Thread, error)
-	GetThreadsByForumSlug(ctx context.Context, forumSlug string, limit int, since *time.Time, desc bool) ([]models.Thread, error)
-	GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool) ([]models.User, error)
+	GetThreadsByForumSlug(ctx context.Context, forumSlug string, tags []string, limit int, since *time.Time, desc bool) ([]models.Thread, error)
+	GetThreadsByTag(ctx context.Context, tag string, limit int, since *time.Time, desc bool) ([]models.Thread, error)
+	GetFeaturedThreads(ctx context.Context, limit int, since *time.Time, desc bool) ([]models.Thread, error)
+	GetForumTags(ctx context.Context, forumSlug string) ([]models.TagCount, error)
+	GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool, excludeBanned bool) ([]models.User, error)
 }
 
 type postgresForumStorage struct {
@@ -36,21 +42,15 @@ func NewPostgresForumStorage(pool *pgxpool.Pool) ForumStorage {
 
 func (s *postgresForumStorage) GetForumBySlug(ctx context.Context, slug string) (*models.Forum, error) {
 	query := `
-        SELECT slug, title, user_nickname, posts, threads -- Удален id из SELECT
+        SELECT slug, title, user_nickname, posts, threads, parent, category -- Удален id из SELECT
         FROM forums
         WHERE slug = $1`
 
 	forum := &models.Forum{}
-	err := s.pool.QueryRow(ctx, query, slug).Scan(
-		&forum.Slug,
-		&forum.Title,
-		&forum.User,
-		&forum.Posts,
-		&forum.Threads,
-	)
+	err := scanForum(s.pool.QueryRow(ctx, query, slug), forum)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, models.ErrNotFound
+			return s.getForumBySlugAlias(ctx, slug)
 		}
 		return nil, fmt.Errorf("failed to get forum by slug: %w", err)
 	}
@@ -58,6 +58,185 @@ func (s *postgresForumStorage) GetForumBySlug(ctx context.Context, slug string)
 	return forum, nil
 }
 
+// getForumBySlugAlias находит переименованный форум по одному из его прежних slug.
+func (s *postgresForumStorage) getForumBySlugAlias(ctx context.Context, slug string) (*models.Forum, error) {
+	query := `
+        SELECT f.slug, f.title, f.user_nickname, f.posts, f.threads, f.parent, f.category
+        FROM forum_slug_aliases a
+        JOIN forums f ON f.slug = a.forum_slug
+        WHERE a.slug = $1`
+
+	forum := &models.Forum{}
+	err := scanForum(s.pool.QueryRow(ctx, query, slug), forum)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get forum by slug alias: %w", err)
+	}
+	return forum, nil
+}
+
+// UpdateForum меняет заголовок и slug форума. Ссылки на форум обновляются каскадно,
+// а прежний slug остаётся алиасом.
+func (s *postgresForumStorage) UpdateForum(ctx context.Context, slug string, update models.ForumUpdate) (*models.Forum, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for forum update: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	forum := &models.Forum{}
+	err = scanForum(tx.QueryRow(ctx, `
+        SELECT slug, title, user_nickname, posts, threads, parent, category
+        FROM forums
+        WHERE slug = $1
+        FOR UPDATE`, slug), forum)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock forum for update: %w", err)
+	}
+
+	previousSlug := forum.Slug
+	if update.Title != nil {
+		forum.Title = *update.Title
+	}
+	if update.Slug != nil {
+		forum.Slug = *update.Slug
+	}
+	if update.Parent != nil {
+		forum.Parent = nil
+		if *update.Parent != "" {
+			parent, err := lockForumAncestors(ctx, tx, *update.Parent, previousSlug)
+			if err != nil {
+				return nil, err
+			}
+			forum.Parent = &parent
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE forums SET slug = $1, title = $2, parent = $3 WHERE slug = $4`,
+		forum.Slug, forum.Title, forum.Parent, previousSlug)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, models.ErrForumConflict
+		}
+		return nil, fmt.Errorf("failed to update forum %s: %w", previousSlug, err)
+	}
+
+	if !strings.EqualFold(forum.Slug, previousSlug) {
+		_, err = tx.Exec(ctx, `DELETE FROM forum_slug_aliases WHERE slug = $1`, forum.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to drop forum slug alias: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+            INSERT INTO forum_slug_aliases (slug, forum_slug)
+            VALUES ($1, $2)
+            ON CONFLICT (slug) DO UPDATE SET forum_slug = EXCLUDED.forum_slug, created = now()`,
+			previousSlug, forum.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save forum slug alias: %w", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit forum update: %w", err)
+	}
+	return forum, nil
+}
+
+// lockForumAncestors блокирует будущего родителя и всех его предков и возвращает canonical slug родителя.
+// Если среди предков есть сам переносимый форум, перенос создал бы цикл.
+func lockForumAncestors(ctx context.Context, tx pgx.Tx, parentSlug string, forumSlug string) (string, error) {
+	rows, err := tx.Query(ctx, `
+        WITH RECURSIVE ancestors AS (
+            SELECT slug, parent, 0 AS depth FROM forums WHERE slug = $1
+            UNION ALL
+            SELECT f.slug, f.parent, a.depth + 1 FROM forums f JOIN ancestors a ON f.slug = a.parent
+        )
+        SELECT f.slug
+        FROM forums f
+        JOIN ancestors a ON a.slug = f.slug
+        ORDER BY a.depth
+        FOR UPDATE OF f`, parentSlug)
+	if err != nil {
+		return "", fmt.Errorf("failed to lock ancestors of forum %s: %w", parentSlug, err)
+	}
+	defer rows.Close()
+
+	var ancestors []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", fmt.Errorf("failed to scan forum ancestor: %w", err)
+		}
+		ancestors = append(ancestors, slug)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if len(ancestors) == 0 {
+		return "", models.ErrParentNotFound
+	}
+	for _, slug := range ancestors {
+		if strings.EqualFold(slug, forumSlug) {
+			return "", models.ErrForumCycle
+		}
+	}
+	return ancestors[0], nil
+}
+
+func (s *postgresForumStorage) GetForums(ctx context.Context) ([]models.Forum, error) {
+	rows, err := s.pool.Query(ctx, `
+        SELECT slug, title, user_nickname, posts, threads, parent, category
+        FROM forums
+        ORDER BY slug`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query forums: %w", err)
+	}
+	defer rows.Close()
+
+	forums := make([]models.Forum, 0)
+	for rows.Next() {
+		var forum models.Forum
+		if err := scanForum(rows, &forum); err != nil {
+			return nil, fmt.Errorf("failed to scan forum: %w", err)
+		}
+		forums = append(forums, forum)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return forums, nil
+}
+
+// GetSubforumTotals суммирует счётчики всех подфорумов slug на любой глубине, без самого форума.
+// Рекурсия идёт только по поддереву через idx_forums_parent, так что у форума без подфорумов
+// это один поиск по индексу.
+func (s *postgresForumStorage) GetSubforumTotals(ctx context.Context, slug string) (int64, int64, error) {
+	var posts, threads int64
+	err := s.pool.QueryRow(ctx, `
+        WITH RECURSIVE subforums AS (
+            SELECT slug, posts, threads FROM forums WHERE parent = $1
+            UNION ALL
+            SELECT f.slug, f.posts, f.threads FROM forums f JOIN subforums sf ON f.parent = sf.slug
+        )
+        SELECT COALESCE(SUM(posts), 0)::BIGINT, COALESCE(SUM(threads), 0)::BIGINT FROM subforums`, slug).Scan(&posts, &threads)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to sum subforum counters of forum %s: %w", slug, err)
+	}
+	return posts, threads, nil
+}
+
+func scanForum(row pgx.Row, forum *models.Forum) error {
+	return row.Scan(&forum.Slug, &forum.Title, &forum.User, &forum.Posts, &forum.Threads, &forum.Parent, &forum.Category)
+}
+
 func (s *postgresForumStorage) CreateForum(ctx context.Context, forum *models.Forum) (*models.Forum, error) {
 
 	var canonicalUserNickname string
@@ -71,24 +250,24 @@ func (s *postgresForumStorage) CreateForum(ctx context.Context, forum *models.Fo
 	}
 
 	query := `
-        INSERT INTO forums (slug, title, user_nickname)
-        VALUES ($1, $2, $3)
-        RETURNING slug, title, user_nickname, posts, threads`
+        INSERT INTO forums (slug, title, user_nickname, parent, category)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING slug, title, user_nickname, posts, threads, parent, category`
 
 	var createdForum models.Forum
-	err = s.pool.QueryRow(ctx, query, forum.Slug, forum.Title, canonicalUserNickname).Scan( // Используем canonicalUserNickname
-		&createdForum.Slug,
-		&createdForum.Title,
-		&createdForum.User,
-		&createdForum.Posts,
-		&createdForum.Threads,
-	)
+	err = scanForum(s.pool.QueryRow(ctx, query, forum.Slug, forum.Title, canonicalUserNickname, forum.Parent, forum.Category), &createdForum) // Используем canonicalUserNickname
 	if err != nil {
 		var pgErr *pgconn.PgError
 
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			return nil, models.ErrForumConflict
 		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, models.ErrParentNotFound
+		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+			return nil, models.ErrForumCycle
+		}
 
 		return nil, fmt.Errorf("failed to insert forum: %w", err)
 	}
@@ -123,7 +302,7 @@ func (s *postgresForumStorage) IncrementForumThreadsCount(ctx context.Context, f
 
 func (s *postgresForumStorage) GetThreadBySlug(ctx context.Context, slug string) (*models.Thread, error) {
 	query := `
-        SELECT id, title, author, forum, message, votes, slug, created
+        SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured
         FROM threads
         WHERE slug = $1`
 
@@ -138,6 +317,10 @@ func (s *postgresForumStorage) GetThreadBySlug(ctx context.Context, slug string)
 		&thread.Votes,
 		&nullSlug,
 		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -178,9 +361,9 @@ func (s *postgresForumStorage) CreateThread(ctx context.Context, thread *models.
 	}
 
 	query := `
-        INSERT INTO threads (title, author, forum, message, slug, created)
-        VALUES ($1, $2, $3, $4, $5, $6)
-        RETURNING id, title, author, forum, message, votes, slug, created`
+        INSERT INTO threads (title, author, forum, message, slug, created, tags)
+        VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7::text[], '{}'))
+        RETURNING id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured`
 
 	var slugSQL sql.NullString
 	if thread.Slug != nil && *thread.Slug != "" {
@@ -193,15 +376,22 @@ func (s *postgresForumStorage) CreateThread(ctx context.Context, thread *models.
 		createdTime = thread.Created
 	}
 
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var created models.Thread
 	var scannedSlug sql.NullString
-	err = s.pool.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		thread.Title,
 		canonicalAuthorNickname,
 		thread.Forum,
 		thread.Message,
 		slugSQL,
 		createdTime,
+		thread.Tags,
 	).Scan(
 		&created.ID,
 		&created.Title,
@@ -211,6 +401,10 @@ func (s *postgresForumStorage) CreateThread(ctx context.Context, thread *models.
 		&created.Votes,
 		&scannedSlug,
 		&created.Created,
+		&created.State,
+		&created.Tags,
+		&created.Pinned,
+		&created.Featured,
 	)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -232,17 +426,40 @@ func (s *postgresForumStorage) CreateThread(ctx context.Context, thread *models.
 		created.Slug = nil
 	}
 
-	err = s.EnsureForumUserExists(ctx, created.Forum, created.Author)
+	_, err = tx.Exec(ctx, `
+        INSERT INTO forum_users (forum_slug, user_nickname)
+        VALUES ($1, $2)
+        ON CONFLICT (forum_slug, user_nickname) DO NOTHING`, created.Forum, created.Author)
 	if err != nil {
 		return nil, fmt.Errorf("failed to ensure forum user exists for thread creator %s in forum %s: %w", created.Author, created.Forum, err)
 	}
 
+	if thread.Poll != nil {
+		if err := createThreadPoll(ctx, tx, created.ID, thread.Poll); err != nil {
+			return nil, err
+		}
+		created.Poll, err = readThreadPoll(ctx, tx, created.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, created.Forum, models.WebhookEventThreadCreated, created)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return &created, nil
 }
 
 func (s *postgresForumStorage) GetThreadByID(ctx context.Context, id uuid.UUID) (*models.Thread, error) {
 	query := `
-        SELECT id, title, author, forum, message, votes, slug, created
+        SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured
         FROM threads
         WHERE id = $1`
 
@@ -257,6 +474,10 @@ func (s *postgresForumStorage) GetThreadByID(ctx context.Context, id uuid.UUID)
 		&thread.Votes,
 		&nullSlug,
 		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -274,39 +495,87 @@ func (s *postgresForumStorage) GetThreadByID(ctx context.Context, id uuid.UUID)
 	return &thread, nil
 }
 
-func (s *postgresForumStorage) GetThreadsByForumSlug(ctx context.Context, forumSlug string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+func (s *postgresForumStorage) GetThreadsByForumSlug(ctx context.Context, forumSlug string, tags []string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	return s.queryThreads(ctx, threadFilter{forum: forumSlug, tags: tags}, limit, since, desc)
+}
+
+func (s *postgresForumStorage) GetThreadsByTag(ctx context.Context, tag string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	return s.queryThreads(ctx, threadFilter{tags: []string{tag}}, limit, since, desc)
+}
+
+func (s *postgresForumStorage) GetFeaturedThreads(ctx context.Context, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	return s.queryThreads(ctx, threadFilter{featured: true}, limit, since, desc)
+}
+
+// threadFilter отбирает ветки для списков: пустой forum означает все форумы,
+// ветка должна быть отмечена всеми тегами из tags.
+type threadFilter struct {
+	forum    string
+	tags     []string
+	featured bool
+}
+
+// queryThreads выбирает ветки по фильтру. В списке одного форума все закреплённые ветки идут
+// отдельным блоком в начале каждой страницы: since и limit на них не действуют, поэтому курсор
+// следующей страницы берётся от последней незакреплённой ветки.
+func (s *postgresForumStorage) queryThreads(ctx context.Context, filter threadFilter, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
 	var (
 		queryBuilder strings.Builder
+		where        strings.Builder
 		args         []interface{}
 		argCount     int
 	)
+	forumSlug := filter.forum
 
-	queryBuilder.WriteString(`
-        SELECT id, title, author, forum, message, votes, slug, created
-        FROM threads
-        WHERE forum = $1`)
-	args = append(args, forumSlug)
-	argCount = 1
+	where.WriteString("state <> 'deleted'")
+
+	if forumSlug != "" {
+		argCount++
+		where.WriteString(fmt.Sprintf(" AND forum = $%d", argCount))
+		args = append(args, forumSlug)
+	}
+
+	if len(filter.tags) > 0 {
+		argCount++
+		where.WriteString(fmt.Sprintf(" AND tags @> $%d::text[]", argCount))
+		args = append(args, filter.tags)
+	}
+
+	if filter.featured {
+		where.WriteString(" AND featured")
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	order := fmt.Sprintf(" ORDER BY created %s, id %s", direction, direction)
+	pinnedWhere := where.String()
 
 	if since != nil {
 		argCount++
 		if desc {
-			queryBuilder.WriteString(fmt.Sprintf(" AND created <= $%d", argCount))
+			where.WriteString(fmt.Sprintf(" AND created <= $%d", argCount))
 		} else {
-			queryBuilder.WriteString(fmt.Sprintf(" AND created >= $%d", argCount))
+			where.WriteString(fmt.Sprintf(" AND created >= $%d", argCount))
 		}
 		args = append(args, *since)
 	}
 
-	if desc {
-		queryBuilder.WriteString(" ORDER BY created DESC")
-	} else {
-		queryBuilder.WriteString(" ORDER BY created ASC")
-	}
-
 	argCount++
-	queryBuilder.WriteString(fmt.Sprintf(" LIMIT $%d", argCount))
 	args = append(args, limit)
+	page := fmt.Sprintf("%s LIMIT $%d", order, argCount)
+
+	const columns = "id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured"
+	if forumSlug != "" {
+		queryBuilder.WriteString("SELECT " + columns + " FROM (")
+		queryBuilder.WriteString("(SELECT " + columns + " FROM threads WHERE " + pinnedWhere + " AND pinned)")
+		queryBuilder.WriteString(" UNION ALL ")
+		queryBuilder.WriteString("(SELECT " + columns + " FROM threads WHERE " + where.String() + " AND NOT pinned" + page + ")")
+		queryBuilder.WriteString(fmt.Sprintf(") AS page ORDER BY pinned DESC, created %s, id %s", direction, direction))
+	} else {
+		queryBuilder.WriteString("SELECT " + columns + " FROM threads WHERE " + where.String() + page)
+	}
 
 	rows, err := s.pool.Query(ctx, queryBuilder.String(), args...)
 	if err != nil {
@@ -328,6 +597,10 @@ func (s *postgresForumStorage) GetThreadsByForumSlug(ctx context.Context, forumS
 			&thread.Votes,
 			&nullSlug,
 			&thread.Created,
+			&thread.State,
+			&thread.Tags,
+			&thread.Pinned,
+			&thread.Featured,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan thread row for forum %s: %w", forumSlug, err)
@@ -352,12 +625,38 @@ func (s *postgresForumStorage) GetThreadsByForumSlug(ctx context.Context, forumS
 	return threads, nil
 }
 
-func (s *postgresForumStorage) GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool) ([]models.User, error) {
+func (s *postgresForumStorage) GetForumTags(ctx context.Context, forumSlug string) ([]models.TagCount, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT tag, COUNT(*)
+		FROM threads, unnest(tags) AS tag
+		WHERE forum = $1 AND state <> 'deleted'
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag`, forumSlug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags of forum %s: %w", forumSlug, err)
+	}
+	defer rows.Close()
+
+	counts := make([]models.TagCount, 0)
+	for rows.Next() {
+		var count models.TagCount
+		if err := rows.Scan(&count.Tag, &count.Threads); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading tags of forum %s: %w", forumSlug, err)
+	}
+	return counts, nil
+}
+
+func (s *postgresForumStorage) GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool, excludeBanned bool) ([]models.User, error) {
 	users := make([]models.User, 0)
 
 	baseQuery := `
         SELECT
-            u.nickname, u.fullname, u.about, u.email
+            u.nickname, u.fullname, u.about, u.email, u.avatar_key
         FROM users u
         JOIN forum_users fu ON u.nickname = fu.user_nickname
         WHERE fu.forum_slug = $1
@@ -366,6 +665,13 @@ func (s *postgresForumStorage) GetForumUsers(ctx context.Context, slug string, l
 	args := []interface{}{slug}
 	paramCounter := 2
 
+	if excludeBanned {
+		baseQuery += ` AND NOT EXISTS (
+            SELECT 1 FROM forum_bans b
+            WHERE b.forum_slug = fu.forum_slug AND b.user_nickname = u.nickname
+              AND (b.expires IS NULL OR b.expires > now())) `
+	}
+
 	if since != "" {
 		if desc {
 			baseQuery += fmt.Sprintf(" AND lower(u.nickname) COLLATE \"C\" < lower($%d) COLLATE \"C\" ", paramCounter)
@@ -398,6 +704,7 @@ func (s *postgresForumStorage) GetForumUsers(ctx context.Context, slug string, l
 			&user.Fullname,
 			&user.About,
 			&user.Email,
+			&user.AvatarKey,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan forum user: %w", err)
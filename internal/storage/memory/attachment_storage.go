@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryAttachmentStorage struct {
+	db *DB
+}
+
+func NewAttachmentStorage(db *DB) storage.AttachmentStorage {
+	return &memoryAttachmentStorage{db: db}
+}
+
+func (s *memoryAttachmentStorage) CreateAttachment(ctx context.Context, attachment models.Attachment, quota int64) (*models.Attachment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	owner, ok := s.db.users[fold(attachment.Owner)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	used := attachment.Size
+	for _, stored := range s.db.attachments {
+		if fold(stored.Owner) == fold(owner.Nickname) {
+			used += stored.Size
+		}
+	}
+	if used > quota {
+		return nil, models.ErrQuotaExceeded
+	}
+
+	s.db.nextAttachmentID++
+	stored := &models.Attachment{
+		ID:          s.db.nextAttachmentID,
+		Owner:       owner.Nickname,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Key:         attachment.Key,
+		Created:     time.Now(),
+	}
+	s.db.attachments[stored.ID] = stored
+
+	created := *stored
+	return &created, nil
+}
+
+func (s *memoryAttachmentStorage) GetAttachment(ctx context.Context, id int64) (*models.Attachment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	stored, ok := s.db.attachments[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	attachment := *stored
+	return &attachment, nil
+}
+
+func (s *memoryAttachmentStorage) DeleteUnboundAttachment(ctx context.Context, id int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.attachments[id]
+	if !ok || stored.Post != 0 {
+		return models.ErrNotFound
+	}
+	delete(s.db.attachments, id)
+	return nil
+}
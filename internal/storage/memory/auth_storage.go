@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryAuthStorage struct {
+	db *DB
+}
+
+func NewAuthStorage(db *DB) storage.AuthStorage {
+	return &memoryAuthStorage{db: db}
+}
+
+func (s *memoryAuthStorage) GetPasswordHash(ctx context.Context, nickname string) (string, string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	user, ok := s.db.users[fold(nickname)]
+	if !ok {
+		return "", "", models.ErrNotFound
+	}
+	return user.Nickname, s.db.passwordHashes[fold(nickname)], nil
+}
+
+func (s *memoryAuthStorage) SetPasswordHash(ctx context.Context, nickname string, hash string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[fold(nickname)]; !ok {
+		return models.ErrNotFound
+	}
+	s.db.passwordHashes[fold(nickname)] = hash
+	return nil
+}
+
+func (s *memoryAuthStorage) CreateToken(ctx context.Context, token models.AuthToken, tokenHash string) (*models.AuthToken, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[fold(token.User)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	s.db.nextTokenID++
+	stored := &models.AuthToken{
+		ID:      s.db.nextTokenID,
+		User:    user.Nickname,
+		Kind:    token.Kind,
+		Name:    token.Name,
+		Created: time.Now(),
+		Expires: token.Expires,
+	}
+	s.db.tokens[tokenHash] = stored
+
+	created := *stored
+	return &created, nil
+}
+
+func (s *memoryAuthStorage) GetTokenOwner(ctx context.Context, tokenHash string) (string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	token, ok := s.db.tokens[tokenHash]
+	if !ok || (token.Expires != nil && !token.Expires.After(time.Now())) {
+		return "", models.ErrNotFound
+	}
+	return token.User, nil
+}
+
+func (s *memoryAuthStorage) DeleteToken(ctx context.Context, tokenHash string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	delete(s.db.tokens, tokenHash)
+	return nil
+}
+
+func (s *memoryAuthStorage) GetTokensByUser(ctx context.Context, nickname string, kind string) ([]models.AuthToken, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	tokens := make([]models.AuthToken, 0)
+	for _, token := range s.db.tokens {
+		if fold(token.User) == fold(nickname) && token.Kind == kind {
+			tokens = append(tokens, *token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (s *memoryAuthStorage) DeleteTokenByID(ctx context.Context, nickname string, id int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for hash, token := range s.db.tokens {
+		if token.ID == id && fold(token.User) == fold(nickname) {
+			delete(s.db.tokens, hash)
+			return nil
+		}
+	}
+	return models.ErrNotFound
+}
@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type conversation struct {
+	id      int64
+	created time.Time
+	updated time.Time
+	// members: участник (fold) -> id последнего прочитанного сообщения.
+	members map[string]int64
+	// messages упорядочены по возрастанию id.
+	messages []*models.ConversationMessage
+}
+
+type memoryConversationStorage struct {
+	db *DB
+}
+
+func NewConversationStorage(db *DB) storage.ConversationStorage {
+	return &memoryConversationStorage{db: db}
+}
+
+func (s *memoryConversationStorage) CreateConversation(ctx context.Context, members []string) (*models.Conversation, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, member := range members {
+		if _, ok := s.db.users[fold(member)]; !ok {
+			return nil, models.ErrNotFound
+		}
+	}
+
+	now := time.Now()
+	s.db.nextConversationID++
+	c := &conversation{id: s.db.nextConversationID, created: now, updated: now, members: make(map[string]int64, len(members))}
+	for _, member := range members {
+		c.members[fold(member)] = 0
+	}
+	s.db.conversations[c.id] = c
+
+	return s.db.conversationFor(c, fold(members[0])), nil
+}
+
+func (s *memoryConversationStorage) GetConversation(ctx context.Context, id int64, nickname string) (*models.Conversation, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	c, ok := s.db.conversations[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if _, ok := c.members[fold(nickname)]; !ok {
+		return nil, models.ErrNotFound
+	}
+	return s.db.conversationFor(c, fold(nickname)), nil
+}
+
+func (s *memoryConversationStorage) GetConversations(ctx context.Context, nickname string, limit int, after *models.ConversationCursor) ([]models.Conversation, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	key := fold(nickname)
+	found := make([]*conversation, 0)
+	for _, c := range s.db.conversations {
+		if _, ok := c.members[key]; !ok {
+			continue
+		}
+		if after != nil && !conversationBefore(c, after.Updated, after.ID) {
+			continue
+		}
+		found = append(found, c)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return conversationBefore(found[j], found[i].updated, found[i].id)
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	conversations := make([]models.Conversation, 0, len(found))
+	for _, c := range found {
+		conversations = append(conversations, *s.db.conversationFor(c, key))
+	}
+	return conversations, nil
+}
+
+// conversationBefore сообщает, идёт ли c в списке после позиции (updated, id).
+func conversationBefore(c *conversation, updated time.Time, id int64) bool {
+	if !c.updated.Equal(updated) {
+		return c.updated.Before(updated)
+	}
+	return c.id < id
+}
+
+func (s *memoryConversationStorage) CreateMessage(ctx context.Context, conversationID int64, author string, message string) (*models.ConversationMessage, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	c, ok := s.db.conversations[conversationID]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	user, ok := s.db.users[fold(author)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	s.db.nextConversationMessageID++
+	stored := &models.ConversationMessage{
+		ID:           s.db.nextConversationMessageID,
+		Conversation: c.id,
+		Author:       user.Nickname,
+		Message:      message,
+		Created:      time.Now(),
+	}
+	c.messages = append(c.messages, stored)
+	c.updated = stored.Created
+	if _, ok := c.members[fold(author)]; ok {
+		c.members[fold(author)] = stored.ID
+	}
+
+	created := *stored
+	return &created, nil
+}
+
+func (s *memoryConversationStorage) GetMessages(ctx context.Context, conversationID int64, limit int, before int64) ([]models.ConversationMessage, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	messages := make([]models.ConversationMessage, 0)
+	c, ok := s.db.conversations[conversationID]
+	if !ok {
+		return messages, nil
+	}
+	for i := len(c.messages) - 1; i >= 0 && len(messages) < limit; i-- {
+		if before != 0 && c.messages[i].ID >= before {
+			continue
+		}
+		messages = append(messages, *c.messages[i])
+	}
+	return messages, nil
+}
+
+func (s *memoryConversationStorage) MarkConversationRead(ctx context.Context, conversationID int64, nickname string, upTo int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	c, ok := s.db.conversations[conversationID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	key := fold(nickname)
+	lastRead, ok := c.members[key]
+	if !ok {
+		return models.ErrNotFound
+	}
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		if upTo == 0 || c.messages[i].ID <= upTo {
+			c.members[key] = max(lastRead, c.messages[i].ID)
+			break
+		}
+	}
+	return nil
+}
+
+// conversationFor собирает переписку глазами участника key.
+func (db *DB) conversationFor(c *conversation, key string) *models.Conversation {
+	conversation := &models.Conversation{
+		ID:      c.id,
+		Members: make([]string, 0, len(c.members)),
+		Created: c.created,
+		Updated: c.updated,
+	}
+	for member := range c.members {
+		conversation.Members = append(conversation.Members, db.users[member].Nickname)
+	}
+	sort.Slice(conversation.Members, func(i, j int) bool {
+		return fold(conversation.Members[i]) < fold(conversation.Members[j])
+	})
+
+	lastRead := c.members[key]
+	for _, message := range c.messages {
+		if message.ID > lastRead && fold(message.Author) != key {
+			conversation.Unread++
+		}
+	}
+	if len(c.messages) > 0 {
+		last := *c.messages[len(c.messages)-1]
+		conversation.LastMessage = &last
+	}
+	return conversation
+}
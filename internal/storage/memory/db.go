@@ -0,0 +1,741 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"hardhw/internal/models"
+)
+
+type voteKey struct {
+	threadID int64
+	nickname string
+}
+
+type DB struct {
+	mu sync.RWMutex
+
+	users        map[string]*models.User
+	usersByEmail map[string]string
+	userAliases  map[string]string
+
+	forums     map[string]*models.Forum
+	forumUsers map[string]map[string]struct{}
+
+	threads       map[int64]*models.Thread
+	threadsBySlug map[string]int64
+	nextThreadID  int64
+
+	threadRevisions      map[int64][]models.ThreadRevision
+	nextThreadRevisionID int64
+	threadSlugAliases    map[string]int64
+	forumSlugAliases     map[string]string
+
+	posts       map[int64]*models.Post
+	threadPosts map[int64][]int64
+	nextPostID  int64
+
+	postRevisions  map[int64][]models.PostRevision
+	nextRevisionID int64
+
+	// postReactions: пост -> пользователь (fold) -> виды реакций.
+	postReactions map[int64]map[string][]string
+	// postMentions: пост -> упомянутые пользователи (fold).
+	postMentions map[int64][]string
+
+	// polls хранит опросы без результатов, pollVotes: ветка -> пользователь (fold) -> выбранные варианты.
+	polls     map[int64]*models.Poll
+	pollVotes map[int64]map[string][]int
+
+	votes map[voteKey]int
+
+	attachments      map[int64]*models.Attachment
+	nextAttachmentID int64
+
+	// Подписки: ветка или форум (fold) -> пользователи (fold).
+	threadSubscriptions map[int64]map[string]struct{}
+	forumSubscriptions  map[string]map[string]struct{}
+	// notifications: пользователь (fold) -> уведомления по возрастанию id.
+	notifications      map[string][]*models.Notification
+	nextNotificationID int64
+	// postNotificationQueue — аналог таблицы post_notification_queue: посты, которые ещё не разобрал воркер уведомлений.
+	postNotificationQueue []int64
+
+	// userBlocks: кто блокирует (fold) -> кого (fold) -> когда.
+	userBlocks map[string]map[string]time.Time
+
+	conversations             map[int64]*conversation
+	nextConversationID        int64
+	nextConversationMessageID int64
+
+	passwordHashes map[string]string
+	tokens         map[string]*models.AuthToken
+	nextTokenID    int64
+
+	admins      map[string]bool
+	moderators  map[string]map[string]struct{}
+	bans        map[string]map[string]*models.ForumBan
+	suspensions map[string]*models.Suspension
+
+	webhooks          map[int64]*models.Webhook
+	nextWebhookID     int64
+	deliveries        map[int64]*models.WebhookDelivery
+	webhookDeliveries map[int64][]int64
+	nextDeliveryID    int64
+
+	// events не очищается в reset: подписчик живёт дольше очистки базы.
+	events chan models.ThreadNotification
+}
+
+// eventsBuffer — сколько уведомлений копится без слушателя, дальше они отбрасываются.
+const eventsBuffer = 1024
+
+func New() *DB {
+	db := &DB{events: make(chan models.ThreadNotification, eventsBuffer)}
+	db.reset()
+	return db
+}
+
+func (db *DB) reset() {
+	db.users = make(map[string]*models.User)
+	db.usersByEmail = make(map[string]string)
+	db.userAliases = make(map[string]string)
+	db.forums = make(map[string]*models.Forum)
+	db.forumUsers = make(map[string]map[string]struct{})
+	db.threads = make(map[int64]*models.Thread)
+	db.threadsBySlug = make(map[string]int64)
+	db.nextThreadID = 0
+	db.threadRevisions = make(map[int64][]models.ThreadRevision)
+	db.nextThreadRevisionID = 0
+	db.threadSlugAliases = make(map[string]int64)
+	db.forumSlugAliases = make(map[string]string)
+	db.posts = make(map[int64]*models.Post)
+	db.threadPosts = make(map[int64][]int64)
+	db.nextPostID = 0
+	db.postRevisions = make(map[int64][]models.PostRevision)
+	db.nextRevisionID = 0
+	db.postReactions = make(map[int64]map[string][]string)
+	db.postMentions = make(map[int64][]string)
+	db.polls = make(map[int64]*models.Poll)
+	db.pollVotes = make(map[int64]map[string][]int)
+	db.votes = make(map[voteKey]int)
+	db.attachments = make(map[int64]*models.Attachment)
+	db.nextAttachmentID = 0
+	db.threadSubscriptions = make(map[int64]map[string]struct{})
+	db.forumSubscriptions = make(map[string]map[string]struct{})
+	db.notifications = make(map[string][]*models.Notification)
+	db.nextNotificationID = 0
+	db.postNotificationQueue = nil
+	db.userBlocks = make(map[string]map[string]time.Time)
+	db.conversations = make(map[int64]*conversation)
+	db.nextConversationID = 0
+	db.nextConversationMessageID = 0
+	db.passwordHashes = make(map[string]string)
+	db.tokens = make(map[string]*models.AuthToken)
+	db.nextTokenID = 0
+	db.admins = make(map[string]bool)
+	db.moderators = make(map[string]map[string]struct{})
+	db.bans = make(map[string]map[string]*models.ForumBan)
+	db.suspensions = make(map[string]*models.Suspension)
+	db.webhooks = make(map[int64]*models.Webhook)
+	db.nextWebhookID = 0
+	db.deliveries = make(map[int64]*models.WebhookDelivery)
+	db.webhookDeliveries = make(map[int64][]int64)
+	db.nextDeliveryID = 0
+
+	// Служебный пользователь для удалённых аккаунтов, как в миграции 0023.
+	placeholder := &models.User{Nickname: models.DeletedUserNickname, Fullname: "Deleted user", Email: "deleted@deleted.invalid"}
+	db.users[fold(placeholder.Nickname)] = placeholder
+	db.usersByEmail[fold(placeholder.Email)] = fold(placeholder.Nickname)
+}
+
+// notify не блокирует запись: слушатель сам читает посты из базы, а без слушателя событие теряется.
+func (db *DB) notify(event models.ThreadNotification) {
+	select {
+	case db.events <- event:
+	default:
+	}
+}
+
+// fold приводит ключ к нижнему регистру, повторяя сравнение CITEXT в PostgreSQL.
+func fold(s string) string {
+	return strings.ToLower(s)
+}
+
+func (db *DB) addForumUser(forumSlug, nickname string) {
+	key := fold(forumSlug)
+	members, ok := db.forumUsers[key]
+	if !ok {
+		members = make(map[string]struct{})
+		db.forumUsers[key] = members
+	}
+	members[fold(nickname)] = struct{}{}
+}
+
+// isBanned учитывает только действующие баны: истёкшие остаются в списке, но не мешают.
+func (db *DB) isBanned(forumSlug, nickname string, now time.Time) bool {
+	ban, ok := db.bans[fold(forumSlug)][fold(nickname)]
+	return ok && (ban.Expires == nil || ban.Expires.After(now))
+}
+
+// blockedUsers возвращает чёрный список пользователя в порядке добавления.
+func (db *DB) blockedUsers(key string) []string {
+	blocked := db.userBlocks[key]
+	keys := slices.SortedFunc(maps.Keys(blocked), func(a, b string) int {
+		if c := blocked[a].Compare(blocked[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	nicknames := make([]string, 0, len(keys))
+	for _, k := range keys {
+		nicknames = append(nicknames, db.users[k].Nickname)
+	}
+	return nicknames
+}
+
+// forumBySlug находит форум по действующему slug, а при промахе — по прежнему.
+func (db *DB) forumBySlug(slug string) *models.Forum {
+	if forum, ok := db.forums[fold(slug)]; ok {
+		return forum
+	}
+	if current, ok := db.forumSlugAliases[fold(slug)]; ok {
+		return db.forums[fold(current)]
+	}
+	return nil
+}
+
+func (db *DB) parentForum(forum *models.Forum) *models.Forum {
+	if forum.Parent == nil {
+		return nil
+	}
+	return db.forums[fold(*forum.Parent)]
+}
+
+func (db *DB) threadBySlugOrID(slugOrID string, id int64) *models.Thread {
+	thread, ok := db.threads[id]
+	if threadID, bySlug := db.threadsBySlug[fold(slugOrID)]; bySlug {
+		thread, ok = db.threads[threadID]
+	} else if threadID, byAlias := db.threadSlugAliases[fold(slugOrID)]; byAlias && !ok {
+		thread, ok = db.threads[threadID]
+	}
+	if !ok || thread.State == models.ThreadStateDeleted {
+		return nil
+	}
+	return thread
+}
+
+// threadDeleted сообщает, что ветки нет или она удалена: её посты скрыты вместе с ней.
+func (db *DB) threadDeleted(id int64) bool {
+	thread, ok := db.threads[id]
+	return !ok || thread.State == models.ThreadStateDeleted
+}
+
+func copyThread(t *models.Thread) *models.Thread {
+	c := *t
+	if t.Slug != nil {
+		slug := *t.Slug
+		c.Slug = &slug
+	}
+	c.Tags = append([]string(nil), t.Tags...)
+	return &c
+}
+
+// hasTags сообщает, отмечена ли ветка всеми тегами из tags.
+func hasTags(thread *models.Thread, tags []string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(thread.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func copyPost(p *models.Post) models.Post {
+	c := *p
+	c.Path = append([]int64(nil), p.Path...)
+	c.Reactions = maps.Clone(p.Reactions)
+	c.Attachments = slices.Clone(p.Attachments)
+	return c
+}
+
+// threadPoll возвращает копию опроса ветки с результатами или nil, если опроса нет.
+func (db *DB) threadPoll(threadID int64) *models.Poll {
+	stored, ok := db.polls[threadID]
+	if !ok {
+		return nil
+	}
+	poll := *stored
+	poll.Options = append([]models.PollOption(nil), stored.Options...)
+	if stored.Closes != nil {
+		closes := *stored.Closes
+		poll.Closes = &closes
+		poll.Closed = !closes.After(time.Now())
+	}
+	for _, options := range db.pollVotes[threadID] {
+		poll.Voters++
+		for _, id := range options {
+			poll.Options[id-1].Votes++
+		}
+	}
+	return &poll
+}
+
+// recountPostReactions пересчитывает Score и Reactions поста по db.postReactions.
+func (db *DB) recountPostReactions(post *models.Post) {
+	post.Score = 0
+	post.Reactions = nil
+	for _, kinds := range db.postReactions[post.ID] {
+		for _, kind := range kinds {
+			if post.Reactions == nil {
+				post.Reactions = make(map[string]int32)
+			}
+			post.Reactions[kind]++
+			switch kind {
+			case models.ReactionUp:
+				post.Score++
+			case models.ReactionDown:
+				post.Score--
+			}
+		}
+	}
+}
+
+// comparePaths сравнивает массивы так же, как PostgreSQL сравнивает BIGINT[].
+func comparePaths(a, b []int64) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+func renameSubscriber(subscribers map[string]struct{}, oldKey, newKey string) {
+	if _, ok := subscribers[oldKey]; ok {
+		delete(subscribers, oldKey)
+		subscribers[newKey] = struct{}{}
+	}
+}
+
+// renameForum повторяет ON UPDATE CASCADE: переносит все ссылки на форум под новый slug
+// и оставляет прежний slug алиасом.
+func (db *DB) renameForum(forum *models.Forum, newSlug string) {
+	oldKey, newKey := fold(forum.Slug), fold(newSlug)
+	forum.Slug = newSlug
+
+	if oldKey != newKey {
+		delete(db.forums, oldKey)
+		db.forums[newKey] = forum
+		if members, ok := db.forumUsers[oldKey]; ok {
+			delete(db.forumUsers, oldKey)
+			db.forumUsers[newKey] = members
+		}
+		if moderators, ok := db.moderators[oldKey]; ok {
+			delete(db.moderators, oldKey)
+			db.moderators[newKey] = moderators
+		}
+		if bans, ok := db.bans[oldKey]; ok {
+			delete(db.bans, oldKey)
+			db.bans[newKey] = bans
+		}
+		if subscribers, ok := db.forumSubscriptions[oldKey]; ok {
+			delete(db.forumSubscriptions, oldKey)
+			db.forumSubscriptions[newKey] = subscribers
+		}
+	}
+
+	for _, ban := range db.bans[newKey] {
+		ban.Forum = newSlug
+	}
+	for _, thread := range db.threads {
+		if fold(thread.Forum) == oldKey {
+			thread.Forum = newSlug
+		}
+	}
+	for _, post := range db.posts {
+		if fold(post.Forum) == oldKey {
+			post.Forum = newSlug
+		}
+	}
+	for _, hook := range db.webhooks {
+		if fold(hook.Forum) == oldKey {
+			hook.Forum = newSlug
+		}
+	}
+	for _, child := range db.forums {
+		if child.Parent != nil && fold(*child.Parent) == oldKey {
+			parent := newSlug
+			child.Parent = &parent
+		}
+	}
+
+	for alias, current := range db.forumSlugAliases {
+		if fold(current) == oldKey {
+			db.forumSlugAliases[alias] = newSlug
+		}
+	}
+	if oldKey != newKey {
+		delete(db.forumSlugAliases, newKey)
+		db.forumSlugAliases[oldKey] = newSlug
+	}
+}
+
+// renameUser повторяет ON UPDATE CASCADE для ссылок на пользователя и оставляет прежний nickname алиасом.
+func (db *DB) renameUser(user *models.User, newNickname string) {
+	oldKey, newKey := fold(user.Nickname), fold(newNickname)
+	rename := func(nickname *string) {
+		if *nickname != "" && fold(*nickname) == oldKey {
+			*nickname = newNickname
+		}
+	}
+	user.Nickname = newNickname
+
+	if oldKey != newKey {
+		delete(db.users, oldKey)
+		db.users[newKey] = user
+		db.usersByEmail[fold(user.Email)] = newKey
+		if hash, ok := db.passwordHashes[oldKey]; ok {
+			delete(db.passwordHashes, oldKey)
+			db.passwordHashes[newKey] = hash
+		}
+		if db.admins[oldKey] {
+			delete(db.admins, oldKey)
+			db.admins[newKey] = true
+		}
+		if suspension, ok := db.suspensions[oldKey]; ok {
+			delete(db.suspensions, oldKey)
+			db.suspensions[newKey] = suspension
+		}
+		for _, members := range db.forumUsers {
+			if _, ok := members[oldKey]; ok {
+				delete(members, oldKey)
+				members[newKey] = struct{}{}
+			}
+		}
+		for _, moderators := range db.moderators {
+			if _, ok := moderators[oldKey]; ok {
+				delete(moderators, oldKey)
+				moderators[newKey] = struct{}{}
+			}
+		}
+		for _, bans := range db.bans {
+			if ban, ok := bans[oldKey]; ok {
+				delete(bans, oldKey)
+				bans[newKey] = ban
+			}
+		}
+		for key, voice := range db.votes {
+			if key.nickname == oldKey {
+				delete(db.votes, key)
+				db.votes[voteKey{threadID: key.threadID, nickname: newKey}] = voice
+			}
+		}
+		for _, reactions := range db.postReactions {
+			if kinds, ok := reactions[oldKey]; ok {
+				delete(reactions, oldKey)
+				reactions[newKey] = kinds
+			}
+		}
+		for _, votes := range db.pollVotes {
+			if options, ok := votes[oldKey]; ok {
+				delete(votes, oldKey)
+				votes[newKey] = options
+			}
+		}
+		for _, mentioned := range db.postMentions {
+			if i := slices.Index(mentioned, oldKey); i >= 0 {
+				mentioned[i] = newKey
+			}
+		}
+		for _, subscribers := range db.threadSubscriptions {
+			renameSubscriber(subscribers, oldKey, newKey)
+		}
+		for _, subscribers := range db.forumSubscriptions {
+			renameSubscriber(subscribers, oldKey, newKey)
+		}
+		if notifications, ok := db.notifications[oldKey]; ok {
+			delete(db.notifications, oldKey)
+			db.notifications[newKey] = notifications
+		}
+		if blocked, ok := db.userBlocks[oldKey]; ok {
+			delete(db.userBlocks, oldKey)
+			db.userBlocks[newKey] = blocked
+		}
+		for _, blocked := range db.userBlocks {
+			if created, ok := blocked[oldKey]; ok {
+				delete(blocked, oldKey)
+				blocked[newKey] = created
+			}
+		}
+		for _, c := range db.conversations {
+			if lastRead, ok := c.members[oldKey]; ok {
+				delete(c.members, oldKey)
+				c.members[newKey] = lastRead
+			}
+		}
+	}
+
+	for _, token := range db.tokens {
+		rename(&token.User)
+	}
+	for _, suspension := range db.suspensions {
+		rename(&suspension.User)
+		rename(&suspension.SuspendedBy)
+	}
+	for _, bans := range db.bans {
+		for _, ban := range bans {
+			rename(&ban.User)
+			rename(&ban.BannedBy)
+		}
+	}
+	for _, forum := range db.forums {
+		rename(&forum.User)
+	}
+	for _, thread := range db.threads {
+		rename(&thread.Author)
+	}
+	for _, post := range db.posts {
+		rename(&post.Author)
+	}
+	for _, hook := range db.webhooks {
+		rename(&hook.CreatedBy)
+	}
+	for _, attachment := range db.attachments {
+		rename(&attachment.Owner)
+	}
+	for _, c := range db.conversations {
+		for _, message := range c.messages {
+			rename(&message.Author)
+		}
+	}
+	for _, revisions := range db.postRevisions {
+		for i := range revisions {
+			rename(&revisions[i].Editor)
+		}
+	}
+	for _, revisions := range db.threadRevisions {
+		for i := range revisions {
+			rename(&revisions[i].Editor)
+		}
+	}
+
+	for alias, current := range db.userAliases {
+		if fold(current) == oldKey {
+			db.userAliases[alias] = newNickname
+		}
+	}
+	if oldKey != newKey {
+		delete(db.userAliases, newKey)
+		db.userAliases[oldKey] = newNickname
+	}
+}
+
+// deleteUser повторяет postgresUserStorage.DeleteUser: снимает голоса, передаёт контент
+// служебному пользователю (в режиме erase — предварительно стерев его) и удаляет аккаунт.
+func (db *DB) deleteUser(user *models.User, mode string) *models.UserFiles {
+	key := fold(user.Nickname)
+	files := &models.UserFiles{Attachments: make([]string, 0), Avatar: user.AvatarKey}
+
+	placeholder := db.users[fold(models.DeletedUserNickname)]
+
+	for vote, voice := range db.votes {
+		if vote.nickname == key {
+			delete(db.votes, vote)
+			if thread, ok := db.threads[vote.threadID]; ok {
+				thread.Votes -= int32(voice)
+			}
+		}
+	}
+	for _, votes := range db.pollVotes {
+		delete(votes, key)
+	}
+	for postID, mentioned := range db.postMentions {
+		db.postMentions[postID] = slices.DeleteFunc(mentioned, func(k string) bool { return k == key })
+	}
+	for _, subscribers := range db.threadSubscriptions {
+		delete(subscribers, key)
+	}
+	for _, subscribers := range db.forumSubscriptions {
+		delete(subscribers, key)
+	}
+	delete(db.notifications, key)
+	for postID, reactions := range db.postReactions {
+		if _, ok := reactions[key]; ok {
+			delete(reactions, key)
+			if post, ok := db.posts[postID]; ok {
+				db.recountPostReactions(post)
+			}
+		}
+	}
+
+	if mode == models.DeleteModeErase {
+		for _, thread := range db.threads {
+			if fold(thread.Author) != key {
+				continue
+			}
+			if thread.State != models.ThreadStateDeleted {
+				if forum, ok := db.forums[fold(thread.Forum)]; ok {
+					forum.Threads--
+					for _, id := range db.threadPosts[thread.ID] {
+						if !db.posts[id].IsDeleted {
+							forum.Posts--
+						}
+					}
+				}
+			}
+			if thread.Slug != nil {
+				delete(db.threadsBySlug, fold(*thread.Slug))
+			}
+			for alias, threadID := range db.threadSlugAliases {
+				if threadID == thread.ID {
+					delete(db.threadSlugAliases, alias)
+				}
+			}
+			delete(db.threadRevisions, thread.ID)
+			delete(db.polls, thread.ID)
+			delete(db.pollVotes, thread.ID)
+			thread.State = models.ThreadStateDeleted
+			thread.Title, thread.Message, thread.Slug, thread.Tags = "", "", nil, nil
+		}
+		for _, post := range db.posts {
+			if fold(post.Author) != key {
+				continue
+			}
+			if !post.IsDeleted && db.threads[post.Thread].State != models.ThreadStateDeleted {
+				if forum, ok := db.forums[fold(post.Forum)]; ok {
+					forum.Posts--
+				}
+			}
+			delete(db.postRevisions, post.ID)
+			delete(db.postMentions, post.ID)
+			post.IsDeleted = true
+			post.Message = ""
+			post.Attachments = nil
+		}
+		for attachmentID, attachment := range db.attachments {
+			if fold(attachment.Owner) == key {
+				files.Attachments = append(files.Attachments, attachment.Key)
+				delete(db.attachments, attachmentID)
+			}
+		}
+		for _, c := range db.conversations {
+			c.messages = slices.DeleteFunc(c.messages, func(m *models.ConversationMessage) bool { return fold(m.Author) == key })
+		}
+	}
+
+	for _, thread := range db.threads {
+		if fold(thread.Author) == key {
+			thread.Author = placeholder.Nickname
+		}
+	}
+	for _, post := range db.posts {
+		if fold(post.Author) == key {
+			post.Author = placeholder.Nickname
+		}
+	}
+	for _, forum := range db.forums {
+		if fold(forum.User) == key {
+			forum.User = placeholder.Nickname
+		}
+	}
+	for _, attachment := range db.attachments {
+		if fold(attachment.Owner) == key {
+			attachment.Owner = placeholder.Nickname
+		}
+	}
+	for _, c := range db.conversations {
+		for _, message := range c.messages {
+			if fold(message.Author) == key {
+				message.Author = placeholder.Nickname
+			}
+		}
+	}
+	for _, members := range db.forumUsers {
+		delete(members, key)
+	}
+
+	// Дальше — то, что в PostgreSQL делают ON DELETE CASCADE и ON DELETE SET NULL.
+	unset := func(nickname *string) {
+		if fold(*nickname) == key {
+			*nickname = ""
+		}
+	}
+	for hash, token := range db.tokens {
+		if fold(token.User) == key {
+			delete(db.tokens, hash)
+		}
+	}
+	for _, moderators := range db.moderators {
+		delete(moderators, key)
+	}
+	delete(db.userBlocks, key)
+	for _, blocked := range db.userBlocks {
+		delete(blocked, key)
+	}
+	for _, c := range db.conversations {
+		delete(c.members, key)
+	}
+	for _, bans := range db.bans {
+		delete(bans, key)
+		for _, ban := range bans {
+			unset(&ban.BannedBy)
+		}
+	}
+	delete(db.suspensions, key)
+	for _, suspension := range db.suspensions {
+		unset(&suspension.SuspendedBy)
+	}
+	for _, hook := range db.webhooks {
+		unset(&hook.CreatedBy)
+	}
+	for _, revisions := range db.postRevisions {
+		for i := range revisions {
+			unset(&revisions[i].Editor)
+		}
+	}
+	for _, revisions := range db.threadRevisions {
+		for i := range revisions {
+			unset(&revisions[i].Editor)
+		}
+	}
+	for alias, current := range db.userAliases {
+		if fold(current) == key {
+			delete(db.userAliases, alias)
+		}
+	}
+
+	delete(db.admins, key)
+	delete(db.passwordHashes, key)
+	delete(db.usersByEmail, fold(user.Email))
+	delete(db.users, key)
+	return files
+}
+
+// setPostMentions запоминает упоминания поста, пропуская несуществующих пользователей.
+func (db *DB) setPostMentions(postID int64, nicknames []string) {
+	mentioned := make([]string, 0, len(nicknames))
+	for _, nickname := range nicknames {
+		key := fold(nickname)
+		if _, ok := db.users[key]; ok && !slices.Contains(mentioned, key) {
+			mentioned = append(mentioned, key)
+		}
+	}
+	if len(mentioned) == 0 {
+		delete(db.postMentions, postID)
+		return
+	}
+	db.postMentions[postID] = mentioned
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryEventListener struct {
+	db *DB
+}
+
+func NewEventListener(db *DB) storage.EventListener {
+	return &memoryEventListener{db: db}
+}
+
+func (l *memoryEventListener) Listen(ctx context.Context, handle func(models.ThreadNotification)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-l.db.events:
+			handle(event)
+		}
+	}
+}
+
+type memoryEventPublisher struct {
+	db *DB
+}
+
+func NewEventPublisher(db *DB) storage.EventPublisher {
+	return &memoryEventPublisher{db: db}
+}
+
+func (p *memoryEventPublisher) Publish(ctx context.Context, event models.ThreadNotification) error {
+	p.db.notify(event)
+	return nil
+}
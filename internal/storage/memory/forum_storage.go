@@ -0,0 +1,383 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+type memoryForumStorage struct {
+	db *DB
+}
+
+func NewForumStorage(db *DB) storage.ForumStorage {
+	return &memoryForumStorage{db: db}
+}
+
+func (s *memoryForumStorage) GetForumBySlug(ctx context.Context, slug string) (*models.Forum, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	forum := s.db.forumBySlug(slug)
+	if forum == nil {
+		return nil, models.ErrNotFound
+	}
+	found := *forum
+	return &found, nil
+}
+
+func (s *memoryForumStorage) CreateForum(ctx context.Context, forum *models.Forum) (*models.Forum, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	owner, ok := s.db.users[fold(forum.User)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if _, exists := s.db.forums[fold(forum.Slug)]; exists {
+		return nil, models.ErrForumConflict
+	}
+
+	var parent *string
+	if forum.Parent != nil {
+		if fold(*forum.Parent) == fold(forum.Slug) {
+			return nil, models.ErrForumCycle
+		}
+		parentForum, ok := s.db.forums[fold(*forum.Parent)]
+		if !ok {
+			return nil, models.ErrParentNotFound
+		}
+		parentSlug := parentForum.Slug
+		parent = &parentSlug
+	}
+
+	stored := &models.Forum{
+		Slug:     forum.Slug,
+		Title:    forum.Title,
+		User:     owner.Nickname,
+		Parent:   parent,
+		Category: forum.Category,
+	}
+	s.db.forums[fold(forum.Slug)] = stored
+
+	created := *stored
+	return &created, nil
+}
+
+func (s *memoryForumStorage) UpdateForum(ctx context.Context, slug string, update models.ForumUpdate) (*models.Forum, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	forum, ok := s.db.forums[fold(slug)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if update.Slug != nil && fold(*update.Slug) != fold(forum.Slug) {
+		if _, exists := s.db.forums[fold(*update.Slug)]; exists {
+			return nil, models.ErrForumConflict
+		}
+	}
+
+	var parent *string
+	if update.Parent != nil && *update.Parent != "" {
+		parentForum, ok := s.db.forums[fold(*update.Parent)]
+		if !ok {
+			return nil, models.ErrParentNotFound
+		}
+		// Перенос под собственного потомка замкнул бы дерево в цикл.
+		for ancestor := parentForum; ancestor != nil; ancestor = s.db.parentForum(ancestor) {
+			if ancestor == forum {
+				return nil, models.ErrForumCycle
+			}
+		}
+		parentSlug := parentForum.Slug
+		parent = &parentSlug
+	}
+
+	if update.Title != nil {
+		forum.Title = *update.Title
+	}
+	if update.Parent != nil {
+		forum.Parent = parent
+	}
+	if update.Slug != nil && *update.Slug != forum.Slug {
+		s.db.renameForum(forum, *update.Slug)
+	}
+
+	updated := *forum
+	return &updated, nil
+}
+
+func (s *memoryForumStorage) GetForums(ctx context.Context) ([]models.Forum, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	forums := make([]models.Forum, 0, len(s.db.forums))
+	for _, forum := range s.db.forums {
+		forums = append(forums, *forum)
+	}
+	sort.Slice(forums, func(i, j int) bool { return fold(forums[i].Slug) < fold(forums[j].Slug) })
+	return forums, nil
+}
+
+func (s *memoryForumStorage) GetSubforumTotals(ctx context.Context, slug string) (int64, int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var posts, threads int64
+	parents := []string{fold(slug)}
+	for len(parents) > 0 {
+		var next []string
+		for _, forum := range s.db.forums {
+			if forum.Parent != nil && slices.Contains(parents, fold(*forum.Parent)) {
+				posts += forum.Posts
+				threads += int64(forum.Threads)
+				next = append(next, fold(forum.Slug))
+			}
+		}
+		parents = next
+	}
+	return posts, threads, nil
+}
+
+func (s *memoryForumStorage) IncrementForumThreadsCount(ctx context.Context, forumSlug string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	forum, ok := s.db.forums[fold(forumSlug)]
+	if !ok {
+		return models.ErrNotFound
+	}
+	forum.Threads++
+	return nil
+}
+
+func (s *memoryForumStorage) GetThreadBySlug(ctx context.Context, slug string) (*models.Thread, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	threadID, ok := s.db.threadsBySlug[fold(slug)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return copyThread(s.db.threads[threadID]), nil
+}
+
+func (s *memoryForumStorage) CreateThread(ctx context.Context, thread *models.Thread) (*models.Thread, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	author, ok := s.db.users[fold(thread.Author)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	forum, ok := s.db.forums[fold(thread.Forum)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	var slug *string
+	if thread.Slug != nil && *thread.Slug != "" {
+		if _, exists := s.db.threadsBySlug[fold(*thread.Slug)]; exists {
+			return nil, models.ErrThreadConflict
+		}
+		value := *thread.Slug
+		slug = &value
+	}
+
+	createdTime := time.Now()
+	if !thread.Created.IsZero() {
+		createdTime = thread.Created
+	}
+
+	s.db.nextThreadID++
+	stored := &models.Thread{
+		ID:      s.db.nextThreadID,
+		Title:   thread.Title,
+		Author:  author.Nickname,
+		Forum:   forum.Slug,
+		Message: thread.Message,
+		Slug:    slug,
+		Created: createdTime,
+		State:   models.ThreadStateOpen,
+		Tags:    append([]string(nil), thread.Tags...),
+	}
+	s.db.threads[stored.ID] = stored
+	if slug != nil {
+		s.db.threadsBySlug[fold(*slug)] = stored.ID
+	}
+	s.db.addForumUser(forum.Slug, author.Nickname)
+
+	created := copyThread(stored)
+	if thread.Poll != nil {
+		poll := &models.Poll{
+			Question: thread.Poll.Question,
+			Multiple: thread.Poll.Multiple,
+			Closes:   thread.Poll.Closes,
+			Options:  make([]models.PollOption, 0, len(thread.Poll.Options)),
+		}
+		for i, option := range thread.Poll.Options {
+			poll.Options = append(poll.Options, models.PollOption{ID: i + 1, Text: option.Text})
+		}
+		s.db.polls[stored.ID] = poll
+		created.Poll = s.db.threadPoll(stored.ID)
+	}
+	s.db.enqueueWebhookEvent(forum.Slug, models.WebhookEventThreadCreated, created)
+
+	return created, nil
+}
+
+func (s *memoryForumStorage) GetThreadByID(ctx context.Context, id uuid.UUID) (*models.Thread, error) {
+	// Ветки нумеруются целыми числами, поэтому UUID никогда не совпадёт.
+	return nil, models.ErrNotFound
+}
+
+func (s *memoryForumStorage) GetThreadsByForumSlug(ctx context.Context, forumSlug string, tags []string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	return s.queryThreads(threadFilter{forum: forumSlug, tags: tags}, limit, since, desc), nil
+}
+
+func (s *memoryForumStorage) GetThreadsByTag(ctx context.Context, tag string, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	return s.queryThreads(threadFilter{tags: []string{tag}}, limit, since, desc), nil
+}
+
+func (s *memoryForumStorage) GetFeaturedThreads(ctx context.Context, limit int, since *time.Time, desc bool) ([]models.Thread, error) {
+	return s.queryThreads(threadFilter{featured: true}, limit, since, desc), nil
+}
+
+type threadFilter struct {
+	forum    string
+	tags     []string
+	featured bool
+}
+
+// queryThreads повторяет порядок PostgreSQL: в списке форума все закреплённые ветки идут первыми
+// на любой странице, а since и limit действуют только на незакреплённые.
+func (s *memoryForumStorage) queryThreads(filter threadFilter, limit int, since *time.Time, desc bool) []models.Thread {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	pinned := make([]models.Thread, 0)
+	threads := make([]models.Thread, 0)
+	for _, thread := range s.db.threads {
+		if thread.State == models.ThreadStateDeleted || !hasTags(thread, filter.tags) {
+			continue
+		}
+		if filter.forum != "" && fold(thread.Forum) != fold(filter.forum) {
+			continue
+		}
+		if filter.featured && !thread.Featured {
+			continue
+		}
+		if filter.forum != "" && thread.Pinned {
+			pinned = append(pinned, *copyThread(thread))
+			continue
+		}
+		if since != nil {
+			if desc && thread.Created.After(*since) {
+				continue
+			}
+			if !desc && thread.Created.Before(*since) {
+				continue
+			}
+		}
+		threads = append(threads, *copyThread(thread))
+	}
+
+	sortThreads(pinned, desc)
+	sortThreads(threads, desc)
+
+	if limit >= 0 && len(threads) > limit {
+		threads = threads[:limit]
+	}
+	return append(pinned, threads...)
+}
+
+func sortThreads(threads []models.Thread, desc bool) {
+	sort.Slice(threads, func(i, j int) bool {
+		if !threads[i].Created.Equal(threads[j].Created) {
+			if desc {
+				return threads[i].Created.After(threads[j].Created)
+			}
+			return threads[i].Created.Before(threads[j].Created)
+		}
+		if desc {
+			return threads[i].ID > threads[j].ID
+		}
+		return threads[i].ID < threads[j].ID
+	})
+}
+
+func (s *memoryForumStorage) GetForumTags(ctx context.Context, forumSlug string) ([]models.TagCount, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	byTag := make(map[string]int64)
+	for _, thread := range s.db.threads {
+		if fold(thread.Forum) != fold(forumSlug) || thread.State == models.ThreadStateDeleted {
+			continue
+		}
+		for _, tag := range thread.Tags {
+			byTag[tag]++
+		}
+	}
+
+	counts := make([]models.TagCount, 0, len(byTag))
+	for tag, threads := range byTag {
+		counts = append(counts, models.TagCount{Tag: tag, Threads: threads})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Threads != counts[j].Threads {
+			return counts[i].Threads > counts[j].Threads
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+	return counts, nil
+}
+
+func (s *memoryForumStorage) GetForumUsers(ctx context.Context, slug string, limit int, since string, desc bool, excludeBanned bool) ([]models.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+	users := make([]models.User, 0)
+	for nickname := range s.db.forumUsers[fold(slug)] {
+		if excludeBanned && s.db.isBanned(slug, nickname, now) {
+			continue
+		}
+		if since != "" {
+			if desc && nickname >= fold(since) {
+				continue
+			}
+			if !desc && nickname <= fold(since) {
+				continue
+			}
+		}
+		user, ok := s.db.users[nickname]
+		if !ok {
+			continue
+		}
+		users = append(users, *user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		a, b := fold(users[i].Nickname), fold(users[j].Nickname)
+		if a == b {
+			a, b = users[i].Nickname, users[j].Nickname
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+
+	if limit >= 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryModerationStorage struct {
+	db *DB
+}
+
+func NewModerationStorage(db *DB) storage.ModerationStorage {
+	return &memoryModerationStorage{db: db}
+}
+
+func (s *memoryModerationStorage) IsSiteAdmin(ctx context.Context, nickname string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	if _, ok := s.db.users[fold(nickname)]; !ok {
+		return false, models.ErrNotFound
+	}
+	return s.db.admins[fold(nickname)], nil
+}
+
+func (s *memoryModerationStorage) SetSiteAdmin(ctx context.Context, nickname string, isAdmin bool) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[fold(nickname)]; !ok {
+		return models.ErrNotFound
+	}
+	if isAdmin {
+		s.db.admins[fold(nickname)] = true
+	} else {
+		delete(s.db.admins, fold(nickname))
+	}
+	return nil
+}
+
+func (s *memoryModerationStorage) GetForumRole(ctx context.Context, forumSlug string, nickname string) (string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	user, ok := s.db.users[fold(nickname)]
+	if !ok {
+		return "", models.ErrNotFound
+	}
+	forum, ok := s.db.forums[fold(forumSlug)]
+	if !ok {
+		return "", models.ErrNotFound
+	}
+
+	switch {
+	case s.db.admins[fold(user.Nickname)]:
+		return models.RoleAdmin, nil
+	case fold(forum.User) == fold(user.Nickname):
+		return models.RoleOwner, nil
+	}
+	if _, ok := s.db.moderators[fold(forum.Slug)][fold(user.Nickname)]; ok {
+		return models.RoleModerator, nil
+	}
+	return models.RoleMember, nil
+}
+
+func (s *memoryModerationStorage) GetForumModerators(ctx context.Context, forumSlug string) ([]models.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	users := make([]models.User, 0, len(s.db.moderators[fold(forumSlug)]))
+	for nickname := range s.db.moderators[fold(forumSlug)] {
+		users = append(users, *s.db.users[nickname])
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return fold(users[i].Nickname) < fold(users[j].Nickname)
+	})
+	return users, nil
+}
+
+func (s *memoryModerationStorage) AddForumModerator(ctx context.Context, forumSlug string, nickname string, grantedBy string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[fold(nickname)]; !ok {
+		return models.ErrNotFound
+	}
+	if _, ok := s.db.forums[fold(forumSlug)]; !ok {
+		return models.ErrNotFound
+	}
+	moderators, ok := s.db.moderators[fold(forumSlug)]
+	if !ok {
+		moderators = make(map[string]struct{})
+		s.db.moderators[fold(forumSlug)] = moderators
+	}
+	moderators[fold(nickname)] = struct{}{}
+	return nil
+}
+
+func (s *memoryModerationStorage) RemoveForumModerator(ctx context.Context, forumSlug string, nickname string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.moderators[fold(forumSlug)][fold(nickname)]; !ok {
+		return models.ErrNotFound
+	}
+	delete(s.db.moderators[fold(forumSlug)], fold(nickname))
+	return nil
+}
+
+func (s *memoryModerationStorage) BanUser(ctx context.Context, ban models.ForumBan) (*models.ForumBan, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[fold(ban.User)]; !ok {
+		return nil, models.ErrNotFound
+	}
+	if _, ok := s.db.forums[fold(ban.Forum)]; !ok {
+		return nil, models.ErrNotFound
+	}
+
+	bans, ok := s.db.bans[fold(ban.Forum)]
+	if !ok {
+		bans = make(map[string]*models.ForumBan)
+		s.db.bans[fold(ban.Forum)] = bans
+	}
+	if existing, ok := bans[fold(ban.User)]; ok {
+		ban.Created = existing.Created
+	} else {
+		ban.Created = time.Now()
+	}
+	stored := ban
+	bans[fold(ban.User)] = &stored
+	return &ban, nil
+}
+
+func (s *memoryModerationStorage) UnbanUser(ctx context.Context, forumSlug string, nickname string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.bans[fold(forumSlug)][fold(nickname)]; !ok {
+		return models.ErrNotFound
+	}
+	delete(s.db.bans[fold(forumSlug)], fold(nickname))
+	return nil
+}
+
+func (s *memoryModerationStorage) GetBannedUsers(ctx context.Context, forumSlug string, nicknames []string) ([]string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+	banned := make([]string, 0)
+	for _, nickname := range nicknames {
+		if s.db.isBanned(forumSlug, nickname, now) {
+			banned = append(banned, s.db.users[fold(nickname)].Nickname)
+		}
+	}
+	return banned, nil
+}
+
+func (s *memoryModerationStorage) GetForumBans(ctx context.Context, forumSlug string) ([]models.ForumBan, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+	bans := make([]models.ForumBan, 0)
+	for nickname, ban := range s.db.bans[fold(forumSlug)] {
+		if s.db.isBanned(forumSlug, nickname, now) {
+			bans = append(bans, *ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		if !bans[i].Created.Equal(bans[j].Created) {
+			return bans[i].Created.After(bans[j].Created)
+		}
+		return fold(bans[i].User) < fold(bans[j].User)
+	})
+	return bans, nil
+}
+
+func (s *memoryModerationStorage) SuspendUser(ctx context.Context, suspension models.Suspension) (*models.Suspension, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[fold(suspension.User)]; !ok {
+		return nil, models.ErrNotFound
+	}
+	suspension.Created = time.Now()
+	stored := suspension
+	s.db.suspensions[fold(suspension.User)] = &stored
+	return &suspension, nil
+}
+
+func (s *memoryModerationStorage) UnsuspendUser(ctx context.Context, nickname string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.suspensions[fold(nickname)]; !ok {
+		return models.ErrNotFound
+	}
+	delete(s.db.suspensions, fold(nickname))
+	return nil
+}
+
+func (s *memoryModerationStorage) GetSuspendedUsers(ctx context.Context, nicknames []string) ([]string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+	suspended := make([]string, 0)
+	for _, nickname := range nicknames {
+		suspension, ok := s.db.suspensions[fold(nickname)]
+		if ok && (suspension.Until == nil || suspension.Until.After(now)) {
+			suspended = append(suspended, s.db.users[fold(nickname)].Nickname)
+		}
+	}
+	return suspended, nil
+}
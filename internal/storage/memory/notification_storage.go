@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryNotificationStorage struct {
+	db *DB
+}
+
+func NewNotificationStorage(db *DB) storage.NotificationStorage {
+	return &memoryNotificationStorage{db: db}
+}
+
+func (s *memoryNotificationStorage) SubscribeThread(ctx context.Context, threadID int64, nickname string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := fold(nickname)
+	if _, ok := s.db.threads[threadID]; !ok {
+		return models.ErrNotFound
+	}
+	if _, ok := s.db.users[key]; !ok {
+		return models.ErrNotFound
+	}
+	addSubscriber(s.db.threadSubscriptions, threadID, key)
+	return nil
+}
+
+func (s *memoryNotificationStorage) UnsubscribeThread(ctx context.Context, threadID int64, nickname string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	delete(s.db.threadSubscriptions[threadID], fold(nickname))
+	return nil
+}
+
+func (s *memoryNotificationStorage) SubscribeForum(ctx context.Context, forumSlug string, nickname string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key := fold(nickname)
+	if _, ok := s.db.forums[fold(forumSlug)]; !ok {
+		return models.ErrNotFound
+	}
+	if _, ok := s.db.users[key]; !ok {
+		return models.ErrNotFound
+	}
+	addSubscriber(s.db.forumSubscriptions, fold(forumSlug), key)
+	return nil
+}
+
+func (s *memoryNotificationStorage) UnsubscribeForum(ctx context.Context, forumSlug string, nickname string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	delete(s.db.forumSubscriptions[fold(forumSlug)], fold(nickname))
+	return nil
+}
+
+func addSubscriber[K comparable](subscriptions map[K]map[string]struct{}, target K, key string) {
+	subscribers := subscriptions[target]
+	if subscribers == nil {
+		subscribers = make(map[string]struct{})
+		subscriptions[target] = subscribers
+	}
+	subscribers[key] = struct{}{}
+}
+
+func (s *memoryNotificationStorage) GetSubscriptions(ctx context.Context, nickname string) (*models.Subscriptions, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	key := fold(nickname)
+	subscriptions := &models.Subscriptions{Threads: make([]int64, 0), Forums: make([]string, 0)}
+	for threadID, subscribers := range s.db.threadSubscriptions {
+		if _, ok := subscribers[key]; ok {
+			subscriptions.Threads = append(subscriptions.Threads, threadID)
+		}
+	}
+	for forumKey, subscribers := range s.db.forumSubscriptions {
+		if _, ok := subscribers[key]; ok {
+			subscriptions.Forums = append(subscriptions.Forums, s.db.forums[forumKey].Slug)
+		}
+	}
+	slices.Sort(subscriptions.Threads)
+	sort.Strings(subscriptions.Forums)
+	return subscriptions, nil
+}
+
+func (s *memoryNotificationStorage) GetNotifications(ctx context.Context, nickname string, unread bool, limit int, before int64) ([]models.Notification, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	stored := s.db.notifications[fold(nickname)]
+	notifications := make([]models.Notification, 0)
+	for i := len(stored) - 1; i >= 0 && len(notifications) < limit; i-- {
+		n := stored[i]
+		if (before != 0 && n.ID >= before) || (unread && n.Read) {
+			continue
+		}
+		post, ok := s.db.posts[n.Post]
+		if !ok {
+			continue
+		}
+		c := *n
+		c.Thread, c.Forum, c.Author = post.Thread, post.Forum, post.Author
+		notifications = append(notifications, c)
+	}
+	return notifications, nil
+}
+
+func (s *memoryNotificationStorage) MarkNotificationsRead(ctx context.Context, nickname string, ids []int64) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var marked int64
+	for _, n := range s.db.notifications[fold(nickname)] {
+		if (len(ids) == 0 && !n.Read) || slices.Contains(ids, n.ID) {
+			n.Read = true
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (s *memoryNotificationStorage) NotifyPendingPosts(ctx context.Context, limit int) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	batch := s.db.postNotificationQueue[:min(limit, len(s.db.postNotificationQueue))]
+	s.db.postNotificationQueue = s.db.postNotificationQueue[len(batch):]
+
+	now := time.Now()
+	for _, postID := range batch {
+		post, ok := s.db.posts[postID]
+		if !ok || post.IsDeleted || s.db.threadDeleted(post.Thread) {
+			continue
+		}
+		// Порядок важен: первое уведомление на пост вытесняет остальные.
+		for _, key := range s.db.postMentions[postID] {
+			s.db.addNotification(key, models.NotificationKindMention, post, now)
+		}
+		if parent, ok := s.db.posts[post.Parent]; ok {
+			s.db.addNotification(fold(parent.Author), models.NotificationKindReply, post, now)
+		}
+		for key := range s.db.threadSubscriptions[post.Thread] {
+			s.db.addNotification(key, models.NotificationKindSubscription, post, now)
+		}
+		for key := range s.db.forumSubscriptions[fold(post.Forum)] {
+			s.db.addNotification(key, models.NotificationKindSubscription, post, now)
+		}
+	}
+	return len(batch), nil
+}
+
+// addNotification пропускает автора поста, служебного пользователя и повторы. Посты
+// разбираются по порядку, поэтому уведомление о том же посте может быть только последним.
+func (db *DB) addNotification(key string, kind string, post *models.Post, now time.Time) {
+	if _, ok := db.users[key]; !ok || key == fold(post.Author) || key == fold(models.DeletedUserNickname) {
+		return
+	}
+	notifications := db.notifications[key]
+	if n := len(notifications); n > 0 && notifications[n-1].Post == post.ID {
+		return
+	}
+
+	db.nextNotificationID++
+	db.notifications[key] = append(notifications, &models.Notification{
+		ID:      db.nextNotificationID,
+		Kind:    kind,
+		Post:    post.ID,
+		Created: now,
+	})
+}
@@ -0,0 +1,267 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryPostStorage struct {
+	db *DB
+}
+
+func NewPostStorage(db *DB) storage.PostStorage {
+	return &memoryPostStorage{db: db}
+}
+
+func (s *memoryPostStorage) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	post, ok := s.db.posts[id]
+	if !ok || s.db.threadDeleted(post.Thread) {
+		return nil, models.ErrNotFound
+	}
+	found := copyPost(post)
+	return &found, nil
+}
+
+func (s *memoryPostStorage) GetPostsByIDs(ctx context.Context, ids []int64) ([]models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	posts := make([]models.Post, 0, len(ids))
+	for _, id := range ids {
+		if post, ok := s.db.posts[id]; ok {
+			posts = append(posts, copyPost(post))
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+	return posts, nil
+}
+
+func (s *memoryPostStorage) GetThreadPostsAfter(ctx context.Context, threadID int64, afterID int64, limit int) ([]models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	// threadPosts хранит id в порядке вставки, то есть по возрастанию.
+	posts := make([]models.Post, 0)
+	for _, id := range s.db.threadPosts[threadID] {
+		if id <= afterID {
+			continue
+		}
+		if len(posts) >= limit {
+			break
+		}
+		posts = append(posts, copyPost(s.db.posts[id]))
+	}
+	return posts, nil
+}
+
+func (s *memoryPostStorage) UpdatePostMessage(ctx context.Context, id int64, newMessage string, editor string, mentions []string) (*models.Post, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	post, ok := s.db.posts[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	if user, ok := s.db.users[fold(editor)]; ok {
+		editor = user.Nickname
+	}
+	s.db.nextRevisionID++
+	s.db.postRevisions[id] = append(s.db.postRevisions[id], models.PostRevision{
+		ID:      s.db.nextRevisionID,
+		Post:    id,
+		Editor:  editor,
+		Message: post.Message,
+		Created: time.Now(),
+	})
+	post.Message = newMessage
+	post.IsEdited = true
+	s.db.setPostMentions(id, mentions)
+
+	updated := copyPost(post)
+	s.db.enqueueWebhookEvent(post.Forum, models.WebhookEventPostUpdated, updated)
+	return &updated, nil
+}
+
+func (s *memoryPostStorage) GetUserMentions(ctx context.Context, nickname string, limit int, before int64) ([]models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	key := fold(nickname)
+	ids := make([]int64, 0)
+	for postID, mentioned := range s.db.postMentions {
+		if (before != 0 && postID >= before) || !slices.Contains(mentioned, key) {
+			continue
+		}
+		if post := s.db.posts[postID]; !post.IsDeleted && !s.db.threadDeleted(post.Thread) {
+			ids = append(ids, postID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	posts := make([]models.Post, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, copyPost(s.db.posts[id]))
+	}
+	return posts, nil
+}
+
+func (s *memoryPostStorage) GetPostRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return append(make([]models.PostRevision, 0), s.db.postRevisions[postID]...), nil
+}
+
+func (s *memoryPostStorage) GetPostRevision(ctx context.Context, postID int64, revisionID int64) (*models.PostRevision, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, revision := range s.db.postRevisions[postID] {
+		if revision.ID == revisionID {
+			found := revision
+			return &found, nil
+		}
+	}
+	return nil, models.ErrNotFound
+}
+
+func (s *memoryPostStorage) DeletePost(ctx context.Context, id int64) (*models.Post, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	post, ok := s.db.posts[id]
+	if !ok || s.db.threadDeleted(post.Thread) {
+		return nil, models.ErrNotFound
+	}
+	if !post.IsDeleted {
+		post.IsDeleted = true
+		if forum, ok := s.db.forums[fold(post.Forum)]; ok {
+			forum.Posts--
+		}
+	}
+
+	deleted := copyPost(post)
+	return &deleted, nil
+}
+
+func (s *memoryPostStorage) SetPostReaction(ctx context.Context, id int64, nickname string, kind string, active bool) (*models.Post, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	post, ok := s.db.posts[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	key := fold(nickname)
+	if _, ok := s.db.users[key]; !ok {
+		return nil, models.ErrOwnerNotFound
+	}
+
+	reactions := s.db.postReactions[id]
+	if reactions == nil {
+		reactions = make(map[string][]string)
+		s.db.postReactions[id] = reactions
+	}
+	kinds := slices.DeleteFunc(reactions[key], func(k string) bool {
+		return k == kind || (active && isOppositeVote(k, kind))
+	})
+	if active {
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		delete(reactions, key)
+	} else {
+		reactions[key] = kinds
+	}
+	s.db.recountPostReactions(post)
+
+	updated := copyPost(post)
+	return &updated, nil
+}
+
+func isOppositeVote(a, b string) bool {
+	return (a == models.ReactionUp && b == models.ReactionDown) || (a == models.ReactionDown && b == models.ReactionUp)
+}
+
+func (s *memoryPostStorage) PurgePostSubtree(ctx context.Context, id int64) (int64, []string, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	root, ok := s.db.posts[id]
+	if !ok {
+		return 0, nil, models.ErrNotFound
+	}
+	prefix := root.Path
+
+	var purged, purgedAlive int64
+	remaining := make([]int64, 0, len(s.db.threadPosts[root.Thread]))
+	for _, postID := range s.db.threadPosts[root.Thread] {
+		post := s.db.posts[postID]
+		if len(post.Path) < len(prefix) || comparePaths(post.Path[:len(prefix)], prefix) != 0 {
+			remaining = append(remaining, postID)
+			continue
+		}
+		purged++
+		if !post.IsDeleted {
+			purgedAlive++
+		}
+		delete(s.db.posts, postID)
+		delete(s.db.postRevisions, postID)
+		delete(s.db.postReactions, postID)
+		delete(s.db.postMentions, postID)
+	}
+	s.db.threadPosts[root.Thread] = remaining
+	keys := make([]string, 0)
+	for attachmentID, attachment := range s.db.attachments {
+		if _, ok := s.db.posts[attachment.Post]; attachment.Post != 0 && !ok {
+			keys = append(keys, attachment.Key)
+			delete(s.db.attachments, attachmentID)
+		}
+	}
+	for key, notifications := range s.db.notifications {
+		s.db.notifications[key] = slices.DeleteFunc(notifications, func(n *models.Notification) bool {
+			_, ok := s.db.posts[n.Post]
+			return !ok
+		})
+	}
+
+	// Посты удалённой ветки уже вычтены из счётчика форума при её удалении.
+	if forum, ok := s.db.forums[fold(root.Forum)]; ok && !s.db.threadDeleted(root.Thread) {
+		forum.Posts -= purgedAlive
+	}
+
+	return purged, keys, nil
+}
+
+func (s *memoryPostStorage) CountTableRows(ctx context.Context) (*models.Status, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	// Служебного пользователя для удалённых аккаунтов не считаем.
+	return &models.Status{
+		User:   len(s.db.users) - 1,
+		Forum:  len(s.db.forums),
+		Thread: len(s.db.threads),
+		Post:   len(s.db.posts),
+	}, nil
+}
+
+func (s *memoryPostStorage) ClearAllTables(ctx context.Context) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.reset()
+	return nil
+}
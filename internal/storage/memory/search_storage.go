@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+// snippetWords — сколько слов вокруг первого совпадения попадает в сниппет.
+const snippetWords = 20
+
+type memorySearchStorage struct {
+	db *DB
+}
+
+func NewSearchStorage(db *DB) storage.SearchStorage {
+	return &memorySearchStorage{db: db}
+}
+
+// Search повторяет поведение PostgreSQL-версии приближённо: все слова запроса
+// должны встретиться в тексте, ранг — доля совпавших слов, совпадения в заголовке ветки весят больше.
+func (s *memorySearchStorage) Search(ctx context.Context, query models.SearchQuery) ([]models.SearchHit, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	terms := searchTerms(query.Query)
+	if len(terms) == 0 {
+		return []models.SearchHit{}, nil
+	}
+
+	matchesFilters := func(forum, author string, created time.Time) bool {
+		if query.Forum != "" && fold(forum) != fold(query.Forum) {
+			return false
+		}
+		if query.Author != "" && fold(author) != fold(query.Author) {
+			return false
+		}
+		if query.Since != nil && created.Before(*query.Since) {
+			return false
+		}
+		return true
+	}
+
+	hits := make([]models.SearchHit, 0)
+	for _, post := range s.db.posts {
+		if post.IsDeleted || !matchesFilters(post.Forum, post.Author, post.Created) {
+			continue
+		}
+		if thread, ok := s.db.threads[post.Thread]; !ok || thread.State == models.ThreadStateDeleted {
+			continue
+		}
+		rank := termsRank(terms, post.Message)
+		if rank == 0 {
+			continue
+		}
+		found := copyPost(post)
+		hits = append(hits, models.SearchHit{
+			Kind:    models.SearchKindPost,
+			Rank:    rank,
+			Snippet: highlight(terms, post.Message),
+			Post:    &found,
+		})
+	}
+	for _, thread := range s.db.threads {
+		if thread.State == models.ThreadStateDeleted || !matchesFilters(thread.Forum, thread.Author, thread.Created) {
+			continue
+		}
+		body := thread.Title + " " + thread.Message
+		rank := termsRank(terms, body)
+		if rank == 0 {
+			continue
+		}
+		rank += termsRank(terms, thread.Title)
+		hits = append(hits, models.SearchHit{
+			Kind:    models.SearchKindThread,
+			Rank:    rank,
+			Snippet: highlight(terms, body),
+			Thread:  copyThread(thread),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return compareHits(hits[i], hits[j]) > 0
+	})
+
+	page := make([]models.SearchHit, 0, query.Limit)
+	for _, hit := range hits {
+		if len(page) >= query.Limit {
+			break
+		}
+		if query.After != nil && compareHits(hit, models.SearchHit{Rank: query.After.Rank, Kind: query.After.Kind, Post: &models.Post{ID: query.After.ID}}) >= 0 {
+			continue
+		}
+		page = append(page, hit)
+	}
+	return page, nil
+}
+
+// compareHits сравнивает кортежи (rank, kind, id) так же, как PostgreSQL в ORDER BY.
+func compareHits(a, b models.SearchHit) int {
+	switch {
+	case a.Rank != b.Rank:
+		if a.Rank < b.Rank {
+			return -1
+		}
+		return 1
+	case a.Kind != b.Kind:
+		return strings.Compare(a.Kind, b.Kind)
+	}
+	idA, idB := hitID(a), hitID(b)
+	switch {
+	case idA < idB:
+		return -1
+	case idA > idB:
+		return 1
+	}
+	return 0
+}
+
+func hitID(hit models.SearchHit) int64 {
+	if hit.Post != nil {
+		return hit.Post.ID
+	}
+	return hit.Thread.ID
+}
+
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// termsRank возвращает 0, если хотя бы одно слово запроса не найдено.
+func termsRank(terms []string, text string) float32 {
+	words := searchTerms(text)
+	if len(words) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(words))
+	for _, word := range words {
+		counts[word]++
+	}
+
+	matched := 0
+	for _, term := range terms {
+		if counts[term] == 0 {
+			return 0
+		}
+		matched += counts[term]
+	}
+	return float32(matched) / float32(len(words))
+}
+
+// highlight возвращает HTML: слова текста экранируются, совпадения оборачиваются в <b>.
+func highlight(terms []string, text string) string {
+	wanted := make(map[string]struct{}, len(terms))
+	for _, term := range terms {
+		wanted[term] = struct{}{}
+	}
+
+	words := strings.Fields(text)
+	first := -1
+	for i, word := range words {
+		words[i] = html.EscapeString(word)
+		for _, term := range searchTerms(word) {
+			if _, ok := wanted[term]; ok {
+				words[i] = "<b>" + words[i] + "</b>"
+				if first < 0 {
+					first = i
+				}
+				break
+			}
+		}
+	}
+
+	start := first - snippetWords/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+	}
+	return strings.Join(words[start:end], " ")
+}
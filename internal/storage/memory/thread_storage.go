@@ -0,0 +1,597 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryThreadStorage struct {
+	db *DB
+}
+
+func NewThreadStorage(db *DB) storage.ThreadStorage {
+	return &memoryThreadStorage{db: db}
+}
+
+func (s *memoryThreadStorage) GetThreadIDBySlugOrID(ctx context.Context, slugOrID string) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	idInt, _ := strconv.ParseInt(slugOrID, 10, 64)
+	thread := s.db.threadBySlugOrID(slugOrID, idInt)
+	if thread == nil {
+		return 0, models.ErrNotFound
+	}
+	return thread.ID, nil
+}
+
+func (s *memoryThreadStorage) CheckParentPostExistsInThread(ctx context.Context, parentID int64, threadID int64) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	post, ok := s.db.posts[parentID]
+	return ok && post.Thread == threadID, nil
+}
+
+func (s *memoryThreadStorage) CreatePosts(ctx context.Context, posts []*models.Post) ([]models.Post, error) {
+	if len(posts) == 0 {
+		return []models.Post{}, nil
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	thread, ok := s.db.threads[posts[0].Thread]
+	if !ok || thread.State == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+	if thread.State == models.ThreadStateLocked || thread.State == models.ThreadStateArchived {
+		return nil, models.ErrThreadLocked
+	}
+	forum := s.db.forums[fold(thread.Forum)]
+
+	// Проверяем всё до вставки, чтобы пачка применялась целиком или никак.
+	claimed := make(map[int64]struct{})
+	for i, p := range posts {
+		if _, ok := s.db.users[fold(p.Author)]; !ok {
+			return nil, models.ErrNotFound
+		}
+		for _, attachment := range p.Attachments {
+			stored, ok := s.db.attachments[attachment.ID]
+			if _, dup := claimed[attachment.ID]; dup || !ok || stored.Post != 0 || fold(stored.Owner) != fold(p.Author) {
+				return nil, models.ErrInvalidAttachment
+			}
+			claimed[attachment.ID] = struct{}{}
+		}
+		if p.Parent == 0 {
+			continue
+		}
+		if parent, ok := s.db.posts[p.Parent]; ok && parent.Thread == thread.ID {
+			continue
+		}
+		if p.Parent > s.db.nextPostID && p.Parent <= s.db.nextPostID+int64(i) {
+			continue
+		}
+		return nil, models.ErrParentNotFound
+	}
+
+	created := time.Now()
+	result := make([]models.Post, 0, len(posts))
+	for _, p := range posts {
+		s.db.nextPostID++
+		author := s.db.users[fold(p.Author)]
+		stored := &models.Post{
+			ID:      s.db.nextPostID,
+			Parent:  p.Parent,
+			Author:  author.Nickname,
+			Message: p.Message,
+			Forum:   thread.Forum,
+			Thread:  thread.ID,
+			Created: created,
+		}
+		for _, attachment := range p.Attachments {
+			bound := s.db.attachments[attachment.ID]
+			bound.Post = stored.ID
+			stored.Attachments = append(stored.Attachments, models.PostAttachment{
+				ID:          bound.ID,
+				Filename:    bound.Filename,
+				ContentType: bound.ContentType,
+				Size:        bound.Size,
+			})
+		}
+		s.db.posts[stored.ID] = stored
+		s.db.threadPosts[thread.ID] = append(s.db.threadPosts[thread.ID], stored.ID)
+		s.db.setPostMentions(stored.ID, p.Mentions)
+		s.db.addForumUser(thread.Forum, author.Nickname)
+
+		p.Created = created
+		p.Forum = thread.Forum
+	}
+
+	for id := s.db.nextPostID - int64(len(posts)) + 1; id <= s.db.nextPostID; id++ {
+		stored := s.db.posts[id]
+		if stored.Parent == 0 {
+			stored.Path = []int64{stored.ID}
+			stored.RootParentID = stored.ID
+		} else {
+			parent := s.db.posts[stored.Parent]
+			stored.Path = append(append([]int64(nil), parent.Path...), stored.ID)
+			stored.RootParentID = parent.RootParentID
+		}
+		result = append(result, copyPost(stored))
+	}
+
+	if forum != nil {
+		forum.Posts += int64(len(posts))
+	}
+
+	createdIDs := make([]int64, len(result))
+	for i, p := range result {
+		createdIDs[i] = p.ID
+	}
+	s.db.postNotificationQueue = append(s.db.postNotificationQueue, createdIDs...)
+	s.db.enqueueWebhookEvent(thread.Forum, models.WebhookEventPostCreated, result)
+
+	return result, nil
+}
+
+func (s *memoryThreadStorage) UpdateThreadVote(ctx context.Context, threadID int64, nickname string, voice int) (*models.Thread, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	thread, ok := s.db.threads[threadID]
+	if !ok || thread.State == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+	if thread.State == models.ThreadStateLocked || thread.State == models.ThreadStateArchived {
+		return nil, models.ErrThreadLocked
+	}
+
+	key := voteKey{threadID: threadID, nickname: fold(nickname)}
+	oldVoice, voted := s.db.votes[key]
+	s.db.votes[key] = voice
+	if voted {
+		thread.Votes += int32(voice - oldVoice)
+	} else {
+		thread.Votes += int32(voice)
+	}
+
+	s.db.enqueueWebhookEvent(thread.Forum, models.WebhookEventThreadVoted, copyThread(thread))
+
+	return copyThread(thread), nil
+}
+
+func (s *memoryThreadStorage) GetThreadBySlugOrID(ctx context.Context, slugOrID string) (*models.Thread, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	idInt, _ := strconv.ParseInt(slugOrID, 10, 64)
+	thread := s.db.threadBySlugOrID(slugOrID, idInt)
+	if thread == nil {
+		return nil, models.ErrNotFound
+	}
+	return copyThread(thread), nil
+}
+
+func (s *memoryThreadStorage) threadPosts(threadID int64) []*models.Post {
+	ids := s.db.threadPosts[threadID]
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, s.db.posts[id])
+	}
+	return posts
+}
+
+func (s *memoryThreadStorage) GetFlatThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	less := func(a, b *models.Post) bool {
+		if !a.Created.Equal(b.Created) {
+			return a.Created.Before(b.Created)
+		}
+		return a.ID < b.ID
+	}
+
+	var sincePost *models.Post
+	if since > 0 {
+		p, ok := s.db.posts[since]
+		if !ok || p.Thread != threadID {
+			return []models.Post{}, nil
+		}
+		sincePost = p
+	}
+
+	selected := make([]*models.Post, 0)
+	for _, p := range s.threadPosts(threadID) {
+		if sincePost != nil {
+			if desc && !less(p, sincePost) {
+				continue
+			}
+			if !desc && !less(sincePost, p) {
+				continue
+			}
+		}
+		selected = append(selected, p)
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		if desc {
+			return less(selected[j], selected[i])
+		}
+		return less(selected[i], selected[j])
+	})
+
+	return limitPosts(selected, limit), nil
+}
+
+func (s *memoryThreadStorage) GetTreeThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var sincePath []int64
+	if since > 0 {
+		p, ok := s.db.posts[since]
+		if !ok || p.Thread != threadID {
+			return []models.Post{}, nil
+		}
+		sincePath = p.Path
+	}
+
+	selected := make([]*models.Post, 0)
+	for _, p := range s.threadPosts(threadID) {
+		if sincePath != nil {
+			cmp := comparePaths(p.Path, sincePath)
+			if desc && cmp >= 0 {
+				continue
+			}
+			if !desc && cmp <= 0 {
+				continue
+			}
+		}
+		selected = append(selected, p)
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		cmp := comparePaths(selected[i].Path, selected[j].Path)
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	return limitPosts(selected, limit), nil
+}
+
+func (s *memoryThreadStorage) GetParentTreeThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var sinceRootID int64
+	if since > 0 {
+		p, ok := s.db.posts[since]
+		if !ok || p.Thread != threadID {
+			return []models.Post{}, nil
+		}
+		sinceRootID = p.RootParentID
+	}
+
+	threadPosts := s.threadPosts(threadID)
+
+	roots := make([]int64, 0)
+	for _, p := range threadPosts {
+		if p.Parent != 0 {
+			continue
+		}
+		if since > 0 {
+			if desc && p.ID >= sinceRootID {
+				continue
+			}
+			if !desc && p.ID <= sinceRootID {
+				continue
+			}
+		}
+		roots = append(roots, p.ID)
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		if desc {
+			return roots[i] > roots[j]
+		}
+		return roots[i] < roots[j]
+	})
+	if limit > 0 && len(roots) > limit {
+		roots = roots[:limit]
+	}
+
+	rootOrder := make(map[int64]int, len(roots))
+	for i, id := range roots {
+		rootOrder[id] = i
+	}
+
+	selected := make([]*models.Post, 0)
+	for _, p := range threadPosts {
+		if _, ok := rootOrder[p.RootParentID]; ok {
+			selected = append(selected, p)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		a, b := selected[i], selected[j]
+		if a.RootParentID != b.RootParentID {
+			return rootOrder[a.RootParentID] < rootOrder[b.RootParentID]
+		}
+		return comparePaths(a.Path, b.Path) < 0
+	})
+
+	return limitPosts(selected, 0), nil
+}
+
+func (s *memoryThreadStorage) GetTopThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	// before сравнивает корни в порядке выдачи: по убыванию score, при равенстве — по id.
+	before := func(a, b *models.Post) bool {
+		if a.Score != b.Score {
+			return (a.Score > b.Score) != desc
+		}
+		// Корень не идёт раньше самого себя, иначе since с desc вернул бы и его.
+		return a.ID != b.ID && (a.ID < b.ID) != desc
+	}
+
+	var sinceRoot *models.Post
+	if since > 0 {
+		p, ok := s.db.posts[since]
+		if !ok || p.Thread != threadID {
+			return []models.Post{}, nil
+		}
+		sinceRoot = s.db.posts[p.RootParentID]
+	}
+
+	threadPosts := s.threadPosts(threadID)
+
+	roots := make([]*models.Post, 0)
+	for _, p := range threadPosts {
+		if p.Parent != 0 {
+			continue
+		}
+		if sinceRoot != nil && !before(sinceRoot, p) {
+			continue
+		}
+		roots = append(roots, p)
+	}
+
+	sort.Slice(roots, func(i, j int) bool { return before(roots[i], roots[j]) })
+	if limit > 0 && len(roots) > limit {
+		roots = roots[:limit]
+	}
+
+	rootOrder := make(map[int64]int, len(roots))
+	for i, root := range roots {
+		rootOrder[root.ID] = i
+	}
+
+	selected := make([]*models.Post, 0)
+	for _, p := range threadPosts {
+		if _, ok := rootOrder[p.RootParentID]; ok {
+			selected = append(selected, p)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		a, b := selected[i], selected[j]
+		if a.RootParentID != b.RootParentID {
+			return rootOrder[a.RootParentID] < rootOrder[b.RootParentID]
+		}
+		return comparePaths(a.Path, b.Path) < 0
+	})
+
+	return limitPosts(selected, 0), nil
+}
+
+func (s *memoryThreadStorage) UpdateThread(ctx context.Context, slugOrID string, updateData models.ThreadUpdate, editor string) (models.Thread, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	idInt, _ := strconv.ParseInt(slugOrID, 10, 64)
+	thread := s.db.threadBySlugOrID(slugOrID, idInt)
+	if thread == nil {
+		return models.Thread{}, models.ErrNotFound
+	}
+
+	titleChanged := updateData.Title != nil && *updateData.Title != thread.Title
+	messageChanged := updateData.Message != nil && *updateData.Message != thread.Message
+	slugChanged := updateData.Slug != nil && (thread.Slug == nil || *updateData.Slug != *thread.Slug)
+	tagsChanged := updateData.Tags != nil && !slices.Equal(*updateData.Tags, thread.Tags)
+	if !titleChanged && !messageChanged && !slugChanged && !tagsChanged {
+		return *copyThread(thread), nil
+	}
+
+	if slugChanged {
+		if ownerID, taken := s.db.threadsBySlug[fold(*updateData.Slug)]; taken && ownerID != thread.ID {
+			return models.Thread{}, models.ErrThreadConflict
+		}
+	}
+
+	previous := copyThread(thread)
+	if titleChanged || messageChanged || slugChanged {
+		if user, ok := s.db.users[fold(editor)]; ok {
+			editor = user.Nickname
+		}
+		s.db.nextThreadRevisionID++
+		s.db.threadRevisions[thread.ID] = append(s.db.threadRevisions[thread.ID], models.ThreadRevision{
+			ID:      s.db.nextThreadRevisionID,
+			Thread:  thread.ID,
+			Editor:  editor,
+			Title:   previous.Title,
+			Message: previous.Message,
+			Slug:    previous.Slug,
+			Created: time.Now(),
+		})
+	}
+
+	if tagsChanged {
+		thread.Tags = append([]string(nil), *updateData.Tags...)
+	}
+
+	if titleChanged {
+		thread.Title = *updateData.Title
+	}
+	if messageChanged {
+		thread.Message = *updateData.Message
+	}
+	if slugChanged {
+		slug := *updateData.Slug
+		thread.Slug = &slug
+		delete(s.db.threadSlugAliases, fold(slug))
+		if previous.Slug != nil {
+			delete(s.db.threadsBySlug, fold(*previous.Slug))
+			if fold(*previous.Slug) != fold(slug) {
+				s.db.threadSlugAliases[fold(*previous.Slug)] = thread.ID
+			}
+		}
+		s.db.threadsBySlug[fold(slug)] = thread.ID
+	}
+
+	return *copyThread(thread), nil
+}
+
+func (s *memoryThreadStorage) GetThreadRevisions(ctx context.Context, threadID int64) ([]models.ThreadRevision, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	revisions := make([]models.ThreadRevision, 0, len(s.db.threadRevisions[threadID]))
+	for _, revision := range s.db.threadRevisions[threadID] {
+		if revision.Slug != nil {
+			slug := *revision.Slug
+			revision.Slug = &slug
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+func (s *memoryThreadStorage) GetThreadByID(ctx context.Context, id int64) (*models.Thread, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	thread, ok := s.db.threads[id]
+	if !ok || thread.State == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+	return copyThread(thread), nil
+}
+
+func (s *memoryThreadStorage) SetThreadState(ctx context.Context, threadID int64, state string) (*models.Thread, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	thread, ok := s.db.threads[threadID]
+	if !ok || thread.State == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+	thread.State = state
+
+	if state == models.ThreadStateDeleted {
+		if forum, ok := s.db.forums[fold(thread.Forum)]; ok {
+			forum.Threads--
+			for _, p := range s.threadPosts(threadID) {
+				if !p.IsDeleted {
+					forum.Posts--
+				}
+			}
+		}
+	}
+
+	return copyThread(thread), nil
+}
+
+func (s *memoryThreadStorage) SetThreadPinned(ctx context.Context, threadID int64, pinned bool) (*models.Thread, error) {
+	return s.setThreadFlag(threadID, func(thread *models.Thread) { thread.Pinned = pinned })
+}
+
+func (s *memoryThreadStorage) SetThreadFeatured(ctx context.Context, threadID int64, featured bool) (*models.Thread, error) {
+	return s.setThreadFlag(threadID, func(thread *models.Thread) { thread.Featured = featured })
+}
+
+func (s *memoryThreadStorage) setThreadFlag(threadID int64, set func(thread *models.Thread)) (*models.Thread, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	thread, ok := s.db.threads[threadID]
+	if !ok || thread.State == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+	set(thread)
+	return copyThread(thread), nil
+}
+
+func limitPosts(posts []*models.Post, limit int) []models.Post {
+	if limit > 0 && len(posts) > limit {
+		posts = posts[:limit]
+	}
+	result := make([]models.Post, 0, len(posts))
+	for _, p := range posts {
+		result = append(result, copyPost(p))
+	}
+	return result
+}
+
+func (s *memoryThreadStorage) GetThreadPoll(ctx context.Context, threadID int64) (*models.Poll, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	poll := s.db.threadPoll(threadID)
+	if poll == nil {
+		return nil, models.ErrNotFound
+	}
+	return poll, nil
+}
+
+func (s *memoryThreadStorage) VoteThreadPoll(ctx context.Context, threadID int64, nickname string, options []int) (*models.Poll, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	thread, ok := s.db.threads[threadID]
+	if !ok || thread.State == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+	if thread.State == models.ThreadStateLocked || thread.State == models.ThreadStateArchived {
+		return nil, models.ErrThreadLocked
+	}
+	poll := s.db.threadPoll(threadID)
+	if poll == nil {
+		return nil, models.ErrNotFound
+	}
+	if poll.Closed {
+		return nil, models.ErrPollClosed
+	}
+	key := fold(nickname)
+	if _, ok := s.db.users[key]; !ok {
+		return nil, models.ErrOwnerNotFound
+	}
+	for _, id := range options {
+		if id < 1 || id > len(poll.Options) {
+			return nil, models.ErrInvalidPollVote
+		}
+	}
+
+	votes := s.db.pollVotes[threadID]
+	if votes == nil {
+		votes = make(map[string][]int)
+		s.db.pollVotes[threadID] = votes
+	}
+	if len(options) == 0 {
+		delete(votes, key)
+	} else {
+		votes[key] = append([]int(nil), options...)
+	}
+	return s.db.threadPoll(threadID), nil
+}
@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"hardhw/internal/models"
+)
+
+// newPostTree заполняет базу веткой с деревом постов:
+//
+//	1
+//	├─ 3
+//	│  └─ 4
+//	└─ 6
+//	2
+//	└─ 5
+//
+// Все посты созданы в одну секунду, кроме поста 2, который создан позже остальных.
+// Вторая ветка с постом 7 нужна, чтобы проверить since из чужой ветки.
+func newPostTree(t *testing.T) (*DB, models.Thread) {
+	t.Helper()
+	ctx := context.Background()
+	db := New()
+
+	if err := NewUserStorage(db).CreateUser(ctx, &models.User{Nickname: "Ann", Email: "ann@example.com"}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	forums := NewForumStorage(db)
+	if _, err := forums.CreateForum(ctx, &models.Forum{Slug: "F1", Title: "Forum", User: "ann"}); err != nil {
+		t.Fatalf("failed to create forum: %v", err)
+	}
+	slug := "My-Thread"
+	thread, err := forums.CreateThread(ctx, &models.Thread{Title: "Thread", Author: "ANN", Forum: "f1", Message: "text", Slug: &slug})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	other, err := forums.CreateThread(ctx, &models.Thread{Title: "Other", Author: "ann", Forum: "f1", Message: "text"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+
+	threads := NewThreadStorage(db)
+	for _, parent := range []int64{0, 0, 1, 3, 2, 1} {
+		if _, err := threads.CreatePosts(ctx, []*models.Post{{Parent: parent, Author: "ann", Message: "post", Thread: thread.ID}}); err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+	if _, err := threads.CreatePosts(ctx, []*models.Post{{Author: "ann", Message: "post", Thread: other.ID}}); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, post := range db.posts {
+		post.Created = start
+	}
+	db.posts[2].Created = start.Add(time.Second)
+
+	return db, *thread
+}
+
+func postIDs(posts []models.Post) []int64 {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+func TestThreadPostsOrder(t *testing.T) {
+	db, thread := newPostTree(t)
+	threads := NewThreadStorage(db)
+
+	type getter func(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error)
+	sorts := map[string]getter{
+		"flat":        threads.GetFlatThreadPosts,
+		"tree":        threads.GetTreeThreadPosts,
+		"parent_tree": threads.GetParentTreeThreadPosts,
+	}
+
+	tests := []struct {
+		sort  string
+		limit int
+		since int64
+		desc  bool
+		want  []int64
+	}{
+		// flat: по времени создания, при равном времени — по id.
+		{sort: "flat", want: []int64{1, 3, 4, 5, 6, 2}},
+		{sort: "flat", desc: true, want: []int64{2, 6, 5, 4, 3, 1}},
+		{sort: "flat", limit: 3, desc: true, want: []int64{2, 6, 5}},
+		{sort: "flat", since: 3, want: []int64{4, 5, 6, 2}},
+		{sort: "flat", since: 2, desc: true, want: []int64{6, 5, 4, 3, 1}},
+		{sort: "flat", since: 2, want: []int64{}},
+
+		// tree: по пути, desc переворачивает весь обход.
+		{sort: "tree", want: []int64{1, 3, 4, 6, 2, 5}},
+		{sort: "tree", desc: true, want: []int64{5, 2, 6, 4, 3, 1}},
+		{sort: "tree", limit: 2, want: []int64{1, 3}},
+		{sort: "tree", since: 3, want: []int64{4, 6, 2, 5}},
+		{sort: "tree", since: 6, desc: true, want: []int64{4, 3, 1}},
+		{sort: "tree", since: 3, desc: true, want: []int64{1}},
+
+		// parent_tree: limit и since считаются по корням, поддеревья всегда идут по пути.
+		{sort: "parent_tree", want: []int64{1, 3, 4, 6, 2, 5}},
+		{sort: "parent_tree", desc: true, want: []int64{2, 5, 1, 3, 4, 6}},
+		{sort: "parent_tree", limit: 1, desc: true, want: []int64{2, 5}},
+		{sort: "parent_tree", since: 4, want: []int64{2, 5}},
+		{sort: "parent_tree", since: 5, desc: true, want: []int64{1, 3, 4, 6}},
+		{sort: "parent_tree", since: 1, desc: true, want: []int64{}},
+
+		// since из другой ветки или несуществующий пост дают пустую страницу.
+		{sort: "flat", since: 7, want: []int64{}},
+		{sort: "tree", since: 7, want: []int64{}},
+		{sort: "parent_tree", since: 100, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		posts, err := sorts[tt.sort](context.Background(), thread.ID, tt.limit, tt.since, tt.desc)
+		if err != nil {
+			t.Fatalf("%s limit=%d since=%d desc=%t: %v", tt.sort, tt.limit, tt.since, tt.desc, err)
+		}
+		if got := postIDs(posts); !slices.Equal(got, tt.want) {
+			t.Errorf("%s limit=%d since=%d desc=%t: got posts %v, want %v", tt.sort, tt.limit, tt.since, tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestThreadPostsFoldKeys(t *testing.T) {
+	db, thread := newPostTree(t)
+	threads := NewThreadStorage(db)
+	ctx := context.Background()
+
+	// Ключи сравниваются как CITEXT, а наружу отдаётся написание, с которым запись создана.
+	if thread.Author != "Ann" || thread.Forum != "F1" {
+		t.Fatalf("thread has author %q and forum %q, want the stored spelling", thread.Author, thread.Forum)
+	}
+	found, err := threads.GetThreadBySlugOrID(ctx, "my-THREAD")
+	if err != nil || found.ID != thread.ID {
+		t.Fatalf("got thread %v (%v) by folded slug, want %d", found, err, thread.ID)
+	}
+	posts, err := threads.GetFlatThreadPosts(ctx, thread.ID, 1, 0, false)
+	if err != nil || posts[0].Author != "Ann" || posts[0].Forum != "F1" {
+		t.Fatalf("got posts %v (%v), want the stored author and forum spelling", posts, err)
+	}
+	if _, err := threads.CreatePosts(ctx, []*models.Post{{Author: "bob", Message: "post", Thread: thread.ID}}); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("post by unknown author: got %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestUpdateThreadVote(t *testing.T) {
+	db, thread := newPostTree(t)
+	if err := NewUserStorage(db).CreateUser(context.Background(), &models.User{Nickname: "bob", Email: "bob@example.com"}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	threads := NewThreadStorage(db)
+
+	votes := []struct {
+		nickname string
+		voice    int
+		want     int32
+	}{
+		{nickname: "ann", voice: 1, want: 1},
+		{nickname: "ANN", voice: 1, want: 1},
+		{nickname: "Ann", voice: -1, want: -1},
+		{nickname: "bob", voice: -1, want: -2},
+		{nickname: "BOB", voice: 1, want: 0},
+	}
+	for _, v := range votes {
+		updated, err := threads.UpdateThreadVote(context.Background(), thread.ID, v.nickname, v.voice)
+		if err != nil {
+			t.Fatalf("%s votes %d: %v", v.nickname, v.voice, err)
+		}
+		if updated.Votes != v.want {
+			t.Fatalf("%s votes %d: thread has %d votes, want %d", v.nickname, v.voice, updated.Votes, v.want)
+		}
+	}
+
+	if _, err := threads.UpdateThreadVote(context.Background(), 100, "ann", 1); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("vote in unknown thread: got %v, want %v", err, models.ErrNotFound)
+	}
+
+	closed := []struct {
+		state string
+		want  error
+	}{
+		{state: models.ThreadStateLocked, want: models.ErrThreadLocked},
+		{state: models.ThreadStateArchived, want: models.ErrThreadLocked},
+		{state: models.ThreadStateDeleted, want: models.ErrNotFound},
+	}
+	for _, c := range closed {
+		db.threads[thread.ID].State = c.state
+		if _, err := threads.UpdateThreadVote(context.Background(), thread.ID, "ann", 1); !errors.Is(err, c.want) {
+			t.Fatalf("vote in %s thread: got %v, want %v", c.state, err, c.want)
+		}
+		if votes := db.threads[thread.ID].Votes; votes != 0 {
+			t.Fatalf("%s thread has %d votes, want 0", c.state, votes)
+		}
+	}
+}
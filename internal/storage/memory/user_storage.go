@@ -0,0 +1,340 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryUserStorage struct {
+	db *DB
+}
+
+func NewUserStorage(db *DB) storage.UserStorage {
+	return &memoryUserStorage{db: db}
+}
+
+func (s *memoryUserStorage) CreateUser(ctx context.Context, user *models.User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[fold(user.Nickname)]; ok {
+		return models.ErrUserConflict
+	}
+	if _, ok := s.db.usersByEmail[fold(user.Email)]; ok {
+		return models.ErrUserConflict
+	}
+
+	stored := *user
+	stored.Password = ""
+	stored.PasswordHash = ""
+	s.db.users[fold(user.Nickname)] = &stored
+	s.db.usersByEmail[fold(user.Email)] = fold(user.Nickname)
+	if user.PasswordHash != "" {
+		s.db.passwordHashes[fold(user.Nickname)] = user.PasswordHash
+	}
+
+	return nil
+}
+
+func (s *memoryUserStorage) GetUserByNickname(ctx context.Context, nickname string) (*models.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	user, ok := s.db.users[fold(nickname)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (s *memoryUserStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	nickname, ok := s.db.usersByEmail[fold(email)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	found := *s.db.users[nickname]
+	return &found, nil
+}
+
+func (s *memoryUserStorage) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	existing, ok := s.db.users[fold(user.Nickname)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	if fold(user.Email) != fold(existing.Email) {
+		if _, taken := s.db.usersByEmail[fold(user.Email)]; taken {
+			return nil, models.ErrUserConflict
+		}
+		delete(s.db.usersByEmail, fold(existing.Email))
+		s.db.usersByEmail[fold(user.Email)] = fold(existing.Nickname)
+	}
+
+	existing.Fullname = user.Fullname
+	existing.Email = user.Email
+	existing.About = user.About
+
+	updated := *existing
+	s.db.enqueueUserUpdated(&updated)
+	return &updated, nil
+}
+
+func (s *memoryUserStorage) GetUserByAlias(ctx context.Context, nickname string) (*models.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	current, ok := s.db.userAliases[fold(nickname)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	user, ok := s.db.users[fold(current)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (s *memoryUserStorage) RenameUser(ctx context.Context, nickname, newNickname string) (*models.User, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[fold(nickname)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if other, taken := s.db.users[fold(newNickname)]; taken && other != user {
+		return nil, models.ErrUserConflict
+	}
+
+	s.db.renameUser(user, newNickname)
+
+	renamed := *user
+	s.db.enqueueUserUpdated(&renamed)
+	return &renamed, nil
+}
+
+func (s *memoryUserStorage) DeleteUser(ctx context.Context, nickname string, mode string) (*models.UserFiles, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[fold(nickname)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return s.db.deleteUser(user, mode), nil
+}
+
+func (s *memoryUserStorage) ExportUser(ctx context.Context, nickname string) (*models.UserExport, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	user, ok := s.db.users[fold(nickname)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	key := fold(user.Nickname)
+
+	export := &models.UserExport{
+		User:        *user,
+		Exported:    time.Now(),
+		Aliases:     make([]string, 0),
+		Forums:      make([]models.Forum, 0),
+		Threads:     make([]models.Thread, 0),
+		Posts:       make([]models.Post, 0),
+		Votes:       make([]models.UserVote, 0),
+		Reactions:   make([]models.UserReaction, 0),
+		PollVotes:   make([]models.UserPollVote, 0),
+		Attachments: make([]models.Attachment, 0),
+		Messages:    make([]models.ConversationMessage, 0),
+		Blocked:     make([]string, 0),
+		Moderates:   make([]string, 0),
+		Bans:        make([]models.ForumBan, 0),
+		Tokens:      make([]models.AuthToken, 0),
+	}
+
+	for alias, current := range s.db.userAliases {
+		if fold(current) == key {
+			export.Aliases = append(export.Aliases, alias)
+		}
+	}
+	for _, forum := range s.db.forums {
+		if fold(forum.User) == key {
+			export.Forums = append(export.Forums, *forum)
+		}
+	}
+	for _, thread := range s.db.threads {
+		if fold(thread.Author) == key {
+			export.Threads = append(export.Threads, *copyThread(thread))
+		}
+	}
+	for _, post := range s.db.posts {
+		if fold(post.Author) == key {
+			export.Posts = append(export.Posts, copyPost(post))
+		}
+	}
+	for vote, voice := range s.db.votes {
+		if vote.nickname == key {
+			export.Votes = append(export.Votes, models.UserVote{Thread: vote.threadID, Voice: voice})
+		}
+	}
+	for postID, reactions := range s.db.postReactions {
+		for _, kind := range reactions[key] {
+			export.Reactions = append(export.Reactions, models.UserReaction{Post: postID, Kind: kind})
+		}
+	}
+	for _, attachment := range s.db.attachments {
+		if fold(attachment.Owner) == key {
+			exported := *attachment
+			exported.Key = ""
+			export.Attachments = append(export.Attachments, exported)
+		}
+	}
+	for _, c := range s.db.conversations {
+		for _, message := range c.messages {
+			if fold(message.Author) == key {
+				export.Messages = append(export.Messages, *message)
+			}
+		}
+	}
+	export.Blocked = s.db.blockedUsers(key)
+	for forumSlug, moderators := range s.db.moderators {
+		if _, ok := moderators[key]; ok {
+			export.Moderates = append(export.Moderates, s.db.forums[forumSlug].Slug)
+		}
+	}
+	for _, bans := range s.db.bans {
+		if ban, ok := bans[key]; ok {
+			export.Bans = append(export.Bans, *ban)
+		}
+	}
+	if suspension, ok := s.db.suspensions[key]; ok {
+		found := *suspension
+		export.Suspension = &found
+	}
+	for _, token := range s.db.tokens {
+		if fold(token.User) == key {
+			export.Tokens = append(export.Tokens, *token)
+		}
+	}
+
+	sort.Strings(export.Aliases)
+	sort.Slice(export.Forums, func(i, j int) bool { return fold(export.Forums[i].Slug) < fold(export.Forums[j].Slug) })
+	sort.Slice(export.Threads, func(i, j int) bool { return export.Threads[i].ID < export.Threads[j].ID })
+	sort.Slice(export.Posts, func(i, j int) bool { return export.Posts[i].ID < export.Posts[j].ID })
+	sort.Slice(export.Votes, func(i, j int) bool { return export.Votes[i].Thread < export.Votes[j].Thread })
+	for threadID, votes := range s.db.pollVotes {
+		for _, option := range votes[key] {
+			export.PollVotes = append(export.PollVotes, models.UserPollVote{Thread: threadID, Option: option})
+		}
+	}
+	sort.Slice(export.PollVotes, func(i, j int) bool {
+		if export.PollVotes[i].Thread != export.PollVotes[j].Thread {
+			return export.PollVotes[i].Thread < export.PollVotes[j].Thread
+		}
+		return export.PollVotes[i].Option < export.PollVotes[j].Option
+	})
+	sort.Slice(export.Reactions, func(i, j int) bool {
+		if export.Reactions[i].Post != export.Reactions[j].Post {
+			return export.Reactions[i].Post < export.Reactions[j].Post
+		}
+		return export.Reactions[i].Kind < export.Reactions[j].Kind
+	})
+	sort.Slice(export.Attachments, func(i, j int) bool { return export.Attachments[i].ID < export.Attachments[j].ID })
+	sort.Slice(export.Messages, func(i, j int) bool { return export.Messages[i].ID < export.Messages[j].ID })
+	sort.Slice(export.Moderates, func(i, j int) bool { return fold(export.Moderates[i]) < fold(export.Moderates[j]) })
+	sort.Slice(export.Bans, func(i, j int) bool { return fold(export.Bans[i].Forum) < fold(export.Bans[j].Forum) })
+	sort.Slice(export.Tokens, func(i, j int) bool { return export.Tokens[i].ID < export.Tokens[j].ID })
+	return export, nil
+}
+
+func (s *memoryUserStorage) GetExistingNicknames(ctx context.Context, nicknames []string) ([]string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	existing := make([]string, 0, len(nicknames))
+	for _, nickname := range nicknames {
+		if user, ok := s.db.users[fold(nickname)]; ok {
+			existing = append(existing, user.Nickname)
+		}
+	}
+	return existing, nil
+}
+
+func (s *memoryUserStorage) SetUserAvatar(ctx context.Context, nickname, key string) (string, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	user, ok := s.db.users[fold(nickname)]
+	if !ok {
+		return "", models.ErrNotFound
+	}
+	previous := user.AvatarKey
+	user.AvatarKey = key
+	return previous, nil
+}
+
+func (s *memoryUserStorage) BlockUser(ctx context.Context, nickname, target string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	key, targetKey := fold(nickname), fold(target)
+	if _, ok := s.db.users[key]; !ok {
+		return models.ErrNotFound
+	}
+	if _, ok := s.db.users[targetKey]; !ok {
+		return models.ErrNotFound
+	}
+	blocked, ok := s.db.userBlocks[key]
+	if !ok {
+		blocked = make(map[string]time.Time)
+		s.db.userBlocks[key] = blocked
+	}
+	if _, ok := blocked[targetKey]; !ok {
+		blocked[targetKey] = time.Now()
+	}
+	return nil
+}
+
+func (s *memoryUserStorage) UnblockUser(ctx context.Context, nickname, target string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	delete(s.db.userBlocks[fold(nickname)], fold(target))
+	return nil
+}
+
+func (s *memoryUserStorage) GetBlockedUsers(ctx context.Context, nickname string) ([]string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.db.blockedUsers(fold(nickname)), nil
+}
+
+func (s *memoryUserStorage) GetBlockedBetween(ctx context.Context, nickname string, others []string) ([]string, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	key := fold(nickname)
+	blocked := make([]string, 0)
+	for _, other := range others {
+		otherKey := fold(other)
+		_, blocks := s.db.userBlocks[key][otherKey]
+		_, blockedBy := s.db.userBlocks[otherKey][key]
+		if user, ok := s.db.users[otherKey]; ok && (blocks || blockedBy) {
+			blocked = append(blocked, user.Nickname)
+		}
+	}
+	return blocked, nil
+}
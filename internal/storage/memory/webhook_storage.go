@@ -0,0 +1,204 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"hardhw/internal/models"
+	"hardhw/internal/storage"
+)
+
+type memoryWebhookStorage struct {
+	db *DB
+}
+
+func NewWebhookStorage(db *DB) storage.WebhookStorage {
+	return &memoryWebhookStorage{db: db}
+}
+
+func (s *memoryWebhookStorage) CreateWebhook(ctx context.Context, hook models.Webhook) (*models.Webhook, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	forum, ok := s.db.forums[fold(hook.Forum)]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	s.db.nextWebhookID++
+	stored := &models.Webhook{
+		ID:        s.db.nextWebhookID,
+		Forum:     forum.Slug,
+		URL:       hook.URL,
+		Secret:    hook.Secret,
+		Events:    append([]string(nil), hook.Events...),
+		CreatedBy: hook.CreatedBy,
+		Active:    true,
+		Created:   time.Now(),
+	}
+	s.db.webhooks[stored.ID] = stored
+	return copyWebhook(stored, true), nil
+}
+
+func (s *memoryWebhookStorage) GetWebhooks(ctx context.Context, forumSlug string) ([]models.Webhook, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	hooks := make([]models.Webhook, 0)
+	for _, hook := range s.db.webhooks {
+		if fold(hook.Forum) == fold(forumSlug) {
+			hooks = append(hooks, *copyWebhook(hook, false))
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks, nil
+}
+
+func (s *memoryWebhookStorage) GetWebhook(ctx context.Context, forumSlug string, id int64) (*models.Webhook, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	hook, ok := s.db.webhooks[id]
+	if !ok || fold(hook.Forum) != fold(forumSlug) {
+		return nil, models.ErrNotFound
+	}
+	return copyWebhook(hook, false), nil
+}
+
+func (s *memoryWebhookStorage) DeleteWebhook(ctx context.Context, forumSlug string, id int64) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	hook, ok := s.db.webhooks[id]
+	if !ok || fold(hook.Forum) != fold(forumSlug) {
+		return models.ErrNotFound
+	}
+	delete(s.db.webhooks, id)
+	for _, deliveryID := range s.db.webhookDeliveries[id] {
+		delete(s.db.deliveries, deliveryID)
+	}
+	delete(s.db.webhookDeliveries, id)
+	return nil
+}
+
+func (s *memoryWebhookStorage) GetDeliveries(ctx context.Context, webhookID int64, limit int, before int64) ([]models.WebhookDelivery, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	ids := s.db.webhookDeliveries[webhookID]
+	deliveries := make([]models.WebhookDelivery, 0)
+	for i := len(ids) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if before != 0 && ids[i] >= before {
+			continue
+		}
+		deliveries = append(deliveries, *s.db.deliveries[ids[i]])
+	}
+	return deliveries, nil
+}
+
+func (s *memoryWebhookStorage) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDispatch, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+	due := make([]*models.WebhookDelivery, 0)
+	for _, delivery := range s.db.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttempt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttempt.Equal(due[j].NextAttempt) {
+			return due[i].NextAttempt.Before(due[j].NextAttempt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]models.WebhookDispatch, 0, len(due))
+	for _, delivery := range due {
+		delivery.NextAttempt = now.Add(lease)
+		hook := s.db.webhooks[delivery.Webhook]
+		claimed = append(claimed, models.WebhookDispatch{Delivery: *delivery, URL: hook.URL, Secret: hook.Secret})
+	}
+	return claimed, nil
+}
+
+func (s *memoryWebhookStorage) UpdateDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.deliveries[d.ID]
+	if !ok {
+		// Подписку могли удалить, пока доставка была в работе.
+		return nil
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.NextAttempt = d.NextAttempt
+	stored.LastError = d.LastError
+	stored.ResponseStatus = d.ResponseStatus
+	stored.Delivered = d.Delivered
+	return nil
+}
+
+// enqueueWebhookEvent кладёт событие в outbox под тем же db.mu, что и само изменение.
+func (db *DB) enqueueWebhookEvent(forumSlug string, event string, data any) {
+	now := time.Now()
+	for _, hook := range db.webhooks {
+		if !hook.Active || fold(hook.Forum) != fold(forumSlug) || !containsEvent(hook.Events, event) {
+			continue
+		}
+
+		payload, err := json.Marshal(models.WebhookPayload{Event: event, Forum: hook.Forum, Created: now, Data: data})
+		if err != nil {
+			log.Printf("Error encoding %s webhook payload: %v", event, err)
+			return
+		}
+
+		db.nextDeliveryID++
+		db.deliveries[db.nextDeliveryID] = &models.WebhookDelivery{
+			ID:          db.nextDeliveryID,
+			Webhook:     hook.ID,
+			Event:       event,
+			Payload:     payload,
+			Status:      models.DeliveryPending,
+			NextAttempt: now,
+			Created:     now,
+		}
+		db.webhookDeliveries[hook.ID] = append(db.webhookDeliveries[hook.ID], db.nextDeliveryID)
+	}
+}
+
+func containsEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func copyWebhook(hook *models.Webhook, withSecret bool) *models.Webhook {
+	c := *hook
+	c.Events = append([]string(nil), hook.Events...)
+	if !withSecret {
+		c.Secret = ""
+	}
+	return &c
+}
+
+// enqueueUserUpdated рассылает событие user.updated подпискам всех форумов, где участвует пользователь.
+func (db *DB) enqueueUserUpdated(user *models.User) {
+	data := models.WebhookUser{Nickname: user.Nickname, Fullname: user.Fullname, About: user.About}
+	for forumSlug, members := range db.forumUsers {
+		if _, ok := members[fold(user.Nickname)]; ok {
+			db.enqueueWebhookEvent(forumSlug, models.WebhookEventUserUpdated, data)
+		}
+	}
+}
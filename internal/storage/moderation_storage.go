@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ModerationStorage interface {
+	IsSiteAdmin(ctx context.Context, nickname string) (bool, error)
+	SetSiteAdmin(ctx context.Context, nickname string, isAdmin bool) error
+	GetForumRole(ctx context.Context, forumSlug string, nickname string) (string, error)
+	GetForumModerators(ctx context.Context, forumSlug string) ([]models.User, error)
+	AddForumModerator(ctx context.Context, forumSlug string, nickname string, grantedBy string) error
+	RemoveForumModerator(ctx context.Context, forumSlug string, nickname string) error
+	BanUser(ctx context.Context, ban models.ForumBan) (*models.ForumBan, error)
+	UnbanUser(ctx context.Context, forumSlug string, nickname string) error
+	GetBannedUsers(ctx context.Context, forumSlug string, nicknames []string) ([]string, error)
+	GetForumBans(ctx context.Context, forumSlug string) ([]models.ForumBan, error)
+	SuspendUser(ctx context.Context, suspension models.Suspension) (*models.Suspension, error)
+	UnsuspendUser(ctx context.Context, nickname string) error
+	GetSuspendedUsers(ctx context.Context, nicknames []string) ([]string, error)
+}
+
+type postgresModerationStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresModerationStorage(pool *pgxpool.Pool) ModerationStorage {
+	return &postgresModerationStorage{pool: pool}
+}
+
+func (s *postgresModerationStorage) IsSiteAdmin(ctx context.Context, nickname string) (bool, error) {
+	var isAdmin bool
+	err := s.pool.QueryRow(ctx, `SELECT is_admin FROM users WHERE nickname = $1`, nickname).Scan(&isAdmin)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, models.ErrNotFound
+		}
+		return false, fmt.Errorf("failed to check site admin %s: %w", nickname, err)
+	}
+	return isAdmin, nil
+}
+
+func (s *postgresModerationStorage) SetSiteAdmin(ctx context.Context, nickname string, isAdmin bool) error {
+	commandTag, err := s.pool.Exec(ctx, `UPDATE users SET is_admin = $1 WHERE nickname = $2`, isAdmin, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to update site admin flag for %s: %w", nickname, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresModerationStorage) GetForumRole(ctx context.Context, forumSlug string, nickname string) (string, error) {
+	query := `
+		SELECT u.is_admin,
+		       f.user_nickname = u.nickname,
+		       EXISTS(SELECT 1 FROM forum_moderators m WHERE m.forum_slug = f.slug AND m.user_nickname = u.nickname)
+		FROM users u, forums f
+		WHERE u.nickname = $1 AND f.slug = $2`
+
+	var isAdmin, isOwner, isModerator bool
+	err := s.pool.QueryRow(ctx, query, nickname, forumSlug).Scan(&isAdmin, &isOwner, &isModerator)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", models.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to get role of %s in forum %s: %w", nickname, forumSlug, err)
+	}
+
+	switch {
+	case isAdmin:
+		return models.RoleAdmin, nil
+	case isOwner:
+		return models.RoleOwner, nil
+	case isModerator:
+		return models.RoleModerator, nil
+	}
+	return models.RoleMember, nil
+}
+
+func (s *postgresModerationStorage) GetForumModerators(ctx context.Context, forumSlug string) ([]models.User, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT u.nickname, u.fullname, u.about, u.email
+		FROM forum_moderators m
+		JOIN users u ON u.nickname = m.user_nickname
+		WHERE m.forum_slug = $1
+		ORDER BY lower(u.nickname) COLLATE "C"`, forumSlug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moderators of forum %s: %w", forumSlug, err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan forum moderator: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading forum moderators: %w", err)
+	}
+	return users, nil
+}
+
+func (s *postgresModerationStorage) AddForumModerator(ctx context.Context, forumSlug string, nickname string, grantedBy string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO forum_moderators (forum_slug, user_nickname, granted_by)
+		VALUES ($1, $2, NULLIF($3, ''))
+		ON CONFLICT (forum_slug, user_nickname) DO NOTHING`, forumSlug, nickname, grantedBy)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to add moderator %s to forum %s: %w", nickname, forumSlug, err)
+	}
+	return nil
+}
+
+func (s *postgresModerationStorage) RemoveForumModerator(ctx context.Context, forumSlug string, nickname string) error {
+	commandTag, err := s.pool.Exec(ctx, `DELETE FROM forum_moderators WHERE forum_slug = $1 AND user_nickname = $2`, forumSlug, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to remove moderator %s from forum %s: %w", nickname, forumSlug, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresModerationStorage) BanUser(ctx context.Context, ban models.ForumBan) (*models.ForumBan, error) {
+	query := `
+		INSERT INTO forum_bans (forum_slug, user_nickname, banned_by, reason, expires)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		ON CONFLICT (forum_slug, user_nickname) DO UPDATE
+		SET banned_by = EXCLUDED.banned_by, reason = EXCLUDED.reason, expires = EXCLUDED.expires
+		RETURNING forum_slug, user_nickname, COALESCE(banned_by, ''), reason, expires, created`
+
+	stored := &models.ForumBan{}
+	err := s.pool.QueryRow(ctx, query, ban.Forum, ban.User, ban.BannedBy, ban.Reason, ban.Expires).
+		Scan(&stored.Forum, &stored.User, &stored.BannedBy, &stored.Reason, &stored.Expires, &stored.Created)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to ban %s in forum %s: %w", ban.User, ban.Forum, err)
+	}
+	return stored, nil
+}
+
+func (s *postgresModerationStorage) UnbanUser(ctx context.Context, forumSlug string, nickname string) error {
+	commandTag, err := s.pool.Exec(ctx, `DELETE FROM forum_bans WHERE forum_slug = $1 AND user_nickname = $2`, forumSlug, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to unban %s in forum %s: %w", nickname, forumSlug, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresModerationStorage) GetBannedUsers(ctx context.Context, forumSlug string, nicknames []string) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT user_nickname
+		FROM forum_bans
+		WHERE forum_slug = $1 AND user_nickname = ANY($2::citext[])
+		  AND (expires IS NULL OR expires > now())`, forumSlug, nicknames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bans in forum %s: %w", forumSlug, err)
+	}
+	defer rows.Close()
+
+	banned := make([]string, 0)
+	for rows.Next() {
+		var nickname string
+		if err := rows.Scan(&nickname); err != nil {
+			return nil, fmt.Errorf("failed to scan banned user: %w", err)
+		}
+		banned = append(banned, nickname)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading bans: %w", err)
+	}
+	return banned, nil
+}
+
+func (s *postgresModerationStorage) GetForumBans(ctx context.Context, forumSlug string) ([]models.ForumBan, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT forum_slug, user_nickname, COALESCE(banned_by, ''), reason, expires, created
+		FROM forum_bans
+		WHERE forum_slug = $1 AND (expires IS NULL OR expires > now())
+		ORDER BY created DESC, lower(user_nickname) COLLATE "C"`, forumSlug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bans of forum %s: %w", forumSlug, err)
+	}
+	defer rows.Close()
+
+	bans := make([]models.ForumBan, 0)
+	for rows.Next() {
+		var ban models.ForumBan
+		if err := rows.Scan(&ban.Forum, &ban.User, &ban.BannedBy, &ban.Reason, &ban.Expires, &ban.Created); err != nil {
+			return nil, fmt.Errorf("failed to scan forum ban: %w", err)
+		}
+		bans = append(bans, ban)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading forum bans: %w", err)
+	}
+	return bans, nil
+}
+
+func (s *postgresModerationStorage) SuspendUser(ctx context.Context, suspension models.Suspension) (*models.Suspension, error) {
+	query := `
+		INSERT INTO user_suspensions (user_nickname, suspended_by, reason, until)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		ON CONFLICT (user_nickname) DO UPDATE
+		SET suspended_by = EXCLUDED.suspended_by, reason = EXCLUDED.reason, until = EXCLUDED.until, created = now()
+		RETURNING user_nickname, COALESCE(suspended_by, ''), reason, until, created`
+
+	stored := &models.Suspension{}
+	err := s.pool.QueryRow(ctx, query, suspension.User, suspension.SuspendedBy, suspension.Reason, suspension.Until).
+		Scan(&stored.User, &stored.SuspendedBy, &stored.Reason, &stored.Until, &stored.Created)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to suspend %s: %w", suspension.User, err)
+	}
+	return stored, nil
+}
+
+func (s *postgresModerationStorage) UnsuspendUser(ctx context.Context, nickname string) error {
+	commandTag, err := s.pool.Exec(ctx, `DELETE FROM user_suspensions WHERE user_nickname = $1`, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to lift suspension of %s: %w", nickname, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresModerationStorage) GetSuspendedUsers(ctx context.Context, nicknames []string) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT user_nickname
+		FROM user_suspensions
+		WHERE user_nickname = ANY($1::citext[])
+		  AND (until IS NULL OR until > now())`, nicknames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suspensions: %w", err)
+	}
+	defer rows.Close()
+
+	suspended := make([]string, 0)
+	for rows.Next() {
+		var nickname string
+		if err := rows.Scan(&nickname); err != nil {
+			return nil, fmt.Errorf("failed to scan suspended user: %w", err)
+		}
+		suspended = append(suspended, nickname)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading suspensions: %w", err)
+	}
+	return suspended, nil
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationStorage interface {
+	SubscribeThread(ctx context.Context, threadID int64, nickname string) error
+	UnsubscribeThread(ctx context.Context, threadID int64, nickname string) error
+	SubscribeForum(ctx context.Context, forumSlug string, nickname string) error
+	UnsubscribeForum(ctx context.Context, forumSlug string, nickname string) error
+	GetSubscriptions(ctx context.Context, nickname string) (*models.Subscriptions, error)
+	GetNotifications(ctx context.Context, nickname string, unread bool, limit int, before int64) ([]models.Notification, error)
+	MarkNotificationsRead(ctx context.Context, nickname string, ids []int64) (int64, error)
+	NotifyPendingPosts(ctx context.Context, limit int) (int, error)
+}
+
+type postgresNotificationStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresNotificationStorage(pool *pgxpool.Pool) NotificationStorage {
+	return &postgresNotificationStorage{pool: pool}
+}
+
+func (s *postgresNotificationStorage) SubscribeThread(ctx context.Context, threadID int64, nickname string) error {
+	return s.subscribe(ctx, `
+		INSERT INTO thread_subscriptions (thread_id, user_nickname)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, threadID, nickname)
+}
+
+func (s *postgresNotificationStorage) UnsubscribeThread(ctx context.Context, threadID int64, nickname string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM thread_subscriptions WHERE thread_id = $1 AND user_nickname = $2`, threadID, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe %s from thread %d: %w", nickname, threadID, err)
+	}
+	return nil
+}
+
+func (s *postgresNotificationStorage) SubscribeForum(ctx context.Context, forumSlug string, nickname string) error {
+	return s.subscribe(ctx, `
+		INSERT INTO forum_subscriptions (forum_slug, user_nickname)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, forumSlug, nickname)
+}
+
+func (s *postgresNotificationStorage) UnsubscribeForum(ctx context.Context, forumSlug string, nickname string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM forum_subscriptions WHERE forum_slug = $1 AND user_nickname = $2`, forumSlug, nickname)
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe %s from forum %s: %w", nickname, forumSlug, err)
+	}
+	return nil
+}
+
+func (s *postgresNotificationStorage) subscribe(ctx context.Context, query string, target any, nickname string) error {
+	_, err := s.pool.Exec(ctx, query, target, nickname)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("failed to subscribe %s to %v: %w", nickname, target, err)
+	}
+	return nil
+}
+
+func (s *postgresNotificationStorage) GetSubscriptions(ctx context.Context, nickname string) (*models.Subscriptions, error) {
+	subscriptions := &models.Subscriptions{Threads: make([]int64, 0), Forums: make([]string, 0)}
+
+	rows, err := s.pool.Query(ctx, `SELECT thread_id FROM thread_subscriptions WHERE user_nickname = $1 ORDER BY thread_id`, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query thread subscriptions of %s: %w", nickname, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var threadID int64
+		if err := rows.Scan(&threadID); err != nil {
+			return nil, fmt.Errorf("failed to scan thread subscription: %w", err)
+		}
+		subscriptions.Threads = append(subscriptions.Threads, threadID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT forum_slug FROM forum_subscriptions WHERE user_nickname = $1 ORDER BY forum_slug`, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query forum subscriptions of %s: %w", nickname, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var forumSlug string
+		if err := rows.Scan(&forumSlug); err != nil {
+			return nil, fmt.Errorf("failed to scan forum subscription: %w", err)
+		}
+		subscriptions.Forums = append(subscriptions.Forums, forumSlug)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (s *postgresNotificationStorage) GetNotifications(ctx context.Context, nickname string, unread bool, limit int, before int64) ([]models.Notification, error) {
+	query := `
+		SELECT n.id, n.kind, p.id, p.thread_id, p.forum, p.author, n.read, n.created
+		FROM notifications n
+		JOIN posts p ON p.id = n.post_id
+		WHERE n.user_nickname = $1 AND (NOT $2 OR NOT n.read) AND ($3 = 0 OR n.id < $3)
+		ORDER BY n.id DESC
+		LIMIT $4`
+
+	rows, err := s.pool.Query(ctx, query, nickname, unread, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications of %s: %w", nickname, err)
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(&n.ID, &n.Kind, &n.Post, &n.Thread, &n.Forum, &n.Author, &n.Read, &n.Created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return notifications, nil
+}
+
+// MarkNotificationsRead отмечает прочитанными уведомления ids, а без ids — все непрочитанные.
+// Возвращает число найденных уведомлений.
+func (s *postgresNotificationStorage) MarkNotificationsRead(ctx context.Context, nickname string, ids []int64) (int64, error) {
+	var commandTag pgconn.CommandTag
+	var err error
+	if len(ids) == 0 {
+		commandTag, err = s.pool.Exec(ctx, `UPDATE notifications SET read = TRUE WHERE user_nickname = $1 AND NOT read`, nickname)
+	} else {
+		commandTag, err = s.pool.Exec(ctx, `UPDATE notifications SET read = TRUE WHERE user_nickname = $1 AND id = ANY($2)`, nickname, ids)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications of %s as read: %w", nickname, err)
+	}
+	return commandTag.RowsAffected(), nil
+}
+
+// enqueuePostNotifications ставит новые посты в очередь воркера уведомлений.
+// Вызывается внутри транзакции, создающей посты.
+func enqueuePostNotifications(ctx context.Context, db execer, postIDs []int64) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO post_notification_queue (post_id)
+		SELECT unnest($1::bigint[])`, postIDs)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue post notifications: %w", err)
+	}
+	return nil
+}
+
+// NotifyPendingPosts разбирает пачку постов из очереди. SKIP LOCKED позволяет
+// нескольким воркерам работать параллельно. На пост и пользователя приходится одно
+// уведомление: упоминание важнее ответа, ответ важнее подписки.
+func (s *postgresNotificationStorage) NotifyPendingPosts(ctx context.Context, limit int) (int, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin notification transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Посты, удалённые вместе с веткой, разбираются без уведомлений: их уже никто не увидит.
+	rows, err := tx.Query(ctx, `
+		SELECT q.post_id, NOT p.is_deleted AND t.state <> 'deleted'
+		FROM post_notification_queue q
+		JOIN posts p ON p.id = q.post_id
+		JOIN threads t ON t.id = p.thread_id
+		ORDER BY q.post_id
+		LIMIT $1
+		FOR UPDATE OF q SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim pending posts: %w", err)
+	}
+	var postIDs, visibleIDs []int64
+	for rows.Next() {
+		var id int64
+		var visible bool
+		if err := rows.Scan(&id, &visible); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan pending post: %w", err)
+		}
+		postIDs = append(postIDs, id)
+		if visible {
+			visibleIDs = append(visibleIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("rows iteration error: %w", err)
+	}
+	if len(postIDs) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notifications (user_nickname, kind, post_id)
+		SELECT m.user_nickname, $2, p.id
+		FROM post_mentions m
+		JOIN posts p ON p.id = m.post_id
+		WHERE m.post_id = ANY($1) AND m.user_nickname <> p.author AND m.user_nickname <> $3
+		ON CONFLICT (user_nickname, post_id) DO NOTHING`,
+		visibleIDs, models.NotificationKindMention, models.DeletedUserNickname)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert mention notifications: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notifications (user_nickname, kind, post_id)
+		SELECT parent.author, $2, p.id
+		FROM posts p
+		JOIN posts parent ON parent.id = p.parent
+		WHERE p.id = ANY($1) AND parent.author <> p.author AND parent.author <> $3
+		ON CONFLICT (user_nickname, post_id) DO NOTHING`,
+		visibleIDs, models.NotificationKindReply, models.DeletedUserNickname)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert reply notifications: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO notifications (user_nickname, kind, post_id)
+		SELECT ts.user_nickname, $2, p.id
+		FROM posts p
+		JOIN thread_subscriptions ts ON ts.thread_id = p.thread_id
+		WHERE p.id = ANY($1) AND ts.user_nickname <> p.author
+		UNION
+		SELECT fs.user_nickname, $2, p.id
+		FROM posts p
+		JOIN forum_subscriptions fs ON fs.forum_slug = p.forum
+		WHERE p.id = ANY($1) AND fs.user_nickname <> p.author
+		ON CONFLICT (user_nickname, post_id) DO NOTHING`,
+		visibleIDs, models.NotificationKindSubscription)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert subscription notifications: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM post_notification_queue WHERE post_id = ANY($1)`, postIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to dequeue notified posts: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit notification transaction: %w", err)
+	}
+	return len(postIDs), nil
+}
@@ -7,12 +7,25 @@ import (
 	"hardhw/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type PostStorage interface {
+	// GetPostByID не находит посты удалённых веток: вместе с веткой скрываются и они.
 	GetPostByID(ctx context.Context, id int64) (*models.Post, error)
-	UpdatePostMessage(ctx context.Context, id int64, newMessage string) (*models.Post, error)
+	GetPostsByIDs(ctx context.Context, ids []int64) ([]models.Post, error)
+	GetThreadPostsAfter(ctx context.Context, threadID int64, afterID int64, limit int) ([]models.Post, error)
+	UpdatePostMessage(ctx context.Context, id int64, newMessage string, editor string, mentions []string) (*models.Post, error)
+	GetUserMentions(ctx context.Context, nickname string, limit int, before int64) ([]models.Post, error)
+	GetPostRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error)
+	GetPostRevision(ctx context.Context, postID int64, revisionID int64) (*models.PostRevision, error)
+	// DeletePost помечает пост удалённым; пост удалённой ветки не находится, как и в GetPostByID.
+	DeletePost(ctx context.Context, id int64) (*models.Post, error)
+	SetPostReaction(ctx context.Context, id int64, nickname string, kind string, active bool) (*models.Post, error)
+	// PurgePostSubtree стирает пост с ответами, в том числе в удалённой ветке.
+	// Возвращает число стёртых постов и ключи файлов их вложений.
+	PurgePostSubtree(ctx context.Context, id int64) (int64, []string, error)
 	CountTableRows(ctx context.Context) (*models.Status, error)
 	ClearAllTables(ctx context.Context) error
 }
@@ -27,9 +40,10 @@ func NewPostgresPostStorage(pool *pgxpool.Pool) PostStorage {
 
 func (s *postgresPostStorage) GetPostByID(ctx context.Context, id int64) (*models.Post, error) {
 	query := `
-		SELECT id, parent, author, message, is_edited, forum, thread_id, created -- ИСПРАВЛЕНО: forum_slug на forum
-		FROM posts
-		WHERE id = $1
+		SELECT p.id, p.parent, p.author, p.message, p.is_edited, p.is_deleted, p.forum, p.thread_id, p.created, p.score, p.reactions, p.attachments
+		FROM posts p
+		JOIN threads t ON t.id = p.thread_id AND t.state <> 'deleted'
+		WHERE p.id = $1
 	`
 	post := &models.Post{}
 	err := s.pool.QueryRow(ctx, query, id).Scan(
@@ -38,9 +52,13 @@ func (s *postgresPostStorage) GetPostByID(ctx context.Context, id int64) (*model
 		&post.Author,
 		&post.Message,
 		&post.IsEdited,
+		&post.IsDeleted,
 		&post.Forum,
 		&post.Thread,
 		&post.Created,
+		&post.Score,
+		&post.Reactions,
+		&post.Attachments,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -51,23 +69,94 @@ func (s *postgresPostStorage) GetPostByID(ctx context.Context, id int64) (*model
 	return post, nil
 }
 
-func (s *postgresPostStorage) UpdatePostMessage(ctx context.Context, id int64, newMessage string) (*models.Post, error) {
+func (s *postgresPostStorage) GetPostsByIDs(ctx context.Context, ids []int64) ([]models.Post, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, score, reactions, attachments
+		FROM posts
+		WHERE id = ANY($1)
+		ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts by IDs: %w", err)
+	}
+	return scanPosts(rows)
+}
+
+// GetThreadPostsAfter возвращает посты ветки с id больше afterID в порядке создания.
+func (s *postgresPostStorage) GetThreadPostsAfter(ctx context.Context, threadID int64, afterID int64, limit int) ([]models.Post, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, score, reactions, attachments
+		FROM posts
+		WHERE thread_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3`, threadID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread posts after %d: %w", afterID, err)
+	}
+	return scanPosts(rows)
+}
+
+func scanPosts(rows pgx.Rows) ([]models.Post, error) {
+	defer rows.Close()
+
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		var post models.Post
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted, &post.Forum, &post.Thread, &post.Created, &post.Score, &post.Reactions, &post.Attachments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading posts: %w", err)
+	}
+	return posts, nil
+}
+
+func (s *postgresPostStorage) UpdatePostMessage(ctx context.Context, id int64, newMessage string, editor string, mentions []string) (*models.Post, error) {
 	query := `
 		UPDATE posts
 		SET message = $1, is_edited = TRUE
 		WHERE id = $2
-		RETURNING id, parent, author, message, is_edited, forum, thread_id, created -- ИСПРАВЛЕНО: forum_slug на forum
+		RETURNING id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, score, reactions, attachments -- ИСПРАВЛЕНО: forum_slug на forum
 	`
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем пост, чтобы параллельные правки не записали одну и ту же «предыдущую» версию.
+	var previousMessage string
+	err = tx.QueryRow(ctx, `SELECT message FROM posts WHERE id = $1 FOR UPDATE`, id).Scan(&previousMessage)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock post for update: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO post_revisions (post_id, editor, message)
+		VALUES ($1, NULLIF($2, ''), $3)`, id, editor, previousMessage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save post revision: %w", err)
+	}
+
 	updatedPost := &models.Post{}
-	err := s.pool.QueryRow(ctx, query, newMessage, id).Scan(
+	err = tx.QueryRow(ctx, query, newMessage, id).Scan(
 		&updatedPost.ID,
 		&updatedPost.Parent,
 		&updatedPost.Author,
 		&updatedPost.Message,
 		&updatedPost.IsEdited,
+		&updatedPost.IsDeleted,
 		&updatedPost.Forum,
 		&updatedPost.Thread,
 		&updatedPost.Created,
+		&updatedPost.Score,
+		&updatedPost.Reactions,
+		&updatedPost.Attachments,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -75,9 +164,307 @@ func (s *postgresPostStorage) UpdatePostMessage(ctx context.Context, id int64, n
 		}
 		return nil, fmt.Errorf("failed to update post message: %w", err)
 	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM post_mentions WHERE post_id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete previous post mentions: %w", err)
+	}
+	postIDs := make([]int64, len(mentions))
+	for i := range mentions {
+		postIDs[i] = id
+	}
+	if err := savePostMentions(ctx, tx, postIDs, mentions); err != nil {
+		return nil, err
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, updatedPost.Forum, models.WebhookEventPostUpdated, updatedPost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return updatedPost, nil
 }
 
+func (s *postgresPostStorage) GetPostRevisions(ctx context.Context, postID int64) ([]models.PostRevision, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, post_id, COALESCE(editor, ''), message, created
+		FROM post_revisions
+		WHERE post_id = $1
+		ORDER BY id`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions of post %d: %w", postID, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]models.PostRevision, 0)
+	for rows.Next() {
+		var revision models.PostRevision
+		err := rows.Scan(&revision.ID, &revision.Post, &revision.Editor, &revision.Message, &revision.Created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading post revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+func (s *postgresPostStorage) GetPostRevision(ctx context.Context, postID int64, revisionID int64) (*models.PostRevision, error) {
+	revision := &models.PostRevision{}
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, post_id, COALESCE(editor, ''), message, created
+		FROM post_revisions
+		WHERE post_id = $1 AND id = $2`, postID, revisionID).
+		Scan(&revision.ID, &revision.Post, &revision.Editor, &revision.Message, &revision.Created)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get revision %d of post %d: %w", revisionID, postID, err)
+	}
+	return revision, nil
+}
+
+func (s *postgresPostStorage) DeletePost(ctx context.Context, id int64) (*models.Post, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for post deletion: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Ветку блокируем на чтение: пока пост удаляется, её не удалят и счётчик форума не уменьшится дважды.
+	post := &models.Post{}
+	var threadState string
+	err = tx.QueryRow(ctx, `
+		SELECT p.id, p.parent, p.author, p.message, p.is_edited, p.is_deleted, p.forum, p.thread_id, p.created, p.score, p.reactions, p.attachments, t.state
+		FROM posts p
+		JOIN threads t ON t.id = p.thread_id
+		WHERE p.id = $1
+		FOR UPDATE OF p FOR SHARE OF t`, id).Scan(
+		&post.ID,
+		&post.Parent,
+		&post.Author,
+		&post.Message,
+		&post.IsEdited,
+		&post.IsDeleted,
+		&post.Forum,
+		&post.Thread,
+		&post.Created,
+		&post.Score,
+		&post.Reactions,
+		&post.Attachments,
+		&threadState,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock post for deletion: %w", err)
+	}
+	if threadState == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+
+	if post.IsDeleted {
+		return post, nil
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE posts SET is_deleted = TRUE WHERE id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark post as deleted: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE forums SET posts = posts - 1 WHERE slug = $1`, post.Forum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrement forum posts count: %w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit post deletion: %w", err)
+	}
+
+	post.IsDeleted = true
+	return post, nil
+}
+
+// SetPostReaction ставит (active) или снимает реакцию и пересчитывает счётчики поста.
+// Голоса «за» и «против» взаимоисключающие: новый голос заменяет противоположный.
+func (s *postgresPostStorage) SetPostReaction(ctx context.Context, id int64, nickname string, kind string, active bool) (*models.Post, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for post reaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var lockedID int64
+	err = tx.QueryRow(ctx, `SELECT id FROM posts WHERE id = $1 FOR UPDATE`, id).Scan(&lockedID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock post %d for reaction: %w", id, err)
+	}
+
+	if active {
+		if opposite := oppositeVote(kind); opposite != "" {
+			_, err = tx.Exec(ctx, `DELETE FROM post_reactions WHERE post_id = $1 AND user_nickname = $2 AND kind = $3`, id, nickname, opposite)
+			if err != nil {
+				return nil, fmt.Errorf("failed to drop opposite vote on post %d: %w", id, err)
+			}
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO post_reactions (post_id, user_nickname, kind)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, id, nickname, kind)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM post_reactions WHERE post_id = $1 AND user_nickname = $2 AND kind = $3`, id, nickname, kind)
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, models.ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to save reaction on post %d: %w", id, err)
+	}
+
+	if err := recountPostReactions(ctx, tx, []int64{id}); err != nil {
+		return nil, err
+	}
+
+	post := &models.Post{}
+	err = tx.QueryRow(ctx, `
+		SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, score, reactions, attachments
+		FROM posts
+		WHERE id = $1`, id).Scan(
+		&post.ID,
+		&post.Parent,
+		&post.Author,
+		&post.Message,
+		&post.IsEdited,
+		&post.IsDeleted,
+		&post.Forum,
+		&post.Thread,
+		&post.Created,
+		&post.Score,
+		&post.Reactions,
+		&post.Attachments,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read post %d after reaction: %w", id, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit post reaction: %w", err)
+	}
+	return post, nil
+}
+
+// recountPostReactions пересчитывает score и reactions постов по таблице post_reactions.
+func recountPostReactions(ctx context.Context, db execer, postIDs []int64) error {
+	_, err := db.Exec(ctx, `
+		UPDATE posts p
+		SET reactions = COALESCE((
+		        SELECT jsonb_object_agg(c.kind, c.n)
+		        FROM (SELECT kind, COUNT(*) AS n FROM post_reactions r WHERE r.post_id = p.id GROUP BY kind) c
+		    ), '{}'),
+		    score = (
+		        SELECT COUNT(*) FILTER (WHERE kind = 'up') - COUNT(*) FILTER (WHERE kind = 'down')
+		        FROM post_reactions r
+		        WHERE r.post_id = p.id
+		    )
+		WHERE p.id = ANY($1)`, postIDs)
+	if err != nil {
+		return fmt.Errorf("failed to recount post reactions: %w", err)
+	}
+	return nil
+}
+
+func oppositeVote(kind string) string {
+	switch kind {
+	case models.ReactionUp:
+		return models.ReactionDown
+	case models.ReactionDown:
+		return models.ReactionUp
+	}
+	return ""
+}
+
+func (s *postgresPostStorage) PurgePostSubtree(ctx context.Context, id int64) (int64, []string, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction for post purge: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		threadID     int64
+		threadState  string
+		forumSlug    string
+		path         []int64
+		rootParentID int64
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT p.thread_id, t.state, p.forum, p.path, p.root_parent_id
+		FROM posts p
+		JOIN threads t ON t.id = p.thread_id
+		WHERE p.id = $1
+		FOR SHARE OF t`, id).Scan(&threadID, &threadState, &forumSlug, &path, &rootParentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil, models.ErrNotFound
+		}
+		return 0, nil, fmt.Errorf("failed to get post path for purge: %w", err)
+	}
+
+	// Поддерево поста — все посты ветки, чей path начинается с path удаляемого поста.
+	// Вложения удалились бы каскадом, но их ключи нужны, чтобы убрать файлы.
+	keys, err := deleteAttachments(ctx, tx, `
+		DELETE FROM attachments
+		WHERE post_id IN (
+			SELECT id FROM posts
+			WHERE thread_id = $1 AND root_parent_id = $2 AND path[1:$3] = $4)
+		RETURNING storage_key`,
+		threadID, rootParentID, len(path), path)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to delete attachments of purged posts: %w", err)
+	}
+
+	var purged, purgedAlive int64
+	err = tx.QueryRow(ctx, `
+		WITH removed AS (
+			DELETE FROM posts
+			WHERE thread_id = $1 AND root_parent_id = $2 AND path[1:$3] = $4
+			RETURNING is_deleted
+		)
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE NOT is_deleted) FROM removed`,
+		threadID, rootParentID, len(path), path).Scan(&purged, &purgedAlive)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to purge post subtree: %w", err)
+	}
+
+	// Посты удалённой ветки уже вычтены из счётчика форума при её удалении.
+	if threadState != models.ThreadStateDeleted {
+		_, err = tx.Exec(ctx, `UPDATE forums SET posts = posts - $1 WHERE slug = $2`, purgedAlive, forumSlug)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to decrement forum posts count after purge: %w", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to commit post purge: %w", err)
+	}
+
+	return purged, keys, nil
+}
+
 func (s *postgresPostStorage) CountTableRows(ctx context.Context) (*models.Status, error) {
 	status := &models.Status{}
 
@@ -93,7 +480,8 @@ func (s *postgresPostStorage) CountTableRows(ctx context.Context) (*models.Statu
 	if err != nil {
 		return nil, fmt.Errorf("failed to count threads: %w", err)
 	}
-	err = s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&status.User)
+	// Служебного пользователя для удалённых аккаунтов не считаем.
+	err = s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE nickname <> $1", models.DeletedUserNickname).Scan(&status.User)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -102,16 +490,85 @@ func (s *postgresPostStorage) CountTableRows(ctx context.Context) (*models.Statu
 }
 
 func (s *postgresPostStorage) ClearAllTables(ctx context.Context) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for clear: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		TRUNCATE TABLE users RESTART IDENTITY CASCADE;
 		TRUNCATE TABLE forums RESTART IDENTITY CASCADE;
 		TRUNCATE TABLE threads RESTART IDENTITY CASCADE;
 		TRUNCATE TABLE posts RESTART IDENTITY CASCADE;
 		TRUNCATE TABLE votes RESTART IDENTITY CASCADE;
+		TRUNCATE TABLE conversations RESTART IDENTITY CASCADE;
 	`
-	_, err := s.pool.Exec(ctx, query)
+	_, err = tx.Exec(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to truncate tables: %w", err)
 	}
+
+	// Служебного пользователя создаёт миграция 0023; очистка возвращает его в той же транзакции.
+	_, err = tx.Exec(ctx, `
+		INSERT INTO users (nickname, fullname, email, about)
+		VALUES ($1, 'Deleted user', 'deleted@deleted.invalid', '')`, models.DeletedUserNickname)
+	if err != nil {
+		return fmt.Errorf("failed to restore deleted user placeholder: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit clear: %w", err)
+	}
+	return nil
+}
+
+// savePostMentions сохраняет пары (пост, ник). Пользователи, удалённые после проверки
+// в сервисе, пропускаются: упоминание остаётся просто текстом.
+func savePostMentions(ctx context.Context, db execer, postIDs []int64, nicknames []string) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	_, err := db.Exec(ctx, `
+		INSERT INTO post_mentions (post_id, user_nickname)
+		SELECT m.post_id, u.nickname
+		FROM unnest($1::int[], $2::text[]) AS m(post_id, nickname)
+		JOIN users u ON u.nickname = m.nickname::citext
+		ON CONFLICT DO NOTHING`, postIDs, nicknames)
+	if err != nil {
+		return fmt.Errorf("failed to save post mentions: %w", err)
+	}
 	return nil
 }
+
+// GetUserMentions отдаёт посты с упоминанием пользователя от новых к старым; before — id
+// последнего поста предыдущей страницы. Удалённые посты не показываются.
+func (s *postgresPostStorage) GetUserMentions(ctx context.Context, nickname string, limit int, before int64) ([]models.Post, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT p.id, p.parent, p.author, p.message, p.is_edited, p.is_deleted, p.forum, p.thread_id, p.created, p.score, p.reactions, p.attachments
+		FROM post_mentions m
+		JOIN posts p ON p.id = m.post_id
+		JOIN threads t ON t.id = p.thread_id
+		WHERE m.user_nickname = $1 AND NOT p.is_deleted AND t.state <> 'deleted' AND ($2 = 0 OR m.post_id < $2)
+		ORDER BY m.post_id DESC
+		LIMIT $3`, nickname, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mentions of %s: %w", nickname, err)
+	}
+	defer rows.Close()
+
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		var p models.Post
+		err := rows.Scan(&p.ID, &p.Parent, &p.Author, &p.Message, &p.IsEdited, &p.IsDeleted,
+			&p.Forum, &p.Thread, &p.Created, &p.Score, &p.Reactions, &p.Attachments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mentioning post: %w", err)
+		}
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return posts, nil
+}
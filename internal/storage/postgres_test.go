@@ -0,0 +1,714 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hardhw/internal/migrate"
+	"hardhw/internal/models"
+	"hardhw/internal/pgtest"
+	"hardhw/migrations"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pgEnv — хранилища PostgreSQL над схемой с применёнными миграциями: пользователи bob и ann,
+// форум f1 bob и ветка bob в нём.
+type pgEnv struct {
+	pool    *pgxpool.Pool
+	users   UserStorage
+	forums  ForumStorage
+	threads ThreadStorage
+	posts   PostStorage
+	thread  *models.Thread
+}
+
+func newPgEnv(t *testing.T) *pgEnv {
+	t.Helper()
+
+	ctx := context.Background()
+	pool := pgtest.NewPool(t)
+	m, err := migrate.New(pool, migrations.FS)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	env := &pgEnv{
+		pool:    pool,
+		users:   NewPostgresUserStorage(pool),
+		forums:  NewPostgresForumStorage(pool),
+		threads: NewPostgresThreadStorage(pool),
+		posts:   NewPostgresPostStorage(pool),
+	}
+	for _, nickname := range []string{"bob", "ann"} {
+		if err := env.users.CreateUser(ctx, &models.User{Nickname: nickname, Fullname: nickname, Email: nickname + "@example.com"}); err != nil {
+			t.Fatalf("failed to create user %s: %v", nickname, err)
+		}
+	}
+	if _, err := env.forums.CreateForum(ctx, &models.Forum{Slug: "f1", Title: "Forum", User: "bob"}); err != nil {
+		t.Fatalf("failed to create forum: %v", err)
+	}
+	env.thread = env.createThread(t, "bob")
+	return env
+}
+
+func (env *pgEnv) createThread(t *testing.T, author string) *models.Thread {
+	t.Helper()
+
+	thread, err := env.forums.CreateThread(context.Background(), &models.Thread{Title: "Thread", Author: author, Forum: "f1", Message: "hello"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	if err := env.forums.IncrementForumThreadsCount(context.Background(), "f1"); err != nil {
+		t.Fatalf("failed to count thread: %v", err)
+	}
+	return thread
+}
+
+func (env *pgEnv) createPost(t *testing.T, author string, parent int64) models.Post {
+	t.Helper()
+
+	posts, err := env.threads.CreatePosts(context.Background(), []*models.Post{{Author: author, Message: "post", Parent: parent, Thread: env.thread.ID}})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	return posts[0]
+}
+
+func (env *pgEnv) forumPosts(t *testing.T) int64 {
+	t.Helper()
+
+	forum, err := env.forums.GetForumBySlug(context.Background(), "f1")
+	if err != nil {
+		t.Fatalf("failed to get forum: %v", err)
+	}
+	return forum.Posts
+}
+
+func TestPostgresDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	env := newPgEnv(t)
+	post := env.createPost(t, "ann", 0)
+	thread := env.createThread(t, "ann")
+
+	if _, err := env.users.DeleteUser(ctx, "ann", models.DeleteModeAnonymize); err != nil {
+		t.Fatalf("failed to delete ann: %v", err)
+	}
+	if got, err := env.posts.GetPostByID(ctx, post.ID); err != nil || got.Author != models.DeletedUserNickname {
+		t.Fatalf("post after delete: %+v (%v), want author %q", got, err, models.DeletedUserNickname)
+	}
+	if got, err := env.threads.GetThreadByID(ctx, thread.ID); err != nil || got.Author != models.DeletedUserNickname {
+		t.Fatalf("thread after delete: %+v (%v), want author %q", got, err, models.DeletedUserNickname)
+	}
+
+	// Форум bob тоже переходит служебному пользователю, и второй удалённый аккаунт ему не мешает.
+	if _, err := env.users.DeleteUser(ctx, "bob", models.DeleteModeAnonymize); err != nil {
+		t.Fatalf("failed to delete bob: %v", err)
+	}
+	forum, err := env.forums.GetForumBySlug(ctx, "f1")
+	if err != nil || forum.User != models.DeletedUserNickname {
+		t.Fatalf("forum after delete: %+v (%v), want owner %q", forum, err, models.DeletedUserNickname)
+	}
+
+	users, err := env.forums.GetForumUsers(ctx, "f1", 100, "", false, false)
+	if err != nil {
+		t.Fatalf("failed to get forum users: %v", err)
+	}
+	if len(users) != 0 {
+		t.Fatalf("got forum users %v, want none", users)
+	}
+	status, err := env.posts.CountTableRows(ctx)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if status.User != 0 {
+		t.Fatalf("status counts %d users, want 0", status.User)
+	}
+
+	_, err = env.users.DeleteUser(ctx, "ann", models.DeleteModeAnonymize)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("second delete returned %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestPostgresClearKeepsPlaceholder(t *testing.T) {
+	ctx := context.Background()
+	env := newPgEnv(t)
+
+	if err := env.posts.ClearAllTables(ctx); err != nil {
+		t.Fatalf("failed to clear tables: %v", err)
+	}
+	if _, err := env.users.GetUserByNickname(ctx, models.DeletedUserNickname); err != nil {
+		t.Fatalf("placeholder is gone after clear: %v", err)
+	}
+	status, err := env.posts.CountTableRows(ctx)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if *status != (models.Status{}) {
+		t.Fatalf("got status %+v after clear, want zeros", status)
+	}
+}
+
+func TestPostgresCreatePostsInClosedThread(t *testing.T) {
+	tests := []struct {
+		state string
+		want  error
+	}{
+		{state: models.ThreadStateLocked, want: models.ErrThreadLocked},
+		{state: models.ThreadStateArchived, want: models.ErrThreadLocked},
+		{state: models.ThreadStateDeleted, want: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			ctx := context.Background()
+			env := newPgEnv(t)
+			if _, err := env.threads.SetThreadState(ctx, env.thread.ID, tt.state); err != nil {
+				t.Fatalf("failed to set thread state: %v", err)
+			}
+
+			_, err := env.threads.CreatePosts(ctx, []*models.Post{{Author: "ann", Message: "late", Thread: env.thread.ID}})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+			if posts := env.forumPosts(t); posts != 0 {
+				t.Fatalf("forum counts %d posts, want 0", posts)
+			}
+		})
+	}
+}
+
+func TestPostgresVoteInClosedThread(t *testing.T) {
+	tests := []struct {
+		state string
+		want  error
+	}{
+		{state: models.ThreadStateLocked, want: models.ErrThreadLocked},
+		{state: models.ThreadStateArchived, want: models.ErrThreadLocked},
+		{state: models.ThreadStateDeleted, want: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			ctx := context.Background()
+			env := newPgEnv(t)
+			if _, err := env.threads.SetThreadState(ctx, env.thread.ID, tt.state); err != nil {
+				t.Fatalf("failed to set thread state: %v", err)
+			}
+
+			_, err := env.threads.UpdateThreadVote(ctx, env.thread.ID, "ann", 1)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+			var votes int
+			if err := env.pool.QueryRow(ctx, `SELECT COUNT(*) FROM votes WHERE thread_id = $1`, env.thread.ID).Scan(&votes); err != nil {
+				t.Fatalf("failed to count votes: %v", err)
+			}
+			if votes != 0 {
+				t.Fatalf("thread has %d votes, want 0", votes)
+			}
+		})
+	}
+}
+
+func TestPostgresPurgePostSubtree(t *testing.T) {
+	ctx := context.Background()
+	env := newPgEnv(t)
+
+	root := env.createPost(t, "bob", 0)
+	child := env.createPost(t, "ann", root.ID)
+	grandchild := env.createPost(t, "bob", child.ID)
+	other := env.createPost(t, "ann", 0)
+	if _, err := env.posts.DeletePost(ctx, grandchild.ID); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+	_, err := env.pool.Exec(ctx, `
+		INSERT INTO attachments (owner_nickname, filename, content_type, size, storage_key, post_id)
+		VALUES ('ann', 'a.txt', 'text/plain', 1, 'child-key', $1), ('ann', 'b.txt', 'text/plain', 1, 'other-key', $2)`,
+		child.ID, other.ID)
+	if err != nil {
+		t.Fatalf("failed to attach files: %v", err)
+	}
+	if posts := env.forumPosts(t); posts != 3 {
+		t.Fatalf("forum counts %d posts before purge, want 3", posts)
+	}
+
+	purged, keys, err := env.posts.PurgePostSubtree(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("failed to purge subtree: %v", err)
+	}
+	if purged != 3 {
+		t.Fatalf("purged %d posts, want 3", purged)
+	}
+	if !slices.Equal(keys, []string{"child-key"}) {
+		t.Fatalf("got attachment keys %v, want [child-key]", keys)
+	}
+	// Удалённый раньше пост уже вычтен из счётчика, повторно его не вычитаем.
+	if posts := env.forumPosts(t); posts != 1 {
+		t.Fatalf("forum counts %d posts after purge, want 1", posts)
+	}
+	for _, id := range []int64{root.ID, child.ID, grandchild.ID} {
+		if _, err := env.posts.GetPostByID(ctx, id); !errors.Is(err, models.ErrNotFound) {
+			t.Fatalf("post %d after purge: %v, want %v", id, err, models.ErrNotFound)
+		}
+	}
+	if _, err := env.posts.GetPostByID(ctx, other.ID); err != nil {
+		t.Fatalf("unrelated post is gone: %v", err)
+	}
+
+	if _, _, err := env.posts.PurgePostSubtree(ctx, root.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("second purge returned %v, want %v", err, models.ErrNotFound)
+	}
+}
+
+func TestPostgresForumThreadsPinnedFirst(t *testing.T) {
+	env := newPgEnv(t)
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Ветка из newPgEnv создана сейчас, то есть позже всех остальных,
+	// а threads[3] создана одновременно с threads[0], и их порядок решает id.
+	threads := make([]*models.Thread, 4)
+	for i := range threads {
+		thread, err := env.forums.CreateThread(ctx, &models.Thread{Title: "Thread", Author: "ann", Forum: "f1", Message: "hello", Created: start.Add(time.Duration(i%3) * time.Hour)})
+		if err != nil {
+			t.Fatalf("failed to create thread: %v", err)
+		}
+		threads[i] = thread
+	}
+	for _, thread := range []*models.Thread{threads[1], threads[2]} {
+		if _, err := env.threads.SetThreadPinned(ctx, thread.ID, true); err != nil {
+			t.Fatalf("failed to pin thread: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		since *time.Time
+		desc  bool
+		want  []int64
+	}{
+		{name: "limit skips pinned threads", limit: 1, want: []int64{threads[1].ID, threads[2].ID, threads[0].ID}},
+		{name: "pinned first", limit: 3, want: []int64{threads[1].ID, threads[2].ID, threads[0].ID, threads[3].ID, env.thread.ID}},
+		{name: "pinned first desc", limit: 3, desc: true, want: []int64{threads[2].ID, threads[1].ID, env.thread.ID, threads[3].ID, threads[0].ID}},
+		{name: "since keeps pinned", limit: 3, since: &threads[2].Created, want: []int64{threads[1].ID, threads[2].ID, env.thread.ID}},
+		{name: "since keeps pinned desc", limit: 3, since: &threads[0].Created, desc: true, want: []int64{threads[2].ID, threads[1].ID, threads[3].ID, threads[0].ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := env.forums.GetThreadsByForumSlug(ctx, "f1", nil, tt.limit, tt.since, tt.desc)
+			if err != nil {
+				t.Fatalf("failed to get threads: %v", err)
+			}
+			got := make([]int64, len(page))
+			for i, thread := range page {
+				got[i] = thread.ID
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got threads %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPostgresConcurrentPollVotes голосует параллельно: строка опроса блокируется,
+// поэтому каждый голос заменяет прежний выбор своего пользователя и итог сходится.
+func TestPostgresSubforumTotals(t *testing.T) {
+	env := newPgEnv(t)
+	ctx := context.Background()
+
+	// cat -> f1 -> f2; у f1 уже есть ветка из newPgEnv.
+	if _, err := env.forums.CreateForum(ctx, &models.Forum{Slug: "cat", Title: "Category", User: "bob", Category: true}); err != nil {
+		t.Fatalf("failed to create category: %v", err)
+	}
+	parent := "cat"
+	if _, err := env.forums.UpdateForum(ctx, "f1", models.ForumUpdate{Parent: &parent}); err != nil {
+		t.Fatalf("failed to move forum: %v", err)
+	}
+	parent = "f1"
+	if _, err := env.forums.CreateForum(ctx, &models.Forum{Slug: "f2", Title: "Nested", User: "bob", Parent: &parent}); err != nil {
+		t.Fatalf("failed to create nested forum: %v", err)
+	}
+	nested, err := env.forums.CreateThread(ctx, &models.Thread{Title: "Thread", Author: "ann", Forum: "f2", Message: "hello"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	if err := env.forums.IncrementForumThreadsCount(ctx, "f2"); err != nil {
+		t.Fatalf("failed to count thread: %v", err)
+	}
+	if _, err := env.threads.CreatePosts(ctx, []*models.Post{{Author: "ann", Message: "post", Thread: nested.ID}}); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	tests := []struct {
+		slug           string
+		posts, threads int64
+	}{
+		{slug: "cat", posts: 1, threads: 2},
+		{slug: "F1", posts: 1, threads: 1},
+		{slug: "f2"},
+	}
+	for _, tt := range tests {
+		posts, threads, err := env.forums.GetSubforumTotals(ctx, tt.slug)
+		if err != nil {
+			t.Fatalf("failed to get subforum totals of %s: %v", tt.slug, err)
+		}
+		if posts != tt.posts || threads != tt.threads {
+			t.Fatalf("subforums of %s have %d posts and %d threads, want %d and %d", tt.slug, posts, threads, tt.posts, tt.threads)
+		}
+	}
+}
+
+func TestPostgresPollVoteInClosedThread(t *testing.T) {
+	tests := []struct {
+		state string
+		want  error
+	}{
+		{state: models.ThreadStateLocked, want: models.ErrThreadLocked},
+		{state: models.ThreadStateArchived, want: models.ErrThreadLocked},
+		{state: models.ThreadStateDeleted, want: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			ctx := context.Background()
+			env := newPgEnv(t)
+			poll := &models.Poll{Question: "Q", Options: []models.PollOption{{Text: "a"}, {Text: "b"}}}
+			thread, err := env.forums.CreateThread(ctx, &models.Thread{Title: "Poll", Author: "bob", Forum: "f1", Message: "hello", Poll: poll})
+			if err != nil {
+				t.Fatalf("failed to create poll thread: %v", err)
+			}
+			if _, err := env.threads.SetThreadState(ctx, thread.ID, tt.state); err != nil {
+				t.Fatalf("failed to set thread state: %v", err)
+			}
+
+			_, err = env.threads.VoteThreadPoll(ctx, thread.ID, "ann", []int{1})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+			var votes int
+			if err := env.pool.QueryRow(ctx, `SELECT COUNT(*) FROM poll_votes WHERE thread_id = $1`, thread.ID).Scan(&votes); err != nil {
+				t.Fatalf("failed to count poll votes: %v", err)
+			}
+			if votes != 0 {
+				t.Fatalf("poll has %d votes, want 0", votes)
+			}
+		})
+	}
+}
+
+func TestPostgresConcurrentPollVotes(t *testing.T) {
+	env := newPgEnv(t)
+	ctx := context.Background()
+
+	poll := &models.Poll{Question: "Q", Multiple: true, Options: []models.PollOption{{Text: "a"}, {Text: "b"}, {Text: "c"}}}
+	thread, err := env.forums.CreateThread(ctx, &models.Thread{Title: "Poll", Author: "bob", Forum: "f1", Message: "hello", Poll: poll})
+	if err != nil {
+		t.Fatalf("failed to create poll thread: %v", err)
+	}
+
+	const voters = 16
+	for i := 0; i < voters; i++ {
+		nickname := fmt.Sprintf("voter%d", i)
+		if err := env.users.CreateUser(ctx, &models.User{Nickname: nickname, Fullname: nickname, Email: nickname + "@example.com"}); err != nil {
+			t.Fatalf("failed to create user %s: %v", nickname, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, voters*3)
+	for i := 0; i < voters; i++ {
+		nickname := fmt.Sprintf("voter%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, options := range [][]int{{1}, {1, 2}, {2, 3}} {
+				_, err := env.threads.VoteThreadPoll(ctx, thread.ID, nickname, options)
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to vote: %v", err)
+		}
+	}
+
+	result, err := env.threads.GetThreadPoll(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("failed to get poll: %v", err)
+	}
+	want := []int32{0, voters, voters}
+	for i, option := range result.Options {
+		if option.Votes != want[i] {
+			t.Fatalf("option %d has %d votes, want %d", option.ID, option.Votes, want[i])
+		}
+	}
+	if result.Voters != voters {
+		t.Fatalf("poll has %d voters, want %d", result.Voters, voters)
+	}
+}
+
+// TestPostgresRenameUserCascades переименовывает пользователя, на которого ссылается каждая
+// таблица со внешним ключом на users. Список ключей берётся из каталога, так что новая
+// таблица без ON UPDATE CASCADE или без строки в этом тесте его уронит.
+func TestPostgresRenameUserCascades(t *testing.T) {
+	env := newPgEnv(t)
+	ctx := context.Background()
+	thread := env.createThread(t, "ann")
+	post := env.createPost(t, "ann", 0)
+
+	// Без параметров pgx отправляет запрос простым протоколом, и несколько команд проходят одной строкой.
+	seed := strings.NewReplacer("$1", strconv.FormatInt(thread.ID, 10), "$2", strconv.FormatInt(post.ID, 10)).Replace(`
+        INSERT INTO forums (slug, title, user_nickname) VALUES ('f2', 'Forum', 'ann');
+        INSERT INTO votes (thread_id, user_nickname, voice) VALUES ($1, 'ann', 1);
+        INSERT INTO forum_users (forum_slug, user_nickname) VALUES ('f2', 'ann');
+        INSERT INTO auth_tokens (token_hash, nickname, kind) VALUES ('hash', 'ann', 'session');
+        INSERT INTO forum_moderators (forum_slug, user_nickname, granted_by) VALUES ('f2', 'ann', 'ann');
+        INSERT INTO forum_bans (forum_slug, user_nickname, banned_by) VALUES ('f1', 'ann', 'ann');
+        INSERT INTO user_suspensions (user_nickname, suspended_by) VALUES ('ann', 'ann');
+        INSERT INTO webhooks (forum_slug, url, secret, events, created_by) VALUES ('f2', 'http://example.com', 's', '{post.created}', 'ann');
+        INSERT INTO post_revisions (post_id, editor, message) VALUES ($2, 'ann', 'old');
+        INSERT INTO thread_revisions (thread_id, editor, title, message) VALUES ($1, 'ann', 'Old', 'old');
+        INSERT INTO post_reactions (post_id, user_nickname, kind) VALUES ($2, 'ann', 'like');
+        INSERT INTO thread_polls (thread_id, question) VALUES ($1, 'Q');
+        INSERT INTO poll_options (thread_id, option_id, text) VALUES ($1, 1, 'a'), ($1, 2, 'b');
+        INSERT INTO poll_votes (thread_id, option_id, user_nickname) VALUES ($1, 1, 'ann');
+        INSERT INTO thread_subscriptions (thread_id, user_nickname) VALUES ($1, 'ann');
+        INSERT INTO forum_subscriptions (forum_slug, user_nickname) VALUES ('f1', 'ann');
+        INSERT INTO notifications (user_nickname, kind, post_id) VALUES ('ann', 'reply', $2);
+        INSERT INTO post_mentions (post_id, user_nickname) VALUES ($2, 'ann');
+        INSERT INTO attachments (owner_nickname, filename, content_type, size, storage_key) VALUES ('ann', 'a.png', 'image/png', 1, 'key');
+        INSERT INTO user_blocks (blocker_nickname, blocked_nickname) VALUES ('ann', 'bob'), ('bob', 'ann');
+        INSERT INTO conversations (id) VALUES (1);
+        INSERT INTO conversation_members (conversation_id, user_nickname) VALUES (1, 'ann'), (1, 'bob');
+        INSERT INTO conversation_messages (conversation_id, author, message) VALUES (1, 'ann', 'hi');
+        INSERT INTO user_nickname_aliases (nickname, user_nickname) VALUES ('annie', 'ann');
+    `)
+	if _, err := env.pool.Exec(ctx, seed); err != nil {
+		t.Fatalf("failed to seed references: %v", err)
+	}
+
+	renamed, err := env.users.RenameUser(ctx, "ANN", "anna")
+	if err != nil || renamed.Nickname != "anna" {
+		t.Fatalf("rename: %+v (%v), want anna", renamed, err)
+	}
+
+	rows, err := env.pool.Query(ctx, `
+        SELECT c.conrelid::regclass::text, a.attname
+        FROM pg_constraint c
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = ANY (c.conkey)
+        WHERE c.contype = 'f' AND c.confrelid = 'users'::regclass
+        ORDER BY 1, 2
+    `)
+	if err != nil {
+		t.Fatalf("failed to list foreign keys: %v", err)
+	}
+	type reference struct{ table, column string }
+	var references []reference
+	for rows.Next() {
+		var ref reference
+		if err := rows.Scan(&ref.table, &ref.column); err != nil {
+			t.Fatalf("failed to scan foreign key: %v", err)
+		}
+		references = append(references, ref)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to list foreign keys: %v", err)
+	}
+
+	for _, ref := range references {
+		var before, after int
+		query := fmt.Sprintf(`SELECT count(*) FILTER (WHERE %[1]s = 'ann'), count(*) FILTER (WHERE %[1]s = 'anna') FROM %[2]s`,
+			pgx.Identifier{ref.column}.Sanitize(), ref.table)
+		if err := env.pool.QueryRow(ctx, query).Scan(&before, &after); err != nil {
+			t.Fatalf("failed to count %s.%s: %v", ref.table, ref.column, err)
+		}
+		if before != 0 || after == 0 {
+			t.Errorf("%s.%s: %d rows reference ann and %d reference anna, want 0 and some", ref.table, ref.column, before, after)
+		}
+	}
+
+	// Прежние ники ведут на новый, а смена одного регистра алиаса не оставляет.
+	for _, alias := range []string{"ann", "Annie"} {
+		user, err := env.users.GetUserByAlias(ctx, alias)
+		if err != nil || user.Nickname != "anna" {
+			t.Fatalf("alias %q: %+v (%v), want anna", alias, user, err)
+		}
+	}
+	if _, err := env.users.RenameUser(ctx, "anna", "Anna"); err != nil {
+		t.Fatalf("failed to change case: %v", err)
+	}
+	if _, err := env.users.GetUserByAlias(ctx, "anna"); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("alias after case-only rename returned %v, want %v", err, models.ErrNotFound)
+	}
+
+	// Ник, занятый другим пользователем, не отдаётся.
+	if _, err := env.users.RenameUser(ctx, "Anna", "BOB"); !errors.Is(err, models.ErrUserConflict) {
+		t.Fatalf("rename to bob returned %v, want %v", err, models.ErrUserConflict)
+	}
+}
+
+// TestPostgresNotificationQueue разбирает очередь двумя воркерами сразу: SKIP LOCKED
+// раздаёт им разные посты, и каждый пост даёт каждому получателю одно уведомление.
+func TestPostgresNotificationQueue(t *testing.T) {
+	env := newPgEnv(t)
+	ctx := context.Background()
+	notifications := NewPostgresNotificationStorage(env.pool)
+	if err := env.users.CreateUser(ctx, &models.User{Nickname: "eve", Fullname: "eve", Email: "eve@example.com"}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := notifications.SubscribeThread(ctx, env.thread.ID, "ann"); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := notifications.SubscribeForum(ctx, "f1", "eve"); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	root := env.createPost(t, "bob", 0)
+	const replies = 20
+	batch := make([]*models.Post, replies)
+	for i := range batch {
+		batch[i] = &models.Post{Author: "eve", Message: "@ann", Parent: root.ID, Thread: env.thread.ID, Mentions: []string{"ann"}}
+	}
+	if _, err := env.threads.CreatePosts(ctx, batch); err != nil {
+		t.Fatalf("failed to create replies: %v", err)
+	}
+
+	var queued int
+	if err := env.pool.QueryRow(ctx, `SELECT count(*) FROM post_notification_queue`).Scan(&queued); err != nil {
+		t.Fatalf("failed to count queue: %v", err)
+	}
+	if queued != replies+1 {
+		t.Fatalf("queue has %d posts, want %d", queued, replies+1)
+	}
+
+	var wg sync.WaitGroup
+	processed := make(chan int, 2*queued)
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n, err := notifications.NotifyPendingPosts(ctx, 3)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if n == 0 {
+					return
+				}
+				processed <- n
+			}
+		}()
+	}
+	wg.Wait()
+	close(processed)
+	close(errs)
+	for err := range errs {
+		t.Fatalf("worker failed: %v", err)
+	}
+	total := 0
+	for n := range processed {
+		total += n
+	}
+	if total != queued {
+		t.Fatalf("workers processed %d posts, want %d", total, queued)
+	}
+
+	tests := []struct {
+		nickname string
+		kind     string
+		want     int
+	}{
+		{nickname: "ann", kind: models.NotificationKindMention, want: replies},
+		{nickname: "ann", kind: models.NotificationKindSubscription, want: 1},
+		{nickname: "bob", kind: models.NotificationKindReply, want: replies},
+		{nickname: "eve", kind: models.NotificationKindSubscription, want: 1},
+	}
+	for _, tt := range tests {
+		var got int
+		err := env.pool.QueryRow(ctx, `SELECT count(*) FROM notifications WHERE user_nickname = $1 AND kind = $2`, tt.nickname, tt.kind).Scan(&got)
+		if err != nil {
+			t.Fatalf("failed to count notifications: %v", err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s has %d %s notifications, want %d", tt.nickname, got, tt.kind, tt.want)
+		}
+	}
+	if err := env.pool.QueryRow(ctx, `SELECT count(*) FROM post_notification_queue`).Scan(&queued); err != nil || queued != 0 {
+		t.Fatalf("queue has %d posts left (%v), want none", queued, err)
+	}
+}
+
+// TestPostgresSearch проверяет, что сниппет ts_headline экранируется целиком, а страницы
+// по курсору (rank, kind, id) не теряют и не повторяют результаты с одинаковым рангом.
+func TestPostgresSearch(t *testing.T) {
+	env := newPgEnv(t)
+	ctx := context.Background()
+	search := NewPostgresSearchStorage(env.pool)
+
+	marked := env.createPost(t, "ann", 0)
+	// Символы маркеров в самом тексте не должны превратиться в теги.
+	_, err := env.pool.Exec(ctx, `UPDATE posts SET message = $2 WHERE id = $1`, marked.ID,
+		"<script>x</script> & <b>banana</b> \uE000fake\uE001 banana")
+	if err != nil {
+		t.Fatalf("failed to set message: %v", err)
+	}
+	hits, err := search.Search(ctx, models.SearchQuery{Query: "banana", Limit: 10})
+	if err != nil || len(hits) != 1 {
+		t.Fatalf("got hits %+v (%v), want one", hits, err)
+	}
+	snippet := hits[0].Snippet
+	if !strings.Contains(snippet, "<b>banana</b>") || !strings.Contains(snippet, "&lt;") {
+		t.Fatalf("snippet %q lacks a highlighted match or escaped markup", snippet)
+	}
+	if rest := strings.ReplaceAll(snippet, "<b>banana</b>", ""); strings.ContainsAny(rest, "<>\uE000\uE001") {
+		t.Fatalf("snippet %q has markup besides the highlighted matches", snippet)
+	}
+
+	var ids []int64
+	for i := 0; i < 7; i++ {
+		ids = append(ids, env.createPost(t, "ann", 0).ID)
+	}
+	if _, err := env.pool.Exec(ctx, `UPDATE posts SET message = 'apple pie' WHERE id = ANY($1)`, ids); err != nil {
+		t.Fatalf("failed to set messages: %v", err)
+	}
+
+	var got []int64
+	query := models.SearchQuery{Query: "apple", Limit: 3}
+	for {
+		page, err := search.Search(ctx, query)
+		if err != nil {
+			t.Fatalf("failed to search: %v", err)
+		}
+		for _, hit := range page {
+			got = append(got, hit.Post.ID)
+		}
+		if len(page) < query.Limit {
+			break
+		}
+		last := page[len(page)-1]
+		query.After = &models.SearchCursor{Rank: last.Rank, Kind: last.Kind, ID: last.Post.ID}
+	}
+	slices.Reverse(ids)
+	if !slices.Equal(got, ids) {
+		t.Fatalf("got posts %v, want %v", got, ids)
+	}
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hardhw/internal/models"
+	"html"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SearchStorage interface {
+	// Search возвращает найденные посты и ветки; Snippet каждого результата — готовый HTML.
+	Search(ctx context.Context, query models.SearchQuery) ([]models.SearchHit, error)
+}
+
+// ts_headline размечает совпадения в сыром тексте, поэтому маркеры не могут быть тегами:
+// текст экранируется уже после подсветки, и только затем маркеры заменяются на <b>.
+// Символы маркеров из области частного использования заранее вырезаются из текста.
+const (
+	snippetStartSel = "\uE000"
+	snippetStopSel  = "\uE001"
+)
+
+var snippetTags = strings.NewReplacer(snippetStartSel, "<b>", snippetStopSel, "</b>")
+
+type postgresSearchStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresSearchStorage(pool *pgxpool.Pool) SearchStorage {
+	return &postgresSearchStorage{pool: pool}
+}
+
+// Search ищет по постам и веткам одним запросом. Сниппеты строятся только для
+// попавших на страницу результатов: ts_headline заметно дороже самого поиска.
+func (s *postgresSearchStorage) Search(ctx context.Context, query models.SearchQuery) ([]models.SearchHit, error) {
+	var afterRank *float32
+	var afterKind *string
+	var afterID *int64
+	if query.After != nil {
+		afterRank = &query.After.Rank
+		afterKind = &query.After.Kind
+		afterID = &query.After.ID
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query),
+		hits AS (
+			SELECT 'post' AS kind, p.id, ts_rank(p.search_vector, q.query) AS rank, p.message AS body
+			FROM posts p
+			JOIN threads t ON t.id = p.thread_id AND t.state <> 'deleted', q
+			WHERE p.search_vector @@ q.query
+			  AND NOT p.is_deleted
+			  AND ($2 = '' OR p.forum = $2::citext)
+			  AND ($3 = '' OR p.author = $3::citext)
+			  AND ($4::timestamptz IS NULL OR p.created >= $4)
+			UNION ALL
+			SELECT 'thread' AS kind, t.id, ts_rank(t.search_vector, q.query) AS rank, t.title || ' ' || t.message AS body
+			FROM threads t, q
+			WHERE t.search_vector @@ q.query
+			  AND t.state <> 'deleted'
+			  AND ($2 = '' OR t.forum = $2::citext)
+			  AND ($3 = '' OR t.author = $3::citext)
+			  AND ($4::timestamptz IS NULL OR t.created >= $4)
+		),
+		page AS (
+			SELECT kind, id, rank, body
+			FROM hits
+			WHERE $5::real IS NULL OR (rank, kind, id) < ($5::real, $6::text, $7::bigint)
+			ORDER BY rank DESC, kind DESC, id DESC
+			LIMIT $8
+		)
+		SELECT page.kind, page.id, page.rank,
+		       ts_headline('simple', translate(page.body, $9::text || $10::text, ''), q.query,
+		                   'StartSel=' || $9::text || ', StopSel=' || $10::text || ', MaxFragments=2, MaxWords=20, MinWords=5')
+		FROM page, q
+		ORDER BY page.rank DESC, page.kind DESC, page.id DESC`,
+		query.Query, query.Forum, query.Author, query.Since, afterRank, afterKind, afterID, query.Limit,
+		snippetStartSel, snippetStopSel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run search query: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]models.SearchHit, 0)
+	var postIDs, threadIDs []int64
+	for rows.Next() {
+		var hit models.SearchHit
+		var id int64
+		var snippet string
+		if err := rows.Scan(&hit.Kind, &id, &hit.Rank, &snippet); err != nil {
+			return nil, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		hit.Snippet = snippetTags.Replace(html.EscapeString(snippet))
+		if hit.Kind == models.SearchKindPost {
+			hit.Post = &models.Post{ID: id}
+			postIDs = append(postIDs, id)
+		} else {
+			hit.Thread = &models.Thread{ID: id}
+			threadIDs = append(threadIDs, id)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading search hits: %w", err)
+	}
+
+	posts, err := s.postsByIDs(ctx, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := s.threadsByIDs(ctx, threadIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Пост или ветку могли удалить между запросами — такие результаты пропускаем.
+	found := hits[:0]
+	for _, hit := range hits {
+		if hit.Post != nil {
+			hit.Post = posts[hit.Post.ID]
+		} else {
+			hit.Thread = threads[hit.Thread.ID]
+		}
+		if hit.Post != nil || hit.Thread != nil {
+			found = append(found, hit)
+		}
+	}
+	return found, nil
+}
+
+func (s *postgresSearchStorage) postsByIDs(ctx context.Context, ids []int64) (map[int64]*models.Post, error) {
+	posts := make(map[int64]*models.Post, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, score, reactions, attachments
+		FROM posts
+		WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load found posts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post := &models.Post{}
+		err := rows.Scan(&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted, &post.Forum, &post.Thread, &post.Created, &post.Score, &post.Reactions, &post.Attachments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan found post: %w", err)
+		}
+		posts[post.ID] = post
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while loading found posts: %w", err)
+	}
+	return posts, nil
+}
+
+func (s *postgresSearchStorage) threadsByIDs(ctx context.Context, ids []int64) (map[int64]*models.Thread, error) {
+	threads := make(map[int64]*models.Thread, len(ids))
+	if len(ids) == 0 {
+		return threads, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured
+		FROM threads
+		WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load found threads: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		thread := &models.Thread{}
+		var slug sql.NullString
+		err := rows.Scan(&thread.ID, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &slug, &thread.Created, &thread.State, &thread.Tags, &thread.Pinned, &thread.Featured)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan found thread: %w", err)
+		}
+		if slug.Valid {
+			thread.Slug = &slug.String
+		}
+		threads[thread.ID] = thread
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while loading found threads: %w", err)
+	}
+	return threads, nil
+}
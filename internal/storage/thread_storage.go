@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -13,6 +14,7 @@ import (
 	"hardhw/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -25,8 +27,15 @@ type ThreadStorage interface {
 	GetFlatThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error)
 	GetTreeThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error)
 	GetParentTreeThreadPosts(ctx context.Context, threadId int64, limit int, since int64, desc bool) ([]models.Post, error)
-	UpdateThread(ctx context.Context, slugOrID string, updateData models.ThreadUpdate) (models.Thread, error)
+	GetTopThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error)
+	UpdateThread(ctx context.Context, slugOrID string, updateData models.ThreadUpdate, editor string) (models.Thread, error)
+	GetThreadRevisions(ctx context.Context, threadID int64) ([]models.ThreadRevision, error)
 	GetThreadByID(ctx context.Context, id int64) (*models.Thread, error)
+	SetThreadState(ctx context.Context, threadID int64, state string) (*models.Thread, error)
+	SetThreadPinned(ctx context.Context, threadID int64, pinned bool) (*models.Thread, error)
+	SetThreadFeatured(ctx context.Context, threadID int64, featured bool) (*models.Thread, error)
+	GetThreadPoll(ctx context.Context, threadID int64) (*models.Poll, error)
+	VoteThreadPoll(ctx context.Context, threadID int64, nickname string, options []int) (*models.Poll, error)
 }
 
 type postgresThreadStorage struct {
@@ -39,7 +48,7 @@ func NewPostgresThreadStorage(pool *pgxpool.Pool) ThreadStorage {
 
 func (s *postgresThreadStorage) GetThreadIDBySlugOrID(ctx context.Context, slugOrID string) (int64, error) {
 	var threadID int64
-	query := `SELECT id FROM threads WHERE slug = $1 OR id = $2::int`
+	query := `SELECT id FROM threads WHERE (slug = $1 OR id = $2::int) AND state <> 'deleted'`
 
 	idInt, err := strconv.ParseInt(slugOrID, 10, 64)
 	if err != nil {
@@ -50,13 +59,31 @@ func (s *postgresThreadStorage) GetThreadIDBySlugOrID(ctx context.Context, slugO
 	err = s.pool.QueryRow(ctx, query, slugOrID, idInt).Scan(&threadID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, models.ErrNotFound
+			return s.threadIDBySlugAlias(ctx, slugOrID)
 		}
 		return 0, fmt.Errorf("failed to get thread ID by slug or ID: %w", err)
 	}
 	return threadID, nil
 }
 
+// threadIDBySlugAlias ищет ветку по старому slug. Вызывается только после промаха
+// по действующим slug, поэтому занятый заново slug всегда ведёт на новую ветку.
+func (s *postgresThreadStorage) threadIDBySlugAlias(ctx context.Context, slug string) (int64, error) {
+	var threadID int64
+	err := s.pool.QueryRow(ctx, `
+		SELECT t.id
+		FROM thread_slug_aliases a
+		JOIN threads t ON t.id = a.thread_id
+		WHERE a.slug = $1 AND t.state <> 'deleted'`, slug).Scan(&threadID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, models.ErrNotFound
+		}
+		return 0, fmt.Errorf("failed to get thread ID by slug alias: %w", err)
+	}
+	return threadID, nil
+}
+
 func (s *postgresThreadStorage) CheckParentPostExistsInThread(ctx context.Context, parentID int64, threadID int64) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1 AND thread_id = $2)`
 	var exists bool
@@ -78,15 +105,23 @@ func (s *postgresThreadStorage) CreatePosts(ctx context.Context, posts []*models
 		return []models.Post{}, nil
 	}
 
-	var threadForumSlug string
-	getThreadForumQuery := `SELECT forum FROM threads WHERE id = $1`
-	err = tx.QueryRow(ctx, getThreadForumQuery, posts[0].Thread).Scan(&threadForumSlug)
+	// FOR SHARE не даёт закрыть или удалить ветку, пока в неё вставляются посты: проверка
+	// состояния в сервисе сделана до транзакции и сама по себе от гонки не защищает.
+	var threadForumSlug, threadState string
+	getThreadForumQuery := `SELECT forum, state FROM threads WHERE id = $1 FOR SHARE`
+	err = tx.QueryRow(ctx, getThreadForumQuery, posts[0].Thread).Scan(&threadForumSlug, &threadState)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, models.ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get forum slug for thread %d: %w", posts[0].Thread, err)
 	}
+	switch threadState {
+	case models.ThreadStateDeleted:
+		return nil, models.ErrNotFound
+	case models.ThreadStateLocked, models.ThreadStateArchived:
+		return nil, models.ErrThreadLocked
+	}
 
 	insertQueryPrefix := `INSERT INTO posts(author, created, forum, message, parent, thread_id) VALUES ` // Используем thread_id
 	valuesBuilder := strings.Builder{}
@@ -224,6 +259,39 @@ func (s *postgresThreadStorage) CreatePosts(ctx context.Context, posts []*models
 		}
 	}
 
+	var mentionPosts []int64
+	var mentionNicknames []string
+	for i, p := range postsInOriginalOrder {
+		for _, nickname := range posts[i].Mentions {
+			mentionPosts = append(mentionPosts, p.ID)
+			mentionNicknames = append(mentionNicknames, nickname)
+		}
+	}
+	if err := savePostMentions(ctx, tx, mentionPosts, mentionNicknames); err != nil {
+		return nil, err
+	}
+
+	for i, p := range postsInOriginalOrder {
+		p.Attachments, err = bindPostAttachments(ctx, tx, p.ID, p.Author, posts[i].Attachments)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	createdIDs := make([]int64, len(postsInOriginalOrder))
+	for i, p := range postsInOriginalOrder {
+		createdIDs[i] = p.ID
+	}
+
+	if err := enqueuePostNotifications(ctx, tx, createdIDs); err != nil {
+		return nil, err
+	}
+
+	err = enqueueWebhookEvent(ctx, tx, threadForumSlug, models.WebhookEventPostCreated, postsInOriginalOrder)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -243,6 +311,24 @@ func (s *postgresThreadStorage) UpdateThreadVote(ctx context.Context, threadID i
 	}
 	defer tx.Rollback(ctx)
 
+	// Состояние ветки перечитывается под блокировкой, как в CreatePosts: проверка в сервисе
+	// сделана до транзакции. FOR NO KEY UPDATE вместо FOR SHARE, потому что ниже эта же строка
+	// обновляется, и два голосующих с FOR SHARE ждали бы друг друга.
+	var threadState string
+	err = tx.QueryRow(ctx, `SELECT state FROM threads WHERE id = $1 FOR NO KEY UPDATE`, threadID).Scan(&threadState)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock thread %d for voting: %w", threadID, err)
+	}
+	switch threadState {
+	case models.ThreadStateDeleted:
+		return nil, models.ErrNotFound
+	case models.ThreadStateLocked, models.ThreadStateArchived:
+		return nil, models.ErrThreadLocked
+	}
+
 	var oldVoice int
 	err = tx.QueryRow(ctx, `SELECT voice FROM votes WHERE thread_id = $1 AND user_nickname = $2`, threadID, nickname).Scan(&oldVoice)
 
@@ -275,7 +361,7 @@ func (s *postgresThreadStorage) UpdateThreadVote(ctx context.Context, threadID i
 
 	var updatedThread models.Thread
 	err = tx.QueryRow(ctx, `
-        SELECT id, title, author, forum, message, votes, slug, created
+        SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured
         FROM threads
         WHERE id = $1`, threadID).Scan(
 		&updatedThread.ID,
@@ -286,6 +372,10 @@ func (s *postgresThreadStorage) UpdateThreadVote(ctx context.Context, threadID i
 		&updatedThread.Votes,
 		&updatedThread.Slug,
 		&updatedThread.Created,
+		&updatedThread.State,
+		&updatedThread.Tags,
+		&updatedThread.Pinned,
+		&updatedThread.Featured,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -294,6 +384,11 @@ func (s *postgresThreadStorage) UpdateThreadVote(ctx context.Context, threadID i
 		return nil, fmt.Errorf("failed to retrieve updated thread after vote: %w", err)
 	}
 
+	err = enqueueWebhookEvent(ctx, tx, updatedThread.Forum, models.WebhookEventThreadVoted, updatedThread)
+	if err != nil {
+		return nil, err
+	}
+
 	err = tx.Commit(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to commit vote transaction: %w", err)
@@ -305,9 +400,9 @@ func (s *postgresThreadStorage) UpdateThreadVote(ctx context.Context, threadID i
 func (s *postgresThreadStorage) GetThreadBySlugOrID(ctx context.Context, slugOrID string) (*models.Thread, error) {
 	var thread models.Thread
 	query := `
-        SELECT id, title, author, forum, message, votes, slug, created
+        SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured
         FROM threads
-        WHERE slug = $1 OR id = $2::int`
+        WHERE (slug = $1 OR id = $2::int) AND state <> 'deleted'`
 
 	idInt, err := strconv.ParseInt(slugOrID, 10, 64)
 	if err != nil {
@@ -323,10 +418,18 @@ func (s *postgresThreadStorage) GetThreadBySlugOrID(ctx context.Context, slugOrI
 		&thread.Votes,
 		&thread.Slug,
 		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, models.ErrNotFound
+			threadID, err := s.threadIDBySlugAlias(ctx, slugOrID)
+			if err != nil {
+				return nil, err
+			}
+			return s.GetThreadByID(ctx, threadID)
 		}
 		return nil, fmt.Errorf("failed to get thread by slug or ID: %w", err)
 	}
@@ -336,7 +439,7 @@ func (s *postgresThreadStorage) GetThreadBySlugOrID(ctx context.Context, slugOrI
 func (s *postgresThreadStorage) GetFlatThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error) {
 
 	baseQuery := `
-        SELECT id, parent, author, message, is_edited, forum, thread_id, created, path, root_parent_id
+        SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, path, root_parent_id, score, reactions, attachments
         FROM posts
         WHERE thread_id = $1
     `
@@ -385,8 +488,8 @@ func (s *postgresThreadStorage) GetFlatThreadPosts(ctx context.Context, threadID
 	for rows.Next() {
 		var post models.Post
 		if err := rows.Scan(
-			&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
-			&post.Forum, &post.Thread, &post.Created, &post.Path, &post.RootParentID,
+			&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted,
+			&post.Forum, &post.Thread, &post.Created, &post.Path, &post.RootParentID, &post.Score, &post.Reactions, &post.Attachments,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan post in flat mode: %w", err)
 		}
@@ -402,7 +505,7 @@ func (s *postgresThreadStorage) GetFlatThreadPosts(ctx context.Context, threadID
 
 func (s *postgresThreadStorage) GetTreeThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error) {
 	baseQuery := `
-        SELECT id, parent, author, message, is_edited, forum, thread_id, created, path, root_parent_id
+        SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, path, root_parent_id, score, reactions, attachments
         FROM posts
         WHERE thread_id = $1
     `
@@ -453,8 +556,8 @@ func (s *postgresThreadStorage) GetTreeThreadPosts(ctx context.Context, threadID
 	for rows.Next() {
 		var post models.Post
 		if err := rows.Scan(
-			&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
-			&post.Forum, &post.Thread, &post.Created, &post.Path, &post.RootParentID,
+			&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted,
+			&post.Forum, &post.Thread, &post.Created, &post.Path, &post.RootParentID, &post.Score, &post.Reactions, &post.Attachments,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan post in tree mode: %w", err)
 		}
@@ -468,49 +571,83 @@ func (s *postgresThreadStorage) GetTreeThreadPosts(ctx context.Context, threadID
 	return posts, nil
 }
 
-func (s *postgresThreadStorage) UpdateThread(ctx context.Context, slugOrID string, updateData models.ThreadUpdate) (models.Thread, error) {
+func (s *postgresThreadStorage) UpdateThread(ctx context.Context, slugOrID string, updateData models.ThreadUpdate, editor string) (models.Thread, error) {
 
 	threadID, err := s.GetThreadIDBySlugOrID(ctx, slugOrID)
 	if err != nil {
 		return models.Thread{}, err
 	}
 
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return models.Thread{}, fmt.Errorf("failed to begin transaction for thread update: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var thread models.Thread
+	err = tx.QueryRow(ctx, `
+        SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured
+        FROM threads
+        WHERE id = $1
+        FOR UPDATE`, threadID).Scan(
+		&thread.ID,
+		&thread.Title,
+		&thread.Author,
+		&thread.Forum,
+		&thread.Message,
+		&thread.Votes,
+		&thread.Slug,
+		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Thread{}, models.ErrNotFound
+		}
+		return models.Thread{}, fmt.Errorf("failed to lock thread for update: %w", err)
+	}
+
 	var args []interface{}
 	paramCounter := 1
 	setClauses := ""
 
-	if updateData.Title != nil {
+	if updateData.Title != nil && *updateData.Title != thread.Title {
 		setClauses += fmt.Sprintf(`title = $%d, `, paramCounter)
 		args = append(args, *updateData.Title)
 		paramCounter++
 	}
-	if updateData.Message != nil {
+	if updateData.Message != nil && *updateData.Message != thread.Message {
 		setClauses += fmt.Sprintf(`message = $%d, `, paramCounter)
 		args = append(args, *updateData.Message)
 		paramCounter++
 	}
+	slugChanged := updateData.Slug != nil && (thread.Slug == nil || *updateData.Slug != *thread.Slug)
+	if slugChanged {
+		setClauses += fmt.Sprintf(`slug = $%d, `, paramCounter)
+		args = append(args, *updateData.Slug)
+		paramCounter++
+	}
 
-	if setClauses == "" {
-
-		query := `SELECT id, title, author, forum, message, votes, slug, created FROM threads WHERE id = $1`
-		row := s.pool.QueryRow(ctx, query, threadID)
-		err := row.Scan(
-			&thread.ID,
-			&thread.Title,
-			&thread.Author,
-			&thread.Forum,
-			&thread.Message,
-			&thread.Votes,
-			&thread.Slug,
-			&thread.Created,
-		)
+	// Теги не входят в ревизию: история хранит только заголовок, текст и slug.
+	if setClauses != "" {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO thread_revisions (thread_id, editor, title, message, slug)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5)`,
+			thread.ID, editor, thread.Title, thread.Message, thread.Slug)
 		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return models.Thread{}, models.ErrNotFound
-			}
-			return models.Thread{}, fmt.Errorf("failed to get thread after no updates: %w", err)
+			return models.Thread{}, fmt.Errorf("failed to save thread revision: %w", err)
 		}
+	}
+	if updateData.Tags != nil && !slices.Equal(*updateData.Tags, thread.Tags) {
+		setClauses += fmt.Sprintf(`tags = COALESCE($%d::text[], '{}'), `, paramCounter)
+		args = append(args, *updateData.Tags)
+		paramCounter++
+	}
+
+	if setClauses == "" {
 		return thread, nil
 	}
 
@@ -523,12 +660,11 @@ func (s *postgresThreadStorage) UpdateThread(ctx context.Context, slugOrID strin
         UPDATE threads
         SET %s
         WHERE id = $%d
-        RETURNING id, title, author, forum, message, votes, slug, created`,
+        RETURNING id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured`,
 		setClauses, whereClauseParam)
 
-	row := s.pool.QueryRow(ctx, query, args...)
-
-	err = row.Scan(
+	previousSlug := thread.Slug
+	err = tx.QueryRow(ctx, query, args...).Scan(
 		&thread.ID,
 		&thread.Title,
 		&thread.Author,
@@ -537,24 +673,77 @@ func (s *postgresThreadStorage) UpdateThread(ctx context.Context, slugOrID strin
 		&thread.Votes,
 		&thread.Slug,
 		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
 	)
-
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-
-			return models.Thread{}, models.ErrNotFound
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.Thread{}, models.ErrThreadConflict
 		}
 		return models.Thread{}, fmt.Errorf("failed to update thread: %w", err)
 	}
 
+	if slugChanged {
+		// Новый slug теперь действующий, его алиас больше не нужен.
+		_, err = tx.Exec(ctx, `DELETE FROM thread_slug_aliases WHERE slug = $1`, *updateData.Slug)
+		if err != nil {
+			return models.Thread{}, fmt.Errorf("failed to drop thread slug alias: %w", err)
+		}
+	}
+	if slugChanged && previousSlug != nil && !strings.EqualFold(*previousSlug, *updateData.Slug) {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO thread_slug_aliases (slug, thread_id)
+			VALUES ($1, $2)
+			ON CONFLICT (slug) DO UPDATE SET thread_id = EXCLUDED.thread_id, created = now()`,
+			*previousSlug, thread.ID)
+		if err != nil {
+			return models.Thread{}, fmt.Errorf("failed to save thread slug alias: %w", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return models.Thread{}, fmt.Errorf("failed to commit thread update: %w", err)
+	}
+
 	return thread, nil
 }
 
+func (s *postgresThreadStorage) GetThreadRevisions(ctx context.Context, threadID int64) ([]models.ThreadRevision, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, thread_id, COALESCE(editor, ''), title, message, slug, created
+		FROM thread_revisions
+		WHERE thread_id = $1
+		ORDER BY id`, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions of thread %d: %w", threadID, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]models.ThreadRevision, 0)
+	for rows.Next() {
+		var revision models.ThreadRevision
+		err := rows.Scan(&revision.ID, &revision.Thread, &revision.Editor, &revision.Title,
+			&revision.Message, &revision.Slug, &revision.Created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan thread revision: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error while reading thread revisions: %w", err)
+	}
+	return revisions, nil
+}
+
 func (s *postgresThreadStorage) GetThreadByID(ctx context.Context, id int64) (*models.Thread, error) {
 	query := `
-		SELECT id, title, author, forum, message, votes, slug, created
+		SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured
 		FROM threads
-		WHERE id = $1
+		WHERE id = $1 AND state <> 'deleted'
 	`
 	thread := &models.Thread{}
 	var slug sql.NullString
@@ -567,6 +756,10 @@ func (s *postgresThreadStorage) GetThreadByID(ctx context.Context, id int64) (*m
 		&thread.Votes,
 		&slug,
 		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -638,7 +831,7 @@ func (s *postgresThreadStorage) GetParentTreeThreadPosts(ctx context.Context, th
 	}
 
 	mainQuery := `
-        SELECT id, parent, author, message, is_edited, forum, thread_id, created, path, root_parent_id
+        SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, path, root_parent_id, score, reactions, attachments
         FROM posts
         WHERE thread_id = $1 AND root_parent_id = ANY($2)
     `
@@ -662,8 +855,8 @@ func (s *postgresThreadStorage) GetParentTreeThreadPosts(ctx context.Context, th
 	for rows.Next() {
 		var post models.Post
 		if err = rows.Scan(
-			&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited,
-			&post.Forum, &post.Thread, &post.Created, &post.Path, &post.RootParentID,
+			&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted,
+			&post.Forum, &post.Thread, &post.Created, &post.Path, &post.RootParentID, &post.Score, &post.Reactions, &post.Attachments,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan row for GetParentTreeThreadPosts: %w", err)
 		}
@@ -676,3 +869,337 @@ func (s *postgresThreadStorage) GetParentTreeThreadPosts(ctx context.Context, th
 
 	return posts, nil
 }
+
+// GetTopThreadPosts работает как parent_tree, но корневые посты упорядочены по score (при desc — наоборот).
+// since указывает на любой пост: выдача продолжается со следующего после его корня.
+func (s *postgresThreadStorage) GetTopThreadPosts(ctx context.Context, threadID int64, limit int, since int64, desc bool) ([]models.Post, error) {
+	rootQuery := `SELECT id FROM posts WHERE thread_id = $1 AND parent = 0`
+	rootArgs := []interface{}{threadID}
+
+	if since > 0 {
+		var sinceScore int32
+		var sinceRootID int64
+		err := s.pool.QueryRow(ctx, `
+			SELECT r.score, r.id
+			FROM posts p
+			JOIN posts r ON r.id = p.root_parent_id
+			WHERE p.id = $1 AND p.thread_id = $2`, since, threadID).Scan(&sinceScore, &sinceRootID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return []models.Post{}, nil
+			}
+			return nil, fmt.Errorf("failed to get root score for since post %d: %w", since, err)
+		}
+
+		if desc {
+			rootQuery += " AND (score > $2 OR (score = $2 AND id < $3))"
+		} else {
+			rootQuery += " AND (score < $2 OR (score = $2 AND id > $3))"
+		}
+		rootArgs = append(rootArgs, sinceScore, sinceRootID)
+	}
+
+	if desc {
+		rootQuery += " ORDER BY score ASC, id DESC"
+	} else {
+		rootQuery += " ORDER BY score DESC, id ASC"
+	}
+	rootQuery += fmt.Sprintf(" LIMIT NULLIF($%d, 0)", len(rootArgs)+1)
+	rootArgs = append(rootArgs, limit)
+
+	rootRows, err := s.pool.Query(ctx, rootQuery, rootArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query root posts for top sort: %w", err)
+	}
+	defer rootRows.Close()
+
+	var rootPostIDs []int64
+	for rootRows.Next() {
+		var id int64
+		if err := rootRows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan root post ID for top sort: %w", err)
+		}
+		rootPostIDs = append(rootPostIDs, id)
+	}
+	if err := rootRows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error in root post IDs query for top sort: %w", err)
+	}
+
+	if len(rootPostIDs) == 0 {
+		return []models.Post{}, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+        SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, path, root_parent_id, score, reactions, attachments
+        FROM posts
+        WHERE thread_id = $1 AND root_parent_id = ANY($2)
+        ORDER BY array_position($2::bigint[], root_parent_id::bigint), path ASC, id ASC`, threadID, rootPostIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query posts for top sort: %w", err)
+	}
+	defer rows.Close()
+
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		var post models.Post
+		if err = rows.Scan(
+			&post.ID, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.IsDeleted,
+			&post.Forum, &post.Thread, &post.Created, &post.Path, &post.RootParentID, &post.Score, &post.Reactions, &post.Attachments,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan post in top sort: %w", err)
+		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error in top sort: %w", err)
+	}
+
+	return posts, nil
+}
+
+func (s *postgresThreadStorage) SetThreadState(ctx context.Context, threadID int64, state string) (*models.Thread, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for thread state change: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldState, forumSlug string
+	err = tx.QueryRow(ctx, `SELECT state, forum FROM threads WHERE id = $1 FOR UPDATE`, threadID).Scan(&oldState, &forumSlug)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock thread %d for state change: %w", threadID, err)
+	}
+	if oldState == models.ThreadStateDeleted {
+		return nil, models.ErrNotFound
+	}
+
+	var thread models.Thread
+	err = tx.QueryRow(ctx, `
+        UPDATE threads
+        SET state = $1
+        WHERE id = $2
+        RETURNING id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured`, state, threadID).Scan(
+		&thread.ID,
+		&thread.Title,
+		&thread.Author,
+		&thread.Forum,
+		&thread.Message,
+		&thread.Votes,
+		&thread.Slug,
+		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update thread %d state: %w", threadID, err)
+	}
+
+	// Удалённая ветка и её посты больше не учитываются в счётчиках форума.
+	if state == models.ThreadStateDeleted {
+		_, err = tx.Exec(ctx, `
+            UPDATE forums
+            SET threads = threads - 1,
+                posts = posts - (SELECT COUNT(*) FROM posts WHERE thread_id = $1 AND NOT is_deleted)
+            WHERE slug = $2`, threadID, forumSlug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update forum counters after thread deletion: %w", err)
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit thread state change: %w", err)
+	}
+
+	return &thread, nil
+}
+
+func (s *postgresThreadStorage) SetThreadPinned(ctx context.Context, threadID int64, pinned bool) (*models.Thread, error) {
+	return s.setThreadFlag(ctx, threadID, "pinned", pinned)
+}
+
+func (s *postgresThreadStorage) SetThreadFeatured(ctx context.Context, threadID int64, featured bool) (*models.Thread, error) {
+	return s.setThreadFlag(ctx, threadID, "featured", featured)
+}
+
+// setThreadFlag меняет булев столбец ветки; column приходит только из кода, не от клиента.
+func (s *postgresThreadStorage) setThreadFlag(ctx context.Context, threadID int64, column string, value bool) (*models.Thread, error) {
+	var thread models.Thread
+	err := s.pool.QueryRow(ctx, fmt.Sprintf(`
+        UPDATE threads
+        SET %s = $1
+        WHERE id = $2 AND state <> 'deleted'
+        RETURNING id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured`, column), value, threadID).Scan(
+		&thread.ID,
+		&thread.Title,
+		&thread.Author,
+		&thread.Forum,
+		&thread.Message,
+		&thread.Votes,
+		&thread.Slug,
+		&thread.Created,
+		&thread.State,
+		&thread.Tags,
+		&thread.Pinned,
+		&thread.Featured,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to set %s of thread %d: %w", column, threadID, err)
+	}
+	return &thread, nil
+}
+
+// querier — общее у пула и транзакции для чтения.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// createThreadPoll сохраняет опрос новой ветки в той же транзакции, что и саму ветку.
+func createThreadPoll(ctx context.Context, db execer, threadID int64, poll *models.Poll) error {
+	_, err := db.Exec(ctx, `
+        INSERT INTO thread_polls (thread_id, question, multiple, closes)
+        VALUES ($1, $2, $3, $4)`, threadID, poll.Question, poll.Multiple, poll.Closes)
+	if err != nil {
+		return fmt.Errorf("failed to insert poll of thread %d: %w", threadID, err)
+	}
+
+	texts := make([]string, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		texts = append(texts, option.Text)
+	}
+	_, err = db.Exec(ctx, `
+        INSERT INTO poll_options (thread_id, option_id, text)
+        SELECT $1, o.n, o.text
+        FROM unnest($2::text[]) WITH ORDINALITY AS o(text, n)`, threadID, texts)
+	if err != nil {
+		return fmt.Errorf("failed to insert poll options of thread %d: %w", threadID, err)
+	}
+	return nil
+}
+
+// readThreadPoll читает опрос вместе с результатами, которые считаются по poll_votes.
+func readThreadPoll(ctx context.Context, db querier, threadID int64) (*models.Poll, error) {
+	poll := &models.Poll{}
+	err := db.QueryRow(ctx, `
+        SELECT question, multiple, closes, closes IS NOT NULL AND closes <= now(),
+               (SELECT count(DISTINCT user_nickname) FROM poll_votes WHERE thread_id = $1)
+        FROM thread_polls
+        WHERE thread_id = $1`, threadID).Scan(&poll.Question, &poll.Multiple, &poll.Closes, &poll.Closed, &poll.Voters)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get poll of thread %d: %w", threadID, err)
+	}
+
+	rows, err := db.Query(ctx, `
+        SELECT o.option_id, o.text, count(v.user_nickname)
+        FROM poll_options o
+        LEFT JOIN poll_votes v ON v.thread_id = o.thread_id AND v.option_id = o.option_id
+        WHERE o.thread_id = $1
+        GROUP BY o.option_id, o.text
+        ORDER BY o.option_id`, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll options of thread %d: %w", threadID, err)
+	}
+	defer rows.Close()
+
+	poll.Options = make([]models.PollOption, 0)
+	for rows.Next() {
+		var option models.PollOption
+		if err := rows.Scan(&option.ID, &option.Text, &option.Votes); err != nil {
+			return nil, fmt.Errorf("failed to scan poll option: %w", err)
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return poll, nil
+}
+
+func (s *postgresThreadStorage) GetThreadPoll(ctx context.Context, threadID int64) (*models.Poll, error) {
+	return readThreadPoll(ctx, s.pool, threadID)
+}
+
+// VoteThreadPoll заменяет выбор пользователя. Строка опроса блокируется, поэтому
+// одновременные голоса идут по очереди и не проходят после закрытия.
+func (s *postgresThreadStorage) VoteThreadPoll(ctx context.Context, threadID int64, nickname string, options []int) (*models.Poll, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction for poll vote: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Как в CreatePosts: FOR SHARE не даёт закрыть или удалить ветку, пока записывается голос.
+	var threadState string
+	err = tx.QueryRow(ctx, `SELECT state FROM threads WHERE id = $1 FOR SHARE`, threadID).Scan(&threadState)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock thread %d for poll vote: %w", threadID, err)
+	}
+	switch threadState {
+	case models.ThreadStateDeleted:
+		return nil, models.ErrNotFound
+	case models.ThreadStateLocked, models.ThreadStateArchived:
+		return nil, models.ErrThreadLocked
+	}
+
+	var closed bool
+	err = tx.QueryRow(ctx, `
+        SELECT closes IS NOT NULL AND closes <= now()
+        FROM thread_polls
+        WHERE thread_id = $1
+        FOR UPDATE`, threadID).Scan(&closed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock poll of thread %d: %w", threadID, err)
+	}
+	if closed {
+		return nil, models.ErrPollClosed
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM poll_votes WHERE thread_id = $1 AND user_nickname = $2`, threadID, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete previous poll vote: %w", err)
+	}
+
+	if len(options) > 0 {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO poll_votes (thread_id, option_id, user_nickname)
+            SELECT $1, o, $2 FROM unnest($3::int[]) AS o`, threadID, nickname, options)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				if pgErr.ConstraintName == "poll_votes_user_nickname_fkey" {
+					return nil, models.ErrOwnerNotFound
+				}
+				return nil, models.ErrInvalidPollVote
+			}
+			return nil, fmt.Errorf("failed to insert poll vote: %w", err)
+		}
+	}
+
+	poll, err := readThreadPoll(ctx, tx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit poll vote transaction: %w", err)
+	}
+	return poll, nil
+}
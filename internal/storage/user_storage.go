@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"hardhw/internal/models"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -16,6 +18,18 @@ type UserStorage interface {
 	GetUserByNickname(ctx context.Context, nickname string) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	UpdateUser(ctx context.Context, user models.User) (*models.User, error)
+	GetUserByAlias(ctx context.Context, nickname string) (*models.User, error)
+	RenameUser(ctx context.Context, nickname, newNickname string) (*models.User, error)
+	DeleteUser(ctx context.Context, nickname string, mode string) (*models.UserFiles, error)
+	ExportUser(ctx context.Context, nickname string) (*models.UserExport, error)
+	GetExistingNicknames(ctx context.Context, nicknames []string) ([]string, error)
+	// SetUserAvatar запоминает ключ новой аватарки и возвращает ключ прежней.
+	SetUserAvatar(ctx context.Context, nickname, key string) (string, error)
+	BlockUser(ctx context.Context, nickname, target string) error
+	UnblockUser(ctx context.Context, nickname, target string) error
+	GetBlockedUsers(ctx context.Context, nickname string) ([]string, error)
+	// GetBlockedBetween возвращает тех из others, с кем у nickname есть блокировка в любую сторону.
+	GetBlockedBetween(ctx context.Context, nickname string, others []string) ([]string, error)
 }
 
 type postgresUserStorage struct {
@@ -28,12 +42,12 @@ func NewPostgresUserStorage(pool *pgxpool.Pool) UserStorage {
 
 func (p *postgresUserStorage) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
-        INSERT INTO users (nickname, fullname, email, about)
-        VALUES ($1, $2, $3, $4)
+        INSERT INTO users (nickname, fullname, email, about, password_hash)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''))
         RETURNING nickname -- Возвращаем nickname
     `
 
-	err := p.pool.QueryRow(ctx, query, user.Nickname, user.Fullname, user.Email, user.About).Scan(&user.Nickname)
+	err := p.pool.QueryRow(ctx, query, user.Nickname, user.Fullname, user.Email, user.About, user.PasswordHash).Scan(&user.Nickname)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -48,13 +62,13 @@ func (p *postgresUserStorage) CreateUser(ctx context.Context, user *models.User)
 func (p *postgresUserStorage) GetUserByNickname(ctx context.Context, nickname string) (*models.User, error) {
 	var user models.User
 	query := `
-        SELECT nickname, fullname, email, about
+        SELECT nickname, fullname, email, about, avatar_key
         FROM users
         WHERE nickname = $1
     `
 	row := p.pool.QueryRow(ctx, query, nickname)
 
-	err := row.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About)
+	err := row.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About, &user.AvatarKey)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, models.ErrNotFound
@@ -68,13 +82,13 @@ func (p *postgresUserStorage) GetUserByNickname(ctx context.Context, nickname st
 func (p *postgresUserStorage) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	query := `
-        SELECT nickname, fullname, email, about
+        SELECT nickname, fullname, email, about, avatar_key
         FROM users
         WHERE email = $1
     `
 	row := p.pool.QueryRow(ctx, query, email)
 
-	err := row.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About)
+	err := row.Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About, &user.AvatarKey)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, models.ErrNotFound
@@ -90,12 +104,18 @@ func (p *postgresUserStorage) UpdateUser(ctx context.Context, user models.User)
         UPDATE users
         SET fullname = $1, email = $2, about = $3
         WHERE nickname = $4
-        RETURNING nickname, fullname, email, about -- Возвращаем без id
+        RETURNING nickname, fullname, email, about, avatar_key -- Возвращаем без id
     `
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	var updatedUser models.User
 
-	err := p.pool.QueryRow(ctx, query, user.Fullname, user.Email, user.About, user.Nickname).
-		Scan(&updatedUser.Nickname, &updatedUser.Fullname, &updatedUser.Email, &updatedUser.About)
+	err = tx.QueryRow(ctx, query, user.Fullname, user.Email, user.About, user.Nickname).
+		Scan(&updatedUser.Nickname, &updatedUser.Fullname, &updatedUser.Email, &updatedUser.About, &updatedUser.AvatarKey)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -110,5 +130,528 @@ func (p *postgresUserStorage) UpdateUser(ctx context.Context, user models.User)
 		return nil, fmt.Errorf("ошибка при обновлении пользователя в БД: %w", err)
 	}
 
+	err = enqueueUserUpdated(ctx, tx, &updatedUser)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
 	return &updatedUser, nil
 }
+
+// GetUserByAlias находит пользователя по нику, который он носил раньше.
+func (p *postgresUserStorage) GetUserByAlias(ctx context.Context, nickname string) (*models.User, error) {
+	var user models.User
+	query := `
+        SELECT u.nickname, u.fullname, u.email, u.about, u.avatar_key
+        FROM user_nickname_aliases a
+        JOIN users u ON u.nickname = a.user_nickname
+        WHERE a.nickname = $1
+    `
+	err := p.pool.QueryRow(ctx, query, nickname).Scan(&user.Nickname, &user.Fullname, &user.Email, &user.About, &user.AvatarKey)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("ошибка при запросе пользователя по прежнему nickname: %w", err)
+	}
+
+	return &user, nil
+}
+
+// RenameUser меняет nickname; ссылки в остальных таблицах обновляет ON UPDATE CASCADE,
+// а прежний nickname остаётся алиасом.
+func (p *postgresUserStorage) RenameUser(ctx context.Context, nickname, newNickname string) (*models.User, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldNickname string
+	err = tx.QueryRow(ctx, `SELECT nickname FROM users WHERE nickname = $1 FOR UPDATE`, nickname).Scan(&oldNickname)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+	}
+
+	var renamed models.User
+	err = tx.QueryRow(ctx, `
+        UPDATE users
+        SET nickname = $2
+        WHERE nickname = $1
+        RETURNING nickname, fullname, email, about, avatar_key
+    `, oldNickname, newNickname).Scan(&renamed.Nickname, &renamed.Fullname, &renamed.Email, &renamed.About, &renamed.AvatarKey)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, models.ErrUserConflict
+		}
+		return nil, fmt.Errorf("ошибка при переименовании пользователя: %w", err)
+	}
+
+	// Смена только регистра не освобождает nickname, алиас для неё не нужен.
+	if !strings.EqualFold(oldNickname, newNickname) {
+		_, err = tx.Exec(ctx, `DELETE FROM user_nickname_aliases WHERE nickname = $1`, newNickname)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при удалении алиаса: %w", err)
+		}
+		_, err = tx.Exec(ctx, `
+            INSERT INTO user_nickname_aliases (nickname, user_nickname)
+            VALUES ($1, $2)
+            ON CONFLICT (nickname) DO UPDATE SET user_nickname = EXCLUDED.user_nickname, created = now()
+        `, oldNickname, renamed.Nickname)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при сохранении алиаса: %w", err)
+		}
+	}
+
+	err = enqueueUserUpdated(ctx, tx, &renamed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+
+	return &renamed, nil
+}
+
+// DeleteUser удаляет аккаунт. Ветки, посты и форумы пользователя переходят служебному
+// пользователю models.DeletedUserNickname (его создаёт миграция 0023), голоса снимаются,
+// а из участников форумов пользователь просто пропадает. В режиме erase вдобавок
+// стираются тексты веток и постов вместе с историей правок.
+func (p *postgresUserStorage) DeleteUser(ctx context.Context, nickname string, mode string) (*models.UserFiles, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	files := &models.UserFiles{Attachments: make([]string, 0)}
+	err = tx.QueryRow(ctx, `SELECT nickname, avatar_key FROM users WHERE nickname = $1 FOR UPDATE`, nickname).Scan(&nickname, &files.Avatar)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `DELETE FROM votes WHERE user_nickname = $1 RETURNING thread_id`, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при удалении голосов: %w", err)
+	}
+	votedThreads := make([]int64, 0)
+	for rows.Next() {
+		var threadID int64
+		if err := rows.Scan(&threadID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка при чтении удалённых голосов: %w", err)
+		}
+		votedThreads = append(votedThreads, threadID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при удалении голосов: %w", err)
+	}
+	if len(votedThreads) > 0 {
+		_, err = tx.Exec(ctx, `
+            UPDATE threads t
+            SET votes = COALESCE((SELECT SUM(voice) FROM votes v WHERE v.thread_id = t.id), 0)
+            WHERE t.id = ANY($1)
+        `, votedThreads)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при пересчёте голосов: %w", err)
+		}
+	}
+
+	rows, err = tx.Query(ctx, `DELETE FROM post_reactions WHERE user_nickname = $1 RETURNING post_id`, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при удалении реакций: %w", err)
+	}
+	reactedPosts := make([]int64, 0)
+	for rows.Next() {
+		var postID int64
+		if err := rows.Scan(&postID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка при чтении удалённых реакций: %w", err)
+		}
+		reactedPosts = append(reactedPosts, postID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при удалении реакций: %w", err)
+	}
+	if len(reactedPosts) > 0 {
+		if err := recountPostReactions(ctx, tx, reactedPosts); err != nil {
+			return nil, err
+		}
+	}
+
+	if mode == models.DeleteModeErase {
+		if files.Attachments, err = erasePostgresUserContent(ctx, tx, nickname); err != nil {
+			return nil, err
+		}
+	}
+
+	reassign := []string{
+		`UPDATE threads SET author = $2 WHERE author = $1`,
+		`UPDATE posts SET author = $2 WHERE author = $1`,
+		`UPDATE attachments SET owner_nickname = $2 WHERE owner_nickname = $1`,
+		`UPDATE conversation_messages SET author = $2 WHERE author = $1`,
+		`UPDATE forums SET user_nickname = $2 WHERE user_nickname = $1`,
+	}
+	for _, query := range reassign {
+		if _, err := tx.Exec(ctx, query, nickname, models.DeletedUserNickname); err != nil {
+			return nil, fmt.Errorf("ошибка при передаче данных служебному пользователю: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM forum_users WHERE user_nickname = $1`, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при удалении пользователя из участников форумов: %w", err)
+	}
+
+	// Токены, роли, баны, блокировки и алиасы удаляются каскадно.
+	_, err = tx.Exec(ctx, `DELETE FROM users WHERE nickname = $1`, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при удалении пользователя: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return files, nil
+}
+
+// erasePostgresUserContent удаляет ветки пользователя и стирает тексты его постов,
+// поправляя счётчики форумов так же, как обычное удаление. Возвращает ключи файлов удалённых вложений.
+func erasePostgresUserContent(ctx context.Context, tx pgx.Tx, nickname string) ([]string, error) {
+	queries := []string{
+		`UPDATE forums f
+         SET threads = f.threads - c.threads, posts = f.posts - c.posts
+         FROM (
+             SELECT t.forum, COUNT(*) AS threads,
+                    SUM((SELECT COUNT(*) FROM posts p WHERE p.thread_id = t.id AND NOT p.is_deleted)) AS posts
+             FROM threads t
+             WHERE t.author = $1 AND t.state <> 'deleted'
+             GROUP BY t.forum
+         ) c
+         WHERE f.slug = c.forum`,
+		`DELETE FROM thread_revisions WHERE thread_id IN (SELECT id FROM threads WHERE author = $1)`,
+		`DELETE FROM thread_slug_aliases WHERE thread_id IN (SELECT id FROM threads WHERE author = $1)`,
+		`DELETE FROM thread_polls WHERE thread_id IN (SELECT id FROM threads WHERE author = $1)`,
+		`UPDATE threads SET state = 'deleted', title = '', message = '', slug = NULL, tags = '{}' WHERE author = $1`,
+		`UPDATE forums f
+         SET posts = f.posts - c.posts
+         FROM (
+             SELECT p.forum, COUNT(*) AS posts
+             FROM posts p
+             JOIN threads t ON t.id = p.thread_id
+             WHERE p.author = $1 AND NOT p.is_deleted AND t.state <> 'deleted'
+             GROUP BY p.forum
+         ) c
+         WHERE f.slug = c.forum`,
+		`DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM posts WHERE author = $1)`,
+		`DELETE FROM post_mentions WHERE post_id IN (SELECT id FROM posts WHERE author = $1)`,
+		`UPDATE posts SET is_deleted = TRUE, message = '', attachments = '[]' WHERE author = $1`,
+		`DELETE FROM conversation_messages WHERE author = $1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(ctx, query, nickname); err != nil {
+			return nil, fmt.Errorf("ошибка при стирании данных пользователя: %w", err)
+		}
+	}
+
+	keys, err := deleteAttachments(ctx, tx, `DELETE FROM attachments WHERE owner_nickname = $1 RETURNING storage_key`, nickname)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при удалении вложений: %w", err)
+	}
+	return keys, nil
+}
+
+// ExportUser собирает данные пользователя в одном снимке базы.
+func (p *postgresUserStorage) ExportUser(ctx context.Context, nickname string) (*models.UserExport, error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	export := &models.UserExport{
+		Exported:    time.Now(),
+		Aliases:     make([]string, 0),
+		Forums:      make([]models.Forum, 0),
+		Threads:     make([]models.Thread, 0),
+		Posts:       make([]models.Post, 0),
+		Votes:       make([]models.UserVote, 0),
+		Reactions:   make([]models.UserReaction, 0),
+		PollVotes:   make([]models.UserPollVote, 0),
+		Attachments: make([]models.Attachment, 0),
+		Messages:    make([]models.ConversationMessage, 0),
+		Blocked:     make([]string, 0),
+		Moderates:   make([]string, 0),
+		Bans:        make([]models.ForumBan, 0),
+		Tokens:      make([]models.AuthToken, 0),
+	}
+	err = tx.QueryRow(ctx, `SELECT nickname, fullname, email, about FROM users WHERE nickname = $1`, nickname).
+		Scan(&export.User.Nickname, &export.User.Fullname, &export.User.Email, &export.User.About)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("ошибка при запросе пользователя: %w", err)
+	}
+	nickname = export.User.Nickname
+
+	// Каждая выборка дописывает строки в свой срез export.
+	sections := []struct {
+		name  string
+		query string
+		scan  func(row pgx.Rows) error
+	}{
+		{"алиасов", `SELECT nickname FROM user_nickname_aliases WHERE user_nickname = $1 ORDER BY created`,
+			func(row pgx.Rows) error {
+				var alias string
+				err := row.Scan(&alias)
+				export.Aliases = append(export.Aliases, alias)
+				return err
+			}},
+		{"форумов", `SELECT title, user_nickname, slug, posts, threads FROM forums WHERE user_nickname = $1 ORDER BY slug`,
+			func(row pgx.Rows) error {
+				var f models.Forum
+				err := row.Scan(&f.Title, &f.User, &f.Slug, &f.Posts, &f.Threads)
+				export.Forums = append(export.Forums, f)
+				return err
+			}},
+		{"веток", `SELECT id, title, author, forum, message, votes, slug, created, state, tags, pinned, featured FROM threads WHERE author = $1 ORDER BY id`,
+			func(row pgx.Rows) error {
+				var t models.Thread
+				err := row.Scan(&t.ID, &t.Title, &t.Author, &t.Forum, &t.Message, &t.Votes, &t.Slug, &t.Created, &t.State, &t.Tags, &t.Pinned, &t.Featured)
+				export.Threads = append(export.Threads, t)
+				return err
+			}},
+		{"постов", `SELECT id, parent, author, message, is_edited, is_deleted, forum, thread_id, created, score, reactions, attachments FROM posts WHERE author = $1 ORDER BY id`,
+			func(row pgx.Rows) error {
+				var p models.Post
+				err := row.Scan(&p.ID, &p.Parent, &p.Author, &p.Message, &p.IsEdited, &p.IsDeleted, &p.Forum, &p.Thread, &p.Created, &p.Score, &p.Reactions, &p.Attachments)
+				export.Posts = append(export.Posts, p)
+				return err
+			}},
+		{"голосов", `SELECT thread_id, voice FROM votes WHERE user_nickname = $1 ORDER BY thread_id`,
+			func(row pgx.Rows) error {
+				var v models.UserVote
+				err := row.Scan(&v.Thread, &v.Voice)
+				export.Votes = append(export.Votes, v)
+				return err
+			}},
+		{"реакций", `SELECT post_id, kind FROM post_reactions WHERE user_nickname = $1 ORDER BY post_id, kind`,
+			func(row pgx.Rows) error {
+				var r models.UserReaction
+				err := row.Scan(&r.Post, &r.Kind)
+				export.Reactions = append(export.Reactions, r)
+				return err
+			}},
+		{"голосов в опросах", `SELECT thread_id, option_id FROM poll_votes WHERE user_nickname = $1 ORDER BY thread_id, option_id`,
+			func(row pgx.Rows) error {
+				var v models.UserPollVote
+				err := row.Scan(&v.Thread, &v.Option)
+				export.PollVotes = append(export.PollVotes, v)
+				return err
+			}},
+		{"файлов", `SELECT id, owner_nickname, filename, content_type, size, COALESCE(post_id, 0), created FROM attachments WHERE owner_nickname = $1 ORDER BY id`,
+			func(row pgx.Rows) error {
+				var a models.Attachment
+				err := row.Scan(&a.ID, &a.Owner, &a.Filename, &a.ContentType, &a.Size, &a.Post, &a.Created)
+				export.Attachments = append(export.Attachments, a)
+				return err
+			}},
+		{"сообщений", `SELECT id, conversation_id, author, message, created FROM conversation_messages WHERE author = $1 ORDER BY id`,
+			func(row pgx.Rows) error {
+				var m models.ConversationMessage
+				err := row.Scan(&m.ID, &m.Conversation, &m.Author, &m.Message, &m.Created)
+				export.Messages = append(export.Messages, m)
+				return err
+			}},
+		{"чёрного списка", `SELECT blocked_nickname FROM user_blocks WHERE blocker_nickname = $1 ORDER BY created`,
+			func(row pgx.Rows) error {
+				var blocked string
+				err := row.Scan(&blocked)
+				export.Blocked = append(export.Blocked, blocked)
+				return err
+			}},
+		{"ролей", `SELECT forum_slug FROM forum_moderators WHERE user_nickname = $1 ORDER BY forum_slug`,
+			func(row pgx.Rows) error {
+				var forumSlug string
+				err := row.Scan(&forumSlug)
+				export.Moderates = append(export.Moderates, forumSlug)
+				return err
+			}},
+		{"банов", `SELECT forum_slug, user_nickname, COALESCE(banned_by, ''), reason, expires, created FROM forum_bans WHERE user_nickname = $1 ORDER BY forum_slug`,
+			func(row pgx.Rows) error {
+				var b models.ForumBan
+				err := row.Scan(&b.Forum, &b.User, &b.BannedBy, &b.Reason, &b.Expires, &b.Created)
+				export.Bans = append(export.Bans, b)
+				return err
+			}},
+		{"блокировки", `SELECT user_nickname, COALESCE(suspended_by, ''), reason, until, created FROM user_suspensions WHERE user_nickname = $1`,
+			func(row pgx.Rows) error {
+				var s models.Suspension
+				err := row.Scan(&s.User, &s.SuspendedBy, &s.Reason, &s.Until, &s.Created)
+				export.Suspension = &s
+				return err
+			}},
+		{"токенов", `SELECT id, nickname, kind, name, created, expires FROM auth_tokens WHERE nickname = $1 ORDER BY id`,
+			func(row pgx.Rows) error {
+				var t models.AuthToken
+				err := row.Scan(&t.ID, &t.User, &t.Kind, &t.Name, &t.Created, &t.Expires)
+				export.Tokens = append(export.Tokens, t)
+				return err
+			}},
+	}
+
+	for _, section := range sections {
+		rows, err := tx.Query(ctx, section.query, nickname)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при выгрузке %s: %w", section.name, err)
+		}
+		for rows.Next() {
+			if err := section.scan(rows); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("ошибка при чтении %s: %w", section.name, err)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("ошибка при выгрузке %s: %w", section.name, err)
+		}
+	}
+
+	return export, nil
+}
+
+// GetExistingNicknames одним запросом отбирает из nicknames существующих пользователей
+// и возвращает их ники в каноничном написании.
+func (p *postgresUserStorage) GetExistingNicknames(ctx context.Context, nicknames []string) ([]string, error) {
+	existing := make([]string, 0, len(nicknames))
+	if len(nicknames) == 0 {
+		return existing, nil
+	}
+
+	rows, err := p.pool.Query(ctx, `SELECT nickname FROM users WHERE nickname = ANY($1::citext[])`, nicknames)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при поиске пользователей: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var nickname string
+		if err := rows.Scan(&nickname); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении пользователя: %w", err)
+		}
+		existing = append(existing, nickname)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении пользователей: %w", err)
+	}
+	return existing, nil
+}
+
+func (p *postgresUserStorage) SetUserAvatar(ctx context.Context, nickname, key string) (string, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("ошибка при открытии транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT avatar_key FROM users WHERE nickname = $1 FOR UPDATE`, nickname).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", models.ErrNotFound
+		}
+		return "", fmt.Errorf("ошибка при блокировке пользователя: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET avatar_key = $2 WHERE nickname = $1`, nickname, key); err != nil {
+		return "", fmt.Errorf("ошибка при сохранении аватарки: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return previous, nil
+}
+
+func (p *postgresUserStorage) BlockUser(ctx context.Context, nickname, target string) error {
+	_, err := p.pool.Exec(ctx, `
+        INSERT INTO user_blocks (blocker_nickname, blocked_nickname)
+        VALUES ($1, $2)
+        ON CONFLICT DO NOTHING
+    `, nickname, target)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ErrNotFound
+		}
+		return fmt.Errorf("ошибка при добавлении в чёрный список: %w", err)
+	}
+	return nil
+}
+
+func (p *postgresUserStorage) UnblockUser(ctx context.Context, nickname, target string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM user_blocks WHERE blocker_nickname = $1 AND blocked_nickname = $2`, nickname, target)
+	if err != nil {
+		return fmt.Errorf("ошибка при удалении из чёрного списка: %w", err)
+	}
+	return nil
+}
+
+func (p *postgresUserStorage) GetBlockedUsers(ctx context.Context, nickname string) ([]string, error) {
+	return p.queryNicknames(ctx, `
+        SELECT blocked_nickname::text
+        FROM user_blocks
+        WHERE blocker_nickname = $1
+        ORDER BY created, blocked_nickname
+    `, nickname)
+}
+
+func (p *postgresUserStorage) GetBlockedBetween(ctx context.Context, nickname string, others []string) ([]string, error) {
+	if len(others) == 0 {
+		return nil, nil
+	}
+	return p.queryNicknames(ctx, `
+        SELECT blocked_nickname::text FROM user_blocks
+        WHERE blocker_nickname = $1 AND blocked_nickname = ANY($2::citext[])
+        UNION
+        SELECT blocker_nickname::text FROM user_blocks
+        WHERE blocked_nickname = $1 AND blocker_nickname = ANY($2::citext[])
+    `, nickname, others)
+}
+
+func (p *postgresUserStorage) queryNicknames(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при запросе чёрного списка: %w", err)
+	}
+	defer rows.Close()
+
+	nicknames := make([]string, 0)
+	for rows.Next() {
+		var nickname string
+		if err := rows.Scan(&nickname); err != nil {
+			return nil, fmt.Errorf("ошибка при чтении чёрного списка: %w", err)
+		}
+		nicknames = append(nicknames, nickname)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при чтении чёрного списка: %w", err)
+	}
+	return nicknames, nil
+}
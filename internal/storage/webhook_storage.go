@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hardhw/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookStorage interface {
+	CreateWebhook(ctx context.Context, hook models.Webhook) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context, forumSlug string) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, forumSlug string, id int64) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, forumSlug string, id int64) error
+	GetDeliveries(ctx context.Context, webhookID int64, limit int, before int64) ([]models.WebhookDelivery, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDispatch, error)
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
+
+type postgresWebhookStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresWebhookStorage(pool *pgxpool.Pool) WebhookStorage {
+	return &postgresWebhookStorage{pool: pool}
+}
+
+func (s *postgresWebhookStorage) CreateWebhook(ctx context.Context, hook models.Webhook) (*models.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (forum_slug, url, secret, events, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, forum_slug, url, secret, events, COALESCE(created_by, ''), active, created`
+
+	created := &models.Webhook{}
+	err := s.pool.QueryRow(ctx, query, hook.Forum, hook.URL, hook.Secret, hook.Events, hook.CreatedBy).Scan(
+		&created.ID, &created.Forum, &created.URL, &created.Secret, &created.Events,
+		&created.CreatedBy, &created.Active, &created.Created,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to create webhook for forum %s: %w", hook.Forum, err)
+	}
+	return created, nil
+}
+
+func (s *postgresWebhookStorage) GetWebhooks(ctx context.Context, forumSlug string) ([]models.Webhook, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, forum_slug, url, events, COALESCE(created_by, ''), active, created
+		FROM webhooks
+		WHERE forum_slug = $1
+		ORDER BY id`, forumSlug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks of forum %s: %w", forumSlug, err)
+	}
+	defer rows.Close()
+
+	hooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		var hook models.Webhook
+		err := rows.Scan(&hook.ID, &hook.Forum, &hook.URL, &hook.Events, &hook.CreatedBy, &hook.Active, &hook.Created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return hooks, nil
+}
+
+func (s *postgresWebhookStorage) GetWebhook(ctx context.Context, forumSlug string, id int64) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, forum_slug, url, events, COALESCE(created_by, ''), active, created
+		FROM webhooks
+		WHERE forum_slug = $1 AND id = $2`, forumSlug, id).Scan(
+		&hook.ID, &hook.Forum, &hook.URL, &hook.Events, &hook.CreatedBy, &hook.Active, &hook.Created,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook %d: %w", id, err)
+	}
+	return hook, nil
+}
+
+func (s *postgresWebhookStorage) DeleteWebhook(ctx context.Context, forumSlug string, id int64) error {
+	commandTag, err := s.pool.Exec(ctx, `DELETE FROM webhooks WHERE forum_slug = $1 AND id = $2`, forumSlug, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %d: %w", id, err)
+	}
+	if commandTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresWebhookStorage) GetDeliveries(ctx context.Context, webhookID int64, limit int, before int64) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, event, payload, status, attempts, next_attempt, last_error, response_status, created, delivered
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`
+
+	rows, err := s.pool.Query(ctx, query, webhookID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries of webhook %d: %w", webhookID, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.Webhook, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttempt, &d.LastError, &d.ResponseStatus, &d.Created, &d.Delivered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimDeliveries берёт созревшие доставки и сдвигает их next_attempt на lease, чтобы
+// параллельные воркеры не отправили одно и то же; если воркер упадёт, доставка вернётся в очередь.
+func (s *postgresWebhookStorage) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDispatch, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt = now() + $2::float8 * interval '1 second'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt <= now()
+			ORDER BY next_attempt
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt, d.last_error, d.created, w.url, w.secret`
+
+	rows, err := s.pool.Query(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	claimed := make([]models.WebhookDispatch, 0)
+	for rows.Next() {
+		var job models.WebhookDispatch
+		d := &job.Delivery
+		err := rows.Scan(&d.ID, &d.Webhook, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttempt, &d.LastError, &d.Created, &job.URL, &job.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan claimed delivery: %w", err)
+		}
+		claimed = append(claimed, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return claimed, nil
+}
+
+func (s *postgresWebhookStorage) UpdateDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt = $4, last_error = $5, response_status = $6, delivered = $7
+		WHERE id = $1`
+
+	_, err := s.pool.Exec(ctx, query, d.ID, d.Status, d.Attempts, d.NextAttempt, d.LastError, d.ResponseStatus, d.Delivered)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery %d: %w", d.ID, err)
+	}
+	return nil
+}
+
+// enqueueWebhookEvent пишет в outbox по строке на каждую активную подписку форума,
+// ожидающую event. Вызывается внутри транзакции, меняющей данные.
+func enqueueWebhookEvent(ctx context.Context, db execer, forumSlug string, event string, data any) error {
+	payload, err := webhookPayload(event, data)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT w.id, $2, jsonb_set($3::jsonb, '{forum}', to_jsonb(w.forum_slug::text))
+		FROM webhooks w
+		WHERE w.forum_slug = $1 AND w.active AND $2 = ANY(w.events)`, forumSlug, event, payload)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s webhooks for forum %s: %w", event, forumSlug, err)
+	}
+	return nil
+}
+
+// enqueueUserUpdated рассылает событие user.updated подпискам всех форумов, где участвует пользователь.
+func enqueueUserUpdated(ctx context.Context, db execer, user *models.User) error {
+	event, nickname := models.WebhookEventUserUpdated, user.Nickname
+	payload, err := webhookPayload(event, models.WebhookUser{Nickname: user.Nickname, Fullname: user.Fullname, About: user.About})
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT w.id, $2, jsonb_set($3::jsonb, '{forum}', to_jsonb(w.forum_slug::text))
+		FROM webhooks w
+		JOIN forum_users fu ON fu.forum_slug = w.forum_slug
+		WHERE fu.user_nickname = $1 AND w.active AND $2 = ANY(w.events)`, nickname, event, payload)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s webhooks for user %s: %w", event, nickname, err)
+	}
+	return nil
+}
+
+func webhookPayload(event string, data any) (string, error) {
+	payload, err := json.Marshal(models.WebhookPayload{Event: event, Created: time.Now(), Data: data})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s webhook payload: %w", event, err)
+	}
+	return string(payload), nil
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS forum_users;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS threads;
+DROP TABLE IF EXISTS forums;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,2 @@
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_state_check;
+ALTER TABLE threads DROP COLUMN IF EXISTS state;
@@ -0,0 +1,5 @@
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'open';
+
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_state_check;
+ALTER TABLE threads ADD CONSTRAINT threads_state_check
+    CHECK (state IN ('open', 'locked', 'archived', 'deleted'));
@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT;
+
+CREATE TABLE IF NOT EXISTS auth_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    token_hash TEXT UNIQUE NOT NULL,
+    nickname   CITEXT NOT NULL REFERENCES users(nickname) ON DELETE CASCADE,
+    kind       TEXT NOT NULL CHECK (kind IN ('session', 'api')),
+    name       TEXT NOT NULL DEFAULT '',
+    created    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_tokens_nickname ON auth_tokens (nickname);
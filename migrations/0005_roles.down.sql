@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS forum_bans;
+DROP TABLE IF EXISTS forum_moderators;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
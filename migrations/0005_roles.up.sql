@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS forum_moderators (
+    forum_slug    CITEXT NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON DELETE CASCADE,
+    granted_by    CITEXT REFERENCES users(nickname) ON DELETE SET NULL,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (forum_slug, user_nickname)
+);
+
+CREATE TABLE IF NOT EXISTS forum_bans (
+    forum_slug    CITEXT NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON DELETE CASCADE,
+    banned_by     CITEXT REFERENCES users(nickname) ON DELETE SET NULL,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (forum_slug, user_nickname)
+);
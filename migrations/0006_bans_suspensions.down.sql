@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_suspensions;
+ALTER TABLE forum_bans DROP COLUMN IF EXISTS expires;
+ALTER TABLE forum_bans DROP COLUMN IF EXISTS reason;
@@ -0,0 +1,10 @@
+ALTER TABLE forum_bans ADD COLUMN IF NOT EXISTS reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE forum_bans ADD COLUMN IF NOT EXISTS expires TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS user_suspensions (
+    user_nickname CITEXT PRIMARY KEY REFERENCES users(nickname) ON DELETE CASCADE,
+    reason        TEXT NOT NULL DEFAULT '',
+    until         TIMESTAMP WITH TIME ZONE,
+    suspended_by  CITEXT REFERENCES users(nickname) ON DELETE SET NULL,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_threads_search_vector;
+DROP INDEX IF EXISTS idx_posts_search_vector;
+ALTER TABLE threads DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         BIGSERIAL PRIMARY KEY,
+    forum_slug CITEXT NOT NULL REFERENCES forums(slug) ON DELETE CASCADE,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    events     TEXT[] NOT NULL,
+    created_by CITEXT REFERENCES users(nickname) ON DELETE SET NULL,
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    created    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_forum ON webhooks (forum_slug) WHERE active;
+
+-- Outbox: строки пишутся в той же транзакции, что и само событие.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event           TEXT NOT NULL,
+    payload         JSONB NOT NULL,
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts        INT NOT NULL DEFAULT 0,
+    next_attempt    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    last_error      TEXT NOT NULL DEFAULT '',
+    response_status INT,
+    created         TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    delivered       TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, id DESC);
@@ -0,0 +1,10 @@
+-- Каждая правка поста сохраняет текст, который был до неё.
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id      BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    editor  CITEXT REFERENCES users(nickname) ON DELETE SET NULL,
+    message TEXT NOT NULL,
+    created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post ON post_revisions (post_id, id);
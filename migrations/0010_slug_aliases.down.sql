@@ -0,0 +1,27 @@
+ALTER TABLE webhooks DROP CONSTRAINT IF EXISTS webhooks_forum_slug_fkey;
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug) ON DELETE CASCADE;
+
+ALTER TABLE forum_bans DROP CONSTRAINT IF EXISTS forum_bans_forum_slug_fkey;
+ALTER TABLE forum_bans ADD CONSTRAINT forum_bans_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug) ON DELETE CASCADE;
+
+ALTER TABLE forum_moderators DROP CONSTRAINT IF EXISTS forum_moderators_forum_slug_fkey;
+ALTER TABLE forum_moderators ADD CONSTRAINT forum_moderators_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug) ON DELETE CASCADE;
+
+ALTER TABLE forum_users DROP CONSTRAINT IF EXISTS forum_users_forum_slug_fkey;
+ALTER TABLE forum_users ADD CONSTRAINT forum_users_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug);
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_forum_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_forum_fkey
+    FOREIGN KEY (forum) REFERENCES forums(slug);
+
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_forum_fkey;
+ALTER TABLE threads ADD CONSTRAINT threads_forum_fkey
+    FOREIGN KEY (forum) REFERENCES forums(slug);
+
+DROP TABLE IF EXISTS forum_slug_aliases;
+DROP TABLE IF EXISTS thread_slug_aliases;
+DROP TABLE IF EXISTS thread_revisions;
@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS thread_revisions (
+    id        BIGSERIAL PRIMARY KEY,
+    thread_id INT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    editor    CITEXT REFERENCES users(nickname) ON DELETE SET NULL,
+    title     VARCHAR(255) NOT NULL,
+    message   TEXT NOT NULL,
+    slug      CITEXT,
+    created   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_thread_revisions_thread ON thread_revisions (thread_id, id);
+
+-- Старые slug продолжают открывать ветку или форум, пока их не займёт кто-то другой.
+CREATE TABLE IF NOT EXISTS thread_slug_aliases (
+    slug      CITEXT PRIMARY KEY,
+    thread_id INT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    created   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS forum_slug_aliases (
+    slug       CITEXT PRIMARY KEY,
+    forum_slug CITEXT NOT NULL REFERENCES forums(slug) ON UPDATE CASCADE ON DELETE CASCADE,
+    created    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Переименование форума меняет forums.slug, поэтому ссылки на него должны обновляться каскадно.
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_forum_fkey;
+ALTER TABLE threads ADD CONSTRAINT threads_forum_fkey
+    FOREIGN KEY (forum) REFERENCES forums(slug) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE threads VALIDATE CONSTRAINT threads_forum_fkey;
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_forum_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_forum_fkey
+    FOREIGN KEY (forum) REFERENCES forums(slug) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE posts VALIDATE CONSTRAINT posts_forum_fkey;
+
+ALTER TABLE forum_users DROP CONSTRAINT IF EXISTS forum_users_forum_slug_fkey;
+ALTER TABLE forum_users ADD CONSTRAINT forum_users_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE forum_users VALIDATE CONSTRAINT forum_users_forum_slug_fkey;
+
+ALTER TABLE forum_moderators DROP CONSTRAINT IF EXISTS forum_moderators_forum_slug_fkey;
+ALTER TABLE forum_moderators ADD CONSTRAINT forum_moderators_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE forum_bans DROP CONSTRAINT IF EXISTS forum_bans_forum_slug_fkey;
+ALTER TABLE forum_bans ADD CONSTRAINT forum_bans_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE webhooks DROP CONSTRAINT IF EXISTS webhooks_forum_slug_fkey;
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_forum_slug_fkey
+    FOREIGN KEY (forum_slug) REFERENCES forums(slug) ON UPDATE CASCADE ON DELETE CASCADE;
@@ -0,0 +1,61 @@
+ALTER TABLE thread_revisions DROP CONSTRAINT IF EXISTS thread_revisions_editor_fkey;
+ALTER TABLE thread_revisions ADD CONSTRAINT thread_revisions_editor_fkey
+    FOREIGN KEY (editor) REFERENCES users(nickname) ON DELETE SET NULL;
+
+ALTER TABLE post_revisions DROP CONSTRAINT IF EXISTS post_revisions_editor_fkey;
+ALTER TABLE post_revisions ADD CONSTRAINT post_revisions_editor_fkey
+    FOREIGN KEY (editor) REFERENCES users(nickname) ON DELETE SET NULL;
+
+ALTER TABLE webhooks DROP CONSTRAINT IF EXISTS webhooks_created_by_fkey;
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_created_by_fkey
+    FOREIGN KEY (created_by) REFERENCES users(nickname) ON DELETE SET NULL;
+
+ALTER TABLE user_suspensions DROP CONSTRAINT IF EXISTS user_suspensions_suspended_by_fkey;
+ALTER TABLE user_suspensions ADD CONSTRAINT user_suspensions_suspended_by_fkey
+    FOREIGN KEY (suspended_by) REFERENCES users(nickname) ON DELETE SET NULL;
+
+ALTER TABLE user_suspensions DROP CONSTRAINT IF EXISTS user_suspensions_user_nickname_fkey;
+ALTER TABLE user_suspensions ADD CONSTRAINT user_suspensions_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON DELETE CASCADE;
+
+ALTER TABLE forum_bans DROP CONSTRAINT IF EXISTS forum_bans_banned_by_fkey;
+ALTER TABLE forum_bans ADD CONSTRAINT forum_bans_banned_by_fkey
+    FOREIGN KEY (banned_by) REFERENCES users(nickname) ON DELETE SET NULL;
+
+ALTER TABLE forum_bans DROP CONSTRAINT IF EXISTS forum_bans_user_nickname_fkey;
+ALTER TABLE forum_bans ADD CONSTRAINT forum_bans_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON DELETE CASCADE;
+
+ALTER TABLE forum_moderators DROP CONSTRAINT IF EXISTS forum_moderators_granted_by_fkey;
+ALTER TABLE forum_moderators ADD CONSTRAINT forum_moderators_granted_by_fkey
+    FOREIGN KEY (granted_by) REFERENCES users(nickname) ON DELETE SET NULL;
+
+ALTER TABLE forum_moderators DROP CONSTRAINT IF EXISTS forum_moderators_user_nickname_fkey;
+ALTER TABLE forum_moderators ADD CONSTRAINT forum_moderators_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON DELETE CASCADE;
+
+ALTER TABLE auth_tokens DROP CONSTRAINT IF EXISTS auth_tokens_nickname_fkey;
+ALTER TABLE auth_tokens ADD CONSTRAINT auth_tokens_nickname_fkey
+    FOREIGN KEY (nickname) REFERENCES users(nickname) ON DELETE CASCADE;
+
+ALTER TABLE forum_users DROP CONSTRAINT IF EXISTS forum_users_user_nickname_fkey;
+ALTER TABLE forum_users ADD CONSTRAINT forum_users_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname);
+
+ALTER TABLE votes DROP CONSTRAINT IF EXISTS votes_user_nickname_fkey;
+ALTER TABLE votes ADD CONSTRAINT votes_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname);
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_author_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_author_fkey
+    FOREIGN KEY (author) REFERENCES users(nickname);
+
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_author_fkey;
+ALTER TABLE threads ADD CONSTRAINT threads_author_fkey
+    FOREIGN KEY (author) REFERENCES users(nickname);
+
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS forums_user_nickname_fkey;
+ALTER TABLE forums ADD CONSTRAINT forums_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname);
+
+DROP TABLE IF EXISTS user_nickname_aliases;
@@ -0,0 +1,72 @@
+-- Прежние ники продолжают открывать профиль, пока их не займёт кто-то другой.
+CREATE TABLE IF NOT EXISTS user_nickname_aliases (
+    nickname      CITEXT PRIMARY KEY,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Переименование меняет users.nickname, поэтому все ссылки на пользователя обновляются каскадно.
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS forums_user_nickname_fkey;
+ALTER TABLE forums ADD CONSTRAINT forums_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE forums VALIDATE CONSTRAINT forums_user_nickname_fkey;
+
+ALTER TABLE threads DROP CONSTRAINT IF EXISTS threads_author_fkey;
+ALTER TABLE threads ADD CONSTRAINT threads_author_fkey
+    FOREIGN KEY (author) REFERENCES users(nickname) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE threads VALIDATE CONSTRAINT threads_author_fkey;
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_author_fkey;
+ALTER TABLE posts ADD CONSTRAINT posts_author_fkey
+    FOREIGN KEY (author) REFERENCES users(nickname) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE posts VALIDATE CONSTRAINT posts_author_fkey;
+
+ALTER TABLE votes DROP CONSTRAINT IF EXISTS votes_user_nickname_fkey;
+ALTER TABLE votes ADD CONSTRAINT votes_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE votes VALIDATE CONSTRAINT votes_user_nickname_fkey;
+
+ALTER TABLE forum_users DROP CONSTRAINT IF EXISTS forum_users_user_nickname_fkey;
+ALTER TABLE forum_users ADD CONSTRAINT forum_users_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON UPDATE CASCADE NOT VALID;
+ALTER TABLE forum_users VALIDATE CONSTRAINT forum_users_user_nickname_fkey;
+
+ALTER TABLE auth_tokens DROP CONSTRAINT IF EXISTS auth_tokens_nickname_fkey;
+ALTER TABLE auth_tokens ADD CONSTRAINT auth_tokens_nickname_fkey
+    FOREIGN KEY (nickname) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE forum_moderators DROP CONSTRAINT IF EXISTS forum_moderators_user_nickname_fkey;
+ALTER TABLE forum_moderators ADD CONSTRAINT forum_moderators_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE forum_moderators DROP CONSTRAINT IF EXISTS forum_moderators_granted_by_fkey;
+ALTER TABLE forum_moderators ADD CONSTRAINT forum_moderators_granted_by_fkey
+    FOREIGN KEY (granted_by) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE forum_bans DROP CONSTRAINT IF EXISTS forum_bans_user_nickname_fkey;
+ALTER TABLE forum_bans ADD CONSTRAINT forum_bans_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE forum_bans DROP CONSTRAINT IF EXISTS forum_bans_banned_by_fkey;
+ALTER TABLE forum_bans ADD CONSTRAINT forum_bans_banned_by_fkey
+    FOREIGN KEY (banned_by) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE user_suspensions DROP CONSTRAINT IF EXISTS user_suspensions_user_nickname_fkey;
+ALTER TABLE user_suspensions ADD CONSTRAINT user_suspensions_user_nickname_fkey
+    FOREIGN KEY (user_nickname) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE user_suspensions DROP CONSTRAINT IF EXISTS user_suspensions_suspended_by_fkey;
+ALTER TABLE user_suspensions ADD CONSTRAINT user_suspensions_suspended_by_fkey
+    FOREIGN KEY (suspended_by) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE webhooks DROP CONSTRAINT IF EXISTS webhooks_created_by_fkey;
+ALTER TABLE webhooks ADD CONSTRAINT webhooks_created_by_fkey
+    FOREIGN KEY (created_by) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE post_revisions DROP CONSTRAINT IF EXISTS post_revisions_editor_fkey;
+ALTER TABLE post_revisions ADD CONSTRAINT post_revisions_editor_fkey
+    FOREIGN KEY (editor) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE thread_revisions DROP CONSTRAINT IF EXISTS thread_revisions_editor_fkey;
+ALTER TABLE thread_revisions ADD CONSTRAINT thread_revisions_editor_fkey
+    FOREIGN KEY (editor) REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE SET NULL;
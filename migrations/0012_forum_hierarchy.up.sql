@@ -0,0 +1,9 @@
+-- Форум может быть вложен в другой форум или категорию; переименование родителя обновляет ссылку.
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS parent CITEXT
+    REFERENCES forums(slug) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE forums ADD COLUMN IF NOT EXISTS category BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE forums DROP CONSTRAINT IF EXISTS forums_parent_check;
+ALTER TABLE forums ADD CONSTRAINT forums_parent_check CHECK (parent IS NULL OR parent <> slug);
+
+CREATE INDEX IF NOT EXISTS idx_forums_parent ON forums (parent);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_threads_tags;
+ALTER TABLE threads DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,4 @@
+-- Теги ветки хранятся уже нормализованными: в нижнем регистре, без повторов, по алфавиту.
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_threads_tags ON threads USING GIN (tags);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_threads_featured;
+DROP INDEX IF EXISTS idx_threads_pinned;
+ALTER TABLE threads DROP COLUMN IF EXISTS featured;
+ALTER TABLE threads DROP COLUMN IF EXISTS pinned;
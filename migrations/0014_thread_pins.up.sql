@@ -0,0 +1,6 @@
+-- Закреплённые ветки идут первыми в списке своего форума, избранные собираются в общий список сайта.
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS featured BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_threads_pinned ON threads (forum) WHERE pinned;
+CREATE INDEX IF NOT EXISTS idx_threads_featured ON threads (created) WHERE featured;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_thread_root_score;
+ALTER TABLE posts DROP COLUMN IF EXISTS reactions;
+ALTER TABLE posts DROP COLUMN IF EXISTS score;
+DROP TABLE IF EXISTS post_reactions;
@@ -0,0 +1,16 @@
+-- Реакции на посты: голос «за» или «против» и эмодзи. Один пользователь ставит каждую реакцию не больше одного раза.
+CREATE TABLE IF NOT EXISTS post_reactions (
+    post_id       INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    kind          TEXT NOT NULL,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (post_id, user_nickname, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reactions_user ON post_reactions (user_nickname);
+
+-- Счётчики хранятся в самом посте, как votes у веток: списки постов их не пересчитывают.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS score INT NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reactions JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_posts_thread_root_score ON posts (thread_id, score DESC, id ASC) WHERE parent = 0;
@@ -0,0 +1,27 @@
+-- Опрос ветки: не больше одного на ветку, создаётся вместе с ней.
+CREATE TABLE IF NOT EXISTS thread_polls (
+    thread_id INT PRIMARY KEY REFERENCES threads(id) ON DELETE CASCADE,
+    question  TEXT NOT NULL,
+    multiple  BOOLEAN NOT NULL DEFAULT FALSE,
+    closes    TIMESTAMP WITH TIME ZONE
+);
+
+-- Варианты нумеруются с 1 внутри опроса.
+CREATE TABLE IF NOT EXISTS poll_options (
+    thread_id INT NOT NULL REFERENCES thread_polls(thread_id) ON DELETE CASCADE,
+    option_id INT NOT NULL,
+    text      TEXT NOT NULL,
+    PRIMARY KEY (thread_id, option_id)
+);
+
+-- Результаты считаются по этой таблице при чтении, поэтому удаление пользователя их не портит.
+CREATE TABLE IF NOT EXISTS poll_votes (
+    thread_id     INT NOT NULL,
+    option_id     INT NOT NULL,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (thread_id, user_nickname, option_id),
+    FOREIGN KEY (thread_id, option_id) REFERENCES poll_options(thread_id, option_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_user ON poll_votes (user_nickname);
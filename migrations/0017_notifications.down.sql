@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_not_notified;
+ALTER TABLE posts DROP COLUMN IF EXISTS notified;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS forum_subscriptions;
+DROP TABLE IF EXISTS thread_subscriptions;
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS thread_subscriptions (
+    thread_id     INT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (thread_id, user_nickname)
+);
+
+CREATE TABLE IF NOT EXISTS forum_subscriptions (
+    forum_slug    CITEXT NOT NULL REFERENCES forums(slug) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (forum_slug, user_nickname)
+);
+
+CREATE INDEX IF NOT EXISTS idx_thread_subscriptions_user ON thread_subscriptions (user_nickname);
+CREATE INDEX IF NOT EXISTS idx_forum_subscriptions_user ON forum_subscriptions (user_nickname);
+
+-- Одно уведомление на пользователя и пост; автор, ветка и форум берутся из поста при чтении.
+CREATE TABLE IF NOT EXISTS notifications (
+    id            BIGSERIAL PRIMARY KEY,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    kind          TEXT NOT NULL,
+    post_id       INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read          BOOLEAN NOT NULL DEFAULT FALSE,
+    created       TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    UNIQUE (user_nickname, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_nickname, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_post ON notifications (post_id);
+
+-- Рассылку делает фоновый воркер: вставка поста только оставляет notified = FALSE.
+-- Уже существующие посты помечаются разосланными, чтобы воркер не разбирал всю историю.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS notified BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE posts ALTER COLUMN notified SET DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_posts_not_notified ON posts (id) WHERE NOT notified;
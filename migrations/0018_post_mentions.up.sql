@@ -0,0 +1,8 @@
+-- Упоминания @nickname в постах. Хранятся только существующие пользователи.
+CREATE TABLE IF NOT EXISTS post_mentions (
+    post_id       INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (post_id, user_nickname)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_mentions_user_post ON post_mentions (user_nickname, post_id DESC);
@@ -0,0 +1,17 @@
+-- Загруженные файлы. Сами данные лежат в хранилище блобов под ключом storage_key.
+CREATE TABLE IF NOT EXISTS attachments (
+    id             BIGSERIAL PRIMARY KEY,
+    owner_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    filename       TEXT NOT NULL,
+    content_type   TEXT NOT NULL,
+    size           BIGINT NOT NULL,
+    storage_key    TEXT NOT NULL UNIQUE,
+    post_id        INT REFERENCES posts(id) ON DELETE CASCADE,
+    created        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_owner ON attachments (owner_nickname);
+CREATE INDEX IF NOT EXISTS idx_attachments_post ON attachments (post_id) WHERE post_id IS NOT NULL;
+
+-- Описания вложений хранятся и в самом посте, как reactions: списки постов их не собирают.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS attachments JSONB NOT NULL DEFAULT '[]';
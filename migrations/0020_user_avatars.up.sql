@@ -0,0 +1,2 @@
+-- Ключ аватарки в хранилище блобов; уменьшенные копии лежат под ключами "<key>-<размер>".
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_key TEXT NOT NULL DEFAULT '';
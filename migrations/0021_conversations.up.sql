@@ -0,0 +1,37 @@
+-- Чёрный список: между blocker и blocked личные сообщения запрещены в обе стороны.
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    blocked_nickname CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    created          TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (blocker_nickname, blocked_nickname)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks (blocked_nickname);
+
+-- updated сдвигается при каждом сообщении: по нему упорядочен список переписок.
+CREATE TABLE IF NOT EXISTS conversations (
+    id      BIGSERIAL PRIMARY KEY,
+    created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- last_read — id последнего прочитанного участником сообщения.
+CREATE TABLE IF NOT EXISTS conversation_members (
+    conversation_id BIGINT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_nickname   CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE ON DELETE CASCADE,
+    last_read       BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (conversation_id, user_nickname)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_members_user ON conversation_members (user_nickname);
+
+CREATE TABLE IF NOT EXISTS conversation_messages (
+    id              BIGSERIAL PRIMARY KEY,
+    conversation_id BIGINT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    author          CITEXT NOT NULL REFERENCES users(nickname) ON UPDATE CASCADE,
+    message         TEXT NOT NULL,
+    created         TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_messages_conversation ON conversation_messages (conversation_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_conversation_messages_author ON conversation_messages (author);
@@ -0,0 +1,9 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS notified BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE posts ALTER COLUMN notified SET DEFAULT FALSE;
+
+UPDATE posts SET notified = FALSE
+WHERE id IN (SELECT post_id FROM post_notification_queue);
+
+CREATE INDEX IF NOT EXISTS idx_posts_not_notified ON posts (id) WHERE NOT notified;
+
+DROP TABLE IF EXISTS post_notification_queue;
@@ -0,0 +1,13 @@
+-- Outbox уведомлений: строка пишется в той же транзакции, что и пост, и удаляется,
+-- когда воркер разослал уведомления. Заменяет флаг posts.notified из 0017.
+CREATE TABLE IF NOT EXISTS post_notification_queue (
+    post_id INT PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+INSERT INTO post_notification_queue (post_id)
+SELECT id FROM posts WHERE NOT notified
+ON CONFLICT (post_id) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_posts_not_notified;
+ALTER TABLE posts DROP COLUMN IF EXISTS notified;
@@ -0,0 +1,7 @@
+-- Служебный пользователь остаётся: на него ссылаются ветки и посты удалённых аккаунтов.
+-- Как и до 0023, он снова участник форумов, где у него есть ветки или посты.
+INSERT INTO forum_users (forum_slug, user_nickname)
+SELECT forum, author FROM threads WHERE author = 'deleted'
+UNION
+SELECT forum, author FROM posts WHERE author = 'deleted'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,36 @@
+-- Служебный пользователь 'deleted' (models.DeletedUserNickname), которому переходят ветки,
+-- посты и форумы удалённых аккаунтов. Раньше он создавался при первом удалении аккаунта
+-- и не создавался вовсе, если его адрес уже занят.
+--
+-- До 0023 ник 'deleted' не был зарезервирован, и его мог занять обычный пользователь.
+-- Такой аккаунт нельзя молча сделать служебным: ему достались бы все анонимизированные
+-- ветки и посты, а пароль и сессии остались бы в силе. Прежний служебный пользователь
+-- узнаётся по адресу 'deleted@deleted.invalid' и отсутствию пароля; с любым другим
+-- аккаунтом 'deleted' миграция останавливается, и его нужно сначала переименовать
+-- вручную: ссылки на ник обновит ON UPDATE CASCADE из 0011.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM users
+        WHERE nickname = 'deleted'
+          AND (email <> 'deleted@deleted.invalid' OR password_hash IS NOT NULL)
+    ) THEN
+        RAISE EXCEPTION 'nickname "deleted" is taken by a regular account'
+            USING HINT = 'rename it before applying migration 0023, e.g. UPDATE users SET nickname = ''deleted_user'' WHERE nickname = ''deleted''';
+    END IF;
+END
+$$;
+
+-- Адрес служебного пользователя тоже мог быть занят; тогда берётся уникальный вариант
+-- (md5 встроен в PostgreSQL и не требует pgcrypto).
+INSERT INTO users (nickname, fullname, email, about)
+SELECT 'deleted', 'Deleted user',
+       CASE WHEN EXISTS (SELECT 1 FROM users WHERE email = 'deleted@deleted.invalid')
+            THEN 'deleted+' || md5(random()::text || clock_timestamp()::text) || '@deleted.invalid'
+            ELSE 'deleted@deleted.invalid'
+       END,
+       ''
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE nickname = 'deleted');
+
+-- Участником форумов служебный пользователь не считается.
+DELETE FROM forum_users WHERE user_nickname = 'deleted';